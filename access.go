@@ -0,0 +1,231 @@
+package loggo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// combinedTimeFormat は Apache の %t の時刻の書式
+const combinedTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogOptions は AccessLogMiddleware の設定
+type AccessLogOptions struct {
+	// Level はリクエストを記録するレベル。nil の場合は slog.LevelInfo です。
+	// ステータスコードが 500 以上のレスポンスは WARN 以上で記録します。
+	Level slog.Leveler
+	// Combined は Apache/NCSA の combined 形式の行を書き込む出力先。nil の場合は書き込みません。
+	// GoAccess や AWStats など combined 形式を前提とするツールに渡すために使います。
+	// AccessLogMiddleware の logger が nil の場合は、構造化したレコードを記録せずにこの形式だけを書き込みます。
+	Combined io.Writer
+}
+
+// AccessLogMiddleware は処理したリクエストごとに、メソッド、パス、ステータスコード、
+// レスポンスのバイト数、所要時間などを "http access" のレコードとして記録するミドルウェアを返します。
+// 属性のキーは method, path, query, proto, host, remote_addr, user_agent, referer, status, bytes, elapsed です
+// （query と referer は空の場合に省きます）。
+//
+// 記録はリクエストのコンテキストで行うため、RequestIDMiddleware の内側で使うとリクエスト ID が付加されます。
+// AccessLogOptions.Combined を指定すると、Apache の combined 形式の行も書き込みます。
+//
+//	http.ListenAndServe(":8080", golog.RequestIDMiddleware(golog.AccessLogMiddleware(logger, nil)(mux)))
+func AccessLogMiddleware(logger *slog.Logger, opts *AccessLogOptions) func(http.Handler) http.Handler {
+	var level slog.Leveler = slog.LevelInfo
+	var combined *combinedWriter
+	if opts != nil {
+		if opts.Level != nil {
+			level = opts.Level
+		}
+		if opts.Combined != nil {
+			combined = &combinedWriter{w: opts.Combined}
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			logEnabled := logger != nil && logger.Enabled(ctx, max(level.Level(), slog.LevelWarn))
+			if !logEnabled && combined == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := time.Now()
+			rw := &accessResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, r)
+			elapsed := time.Since(start)
+
+			status := rw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if combined != nil {
+				combined.write(r, start, status, rw.bytes)
+			}
+			if !logEnabled {
+				return
+			}
+			attrs := make([]slog.Attr, 0, 11)
+			attrs = append(attrs,
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.EscapedPath()),
+			)
+			if r.URL.RawQuery != "" {
+				attrs = append(attrs, slog.String("query", r.URL.RawQuery))
+			}
+			attrs = append(attrs,
+				slog.String("proto", r.Proto),
+				slog.String("host", r.Host),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("user_agent", r.UserAgent()),
+			)
+			if referer := r.Referer(); referer != "" {
+				attrs = append(attrs, slog.String("referer", referer))
+			}
+			attrs = append(attrs,
+				slog.Int("status", status),
+				slog.Int64("bytes", rw.bytes),
+				slog.Duration(ElapsedKey, elapsed),
+			)
+
+			lvl := level.Level()
+			if status >= http.StatusInternalServerError {
+				lvl = max(lvl, slog.LevelWarn)
+			}
+			logger.LogAttrs(ctx, lvl, "http access", attrs...)
+		})
+	}
+}
+
+// accessResponseWriter はステータスコードと書き込んだバイト数を記録する http.ResponseWriter
+type accessResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WriteHeader はステータスコードを記録します
+func (w *accessResponseWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= 200 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write は書き込んだバイト数を記録します
+func (w *accessResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush は元の http.ResponseWriter が http.Flusher を実装している場合にフラッシュします
+func (w *accessResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack は元の http.ResponseWriter が http.Hijacker を実装している場合に接続を引き渡します。
+// WebSocket などでステータスコードを書き込まずに引き渡した場合は 101 として記録します。
+func (w *accessResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("golog: %T does not implement http.Hijacker: %w", w.ResponseWriter, http.ErrNotSupported)
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap は http.ResponseController のために元の http.ResponseWriter を返します
+func (w *accessResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// combinedWriter は Apache の combined 形式の行を書き込みます
+type combinedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// write は %h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i" の1行を書き込みます。
+// 書き込みのエラーはリクエストの処理に影響させないため無視します。
+func (c *combinedWriter) write(r *http.Request, start time.Time, status int, bytes int64) {
+	b := make([]byte, 0, 256)
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	b = appendCombinedField(b, host)
+	b = append(b, " - "...)
+	user, _, _ := r.BasicAuth()
+	b = appendCombinedField(b, user)
+	b = append(b, " ["...)
+	b = start.AppendFormat(b, combinedTimeFormat)
+	b = append(b, "] \""...)
+	b = appendCombinedEscaped(b, r.Method+" "+r.URL.RequestURI()+" "+r.Proto)
+	b = append(b, "\" "...)
+	b = strconv.AppendInt(b, int64(status), 10)
+	b = append(b, ' ')
+	if bytes > 0 {
+		b = strconv.AppendInt(b, bytes, 10)
+	} else {
+		b = append(b, '-')
+	}
+	b = append(b, " \""...)
+	b = appendCombinedEscaped(b, r.Referer())
+	b = append(b, "\" \""...)
+	b = appendCombinedEscaped(b, r.UserAgent())
+	b = append(b, "\"\n"...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.w.Write(b)
+}
+
+// appendCombinedField は空白を含まないフィールドを書き込みます。空の場合は "-" とします。
+func appendCombinedField(b []byte, s string) []byte {
+	if s == "" {
+		return append(b, '-')
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c == 0x7f || c == '"' {
+			b = append(b, '_')
+		} else {
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
+// appendCombinedEscaped は Apache と同じく " と \ をバックスラッシュで、制御文字を \xhh でエスケープして書き込みます。
+// 空の場合は "-" とします。
+func appendCombinedEscaped(b []byte, s string) []byte {
+	if s == "" {
+		return append(b, '-')
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c < ' ' || c == 0x7f:
+			b = append(b, '\\', 'x', hexDigits[c>>4], hexDigits[c&15])
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
@@ -0,0 +1,122 @@
+package loggo
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAccessLogMiddleware はリクエストのメソッド、パス、ステータスコード、バイト数を記録することをテストします
+func TestAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{Format: FormatJSON}))
+	h := RequestIDMiddleware(AccessLogMiddleware(logger, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "hello")
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/orders?id=1", nil)
+	req.Header.Set("User-Agent", "curl/8.0")
+	req.Header.Set(RequestIDHeader, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := buf.String()
+	for _, want := range []string{
+		`"level":"INFO"`, `"msg":"http access"`, `"method":"POST"`, `"path":"/orders"`, `"query":"id=1"`,
+		`"user_agent":"curl/8.0"`, `"status":201`, `"bytes":5`, `"request_id":"req-1"`,
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("%s not found in %s", want, line)
+		}
+	}
+	if strings.Contains(line, "referer") {
+		t.Errorf("empty referer should be omitted: %s", line)
+	}
+}
+
+// TestAccessLogMiddlewareServerError はステータスコードが 500 以上のレスポンスを WARN で記録することをテストします
+func TestAccessLogMiddlewareServerError(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{Format: FormatJSON, Level: slog.LevelWarn}))
+	h := AccessLogMiddleware(logger, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusBadGateway)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if n := strings.Count(buf.String(), `"level":"WARN"`); n != 2 {
+		t.Errorf("expected 2 WARN records, got %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"status":502`) {
+		t.Errorf("status not found: %s", buf.String())
+	}
+}
+
+// TestAccessLogMiddlewareHijack は WebSocket などのために接続を引き渡せることをテストします
+func TestAccessLogMiddlewareHijack(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{Format: FormatJSON}))
+	h := AccessLogMiddleware(logger, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok")
+		rw.Flush()
+	}))
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		close(done)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("body = %q", body)
+	}
+	if _, ok := any(&accessResponseWriter{}).(http.Hijacker); !ok {
+		t.Error("accessResponseWriter should implement http.Hijacker")
+	}
+	<-done // 引き渡した接続は srv.Close で待てないため、ハンドラーの完了を待つ
+	if !strings.Contains(buf.String(), `"status":101`) {
+		t.Errorf("hijacked connection should be logged as 101: %s", buf.String())
+	}
+}
+
+// TestAccessLogMiddlewareCombined は Apache の combined 形式の行を書き込み、logger が nil の場合はその形式だけを書き込むことをテストします
+func TestAccessLogMiddlewareCombined(t *testing.T) {
+	var combined bytes.Buffer
+	h := AccessLogMiddleware(nil, &AccessLogOptions{Combined: &combined})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=a+b", nil)
+	req.RemoteAddr = "192.0.2.1:54321"
+	req.SetBasicAuth("frank", "secret")
+	req.Header.Set("Referer", "https://example.com/")
+	req.Header.Set("User-Agent", `Mozilla/5.0 "test"`)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := combined.String()
+	prefix := `192.0.2.1 - frank [`
+	suffix := `] "GET /search?q=a+b HTTP/1.1" 200 5 "https://example.com/" "Mozilla/5.0 \"test\""` + "\n"
+	if !strings.HasPrefix(line, prefix) || !strings.HasSuffix(line, suffix) {
+		t.Errorf("unexpected line: %q", line)
+	}
+	if _, err := time.Parse(combinedTimeFormat, line[len(prefix):len(line)-len(suffix)]); err != nil {
+		t.Errorf("unexpected time: %v", err)
+	}
+}
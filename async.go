@@ -0,0 +1,249 @@
+package loggo
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultQueueSize は WriteModeAsync のキューに保持するデフォルトのレコード数
+const defaultQueueSize = 1024
+
+// DropPolicy は WriteModeAsync のキューが満杯の場合の動作
+type DropPolicy int
+
+const (
+	// DropPolicyBlock はキューに空きができるまで Handle を待機させます（デフォルト）
+	DropPolicyBlock DropPolicy = iota
+	// DropPolicyDropNewest は新しいレコードを破棄します
+	DropPolicyDropNewest
+	// DropPolicyDropOldest はキューの中で最も古いレコードを破棄して新しいレコードを追加します
+	DropPolicyDropOldest
+)
+
+// dropPolicyNames は DropPolicy のテキスト表現
+var dropPolicyNames = []string{
+	DropPolicyBlock:      "block",
+	DropPolicyDropNewest: "drop-newest",
+	DropPolicyDropOldest: "drop-oldest",
+}
+
+// String はポリシーの名前を返します
+func (p DropPolicy) String() string {
+	if p >= 0 && int(p) < len(dropPolicyNames) {
+		return dropPolicyNames[p]
+	}
+	return "DropPolicy(" + strconv.Itoa(int(p)) + ")"
+}
+
+// MarshalText はポリシーの名前を返します
+func (p DropPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText は "block", "drop-newest", "drop-oldest" を解析します
+func (p *DropPolicy) UnmarshalText(text []byte) error {
+	i, err := parseEnumName("drop policy", dropPolicyNames, string(text))
+	if err != nil {
+		return err
+	}
+	*p = DropPolicy(i)
+	return nil
+}
+
+// asyncOutput はレコードを固定長のキューに追加し、専用のゴルーチンが出力先へ書き出す output。
+//
+// Handle はキューへのコピーのみを行うため、遅い出力先に引きずられません。
+// キューが満杯の場合の動作は DropPolicy で指定します。
+type asyncOutput struct {
+	mu       sync.Mutex // 以下のフィールドを保護
+	notEmpty sync.Cond  // キューにレコードが追加されたか、クローズされたことを通知
+	notFull  sync.Cond  // キューに空きができたか、クローズされたことを通知
+	idle     sync.Cond  // 書き出しが完了したことを通知
+	w        io.Writer  // 書き出し中でない間のみ置き換えられる
+	queue    [][]byte   // リングバッファ。各要素のバッファは再利用する
+	head     int
+	n        int
+	writing  bool
+	closed   bool
+	err      error
+	spare    []byte
+	// unreported は最後に要約を出力してから破棄したレコード数
+	unreported uint64
+
+	policy  DropPolicy
+	summary func(dropped uint64) []byte // nil の場合は要約を出力しない
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+func newAsyncOutput(w io.Writer, size int, policy DropPolicy, summary func(uint64) []byte) *asyncOutput {
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	o := &asyncOutput{
+		w:       w,
+		queue:   make([][]byte, size),
+		policy:  policy,
+		summary: summary,
+		done:    make(chan struct{}),
+	}
+	o.notEmpty.L = &o.mu
+	o.notFull.L = &o.mu
+	o.idle.L = &o.mu
+	go o.loop()
+	registerDrainer(o)
+	return o
+}
+
+func (o *asyncOutput) write(p []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return ErrClosed
+	}
+	if o.n == len(o.queue) {
+		switch o.policy {
+		case DropPolicyDropNewest:
+			o.drop()
+			return nil
+		case DropPolicyDropOldest:
+			o.head = (o.head + 1) % len(o.queue)
+			o.n--
+			o.drop()
+		default:
+			for o.n == len(o.queue) && !o.closed {
+				o.notFull.Wait()
+			}
+			if o.closed {
+				return ErrClosed
+			}
+		}
+	}
+
+	i := (o.head + o.n) % len(o.queue)
+	o.queue[i] = append(o.queue[i][:0], p...)
+	o.n++
+	o.notEmpty.Signal()
+	return nil
+}
+
+// drop は破棄したレコードを数えます。mu を保持して呼び出します。
+func (o *asyncOutput) drop() {
+	o.dropped.Add(1)
+	o.unreported++
+}
+
+// loop はキューのレコードをまとめて出力先へ書き出します
+func (o *asyncOutput) loop() {
+	defer close(o.done)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for {
+		for o.n == 0 && !o.closed {
+			o.notEmpty.Wait()
+		}
+		if o.n == 0 {
+			return
+		}
+
+		batch := o.spare[:0]
+		for ; o.n > 0; o.n-- {
+			slot := &o.queue[o.head]
+			batch = append(batch, *slot...)
+			if cap(*slot) > maxRetainedBatchSize {
+				*slot = nil
+			}
+			o.head = (o.head + 1) % len(o.queue)
+		}
+		// キューが空になった時点で、それまでに破棄したレコードの要約を追加する
+		if o.unreported > 0 && o.summary != nil {
+			batch = append(batch, o.summary(o.unreported)...)
+			o.unreported = 0
+		}
+		o.writing = true
+		w := o.w
+		o.notFull.Broadcast()
+		o.mu.Unlock()
+
+		_, err := w.Write(batch)
+
+		o.mu.Lock()
+		if err != nil {
+			o.err = err
+		}
+		if cap(batch) <= maxRetainedBatchSize {
+			o.spare = batch[:0]
+		} else {
+			o.spare = nil
+		}
+		o.writing = false
+		o.idle.Broadcast()
+	}
+}
+
+// waitIdleLocked はキューが空になり、書き出しが完了するまで待機します。mu を保持して呼び出します。
+func (o *asyncOutput) waitIdleLocked() {
+	for o.n > 0 || o.writing {
+		o.idle.Wait()
+	}
+}
+
+// flush はキューのレコードが書き出されるまで待機し、前回の flush 以降に発生した書き込みエラーを返します
+func (o *asyncOutput) flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.waitIdleLocked()
+	err := o.err
+	o.err = nil
+	return err
+}
+
+// setWriter はキューのレコードを現在の出力先へ書き出してから、出力先を置き換えます
+func (o *asyncOutput) setWriter(w io.Writer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.waitIdleLocked()
+	o.w = w
+}
+
+func (o *asyncOutput) close() error {
+	_, err := o.shutdown(context.Background())
+	return err
+}
+
+// shutdown は新しいレコードの受け付けを停止し、ctx の期限までキューのレコードを書き出します。
+// 期限を過ぎた場合はキューに残っていたレコードを破棄し、その数を返します。
+func (o *asyncOutput) shutdown(ctx context.Context) (int, error) {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return 0, nil
+	}
+	o.closed = true
+	o.notEmpty.Broadcast()
+	o.notFull.Broadcast()
+	o.mu.Unlock()
+	unregisterDrainer(o)
+
+	undelivered := 0
+	select {
+	case <-o.done:
+	case <-ctx.Done():
+		// 書き出し中のバッチの完了は待たず、キューに残っているレコードを破棄する
+		o.mu.Lock()
+		undelivered = o.n
+		o.n = 0
+		o.mu.Unlock()
+	}
+
+	o.mu.Lock()
+	err := o.err
+	o.err = nil
+	o.mu.Unlock()
+	return undelivered, err
+}
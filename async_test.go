@@ -0,0 +1,129 @@
+package loggo
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateWriter は最初の書き込みを release が閉じられるまで止める io.Writer です
+type gateWriter struct {
+	countingWriter
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (g *gateWriter) Write(p []byte) (int, error) {
+	g.once.Do(func() {
+		close(g.started)
+		<-g.release
+	})
+	return g.countingWriter.Write(p)
+}
+
+// fillAsyncQueue は書き出しを止めた状態で 0〜4 のレコードを書き込みます。
+// 0 は書き出し中、1 と 2 はキューに入り、3 と 4 はキューが満杯の状態で書き込まれます。
+func fillAsyncQueue(t *testing.T, policy DropPolicy, summary bool) (*Handler, *gateWriter) {
+	t.Helper()
+	out := newGateWriter()
+	h := NewHandler(out, &Options{WriteMode: WriteModeAsync, QueueSize: 2, DropPolicy: policy, DropSummary: summary})
+	logger := slog.New(h)
+
+	logger.Info("record", "i", 0)
+	<-out.started
+	logger.Info("record", "i", 1)
+	logger.Info("record", "i", 2)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Info("record", "i", 3)
+		logger.Info("record", "i", 4)
+	}()
+	// DropPolicyBlock の場合は書き出しを再開するまで 3 の書き込みが戻らない
+	if policy != DropPolicyBlock {
+		<-done
+	}
+	close(out.release)
+	<-done
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return h, out
+}
+
+// recordIndexes は出力された record の i の値を返します
+func recordIndexes(output string) string {
+	var got []string
+	for _, line := range strings.Split(output, "\n") {
+		if _, i, ok := strings.Cut(line, `msg="record" i=`); ok {
+			got = append(got, i)
+		}
+	}
+	return strings.Join(got, ",")
+}
+
+// TestAsyncDropNewest はキューが満杯の場合に新しいレコードが破棄されることをテストします
+func TestAsyncDropNewest(t *testing.T) {
+	h, out := fillAsyncQueue(t, DropPolicyDropNewest, false)
+
+	if got := recordIndexes(out.String()); got != "0,1,2" {
+		t.Errorf("expected records 0,1,2, got %s", got)
+	}
+	if h.Stats().Dropped != 2 {
+		t.Errorf("expected 2 dropped records, got %d", h.Stats().Dropped)
+	}
+}
+
+// TestAsyncDropOldest はキューが満杯の場合に古いレコードが破棄されることをテストします
+func TestAsyncDropOldest(t *testing.T) {
+	h, out := fillAsyncQueue(t, DropPolicyDropOldest, false)
+
+	if got := recordIndexes(out.String()); got != "0,3,4" {
+		t.Errorf("expected records 0,3,4, got %s", got)
+	}
+	if h.Stats().Dropped != 2 {
+		t.Errorf("expected 2 dropped records, got %d", h.Stats().Dropped)
+	}
+}
+
+// TestAsyncDropSummary は破棄した件数の要約がキューのレコードの後に出力されることをテストします
+func TestAsyncDropSummary(t *testing.T) {
+	_, out := fillAsyncQueue(t, DropPolicyDropNewest, true)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	last := lines[len(lines)-1]
+	if len(lines) != 4 || !strings.Contains(last, `[ WARN] msg="golog: dropped log records" dropped=2`) {
+		t.Errorf("expected summary after queued records, got:\n%s", out.String())
+	}
+}
+
+// TestAsyncBlock はキューが満杯の場合に空きができるまで待機し、レコードを失わないことをテストします
+func TestAsyncBlock(t *testing.T) {
+	h, out := fillAsyncQueue(t, DropPolicyBlock, false)
+
+	if got := recordIndexes(out.String()); got != "0,1,2,3,4" {
+		t.Errorf("expected all records, got %s", got)
+	}
+	if h.Stats().Dropped != 0 {
+		t.Errorf("expected no dropped records, got %d", h.Stats().Dropped)
+	}
+}
+
+// TestAsyncClosed はクローズ後の書き込みがエラーになることをテストします
+func TestAsyncClosed(t *testing.T) {
+	h := NewHandler(&countingWriter{}, &Options{WriteMode: WriteModeAsync})
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Handle(t.Context(), slog.NewRecord(time.Now(), slog.LevelInfo, "late", 0)); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
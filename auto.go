@@ -0,0 +1,54 @@
+package loggo
+
+import (
+	"io"
+	"os"
+)
+
+// NewAutoHandler は出力先に応じて形式を選ぶハンドラーを作成します。
+// w が端末の場合は色付きのテキスト形式の Handler を、それ以外の場合（ファイルやパイプ、
+// Kubernetes や Docker のコンテナ内）は機械で処理しやすい JSON 形式（FormatJSON）の Handler を返します。
+// 1つのバイナリが開発環境と本番環境の両方で適切に振る舞うようにするために使います。
+//
+// 端末の場合は opts の UseColors を、それ以外の場合は Format と UseColors を上書きし、その他のオプションはどちらでもそのまま使用されます。
+func NewAutoHandler(w io.Writer, opts *Options) *Handler {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if isTerminal(w) && !inContainer() {
+		o.UseColors = true
+	} else {
+		o.Format = FormatJSON
+		o.UseColors = false
+	}
+	return NewHandler(w, &o)
+}
+
+// isTerminal は w が端末（キャラクターデバイス）かどうかを判定します。テストで置き換えられます。
+var isTerminal = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// IsTerminal は w が端末（キャラクターデバイスの *os.File）かどうかを返します。
+// golog の出力を表示し直すツールなどで、色付きで出力するかどうかを Handler と同じ基準で判断するために使います。
+func IsTerminal(w io.Writer) bool {
+	return isTerminal(w)
+}
+
+// inContainer は Kubernetes または Docker のコンテナ内で実行されているかを判定します。テストで置き換えられます。
+var inContainer = func() bool {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return true
+	}
+	_, err := os.Stat("/.dockerenv")
+	return err == nil
+}
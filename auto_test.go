@@ -0,0 +1,61 @@
+package loggo
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestNewAutoHandler は出力先に応じて形式が選ばれることをテストします
+func TestNewAutoHandler(t *testing.T) {
+	origTerminal, origContainer := isTerminal, inContainer
+	t.Cleanup(func() { isTerminal, inContainer = origTerminal, origContainer })
+
+	tests := []struct {
+		name      string
+		terminal  bool
+		container bool
+		wantJSON  bool
+	}{
+		{"terminal", true, false, false},
+		{"pipe", false, false, true},
+		{"terminal in container", true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isTerminal = func(io.Writer) bool { return tt.terminal }
+			inContainer = func() bool { return tt.container }
+
+			var buf bytes.Buffer
+			h := NewAutoHandler(&buf, &Options{Level: slog.LevelDebug, SortAttrs: true})
+			slog.New(h).Debug("hello", "n", 1, "a", true)
+
+			var m map[string]any
+			isJSON := json.Unmarshal(buf.Bytes(), &m) == nil
+			if isJSON != tt.wantJSON {
+				t.Fatalf("expected JSON=%v, got: %s", tt.wantJSON, buf.String())
+			}
+			if !isJSON && !strings.Contains(buf.String(), colorCyan+"DEBUG") {
+				t.Errorf("text output on a terminal should be colored, got: %q", buf.String())
+			}
+			if isJSON && (m["msg"] != "hello" || m["n"] != float64(1)) {
+				t.Errorf("unexpected JSON output: %s", buf.String())
+			}
+			// golog のオプションは JSON 形式でも使用される
+			if !strings.Contains(buf.String(), `"a":true,"n":1`) && !strings.Contains(buf.String(), `a=true n=1`) {
+				t.Errorf("SortAttrs should apply, got: %s", buf.String())
+			}
+		})
+	}
+}
+
+// TestIsTerminal は端末以外の出力先が端末と判定されないことをテストします
+func TestIsTerminal(t *testing.T) {
+	if isTerminal(&bytes.Buffer{}) {
+		t.Error("bytes.Buffer should not be a terminal")
+	}
+}
@@ -0,0 +1,78 @@
+package loggo
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// defaultBannerMessage は BannerOptions.Message のデフォルト値
+const defaultBannerMessage = "logger started"
+
+// BannerOptions は Options.Banner で最初に出力する起動時のレコードの内容
+type BannerOptions struct {
+	// Message はレコードのメッセージ。空の場合は "logger started" です。
+	Message string
+	// Service はサービス名。空の場合は実行ファイルの名前です。
+	Service string
+	// Version はサービスのバージョン。空の場合はビルド情報のメインモジュールのバージョンで、
+	// 取得できない場合は出力しません。
+	Version string
+	// Attrs は設定の概要など、追加で出力する属性
+	Attrs []slog.Attr
+}
+
+// banner は Options.Banner のレコードを一度だけ書き込みます。Handler のクローンで共有する。
+type banner struct {
+	once  sync.Once
+	root  *Handler // WithAttrs や WithGroup の属性を含めずに書き込むためのハンドラー
+	msg   string
+	attrs []slog.Attr
+}
+
+// newBanner は opts のレコードを書き込む banner を作成します
+func newBanner(opts BannerOptions) *banner {
+	b := &banner{msg: opts.Message}
+	if b.msg == "" {
+		b.msg = defaultBannerMessage
+	}
+	service := opts.Service
+	if service == "" {
+		if exe, err := os.Executable(); err == nil {
+			service = filepath.Base(exe)
+		}
+	}
+	version := opts.Version
+	if version == "" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+	}
+	if service != "" {
+		b.attrs = append(b.attrs, slog.String("service", service))
+	}
+	if version != "" {
+		b.attrs = append(b.attrs, slog.String("version", version))
+	}
+	b.attrs = append(b.attrs,
+		slog.Int("pid", os.Getpid()),
+		slog.String("go_version", runtime.Version()),
+	)
+	b.attrs = append(b.attrs, opts.Attrs...)
+	return b
+}
+
+// emit は最初の呼び出しでのみバナーのレコードを書き込みます。
+// 書き込みのエラーは、続けて書き込むレコードでも同じく発生するため返しません。
+func (b *banner) emit(ctx context.Context) {
+	b.once.Do(func() {
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, b.msg, 0)
+		r.AddAttrs(b.attrs...)
+		b.root.writeRecord(ctx, r, false)
+	})
+}
@@ -0,0 +1,62 @@
+package loggo
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestBanner は最初のレコードの前にバナーを一度だけ出力することをテストします
+func TestBanner(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{
+		Level: slog.LevelWarn,
+		Banner: &BannerOptions{
+			Service: "api",
+			Version: "1.2.3",
+			Attrs:   []slog.Attr{slog.String("config", "prod.yaml")},
+		},
+	})).With("component", "db")
+
+	logger.Info("ignored")
+	if buf.Len() != 0 {
+		t.Fatalf("banner should not be written before the first record: %s", buf.String())
+	}
+	logger.Warn("first")
+	logger.WithGroup("g").Warn("second")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines: %s", len(lines), buf.String())
+	}
+	want := fmt.Sprintf(`[ INFO] msg="logger started" service="api" version="1.2.3" pid=%d go_version="%s" config="prod.yaml"`, os.Getpid(), runtime.Version())
+	if !strings.HasSuffix(lines[0], want) {
+		t.Errorf("banner = %s\nwant suffix %s", lines[0], want)
+	}
+	if !strings.Contains(lines[1], `msg="first" component="db"`) {
+		t.Errorf("first record = %s", lines[1])
+	}
+}
+
+// TestBannerConcurrent は並行して書き込む場合もバナーが最初に一度だけ出力されることをテストします
+func TestBannerConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{Format: FormatJSON, Banner: &BannerOptions{Message: "boot"}}))
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() { logger.Info("work") })
+	}
+	wg.Wait()
+
+	if n := strings.Count(buf.String(), `"msg":"boot"`); n != 1 {
+		t.Errorf("banner written %d times", n)
+	}
+	if !strings.Contains(strings.SplitN(buf.String(), "\n", 2)[0], `"msg":"boot"`) {
+		t.Errorf("banner should be the first line: %s", buf.String())
+	}
+}
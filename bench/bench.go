@@ -0,0 +1,181 @@
+// Package bench は golog と標準の slog のハンドラーを同じ条件で比較するベンチマークと、
+// 長時間の記録でバッファのプールのリークを検出するソークテストを提供します。
+// 性能の主張や性能の低下を、利用者が自分の環境で再現して確認するために使います。
+//
+// go test のベンチマークとして実行する場合は Run を呼び出します：
+//
+//	func BenchmarkLoggers(b *testing.B) { bench.Run(b) }
+//
+// プログラムから実行する場合は Measure と Soak を使います。
+package bench
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	loggo "github.com/f0reth/golog"
+)
+
+// Target はベンチマークの対象のハンドラー
+type Target struct {
+	Name string
+	// New は w へ出力するハンドラーを作成します
+	New func(w io.Writer) slog.Handler
+}
+
+// Targets は golog のテキスト形式と JSON 形式、標準の slog.TextHandler と slog.JSONHandler を返します
+func Targets() []Target {
+	return []Target{
+		{Name: "golog-text", New: func(w io.Writer) slog.Handler { return loggo.NewHandler(w, nil) }},
+		{Name: "golog-json", New: func(w io.Writer) slog.Handler {
+			return loggo.NewHandler(w, &loggo.Options{Format: loggo.FormatJSON})
+		}},
+		{Name: "slog-text", New: func(w io.Writer) slog.Handler { return slog.NewTextHandler(w, nil) }},
+		{Name: "slog-json", New: func(w io.Writer) slog.Handler { return slog.NewJSONHandler(w, nil) }},
+	}
+}
+
+// Workload はベンチマークで記録するレコードの種類
+type Workload struct {
+	Name string
+	// Setup は記録に使うロガーを準備します。nil の場合は logger をそのまま使います。
+	Setup func(logger *slog.Logger) *slog.Logger
+	// Log は i 回目のレコードを記録します
+	Log func(logger *slog.Logger, i int)
+}
+
+// payload は Workloads の "struct" で記録する構造体
+type payload struct {
+	ID     int               `json:"id"`
+	Name   string            `json:"name"`
+	Tags   []string          `json:"tags"`
+	Labels map[string]string `json:"labels"`
+}
+
+// Workloads は次のレコードを記録する Workload を返します。
+//   - message: 属性の無いメッセージ
+//   - attrs: 文字列、整数、時間、真偽値、時刻の5つの属性
+//   - with: Logger.With で追加した5つの属性と、2つの属性
+//   - grouped: 2段のグループの中の4つの属性
+//   - struct: 構造体の値（リフレクションによるエンコード）
+func Workloads() []Workload {
+	now := time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC)
+	value := payload{ID: 42, Name: "widget", Tags: []string{"a", "b"}, Labels: map[string]string{"env": "prod"}}
+	return []Workload{
+		{Name: "message", Log: func(l *slog.Logger, _ int) {
+			l.Info("request handled")
+		}},
+		{Name: "attrs", Log: func(l *slog.Logger, i int) {
+			l.LogAttrs(context.Background(), slog.LevelInfo, "request handled",
+				slog.String("method", "GET"),
+				slog.Int("iteration", i),
+				slog.Duration("elapsed", 1500*time.Microsecond),
+				slog.Bool("cached", true),
+				slog.Time("start", now),
+			)
+		}},
+		{
+			Name: "with",
+			Setup: func(l *slog.Logger) *slog.Logger {
+				return l.With("service", "api", "version", "1.2.3", "region", "ap-northeast-1", "pid", 4242, "debug", false)
+			},
+			Log: func(l *slog.Logger, i int) {
+				l.Info("request handled", "iteration", i, "path", "/api/users")
+			},
+		},
+		{
+			Name: "grouped",
+			Setup: func(l *slog.Logger) *slog.Logger {
+				return l.WithGroup("http").WithGroup("request")
+			},
+			Log: func(l *slog.Logger, i int) {
+				l.Info("request handled", "iteration", i, "method", "GET", "path", "/api/users", "status", 200)
+			},
+		},
+		{Name: "struct", Log: func(l *slog.Logger, _ int) {
+			l.Info("request handled", "payload", value)
+		}},
+	}
+}
+
+// logger は target の io.Discard へ出力するハンドラーで、workload の記録に使うロガーを返します
+func logger(target Target, workload Workload) *slog.Logger {
+	l := slog.New(target.New(io.Discard))
+	if workload.Setup != nil {
+		l = workload.Setup(l)
+	}
+	return l
+}
+
+// benchmark は b.N 回 workload のレコードを記録します
+func benchmark(b *testing.B, target Target, workload Workload) {
+	l := logger(target, workload)
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		workload.Log(l, i)
+	}
+}
+
+// Run は Workloads と Targets のすべての組み合わせを "workload/target" のサブベンチマークとして実行します。
+// 出力先は io.Discard のため、フォーマットの性能だけを比較します。
+func Run(b *testing.B) {
+	RunWith(b, Targets(), Workloads())
+}
+
+// RunWith は指定した targets と workloads の組み合わせをサブベンチマークとして実行します。
+// 独自のオプションを指定したハンドラーを既定の対象と比較する場合に使います。
+func RunWith(b *testing.B, targets []Target, workloads []Workload) {
+	for _, w := range workloads {
+		for _, t := range targets {
+			b.Run(w.Name+"/"+t.Name, func(b *testing.B) {
+				benchmark(b, t, w)
+			})
+		}
+	}
+}
+
+// Result は Measure の1つの組み合わせの結果
+type Result struct {
+	Target      string
+	Workload    string
+	NsPerOp     float64
+	AllocsPerOp int64
+	BytesPerOp  int64
+}
+
+// RecordsPerSecond は1秒あたりに記録できるレコード数を返します
+func (r Result) RecordsPerSecond() float64 {
+	if r.NsPerOp == 0 {
+		return 0
+	}
+	return float64(time.Second) / r.NsPerOp
+}
+
+// Measure は go test を使わずに Workloads と Targets のすべての組み合わせを計測します。
+// 計測には testing.Benchmark を使うため、go test -bench と同じ方法で回数を決めます。
+func Measure() []Result {
+	return MeasureWith(Targets(), Workloads())
+}
+
+// MeasureWith は指定した targets と workloads の組み合わせを計測します
+func MeasureWith(targets []Target, workloads []Workload) []Result {
+	var results []Result
+	for _, w := range workloads {
+		for _, t := range targets {
+			r := testing.Benchmark(func(b *testing.B) {
+				benchmark(b, t, w)
+			})
+			results = append(results, Result{
+				Target:      t.Name,
+				Workload:    w.Name,
+				NsPerOp:     float64(r.T.Nanoseconds()) / float64(max(r.N, 1)),
+				AllocsPerOp: r.AllocsPerOp(),
+				BytesPerOp:  r.AllocedBytesPerOp(),
+			})
+		}
+	}
+	return results
+}
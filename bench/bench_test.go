@@ -0,0 +1,82 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	loggo "github.com/f0reth/golog"
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// BenchmarkLoggers はすべての対象と記録するレコードの組み合わせのベンチマークです
+func BenchmarkLoggers(b *testing.B) {
+	Run(b)
+}
+
+// TestMeasure は Measure がすべての組み合わせの結果を返すことをテストします
+func TestMeasure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping benchmarks in short mode")
+	}
+	targets := Targets()[:2]
+	workloads := Workloads()[:1]
+	results := MeasureWith(targets, workloads)
+	if len(results) != 2 {
+		t.Fatalf("got %d results", len(results))
+	}
+	for _, r := range results {
+		if r.NsPerOp <= 0 || r.RecordsPerSecond() <= 0 {
+			t.Errorf("unexpected result: %+v", r)
+		}
+	}
+}
+
+// TestSoak はリークの無いハンドラーでソークテストが成功することをテストします
+func TestSoak(t *testing.T) {
+	for _, format := range []loggo.Format{loggo.FormatText, loggo.FormatJSON} {
+		t.Run(format.String(), func(t *testing.T) {
+			report, err := Soak(context.Background(), &SoakOptions{
+				Duration:   50 * time.Millisecond,
+				Goroutines: 4,
+				Handler:    loggo.NewHandler(discard{}, &loggo.Options{Format: format, WriteMode: loggo.WriteModeAsync}),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Records == 0 || report.PoolGets == 0 {
+				t.Errorf("unexpected report: %+v", report)
+			}
+		})
+	}
+}
+
+// TestSoakLeak はバッファをプールへ戻さないハンドラーを検出することをテストします
+func TestSoakLeak(t *testing.T) {
+	_, err := Soak(context.Background(), &SoakOptions{
+		Duration:   10 * time.Millisecond,
+		Goroutines: 1,
+		Handler:    leakyHandler{slog.DiscardHandler},
+		Workloads:  Workloads()[:1],
+	})
+	if !errors.Is(err, ErrBufferLeak) {
+		t.Errorf("Soak = %v, want ErrBufferLeak", err)
+	}
+}
+
+// discard は io.Discard と異なり io.ReaderFrom を実装しない、何もしない io.Writer です
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+
+// leakyHandler はレコードごとにバッファを取り出して戻さないハンドラーです
+type leakyHandler struct{ slog.Handler }
+
+func (h leakyHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h leakyHandler) Handle(ctx context.Context, r slog.Record) error {
+	buffer.New()
+	return nil
+}
@@ -0,0 +1,141 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	loggo "github.com/f0reth/golog"
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// defaultSoakDuration は SoakOptions.Duration のデフォルト値
+const defaultSoakDuration = 10 * time.Second
+
+// ErrBufferLeak はソークテストの終了時に、取り出したバッファがプールへ戻されていない場合のエラー
+var ErrBufferLeak = errors.New("golog: buffers were not returned to the pool")
+
+// SoakOptions は Soak のオプション
+type SoakOptions struct {
+	// Duration は記録を続ける時間。0 の場合は 10 秒です。
+	Duration time.Duration
+	// Goroutines は並行して記録するゴルーチンの数。0 の場合は GOMAXPROCS です。
+	Goroutines int
+	// Handler は記録に使うハンドラー。nil の場合は io.Discard へ出力する JSON 形式の golog のハンドラーです。
+	// Flush を実装している場合は、終了時に呼び出してから計測します。
+	Handler slog.Handler
+	// Workloads は順番に繰り返し記録するレコード。nil の場合は Workloads() です。
+	Workloads []Workload
+}
+
+// SoakReport は Soak の結果
+type SoakReport struct {
+	Records  uint64
+	Duration time.Duration
+	// BuffersOutstanding は終了時にプールへ戻されていないバッファの数。0 でない場合はリークです。
+	BuffersOutstanding int64
+	// PoolGets, PoolMisses, PoolDiscarded はソークテストの間のバッファのプールの統計情報
+	PoolGets      uint64
+	PoolMisses    uint64
+	PoolDiscarded uint64
+	// HeapBefore と HeapAfter は開始前と終了後に GC を実行した後の HeapInuse
+	HeapBefore uint64
+	HeapAfter  uint64
+}
+
+// RecordsPerSecond は1秒あたりに記録したレコード数を返します
+func (r SoakReport) RecordsPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Records) / r.Duration.Seconds()
+}
+
+// Soak は Duration の間、Goroutines 個のゴルーチンで記録を続け、バッファのプールのリークを検査します。
+// ctx がキャンセルされた場合は Duration の前に終了します。
+// 取り出したバッファがすべてプールへ戻されていない場合は ErrBufferLeak を返します。
+// バッファのプールはプロセス全体で共有されるため、他のログの記録と並行して実行しないでください。
+func Soak(ctx context.Context, opts *SoakOptions) (SoakReport, error) {
+	duration := defaultSoakDuration
+	goroutines := runtime.GOMAXPROCS(0)
+	var handler slog.Handler
+	var workloads []Workload
+	if opts != nil {
+		if opts.Duration > 0 {
+			duration = opts.Duration
+		}
+		if opts.Goroutines > 0 {
+			goroutines = opts.Goroutines
+		}
+		handler = opts.Handler
+		workloads = opts.Workloads
+	}
+	if handler == nil {
+		handler = loggo.NewHandler(io.Discard, &loggo.Options{Format: loggo.FormatJSON})
+	}
+	if len(workloads) == 0 {
+		workloads = Workloads()
+	}
+	loggers := make([]*slog.Logger, len(workloads))
+	for i, w := range workloads {
+		loggers[i] = slog.New(handler)
+		if w.Setup != nil {
+			loggers[i] = w.Setup(loggers[i])
+		}
+	}
+
+	var report SoakReport
+	report.HeapBefore = heapInuse()
+	// 計測の間だけプールの統計情報を集計する
+	defer buffer.EnableStats(buffer.EnableStats(true))
+	before := buffer.ReadStats()
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	var records atomic.Uint64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for range goroutines {
+		wg.Go(func() {
+			var n uint64
+			for i := 0; ctx.Err() == nil; i++ {
+				k := i % len(workloads)
+				workloads[k].Log(loggers[k], i)
+				n++
+			}
+			records.Add(n)
+		})
+	}
+	wg.Wait()
+	report.Duration = time.Since(start)
+	report.Records = records.Load()
+
+	var err error
+	if f, ok := handler.(interface{ Flush() error }); ok {
+		err = f.Flush()
+	}
+	after := buffer.ReadStats()
+	report.HeapAfter = heapInuse()
+	report.PoolGets = after.Gets - before.Gets
+	report.PoolMisses = after.Misses - before.Misses
+	report.PoolDiscarded = after.Discarded - before.Discarded
+	report.BuffersOutstanding = int64(after.Gets-before.Gets) - int64(after.Frees-before.Frees)
+	if report.BuffersOutstanding != 0 {
+		err = errors.Join(err, fmt.Errorf("%w: %d buffers outstanding after %d records", ErrBufferLeak, report.BuffersOutstanding, report.Records))
+	}
+	return report, err
+}
+
+// heapInuse は GC を実行した後の HeapInuse を返します
+func heapInuse() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapInuse
+}
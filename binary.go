@@ -0,0 +1,404 @@
+package loggo
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// binaryVersion はバイナリ形式のスキーマのバージョン。互換性の無い変更をする場合に増やします。
+const binaryVersion = 1
+
+// maxBinaryRecordSize は BinaryReader が受け付ける1レコードの最大サイズ。壊れた長さで巨大な領域を確保しないための上限。
+const maxBinaryRecordSize = 64 << 20
+
+// バイナリ形式の値の種類。slog.Kind の番号に依存しないよう独自に定義する。
+const (
+	binKindString byte = iota + 1
+	binKindInt64
+	binKindUint64
+	binKindFloat64
+	binKindBool
+	binKindDuration
+	binKindTime
+	binKindGroup
+)
+
+// BinaryHandlerOptions は BinaryHandler のオプション
+type BinaryHandlerOptions struct {
+	// Level は記録する最小のレベル。nil の場合は slog.LevelInfo です。
+	Level slog.Leveler
+}
+
+// BinaryHandler はレコードを長さ付きのコンパクトなバイナリ形式で保存するハンドラー。
+// 保存したレコードは BinaryReader で読み取り、任意のハンドラーで後からテキストや JSON として出力し直せます。
+// 出力時のマスキングやフィルターを変えて、同じログを何度でも再生できます。
+//
+// 各レコードは「uvarint の長さ」と「スキーマのバージョンで始まる本体」からなるため、既存のファイルに追記できます。
+// 属性の値のうち文字列、数値、真偽値、時間、時刻、グループは型を保ったまま保存し、
+// それ以外（error や構造体など）は fmt.Sprint の文字列として保存します。呼び出し元の位置は保存しません。
+//
+//	f, _ := os.OpenFile("app.binlog", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+//	logger := slog.New(golog.NewBinaryHandler(f, nil))
+type BinaryHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex // WithAttrs や WithGroup で作られたハンドラー間で共有し、書き込みを直列化する
+	level slog.Leveler
+	goas  []groupOrAttrs
+}
+
+// groupOrAttrs は WithGroup のグループ名または WithAttrs の属性
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// NewBinaryHandler は w にレコードをバイナリ形式で書き込むハンドラーを作成します
+func NewBinaryHandler(w io.Writer, opts *BinaryHandlerOptions) *BinaryHandler {
+	h := &BinaryHandler{w: w, mu: new(sync.Mutex), level: slog.LevelInfo}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+// Enabled はレベルが Level 以上かを返します
+func (h *BinaryHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle はレコードを1つのフレームとして書き込みます
+func (h *BinaryHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	// WithGroup と WithAttrs を内側から順に適用し、属性を入れ子のグループにまとめる
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		goa := h.goas[i]
+		if goa.group == "" {
+			attrs = append(slices.Clone(goa.attrs), attrs...)
+		} else if len(attrs) > 0 {
+			attrs = []slog.Attr{{Key: goa.group, Value: slog.GroupValue(attrs...)}}
+		}
+	}
+
+	body := buffer.New()
+	defer body.Free()
+	*body = append(*body, binaryVersion)
+	*body = appendBinaryTime(*body, r.Time)
+	*body = binary.AppendVarint(*body, int64(r.Level))
+	*body = appendBinaryString(*body, r.Message)
+	*body = appendBinaryAttrs(*body, attrs)
+
+	frame := buffer.New()
+	defer frame.Free()
+	*frame = binary.AppendUvarint(*frame, uint64(len(*body)))
+	*frame = append(*frame, *body...)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(*frame)
+	return err
+}
+
+// WithAttrs は属性を追加したハンドラーを返します
+func (h *BinaryHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(slices.Clip(h.goas), groupOrAttrs{attrs: slices.Clone(attrs)})
+	return &h2
+}
+
+// WithGroup はグループを追加したハンドラーを返します
+func (h *BinaryHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(slices.Clip(h.goas), groupOrAttrs{group: name})
+	return &h2
+}
+
+// appendBinaryString は長さ付きの文字列を書き込みます
+func appendBinaryString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendBinaryTime は時刻を UnixNano で書き込みます。ゼロ値は 0 とします。
+func appendBinaryTime(b []byte, t time.Time) []byte {
+	if t.IsZero() {
+		return binary.AppendVarint(b, 0)
+	}
+	return binary.AppendVarint(b, t.UnixNano())
+}
+
+// appendBinaryAttrs は属性の数と各属性を書き込みます。空の属性は書き込みません。
+func appendBinaryAttrs(b []byte, attrs []slog.Attr) []byte {
+	n := 0
+	for _, a := range attrs {
+		if !a.Equal(slog.Attr{}) {
+			n++
+		}
+	}
+	b = binary.AppendUvarint(b, uint64(n))
+	for _, a := range attrs {
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+		b = appendBinaryString(b, a.Key)
+		b = appendBinaryValue(b, a.Value.Resolve())
+	}
+	return b
+}
+
+// appendBinaryValue は値の種類と値を書き込みます
+func appendBinaryValue(b []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		b = append(b, binKindString)
+		return appendBinaryString(b, v.String())
+	case slog.KindInt64:
+		b = append(b, binKindInt64)
+		return binary.AppendVarint(b, v.Int64())
+	case slog.KindUint64:
+		b = append(b, binKindUint64)
+		return binary.AppendUvarint(b, v.Uint64())
+	case slog.KindFloat64:
+		b = append(b, binKindFloat64)
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v.Float64()))
+	case slog.KindBool:
+		b = append(b, binKindBool)
+		if v.Bool() {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case slog.KindDuration:
+		b = append(b, binKindDuration)
+		return binary.AppendVarint(b, int64(v.Duration()))
+	case slog.KindTime:
+		b = append(b, binKindTime)
+		return appendBinaryTime(b, v.Time())
+	case slog.KindGroup:
+		b = append(b, binKindGroup)
+		return appendBinaryAttrs(b, v.Group())
+	default:
+		b = append(b, binKindString)
+		return appendBinaryString(b, fmt.Sprint(v.Any()))
+	}
+}
+
+// BinaryReader は BinaryHandler が書き込んだレコードを読み取ります
+//
+//	f, _ := os.Open("app.binlog")
+//	r := golog.NewBinaryReader(f)
+//	err := r.Replay(ctx, golog.NewHandler(os.Stdout, &golog.Options{Format: golog.FormatJSON}))
+type BinaryReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+// NewBinaryReader は r からレコードを読み取る BinaryReader を作成します
+func NewBinaryReader(r io.Reader) *BinaryReader {
+	return &BinaryReader{r: bufio.NewReader(r)}
+}
+
+// Next は次のレコードを返します。レコードが無い場合は io.EOF を返します。
+// レコードの途中でデータが終わった場合は io.ErrUnexpectedEOF を返します。
+func (br *BinaryReader) Next() (slog.Record, error) {
+	n, err := binary.ReadUvarint(br.r)
+	if err != nil {
+		return slog.Record{}, err // レコードの境界で終わった場合は io.EOF
+	}
+	if n > maxBinaryRecordSize {
+		return slog.Record{}, fmt.Errorf("golog: binary record too large (%d bytes)", n)
+	}
+	if uint64(cap(br.buf)) < n {
+		br.buf = make([]byte, n)
+	}
+	br.buf = br.buf[:n]
+	if _, err := io.ReadFull(br.r, br.buf); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return slog.Record{}, err
+	}
+	return decodeBinaryRecord(br.buf)
+}
+
+// Replay はすべてのレコードを読み取り、h が有効と判断したレコードを h に渡します。
+// h のレベルやマスキング、フィルターに従って、保存したログを出力し直せます。
+func (br *BinaryReader) Replay(ctx context.Context, h slog.Handler) error {
+	for {
+		r, err := br.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r); err != nil {
+			return err
+		}
+	}
+}
+
+// binaryDecoder は1つのレコードの本体を読み取ります
+type binaryDecoder struct {
+	b   []byte
+	err error
+}
+
+// errBinaryCorrupt はレコードの本体が壊れていることを示すエラー
+var errBinaryCorrupt = errors.New("golog: corrupt binary record")
+
+// decodeBinaryRecord はレコードの本体を復元します
+func decodeBinaryRecord(b []byte) (slog.Record, error) {
+	if len(b) == 0 {
+		return slog.Record{}, errBinaryCorrupt
+	}
+	if b[0] != binaryVersion {
+		return slog.Record{}, fmt.Errorf("golog: unsupported binary record version %d", b[0])
+	}
+	d := &binaryDecoder{b: b[1:]}
+	t := d.time()
+	level := slog.Level(d.varint())
+	msg := d.string()
+	attrs := d.attrs()
+	if d.err != nil {
+		return slog.Record{}, d.err
+	}
+	r := slog.NewRecord(t, level, msg, 0)
+	r.AddAttrs(attrs...)
+	return r, nil
+}
+
+// fail は読み取りを失敗として残りを捨てます
+func (d *binaryDecoder) fail() {
+	if d.err == nil {
+		d.err = errBinaryCorrupt
+	}
+	d.b = nil
+}
+
+// varint は符号付きの可変長整数を読み取ります
+func (d *binaryDecoder) varint() int64 {
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+// uvarint は符号無しの可変長整数を読み取ります
+func (d *binaryDecoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+// string は長さ付きの文字列を読み取ります
+func (d *binaryDecoder) string() string {
+	n := d.uvarint()
+	if n > uint64(len(d.b)) {
+		d.fail()
+		return ""
+	}
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
+
+// byte は1バイトを読み取ります
+func (d *binaryDecoder) byte() byte {
+	if len(d.b) == 0 {
+		d.fail()
+		return 0
+	}
+	c := d.b[0]
+	d.b = d.b[1:]
+	return c
+}
+
+// time は appendBinaryTime で書き込んだ時刻を読み取ります
+func (d *binaryDecoder) time() time.Time {
+	n := d.varint()
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// attrs は appendBinaryAttrs で書き込んだ属性を読み取ります
+func (d *binaryDecoder) attrs() []slog.Attr {
+	n := d.uvarint()
+	// 1つの属性は少なくとも3バイト（キーの長さ、種類、値）を占める
+	if n > uint64(len(d.b))/3 {
+		d.fail()
+		return nil
+	}
+	attrs := make([]slog.Attr, 0, n)
+	for range n {
+		key := d.string()
+		v := d.value()
+		if d.err != nil {
+			return nil
+		}
+		attrs = append(attrs, slog.Attr{Key: key, Value: v})
+	}
+	return attrs
+}
+
+// value は appendBinaryValue で書き込んだ値を読み取ります
+func (d *binaryDecoder) value() slog.Value {
+	switch d.byte() {
+	case binKindString:
+		return slog.StringValue(d.string())
+	case binKindInt64:
+		return slog.Int64Value(d.varint())
+	case binKindUint64:
+		return slog.Uint64Value(d.uvarint())
+	case binKindFloat64:
+		if len(d.b) < 8 {
+			d.fail()
+			return slog.Value{}
+		}
+		f := math.Float64frombits(binary.LittleEndian.Uint64(d.b))
+		d.b = d.b[8:]
+		return slog.Float64Value(f)
+	case binKindBool:
+		return slog.BoolValue(d.byte() != 0)
+	case binKindDuration:
+		return slog.DurationValue(time.Duration(d.varint()))
+	case binKindTime:
+		return slog.TimeValue(d.time())
+	case binKindGroup:
+		return slog.GroupValue(d.attrs()...)
+	default:
+		d.fail()
+		return slog.Value{}
+	}
+}
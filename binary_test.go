@@ -0,0 +1,114 @@
+package loggo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBinaryReplay は保存したレコードを再生すると、直接出力した場合と同じ行になることをテストします
+func TestBinaryReplay(t *testing.T) {
+	var direct, stored bytes.Buffer
+	jsonOpts := &Options{
+		Format: FormatJSON,
+		Level:  slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}
+	ts := time.Date(2024, time.March, 5, 9, 4, 7, 0, time.Local)
+	logAll := func(h slog.Handler) {
+		logger := slog.New(h).With("service", "api").WithGroup("req")
+		logger.Info("handled",
+			"path", "/users",
+			"status", 200,
+			"bytes", uint64(512),
+			"ratio", 0.25,
+			"cached", true,
+			"elapsed", 1500*time.Millisecond,
+			"at", ts,
+			slog.Group("user", "id", 42),
+		)
+		logger.Debug("not stored")
+		logger.Warn("no attrs")
+	}
+	logAll(NewHandler(&direct, jsonOpts))
+	logAll(NewBinaryHandler(&stored, nil))
+
+	var replayed bytes.Buffer
+	r := NewBinaryReader(bytes.NewReader(stored.Bytes()))
+	if err := r.Replay(t.Context(), NewHandler(&replayed, jsonOpts)); err != nil {
+		t.Fatal(err)
+	}
+	// Debug のレコードは保存されないため、直接出力した行から除く
+	var want []string
+	for line := range strings.Lines(direct.String()) {
+		if !strings.Contains(line, "not stored") {
+			want = append(want, line)
+		}
+	}
+	if got := replayed.String(); got != strings.Join(want, "") {
+		t.Errorf("got\n%s\nwant\n%s", got, strings.Join(want, ""))
+	}
+}
+
+// TestBinaryReplayFilter は再生先のハンドラーのレベルと ReplaceAttr が適用され、error が文字列として保存されることをテストします
+func TestBinaryReplayFilter(t *testing.T) {
+	var stored bytes.Buffer
+	logger := slog.New(NewBinaryHandler(&stored, &BinaryHandlerOptions{Level: slog.LevelDebug}))
+	logger.Debug("debug")
+	logger.Error("login failed", "password", "hunter2", "err", errors.New("bad credentials"))
+
+	var out bytes.Buffer
+	h := NewHandler(&out, &Options{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "password" {
+				return slog.String("password", "***")
+			}
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	if err := NewBinaryReader(&stored).Replay(t.Context(), h); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "[ERROR] msg=\"login failed\" password=\"***\" err=\"bad credentials\"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// TestBinaryReaderErrors は途中で終わったデータと未対応のバージョンをエラーにすることをテストします
+func TestBinaryReaderErrors(t *testing.T) {
+	var stored bytes.Buffer
+	slog.New(NewBinaryHandler(&stored, nil)).Info("hello", "k", "v")
+	data := stored.Bytes()
+
+	r := NewBinaryReader(bytes.NewReader(data[:len(data)-2]))
+	if _, err := r.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncated: err = %v, want io.ErrUnexpectedEOF", err)
+	}
+
+	bad := bytes.Clone(data)
+	bad[1] = binaryVersion + 1
+	if _, err := NewBinaryReader(bytes.NewReader(bad)).Next(); err == nil || !strings.Contains(err.Error(), "version") {
+		t.Errorf("version: err = %v", err)
+	}
+
+	r = NewBinaryReader(bytes.NewReader(data))
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("err = %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,230 @@
+package loggo
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// CEFOptions は CEFHandler のオプション
+type CEFOptions struct {
+	// Vendor, Product, Version はヘッダーの Device Vendor, Device Product, Device Version。
+	// 空の場合はそれぞれ "golog"、実行ファイルの名前、"1.0" です。
+	Vendor  string
+	Product string
+	Version string
+	// SignatureKey はヘッダーの Signature ID に使う属性のキー。空の場合やレコードにその属性が無い場合はメッセージを使います。
+	// グループの属性は "." で連結したキーで指定します。Signature ID に使った属性は拡張フィールドに出力しません。
+	SignatureKey string
+	// Level は出力する最小のレベル。nil の場合は slog.LevelInfo です。
+	Level slog.Leveler
+	// Severities はレベルから Severity への変換表。nil の場合は CEFSeverities です。
+	Severities *SeverityMap[int]
+}
+
+// CEFHandler はレコードを ArcSight や QRadar などの SIEM が取り込む CEF（Common Event Format）の1行で出力するハンドラー。
+//
+//	CEF:0|Vendor|Product|Version|Signature ID|Name|Severity|rt=... key=value ...
+//
+// Name はメッセージ、Severity はレベルから決まる 0〜10 の値です。拡張フィールドの rt には時刻（Unix エポックからのミリ秒）を、
+// それ以降に属性を出力します。グループの属性のキーは "." で連結し、CEF のキーに使えない文字は "_" に置き換えます。
+type CEFHandler struct {
+	w            io.Writer
+	mu           *sync.Mutex // WithAttrs や WithGroup で作られたハンドラー間で共有し、書き込みを直列化する
+	prefix       string      // "CEF:0|Vendor|Product|Version|"
+	signatureKey string
+	level        slog.Leveler
+	severities   *SeverityMap[int]
+	goas         []groupOrAttrs
+}
+
+// NewCEFHandler は w に CEF の行を書き込むハンドラーを作成します
+//
+//	handler := golog.NewCEFHandler(conn, &golog.CEFOptions{Vendor: "Acme", Product: "billing", Version: "2.3"})
+func NewCEFHandler(w io.Writer, opts *CEFOptions) *CEFHandler {
+	var o CEFOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Vendor == "" {
+		o.Vendor = "golog"
+	}
+	if o.Product == "" {
+		o.Product = filepath.Base(os.Args[0])
+	}
+	if o.Version == "" {
+		o.Version = "1.0"
+	}
+	if o.Level == nil {
+		o.Level = slog.LevelInfo
+	}
+	if o.Severities == nil {
+		o.Severities = CEFSeverities
+	}
+	var prefix strings.Builder
+	prefix.WriteString("CEF:0|")
+	for _, field := range []string{o.Vendor, o.Product, o.Version} {
+		prefix.WriteString(escapeCEFHeader(field))
+		prefix.WriteByte('|')
+	}
+	return &CEFHandler{
+		w:            w,
+		mu:           new(sync.Mutex),
+		prefix:       prefix.String(),
+		signatureKey: o.SignatureKey,
+		level:        o.Level,
+		severities:   o.Severities,
+	}
+}
+
+// Enabled はレベルが Level 以上かを返します
+func (h *CEFHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle はレコードを CEF の1行として書き込みます
+func (h *CEFHandler) Handle(_ context.Context, r slog.Record) error {
+	signature, hasSignature := r.Message, false
+	var ext []byte
+	appendExt := func(key string, v slog.Value) {
+		key = sanitizeCEFKey(key)
+		if key == h.signatureKey && !hasSignature {
+			signature, hasSignature = v.String(), true
+			return
+		}
+		if len(ext) > 0 {
+			ext = append(ext, ' ')
+		}
+		ext = append(ext, key...)
+		ext = append(ext, '=')
+		ext = appendCEFValue(ext, v)
+	}
+	prefix := ""
+	for _, goa := range h.goas {
+		if goa.group != "" {
+			prefix += goa.group + "."
+			continue
+		}
+		for _, a := range goa.attrs {
+			flattenAttr(prefix, a, appendExt)
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		flattenAttr(prefix, a, appendExt)
+		return true
+	})
+
+	buf := buffer.New()
+	defer buf.Free()
+	buf.WriteString(h.prefix)
+	buf.WriteString(escapeCEFHeader(signature))
+	buf.WriteByte('|')
+	buf.WriteString(escapeCEFHeader(r.Message))
+	buf.WriteByte('|')
+	*buf = strconv.AppendInt(*buf, int64(h.severities.Lookup(r.Level)), 10)
+	buf.WriteByte('|')
+	if !r.Time.IsZero() {
+		buf.WriteString("rt=")
+		*buf = strconv.AppendInt(*buf, r.Time.UnixMilli(), 10)
+		if len(ext) > 0 {
+			buf.WriteByte(' ')
+		}
+	}
+	buf.Write(ext)
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(*buf)
+	return err
+}
+
+// WithAttrs は属性を追加したハンドラーを返します
+func (h *CEFHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(slices.Clip(h.goas), groupOrAttrs{attrs: slices.Clone(attrs)})
+	return &h2
+}
+
+// WithGroup はグループを追加したハンドラーを返します
+func (h *CEFHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(slices.Clip(h.goas), groupOrAttrs{group: name})
+	return &h2
+}
+
+// flattenAttr はグループを展開し、グループ名を "." で連結したキーと値ごとに fn を呼び出します
+func flattenAttr(prefix string, a slog.Attr, fn func(key string, v slog.Value)) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			flattenAttr(prefix, ga, fn)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	fn(prefix+a.Key, v)
+}
+
+// sanitizeCEFKey は拡張フィールドのキーに使えない文字を "_" に置き換えます。グループの区切りの "." は残します。
+func sanitizeCEFKey(key string) string {
+	b := []byte(key)
+	for i, c := range b {
+		if !isLetter(c) && (c < '0' || c > '9') && c != '_' && c != '.' {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// escapeCEFHeader はヘッダーのフィールドの "\" と "|" をエスケープし、改行を空白に置き換えます
+func escapeCEFHeader(s string) string {
+	if !strings.ContainsAny(s, "\\|\r\n") {
+		return s
+	}
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ").Replace(s)
+}
+
+// appendCEFValue は拡張フィールドの値の "\" と "=" と改行をエスケープして書き込みます
+func appendCEFValue(b []byte, v slog.Value) []byte {
+	var s string
+	switch v.Kind() {
+	case slog.KindTime:
+		s = v.Time().Format(time.RFC3339Nano)
+	default:
+		s = v.String()
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '=':
+			b = append(b, '\\', c)
+		case '\n':
+			b = append(b, `\n`...)
+		case '\r':
+			b = append(b, `\r`...)
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
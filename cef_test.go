@@ -0,0 +1,72 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCEFHandler はヘッダー、重要度、拡張フィールドのエスケープとグループの展開をテストします
+func TestCEFHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewCEFHandler(&buf, &CEFOptions{
+		Vendor:       "Acme",
+		Product:      "billing|api",
+		Version:      "2.3",
+		SignatureKey: "event",
+	})
+	logger := slog.New(h).With("host", "web1").WithGroup("req")
+	ts := time.UnixMilli(1709629447123)
+	r := slog.NewRecord(ts, slog.LevelWarn, "login failed", 0)
+	r.AddAttrs(
+		slog.String("event", "auth-401"),
+		slog.String("query", "a=1\\b"),
+		slog.Group("user", slog.Int("id", 42), slog.String("display name", "x\ny")),
+	)
+	if err := logger.Handler().Handle(t.Context(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	// "req.event" は Signature ID ではなく拡張フィールドとして出力される
+	want := `CEF:0|Acme|billing\|api|2.3|login failed|login failed|6|rt=1709629447123 host=web1 req.event=auth-401 req.query=a\=1\\b req.user.id=42 req.user.display_name=x\ny` + "\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
+
+// TestCEFHandlerSignature は SignatureKey の属性を Signature ID に使い、拡張フィールドから除くことをテストします
+func TestCEFHandlerSignature(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewCEFHandler(&buf, &CEFOptions{Vendor: "Acme", Product: "api", SignatureKey: "event"}))
+	logger.Error("payment declined", "event", "PAY-002", "amount", 1200)
+
+	line := buf.String()
+	want := "CEF:0|Acme|api|1.0|PAY-002|payment declined|8|rt="
+	if !strings.HasPrefix(line, want) {
+		t.Errorf("got %q, want prefix %q", line, want)
+	}
+	if !strings.HasSuffix(line, " amount=1200\n") {
+		t.Errorf("unexpected extension: %q", line)
+	}
+}
+
+// TestCEFSeverities はレベルから CEF の重要度への変換をテストします
+func TestCEFSeverities(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  int
+	}{
+		{slog.LevelDebug, 1},
+		{slog.LevelInfo, 3},
+		{slog.LevelWarn, 6},
+		{slog.LevelError, 8},
+		{slog.LevelError + 4, 10},
+	}
+	for _, tt := range tests {
+		if got := CEFSeverities.Lookup(tt.level); got != tt.want {
+			t.Errorf("CEFSeverities.Lookup(%v) = %d, want %d", tt.level, got, tt.want)
+		}
+	}
+}
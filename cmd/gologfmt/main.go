@@ -0,0 +1,114 @@
+// gologfmt は JSON 形式または golog のテキスト形式のログを標準入力から読み込み、
+// 色付きのテキスト形式で標準出力へ書き出します。本番環境の JSON のログを手元で読むために使います。
+//
+//	kubectl logs -f deploy/api | gologfmt
+//	gologfmt -level warn < app.log
+//
+// 解析できない行（ログ以外の出力やスタックトレースなど）はそのまま書き出します。
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	loggo "github.com/f0reth/golog"
+	"github.com/f0reth/golog/parse"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run はコマンドを実行し、終了コードを返します
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gologfmt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	color := fs.String("color", "auto", "色付きで出力するかどうか（auto, always, never）")
+	level := fs.String("level", "debug", "出力する最小のレベル（debug, info, warn, error など）")
+	timeFormat := fs.String("time-format", "", "出力する時刻の形式（Go の time パッケージのレイアウト）")
+	inputTimeFormat := fs.String("input-time-format", "", "テキスト形式の入力の時刻の形式。空の場合は golog のデフォルト")
+	separator := fs.String("separator", "", "グループ名とキーの区切り。空の場合は \".\"")
+	sortAttrs := fs.Bool("sort", false, "属性をキーでソートする")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: gologfmt [flags] < log")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	minLevel, err := loggo.ParseLevel(*level)
+	if err != nil {
+		fmt.Fprintf(stderr, "gologfmt: %v\n", err)
+		return 2
+	}
+	var useColors bool
+	switch *color {
+	case "auto":
+		useColors = loggo.IsTerminal(stdout) && os.Getenv("NO_COLOR") == ""
+	case "always":
+		useColors = true
+	case "never":
+	default:
+		fmt.Fprintf(stderr, "gologfmt: invalid -color %q (auto, always, never)\n", *color)
+		return 2
+	}
+
+	w := bufio.NewWriter(stdout)
+	renderer := loggo.NewRenderer(&loggo.Options{
+		UseColors:        useColors,
+		HighlightValues:  true,
+		EmphasizeMessage: true,
+		TimeFormat:       *timeFormat,
+		GroupSeparator:   *separator,
+		QuoteSeparator:   true,
+		SortAttrs:        *sortAttrs,
+		ReplaceAttr:      dropZeroTime,
+	})
+	parser := parse.NewParser(&parse.Options{TimeFormat: *inputTimeFormat, GroupSeparator: *separator})
+
+	r := bufio.NewReader(stdin)
+	var out []byte
+	for {
+		line, readErr := r.ReadString('\n')
+		if line != "" {
+			if rec, err := parser.Parse(line); err != nil || strings.TrimSpace(line) == "" {
+				w.WriteString(strings.TrimRight(line, "\r\n"))
+				w.WriteByte('\n')
+			} else if rec.Level >= minLevel {
+				out = renderer.AppendRecord(out[:0], rec.Slog())
+				w.Write(out)
+			}
+		}
+		// tail -f のような入力でも1行ずつ表示されるよう、入力を待つ前に書き出す
+		if r.Buffered() == 0 || readErr != nil {
+			if err := w.Flush(); err != nil {
+				fmt.Fprintf(stderr, "gologfmt: %v\n", err)
+				return 1
+			}
+		}
+		if readErr == io.EOF {
+			return 0
+		}
+		if readErr != nil {
+			fmt.Fprintf(stderr, "gologfmt: %v\n", readErr)
+			return 1
+		}
+	}
+}
+
+// dropZeroTime は時刻の無い行で時刻を出力しないようにします
+func dropZeroTime(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime && a.Value.Time().IsZero() {
+		return slog.Attr{}
+	}
+	return a
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestRun は JSON とテキスト形式の行を整形し、解析できない行をそのまま出力することをテストします
+func TestRun(t *testing.T) {
+	in := strings.Join([]string{
+		`{"time":"2024-01-15T10:30:45.5Z","level":"WARN","msg":"slow","http":{"status":503},"tags":["x"]}`,
+		`panic: something went wrong`,
+		``,
+		`[2024-01-15 10:30:45.123] [ INFO] msg="hi" "my key"="a b"`,
+		`{"level":"DEBUG","msg":"hidden"}`,
+	}, "\n")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-level", "info", "-time-format", "15:04:05.0"}, strings.NewReader(in), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr.String())
+	}
+	want := `[10:30:45.5] [ WARN] msg="slow" http.status=503 tags=["x"]
+panic: something went wrong
+
+[10:30:45.1] [ INFO] msg="hi" "my key"="a b"
+`
+	if got := stdout.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestRunColor は -color always で色付きで出力することをテストします
+func TestRunColor(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-color", "always"}, strings.NewReader(`{"level":"ERROR","msg":"x"}`), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "\x1b[") {
+		t.Errorf("got %q, want colored output", stdout.String())
+	}
+}
+
+// TestRunInvalidFlags は不正なフラグで終了コード 2 を返すことをテストします
+func TestRunInvalidFlags(t *testing.T) {
+	for _, args := range [][]string{{"-color", "sometimes"}, {"-level", "loud"}, {"-unknown"}} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, strings.NewReader(""), &stdout, &stderr); code != 2 {
+			t.Errorf("run(%v) = %d, want 2", args, code)
+		}
+		if stderr.Len() == 0 {
+			t.Errorf("run(%v) wrote nothing to stderr", args)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package loggo
+
+import (
+	"unicode/utf8"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// messageEllipsis は MessageWidth で切り詰めたメッセージの末尾に付ける印
+const messageEllipsis = "..."
+
+// fitMessage は buf の start 以降に書き込まれたメッセージの値を、表示幅が width になるように揃えます。
+// 幅が足りない場合は空白を補い、超える場合はエスケープや色の途中で切らないように切り詰めて
+// messageEllipsis と閉じるクォートを付けます。style はメッセージ全体に適用されているスタイルです。
+func fitMessage(buf *buffer.Buffer, start, width int, style string) {
+	value := (*buf)[start:]
+	quoted := len(value) > 0 && value[0] == '"'
+	limit := width - len(messageEllipsis) - 1 // 印と閉じるクォートの分を除く
+
+	total, cut, cutWidth := 0, -1, 0
+	colored := false
+	for i := 0; i < len(value); {
+		n, w := displayUnit(value[i:])
+		if value[i] == '\033' {
+			colored = colored || cut < 0
+		}
+		if cut < 0 && total+w > limit {
+			cut, cutWidth = i, total
+		}
+		total += w
+		i += n
+	}
+
+	if total > width && quoted && cut > 0 {
+		buf.SetLen(start + cut)
+		if colored {
+			buf.WriteString(colorReset)
+			buf.WriteString(style)
+		}
+		buf.WriteString(messageEllipsis)
+		buf.WriteByte('"')
+		total = cutWidth + len(messageEllipsis) + 1
+	}
+	for ; total < width; total++ {
+		buf.WriteByte(' ')
+	}
+}
+
+// displayUnit は p の先頭の、途中で切ってはいけない単位のバイト数と表示幅を返します。
+// ANSI のエスケープシーケンスは幅0、バックスラッシュによるエスケープは全体で1つの単位になります。
+func displayUnit(p []byte) (n, width int) {
+	switch p[0] {
+	case '\033':
+		n = 1
+		for n < len(p) && p[n] != 'm' {
+			n++
+		}
+		return min(n+1, len(p)), 0
+	case '\\':
+		n = 2
+		if len(p) > 1 {
+			switch p[1] {
+			case 'x':
+				n = 4
+			case 'u':
+				n = 6
+			case 'U':
+				n = 10
+			}
+		}
+		n = min(n, len(p))
+		return n, n
+	}
+	r, n := utf8.DecodeRune(p)
+	return n, runeWidth(r)
+}
+
+// runeWidth は端末での文字の表示幅を返します。東アジアの全角文字と絵文字を2として扱います。
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x1100:
+		return 1
+	case r <= 0x115f, // ハングル字母
+		0x2e80 <= r && r <= 0xa4cf && r != 0x303f, // CJK 部首から彝文字まで
+		0xac00 <= r && r <= 0xd7a3,                // ハングル音節
+		0xf900 <= r && r <= 0xfaff,                // CJK 互換漢字
+		0xfe30 <= r && r <= 0xfe4f,                // CJK 互換形
+		0xff00 <= r && r <= 0xff60,                // 全角形
+		0xffe0 <= r && r <= 0xffe6,
+		0x1f300 <= r && r <= 0x1f64f, // 絵文字
+		0x1f900 <= r && r <= 0x1f9ff,
+		0x20000 <= r && r <= 0x3fffd: // CJK 統合漢字拡張
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,64 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestMessageWidth はメッセージが固定幅に揃えられることをテストします
+func TestMessageWidth(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{
+		MessageWidth: 12,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	logger.Info("short", "k", 1)
+	logger.Info("a much longer message", "k", 2)
+	logger.Info("日本語のメッセージ", "k", 3)
+	logger.Info("abcdef\tzzz", "k", 4) // エスケープの途中では切らない
+
+	want := []string{
+		`[ INFO] msg="short"      k=1`,
+		`[ INFO] msg="a much ..." k=2`,
+		`[ INFO] msg="日本語..."  k=3`,
+		`[ INFO] msg="abcdef..."  k=4`,
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for i, w := range want {
+		if lines[i] != w {
+			t.Errorf("line %d:\ngot:  %q\nwant: %q", i, lines[i], w)
+		}
+	}
+}
+
+// TestFitMessageColors は色付きのメッセージを切り詰めても色が閉じられることをテストします
+func TestFitMessageColors(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewHandler(&buf, &Options{
+		UseColors:      true,
+		MessageWidth:   10,
+		HighlightRules: []HighlightRule{{Substring: "timeout", Color: ColorRed}},
+	})).Info("timeout while connecting")
+
+	want := `msg="` + colorRed + "timeo" + colorReset + `..."` + "\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("unexpected output:\ngot:  %q\nwant suffix: %q", buf.String(), want)
+	}
+}
+
+// TestRuneWidth は全角文字の表示幅をテストします
+func TestRuneWidth(t *testing.T) {
+	for r, want := range map[rune]int{'a': 1, 'é': 1, 'あ': 2, '漢': 2, 'Ａ': 2, '한': 2, '😀': 2} {
+		if got := runeWidth(r); got != want {
+			t.Errorf("runeWidth(%q) = %d, want %d", r, got, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package loggo
+
+import (
+	"context"
+	"log/slog"
+)
+
+// minLevelKey は WithMinLevel で設定したレベルを保持するコンテキストのキー
+type minLevelKey struct{}
+
+// WithMinLevel はハンドラーの最小ログレベルを上書きするコンテキストを返します。
+// ヘッダーやユーザーで識別した特定のリクエストだけ Debug レベルのログを出力する、といった用途に使います。
+// このコンテキストを渡したログ呼び出し（InfoContext など）にのみ適用されます。
+func WithMinLevel(ctx context.Context, level slog.Level) context.Context {
+	return context.WithValue(ctx, minLevelKey{}, level)
+}
+
+// MinLevelFromContext は WithMinLevel で設定されたレベルを返します
+func MinLevelFromContext(ctx context.Context) (slog.Level, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	level, ok := ctx.Value(minLevelKey{}).(slog.Level)
+	return level, ok
+}
+
+// loggerKey は NewContext で設定したロガーを保持するコンテキストのキー
+type loggerKey struct{}
+
+// NewContext は logger を保持するコンテキストを返します。
+// ミドルウェアでリクエストごとの属性を持つロガーを設定し、ライブラリや下位の処理では
+// 引数で受け渡さずに FromContext で取り出す、といった用途に使います。
+//
+//	ctx := golog.NewContext(r.Context(), logger.With("request_id", id))
+//	next.ServeHTTP(w, r.WithContext(ctx))
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext は NewContext で設定されたロガーを返します。
+// 設定されていない場合は slog.Default() を返すため、常に nil 以外のロガーを返します。
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && logger != nil {
+			return logger
+		}
+	}
+	return slog.Default()
+}
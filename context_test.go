@@ -0,0 +1,60 @@
+package loggo
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestWithMinLevel はコンテキストで最小ログレベルを上書きできることをテストします
+func TestWithMinLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{Level: slog.LevelInfo}))
+
+	debugCtx := WithMinLevel(context.Background(), slog.LevelDebug)
+	quietCtx := WithMinLevel(context.Background(), slog.LevelError)
+
+	logger.DebugContext(context.Background(), "normal debug")
+	logger.DebugContext(debugCtx, "request debug")
+	logger.WarnContext(quietCtx, "quiet warn")
+	logger.InfoContext(context.Background(), "normal info")
+
+	output := buf.String()
+	if strings.Contains(output, "normal debug") || strings.Contains(output, "quiet warn") {
+		t.Errorf("unexpected record in output: %s", output)
+	}
+	if !strings.Contains(output, "request debug") || !strings.Contains(output, "normal info") {
+		t.Errorf("missing record in output: %s", output)
+	}
+
+	if level, ok := MinLevelFromContext(debugCtx); !ok || level != slog.LevelDebug {
+		t.Errorf("unexpected level from context: %v %v", level, ok)
+	}
+	if _, ok := MinLevelFromContext(nil); ok {
+		t.Error("nil context should not have a level")
+	}
+}
+
+// TestLoggerContext はコンテキストに保持したロガーを取り出せること、無い場合はデフォルトのロガーを返すことをテストします
+func TestLoggerContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, nil)).With("request_id", "r1")
+
+	ctx := NewContext(context.Background(), logger)
+	FromContext(ctx).Info("handled")
+	if !strings.Contains(buf.String(), `msg="handled" request_id="r1"`) {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+
+	if got := FromContext(context.Background()); got != slog.Default() {
+		t.Error("FromContext without a logger should return slog.Default()")
+	}
+	if got := FromContext(nil); got != slog.Default() {
+		t.Error("FromContext(nil) should return slog.Default()")
+	}
+	if got := FromContext(NewContext(ctx, nil)); got != slog.Default() {
+		t.Error("nil logger in context should fall back to slog.Default()")
+	}
+}
@@ -0,0 +1,26 @@
+package loggo
+
+import "log/slog"
+
+// debugOnlyValue は DebugOnly の属性を保持する slog.LogValuer
+type debugOnlyValue []slog.Attr
+
+// LogValue は属性をキーの無いグループとして返します。
+// Handler 以外のハンドラーでは、グループがインライン化されて常に出力されます。
+func (v debugOnlyValue) LogValue() slog.Value {
+	return slog.GroupValue(v...)
+}
+
+// DebugOnly は実効レベルが Debug 以下の場合にのみ出力される属性を返します。
+// 同じ呼び出し箇所のまま、通常の運用では Info のレコードを簡潔に保ち、
+// Debug レベルで調査するときだけ詳細な属性を出力できます。
+//
+//	logger.Info("request handled", "status", 200,
+//	    golog.DebugOnly(slog.Any("headers", r.Header), slog.String("query", r.URL.RawQuery)),
+//	)
+//
+// 実効レベルは Options.Level で、レコードの属性では WithMinLevel で上書きしたレベルも考慮します。
+// Logger.With に渡した場合はハンドラーの Options.Level だけで判断します。
+func DebugOnly(attrs ...slog.Attr) slog.Attr {
+	return slog.Any("", debugOnlyValue(attrs))
+}
@@ -0,0 +1,60 @@
+package loggo
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestDebugOnly は DebugOnly の属性が実効レベルが Debug 以下の場合にのみ出力されることをテストします
+func TestDebugOnly(t *testing.T) {
+	tests := []struct {
+		name  string
+		level slog.Level
+		ctx   context.Context
+		want  string
+	}{
+		{"info", slog.LevelInfo, context.Background(), `msg="done" status=200` + "\n"},
+		{"debug", slog.LevelDebug, context.Background(), `msg="done" status=200 query="q=1" n=3` + "\n"},
+		{"context debug", slog.LevelInfo, WithMinLevel(context.Background(), slog.LevelDebug), `msg="done" status=200 query="q=1" n=3` + "\n"},
+		{"context info", slog.LevelDebug, WithMinLevel(context.Background(), slog.LevelInfo), `msg="done" status=200` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, &Options{Level: tt.level}))
+			logger.InfoContext(tt.ctx, "done", "status", 200, DebugOnly(slog.String("query", "q=1"), slog.Int("n", 3)))
+			if !strings.HasSuffix(buf.String(), tt.want) {
+				t.Errorf("got %q, want suffix %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+// TestDebugOnlyJSON は JSON 形式と With での DebugOnly の属性の扱いをテストします
+func TestDebugOnlyJSON(t *testing.T) {
+	var info, debug bytes.Buffer
+	for _, c := range []struct {
+		buf   *bytes.Buffer
+		level slog.Level
+	}{{&info, slog.LevelInfo}, {&debug, slog.LevelDebug}} {
+		logger := slog.New(NewHandler(c.buf, &Options{Level: c.level, Format: FormatJSON})).
+			With(DebugOnly(slog.String("host", "db1")))
+		logger.Info("done", DebugOnly(slog.Group("req", "id", 7)))
+	}
+	if strings.Contains(info.String(), "host") || strings.Contains(info.String(), "req") {
+		t.Errorf("info: %s", info.String())
+	}
+	if !strings.Contains(debug.String(), `"host":"db1"`) || !strings.Contains(debug.String(), `"req":{"id":7}`) {
+		t.Errorf("debug: %s", debug.String())
+	}
+
+	// 他のハンドラーではグループがインライン化されて常に出力される
+	var std bytes.Buffer
+	slog.New(slog.NewTextHandler(&std, nil)).Info("done", DebugOnly(slog.Int("n", 3)))
+	if !strings.Contains(std.String(), " n=3") {
+		t.Errorf("std: %s", std.String())
+	}
+}
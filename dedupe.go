@@ -0,0 +1,187 @@
+package loggo
+
+import (
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// DuplicateKeyPolicy は With で追加された属性とレコードの属性の間で
+// キーが重複した場合の扱い
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeysKeepAll は重複したキーをすべて出力します（デフォルト）
+	DuplicateKeysKeepAll DuplicateKeyPolicy = iota
+	// DuplicateKeysFirstWins は最初に現れた属性のみを出力します
+	DuplicateKeysFirstWins
+	// DuplicateKeysLastWins は最後に現れた属性のみを、その位置に出力します
+	DuplicateKeysLastWins
+)
+
+// duplicateKeyPolicyNames は DuplicateKeyPolicy のテキスト表現
+var duplicateKeyPolicyNames = []string{
+	DuplicateKeysKeepAll:   "keep-all",
+	DuplicateKeysFirstWins: "first-wins",
+	DuplicateKeysLastWins:  "last-wins",
+}
+
+// String は重複の扱いの名前を返します
+func (p DuplicateKeyPolicy) String() string {
+	if p >= 0 && int(p) < len(duplicateKeyPolicyNames) {
+		return duplicateKeyPolicyNames[p]
+	}
+	return "DuplicateKeyPolicy(" + strconv.Itoa(int(p)) + ")"
+}
+
+// MarshalText は重複の扱いの名前を返します
+func (p DuplicateKeyPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText は "keep-all", "first-wins", "last-wins" を解析します
+func (p *DuplicateKeyPolicy) UnmarshalText(text []byte) error {
+	i, err := parseEnumName("duplicate key policy", duplicateKeyPolicyNames, string(text))
+	if err != nil {
+		return err
+	}
+	*p = DuplicateKeyPolicy(i)
+	return nil
+}
+
+// attrSpan は事前フォーマット済みの属性1つ分の位置とキー。グループの値はメンバーごとに記録します。
+type attrSpan struct {
+	prefix     string // ハンドラーのグループのプレフィックス（"a.b." の形式、クォートなし）
+	key        string // 属性の値によるグループを含むキー
+	start, end int
+}
+
+// sameKey は prefix1+key1 と prefix2+key2 が等しいかを文字列を連結せずに判定します
+func sameKey(prefix1, key1, prefix2, key2 string) bool {
+	if len(prefix1)+len(key1) != len(prefix2)+len(key2) {
+		return false
+	}
+	if len(prefix1) > len(prefix2) {
+		prefix1, key1, prefix2, key2 = prefix2, key2, prefix1, key1
+	}
+	// prefix1 は prefix2 より短いか同じ長さ
+	n := len(prefix1)
+	return prefix2[:n] == prefix1 &&
+		key1[:len(prefix2)-n] == prefix2[n:] &&
+		key1[len(prefix2)-n:] == key2
+}
+
+// collectAttrs はレコードの属性に ReplaceAttr を適用して dst に追加します。
+// SortAttrs が有効な場合はキーでソートします。
+func (h *Handler) collectAttrs(dst []slog.Attr, r slog.Record) []slog.Attr {
+	r.Attrs(func(attr slog.Attr) bool {
+		if attr, ok := h.replace(nil, attr); ok {
+			dst = append(dst, attr)
+		}
+		return true
+	})
+	if h.sortAttrs {
+		sortAttrsByKey(dst)
+	}
+	return dst
+}
+
+// leafAttr はグループを展開した後の属性1つ分
+type leafAttr struct {
+	nested []string // 属性の値によるグループ
+	key    string   // nested を含むキー（"g.a" の形式、クォートなし）
+	attr   slog.Attr
+}
+
+// flattenAttr はグループの値をメンバーごとに展開して dst に追加します。
+// メンバーには ReplaceAttr を適用し、キーが空のグループはプレフィックスを付けずにインライン化します（writeAttr と同じ）。
+func (h *Handler) flattenAttr(dst []leafAttr, nested []string, attr slog.Attr) []leafAttr {
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			nested = append(slices.Clip(nested), attr.Key)
+		}
+		for _, member := range attr.Value.Group() {
+			if member, ok := h.replace(nested, member); ok {
+				dst = h.flattenAttr(dst, nested, member)
+			}
+		}
+		return dst
+	}
+	key := attr.Key
+	if len(nested) > 0 {
+		key = strings.Join(nested, ".") + "." + key
+	}
+	return append(dst, leafAttr{nested: nested, key: key, attr: attr})
+}
+
+// appendPreformattedDeduped は重複の規則に従って事前フォーマット済みの属性を書き込みます。
+// attrs はグループを展開したレコードの属性です。
+func (h *Handler) appendPreformattedDeduped(buf *buffer.Buffer, attrs []leafAttr) {
+	spans := h.preformattedSpans
+	for i, s := range spans {
+		keep := true
+		switch {
+		case s.key == badKey:
+			// slog と同じく、!BADKEY は重複として扱わない
+		case h.duplicateKeys == DuplicateKeysFirstWins:
+			for _, prev := range spans[:i] {
+				if sameKey(prev.prefix, prev.key, s.prefix, s.key) {
+					keep = false
+					break
+				}
+			}
+		case h.duplicateKeys == DuplicateKeysLastWins:
+			for _, next := range spans[i+1:] {
+				if sameKey(next.prefix, next.key, s.prefix, s.key) {
+					keep = false
+					break
+				}
+			}
+			for _, a := range attrs {
+				if !keep {
+					break
+				}
+				keep = !sameKey(h.groupPrefix, a.key, s.prefix, s.key)
+			}
+		}
+		if keep {
+			buf.Write(h.preformattedAttrs[s.start:s.end])
+		}
+	}
+}
+
+// appendAttrsDeduped は重複の規則に従って、グループを展開したレコードの属性を書き込みます
+func (h *Handler) appendAttrsDeduped(buf *buffer.Buffer, attrs []leafAttr) {
+	for i, a := range attrs {
+		keep := true
+		switch {
+		case a.attr.Key == badKey:
+		case h.duplicateKeys == DuplicateKeysFirstWins:
+			for _, s := range h.preformattedSpans {
+				if sameKey(s.prefix, s.key, h.groupPrefix, a.key) {
+					keep = false
+					break
+				}
+			}
+			for _, prev := range attrs[:i] {
+				if !keep {
+					break
+				}
+				keep = prev.key != a.key
+			}
+		case h.duplicateKeys == DuplicateKeysLastWins:
+			for _, next := range attrs[i+1:] {
+				if next.key == a.key {
+					keep = false
+					break
+				}
+			}
+		}
+		if keep {
+			h.writeAttr(buf, a.nested, a.attr)
+		}
+	}
+}
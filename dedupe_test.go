@@ -0,0 +1,139 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestDuplicateKeys は重複キーの扱いをテストします
+func TestDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy DuplicateKeyPolicy
+		want   string
+	}{
+		{"keep all", DuplicateKeysKeepAll, `msg="test" a=1 b=1 a=2 c=1 a=3 c=2`},
+		{"first wins", DuplicateKeysFirstWins, `msg="test" a=1 b=1 c=1`},
+		{"last wins", DuplicateKeysLastWins, `msg="test" b=1 a=3 c=2`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, &Options{DuplicateKeys: tt.policy}))
+			logger = logger.With("a", 1, "b", 1).With("a", 2)
+			logger.Info("test", "c", 1, "a", 3, "c", 2)
+
+			if got := strings.TrimSpace(buf.String()); !strings.HasSuffix(got, tt.want) {
+				t.Errorf("want suffix %q, got: %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestDuplicateKeysWithGroups はグループのプレフィックスを含めてキーが比較されることをテストします
+func TestDuplicateKeysWithGroups(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{DuplicateKeys: DuplicateKeysLastWins}))
+	logger = logger.With("id", 1, "g.id", 2).WithGroup("g").With("id", 3)
+	logger.Info("test", "id", 4)
+
+	// "g.id" という名前のキーと g グループ内の id は同じキーとして扱われる
+	if !strings.HasSuffix(strings.TrimSpace(buf.String()), `msg="test" id=1 g.id=4`) {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
+
+// TestDuplicateKeysSameGroup は同じ名前のグループの属性がメンバーのキーごとに比較されることをテストします
+func TestDuplicateKeysSameGroup(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy DuplicateKeyPolicy
+		want   string
+	}{
+		{"first wins", DuplicateKeysFirstWins, `msg="test" g.a=1 g.c=1 g.b=2 g.d=4`},
+		{"last wins", DuplicateKeysLastWins, `msg="test" g.c=1 g.b=2 g.a=3 g.d=4`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, &Options{DuplicateKeys: tt.policy}))
+			logger = logger.With(slog.Group("g", "a", 1, "c", 1))
+			logger.Info("test", slog.Group("g", "b", 2), slog.Group("g", "a", 3, "d", 4))
+
+			if got := strings.TrimSpace(buf.String()); !strings.HasSuffix(got, tt.want) {
+				t.Errorf("want suffix %q, got: %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestDuplicateKeysInlineGroup はキーが空のグループがインライン化されたキーで比較されることをテストします
+func TestDuplicateKeysInlineGroup(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy DuplicateKeyPolicy
+		want   string
+	}{
+		{"first wins", DuplicateKeysFirstWins, `msg="test" a=1 b=2 c=3 d=4`},
+		{"last wins", DuplicateKeysLastWins, `msg="test" b=2 c=3 d=4 a=5`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, &Options{DuplicateKeys: tt.policy}))
+			logger = logger.With(slog.Group("", "a", 1), slog.Group("", "b", 2))
+			logger.Info("test", slog.Group("", "c", 3), slog.Group("", "d", 4), "a", 5)
+
+			if got := strings.TrimSpace(buf.String()); !strings.HasSuffix(got, tt.want) {
+				t.Errorf("want suffix %q, got: %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestDuplicateKeysWithSortAndReplaceAttr は他のオプションとの組み合わせをテストします
+func TestDuplicateKeysWithSortAndReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{
+		DuplicateKeys: DuplicateKeysFirstWins,
+		SortAttrs:     true,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == "alias" {
+				a.Key = "name"
+			}
+			return a
+		},
+	}))
+	logger.With("alias", "first").Info("test", "z", 1, "name", "second", "b", 2)
+
+	if !strings.HasSuffix(strings.TrimSpace(buf.String()), `msg="test" name="first" b=2 z=1`) {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
+
+// TestSameKey は sameKey をテストします
+func TestSameKey(t *testing.T) {
+	tests := []struct {
+		p1, k1, p2, k2 string
+		want           bool
+	}{
+		{"", "a", "", "a", true},
+		{"g.", "a", "", "g.a", true},
+		{"", "g.a", "g.", "a", true},
+		{"g.", "a", "g.", "b", false},
+		{"g.h.", "a", "g.", "h.a", true},
+		{"g.", "ha", "g.h", "a", true},
+		{"g.", "a", "h.", "a", false},
+		{"", "ab", "", "a", false},
+	}
+	for _, tt := range tests {
+		if got := sameKey(tt.p1, tt.k1, tt.p2, tt.k2); got != tt.want {
+			t.Errorf("sameKey(%q, %q, %q, %q) = %v, want %v", tt.p1, tt.k1, tt.p2, tt.k2, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package loggo
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// badKey は slog が値に対応するキーの無い引数に付けるキー
+const badKey = "!BADKEY"
+
+// DevMode はログ呼び出しの誤りを検出した場合の動作
+type DevMode int
+
+const (
+	// DevModeOff は検査を行いません（デフォルト）
+	DevModeOff DevMode = iota
+	// DevModeWarn は誤りを説明する ERROR のレコードを、元のレコードの前に出力します
+	DevModeWarn
+	// DevModePanic は誤りを説明するメッセージでパニックを起こします。テストで誤りを確実に検出するために使います。
+	DevModePanic
+)
+
+// devModeNames は DevMode のテキスト表現
+var devModeNames = []string{
+	DevModeOff:   "off",
+	DevModeWarn:  "warn",
+	DevModePanic: "panic",
+}
+
+// String は動作の名前を返します
+func (m DevMode) String() string {
+	if m >= 0 && int(m) < len(devModeNames) {
+		return devModeNames[m]
+	}
+	return "DevMode(" + strconv.Itoa(int(m)) + ")"
+}
+
+// MarshalText は動作の名前を返します
+func (m DevMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText は "off", "warn", "panic" を解析します
+func (m *DevMode) UnmarshalText(text []byte) error {
+	i, err := parseEnumName("dev mode", devModeNames, string(text))
+	if err != nil {
+		return err
+	}
+	*m = DevMode(i)
+	return nil
+}
+
+// validateRecord はレコードの属性の誤りを検査し、見つかった誤りの説明を返します。
+// 値の無いキーや文字列でないキー（!BADKEY）、空のキー、同じグループ内で重複したキーを検出します。
+func validateRecord(r slog.Record) []string {
+	var problems []string
+	seen := make(map[string]bool)
+	var walk func(prefix string, a slog.Attr)
+	walk = func(prefix string, a slog.Attr) {
+		if a.Value.Kind() == slog.KindGroup {
+			if a.Key != "" {
+				prefix += a.Key + "."
+			}
+			for _, member := range a.Value.Group() {
+				walk(prefix, member)
+			}
+			return
+		}
+		switch {
+		case a.Key == badKey:
+			problems = append(problems, "argument without a key or non-string key: "+a.Value.String())
+		case a.Key == "":
+			if !a.Equal(slog.Attr{}) {
+				problems = append(problems, "empty key for value: "+a.Value.String())
+			}
+		case seen[prefix+a.Key]:
+			problems = append(problems, "duplicate key: "+prefix+a.Key)
+		default:
+			seen[prefix+a.Key] = true
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		walk("", a)
+		return true
+	})
+	return problems
+}
+
+// checkRecord は DevMode に従ってレコードを検査し、誤りがあればパニックを起こすか ERROR のレコードを出力します
+func (h *Handler) checkRecord(ctx context.Context, r slog.Record) {
+	problems := validateRecord(r)
+	if len(problems) == 0 {
+		return
+	}
+	if h.devMode == DevModePanic {
+		panic("golog: invalid log call" + callSite(r.PC) + ": " + strings.Join(problems, "; "))
+	}
+	warn := slog.NewRecord(r.Time, slog.LevelError, "golog: invalid log call", r.PC)
+	warn.AddAttrs(slog.String("problems", strings.Join(problems, "; ")))
+	h.Handle(ctx, warn)
+}
+
+// callSite は " at file.go:42" の形式で呼び出し位置を返します。pc が 0 の場合は空文字列を返します。
+func callSite(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return " at " + filepath.Base(f.File) + ":" + strconv.Itoa(f.Line)
+}
@@ -0,0 +1,64 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestDevModePanic はログ呼び出しの誤りでパニックが起きることをテストします
+func TestDevModePanic(t *testing.T) {
+	tests := []struct {
+		name string
+		args []any
+		want string
+	}{
+		{"odd arguments", []any{"a", 1, "b"}, "argument without a key or non-string key: b"},
+		{"non-string key", []any{42, "x", "y"}, "argument without a key or non-string key: 42"},
+		{"empty key", []any{"", 1}, "empty key for value: 1"},
+		{"duplicate key", []any{"a", 1, "a", 2}, "duplicate key: a"},
+		{"duplicate key in group", []any{slog.Group("g", "a", 1, "a", 2)}, "duplicate key: g.a"},
+	}
+	logger := slog.New(NewHandler(&bytes.Buffer{}, &Options{DevMode: DevModePanic}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.HasPrefix(msg, "golog: invalid log call at devmode_test.go:") || !strings.HasSuffix(msg, tt.want) {
+					t.Errorf("unexpected panic: %q", msg)
+				}
+			}()
+			logger.Info("test", tt.args...)
+		})
+	}
+}
+
+// TestDevModeValid は正しいログ呼び出しでは何もしないことをテストします
+func TestDevModeValid(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{DevMode: DevModePanic}))
+	logger.Info("test", "a", 1, slog.Group("g", "a", 2), slog.Attr{}, slog.Group("", "b", 3))
+	if !strings.HasSuffix(buf.String(), `msg="test" a=1 g.a=2 b=3`+"\n") {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+// TestDevModeWarn は誤りを説明する ERROR のレコードが元のレコードの前に出力されることをテストします
+func TestDevModeWarn(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{DevMode: DevModeWarn, AddSource: true}))
+	logger.Info("test", "a", 1, "a", 2)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d:\n%s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], `[ERROR] msg="golog: invalid log call" source="devmode_test.go:`) ||
+		!strings.HasSuffix(lines[0], `problems="duplicate key: a"`) {
+		t.Errorf("unexpected warning: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `msg="test"`) {
+		t.Errorf("expected original record after warning, got %q", lines[1])
+	}
+}
@@ -0,0 +1,635 @@
+package loggo
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// maxEncodeDepth はポインタや interface を辿る最大の深さ。
+// 循環参照を含む値で無限に再帰しないようにします。
+const maxEncodeDepth = 1000
+
+// errEncodeCycle は値が循環参照を含む場合に返されるエラー
+var errEncodeCycle = errors.New("encountered a cycle or too deep value")
+
+// encoderFunc は reflect.Value を JSON 形式でバッファに書き込む関数型
+type encoderFunc func(buf *buffer.Buffer, v reflect.Value, depth int) error
+
+// encoderCache は型ごとにコンパイル済みの encoderFunc を保持します
+var encoderCache sync.Map // map[reflect.Type]encoderFunc
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// appendReflect は任意の値を JSON 形式でバッファに直接書き込みます。
+// 型ごとのエンコード手順は初回にコンパイルされ、以降はキャッシュから再利用されます。
+// エラーが発生した場合、途中まで書き込んだ内容は取り消されます。
+func appendReflect(buf *buffer.Buffer, v reflect.Value) error {
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+	start := buf.Len()
+	if err := typeEncoder(v.Type())(buf, v, 0); err != nil {
+		buf.SetLen(start)
+		return err
+	}
+	return nil
+}
+
+// typeEncoder は型に対応する encoderFunc をキャッシュから取得し、無ければコンパイルします
+func typeEncoder(t reflect.Type) encoderFunc {
+	if f, ok := encoderCache.Load(t); ok {
+		return f.(encoderFunc)
+	}
+
+	// 再帰的な型のために、コンパイル完了を待つ間接的な関数を先に登録しておく
+	var (
+		wg sync.WaitGroup
+		f  encoderFunc
+	)
+	wg.Add(1)
+	fi, loaded := encoderCache.LoadOrStore(t, encoderFunc(func(buf *buffer.Buffer, v reflect.Value, depth int) error {
+		wg.Wait()
+		return f(buf, v, depth)
+	}))
+	if loaded {
+		return fi.(encoderFunc)
+	}
+
+	f = newTypeEncoder(t, true)
+	wg.Done()
+	encoderCache.Store(t, f)
+	return f
+}
+
+// newTypeEncoder は型に応じた encoderFunc を作成します。
+// allowAddr が true の場合、ポインタレシーバーのマーシャラーもアドレス可能な値に適用します。
+func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
+	if t.Kind() != reflect.Pointer && allowAddr && reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return condAddrEncoder(marshalerEncoder, newTypeEncoder(t, false))
+	}
+	if t.Implements(jsonMarshalerType) {
+		return marshalerEncoder
+	}
+	if t.Kind() != reflect.Pointer && allowAddr && reflect.PointerTo(t).Implements(textMarshalerType) {
+		return condAddrEncoder(textMarshalerEncoder, newTypeEncoder(t, false))
+	}
+	if t.Implements(textMarshalerType) {
+		return textMarshalerEncoder
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return boolEncoder
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intEncoder
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintEncoder
+	case reflect.Float32:
+		return float32Encoder
+	case reflect.Float64:
+		return float64Encoder
+	case reflect.Complex64, reflect.Complex128:
+		return complexEncoder
+	case reflect.String:
+		return stringEncoder
+	case reflect.Interface:
+		return interfaceEncoder
+	case reflect.Struct:
+		return newStructEncoder(t)
+	case reflect.Map:
+		return newMapEncoder(t)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && !reflect.PointerTo(t.Elem()).Implements(jsonMarshalerType) {
+			return bytesEncoder
+		}
+		return newArrayEncoder(t, true)
+	case reflect.Array:
+		return newArrayEncoder(t, false)
+	case reflect.Pointer:
+		return newPointerEncoder(t)
+	default:
+		return unsupportedTypeEncoder
+	}
+}
+
+// condAddrEncoder はアドレス可能な値には addrEnc を、それ以外には elseEnc を使用します
+func condAddrEncoder(addrEnc, elseEnc encoderFunc) encoderFunc {
+	return func(buf *buffer.Buffer, v reflect.Value, depth int) error {
+		if v.CanAddr() {
+			return addrEnc(buf, v.Addr(), depth)
+		}
+		return elseEnc(buf, v, depth)
+	}
+}
+
+func marshalerEncoder(buf *buffer.Buffer, v reflect.Value, _ int) error {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		buf.WriteString("null")
+		return nil
+	}
+	// 出力の検証と圧縮のため encoding/json に委譲する
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+func textMarshalerEncoder(buf *buffer.Buffer, v reflect.Value, _ int) error {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		buf.WriteString("null")
+		return nil
+	}
+	text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return err
+	}
+	*buf = appendJSONString(*buf, string(text))
+	return nil
+}
+
+func boolEncoder(buf *buffer.Buffer, v reflect.Value, _ int) error {
+	*buf = strconv.AppendBool(*buf, v.Bool())
+	return nil
+}
+
+func intEncoder(buf *buffer.Buffer, v reflect.Value, _ int) error {
+	*buf = strconv.AppendInt(*buf, v.Int(), 10)
+	return nil
+}
+
+func uintEncoder(buf *buffer.Buffer, v reflect.Value, _ int) error {
+	*buf = strconv.AppendUint(*buf, v.Uint(), 10)
+	return nil
+}
+
+func float32Encoder(buf *buffer.Buffer, v reflect.Value, _ int) error {
+	return appendJSONFloat(buf, v.Float(), 32)
+}
+
+func float64Encoder(buf *buffer.Buffer, v reflect.Value, _ int) error {
+	return appendJSONFloat(buf, v.Float(), 64)
+}
+
+func stringEncoder(buf *buffer.Buffer, v reflect.Value, _ int) error {
+	*buf = appendJSONString(*buf, v.String())
+	return nil
+}
+
+func bytesEncoder(buf *buffer.Buffer, v reflect.Value, _ int) error {
+	if v.IsNil() {
+		buf.WriteString("null")
+		return nil
+	}
+	buf.WriteByte('"')
+	*buf = base64.StdEncoding.AppendEncode(*buf, v.Bytes())
+	buf.WriteByte('"')
+	return nil
+}
+
+func interfaceEncoder(buf *buffer.Buffer, v reflect.Value, depth int) error {
+	if v.IsNil() {
+		buf.WriteString("null")
+		return nil
+	}
+	if depth++; depth > maxEncodeDepth {
+		return errEncodeCycle
+	}
+	e := v.Elem()
+	return typeEncoder(e.Type())(buf, e, depth)
+}
+
+// complexEncoder は JSON に対応する型が無い複素数を "(1+2i)" の形式の文字列として書き込みます
+func complexEncoder(buf *buffer.Buffer, v reflect.Value, _ int) error {
+	bits := v.Type().Bits()
+	buf.WriteByte('"')
+	*buf = append(*buf, strconv.FormatComplex(v.Complex(), 'g', -1, bits)...)
+	buf.WriteByte('"')
+	return nil
+}
+
+// unsupportedTypeEncoder は JSON で表現できない値を "型名(値)" の文字列として書き込みます。
+// チャネル、関数、unsafe.Pointer は fmt と同様にアドレスを出力します。
+func unsupportedTypeEncoder(buf *buffer.Buffer, v reflect.Value, _ int) error {
+	var s string
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if v.IsNil() {
+			s = v.Type().String() + "(nil)"
+		} else {
+			s = fmt.Sprintf("%s(%#x)", v.Type(), v.Pointer())
+		}
+	default:
+		s = fmt.Sprintf("%s(%v)", v.Type(), v)
+	}
+	*buf = appendJSONString(*buf, s)
+	return nil
+}
+
+func newPointerEncoder(t reflect.Type) encoderFunc {
+	elemEnc := typeEncoder(t.Elem())
+	return func(buf *buffer.Buffer, v reflect.Value, depth int) error {
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		if depth++; depth > maxEncodeDepth {
+			return errEncodeCycle
+		}
+		return elemEnc(buf, v.Elem(), depth)
+	}
+}
+
+func newArrayEncoder(t reflect.Type, isSlice bool) encoderFunc {
+	elemEnc := typeEncoder(t.Elem())
+	return func(buf *buffer.Buffer, v reflect.Value, depth int) error {
+		if isSlice && v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteByte('[')
+		for i := range v.Len() {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := elemEnc(buf, v.Index(i), depth); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+}
+
+func newMapEncoder(t reflect.Type) encoderFunc {
+	switch t.Key().Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		if !t.Key().Implements(textMarshalerType) {
+			return unsupportedTypeEncoder
+		}
+	}
+
+	elemEnc := typeEncoder(t.Elem())
+	return func(buf *buffer.Buffer, v reflect.Value, depth int) error {
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		if depth++; depth > maxEncodeDepth {
+			return errEncodeCycle
+		}
+
+		type entry struct {
+			key string
+			val reflect.Value
+		}
+		entries := make([]entry, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := mapKeyString(iter.Key())
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry{key, iter.Value()})
+		}
+		slices.SortFunc(entries, func(a, b entry) int { return strings.Compare(a.key, b.key) })
+
+		buf.WriteByte('{')
+		for i, e := range entries {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			*buf = appendJSONString(*buf, e.key)
+			buf.WriteByte(':')
+			if err := elemEnc(buf, e.val, depth); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	}
+}
+
+// mapKeyString はマップのキーを encoding/json と同じ規則で文字列に変換します
+func mapKeyString(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Pointer && k.IsNil() {
+			return "", nil
+		}
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	default:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+}
+
+// structField は構造体フィールドのエンコード手順
+type structField struct {
+	name      string
+	nameBytes []byte // エスケープ済みの "name":
+	index     []int
+	tagged    bool
+	omitEmpty bool
+	typ       reflect.Type
+	enc       encoderFunc
+}
+
+func newStructEncoder(t reflect.Type) encoderFunc {
+	fields := typeFields(t)
+	return func(buf *buffer.Buffer, v reflect.Value, depth int) error {
+		buf.WriteByte('{')
+		first := true
+	fieldLoop:
+		for i := range fields {
+			f := &fields[i]
+
+			fv := v
+			for _, idx := range f.index {
+				if fv.Kind() == reflect.Pointer {
+					if fv.IsNil() {
+						continue fieldLoop
+					}
+					fv = fv.Elem()
+				}
+				fv = fv.Field(idx)
+			}
+
+			if f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.Write(f.nameBytes)
+			if err := f.enc(buf, fv, depth); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	}
+}
+
+// parseFieldTag は log タグ（無い場合は json タグ）からフィールド名とオプションを取得します
+func parseFieldTag(sf reflect.StructField) (name string, omitEmpty, ok bool) {
+	tag, found := sf.Tag.Lookup("log")
+	if !found {
+		tag = sf.Tag.Get("json")
+	}
+	if tag == "-" {
+		return "", false, false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, true
+}
+
+// typeFields は構造体の出力対象フィールドを encoding/json と同様の規則で列挙します。
+// 埋め込み構造体のフィールドは昇格され、同名のフィールドはより浅いものが優先されます。
+func typeFields(t reflect.Type) []structField {
+	var candidates []fieldCandidate
+	visited := map[reflect.Type]bool{}
+
+	var walk func(t reflect.Type, index []int, depth int)
+	walk = func(t reflect.Type, index []int, depth int) {
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		defer delete(visited, t)
+
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			ft := sf.Type
+			if sf.Anonymous {
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if !sf.IsExported() && ft.Kind() != reflect.Struct {
+					continue
+				}
+			} else if !sf.IsExported() {
+				continue
+			}
+
+			name, omitEmpty, ok := parseFieldTag(sf)
+			if !ok {
+				continue
+			}
+
+			idx := append(slices.Clip(index), i)
+
+			if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				walk(ft, idx, depth+1)
+				continue
+			}
+
+			tagged := name != ""
+			if name == "" {
+				name = sf.Name
+			}
+			candidates = append(candidates, fieldCandidate{
+				structField: structField{
+					name:      name,
+					index:     idx,
+					tagged:    tagged,
+					omitEmpty: omitEmpty,
+					typ:       sf.Type,
+				},
+				depth: depth,
+			})
+		}
+	}
+	walk(t, nil, 0)
+
+	// 同名フィールドの中から優先されるものを選ぶ
+	byName := map[string][]fieldCandidate{}
+	for _, c := range candidates {
+		byName[c.name] = append(byName[c.name], c)
+	}
+
+	var fields []structField
+	for _, c := range candidates {
+		group := byName[c.name]
+		if group == nil {
+			continue
+		}
+		delete(byName, c.name)
+
+		if f, ok := dominantField(group); ok {
+			fields = append(fields, f.structField)
+		}
+	}
+
+	slices.SortFunc(fields, func(a, b structField) int { return slices.Compare(a.index, b.index) })
+	for i := range fields {
+		f := &fields[i]
+		f.nameBytes = append(appendJSONString(nil, f.name), ':')
+		f.enc = typeEncoder(f.typ)
+	}
+	return fields
+}
+
+// fieldCandidate は埋め込みの深さを含むフィールドの候補
+type fieldCandidate struct {
+	structField
+	depth int
+}
+
+// dominantField は同名フィールドの中で最も浅いものを返します。
+// 同じ深さに複数ある場合は、タグ付きのものが1つだけであればそれを選び、それ以外は無視します。
+func dominantField(group []fieldCandidate) (fieldCandidate, bool) {
+	minDepth := math.MaxInt
+	for _, c := range group {
+		minDepth = min(minDepth, c.depth)
+	}
+
+	var found fieldCandidate
+	count, taggedCount := 0, 0
+	for _, c := range group {
+		if c.depth != minDepth {
+			continue
+		}
+		count++
+		if c.tagged {
+			taggedCount++
+			found = c
+		} else if taggedCount == 0 {
+			found = c
+		}
+	}
+	if count == 1 || taggedCount == 1 {
+		return found, true
+	}
+	return fieldCandidate{}, false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// appendJSONFloat は encoding/json と同じ形式で浮動小数点数を書き込みます
+func appendJSONFloat(buf *buffer.Buffer, f float64, bits int) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return errors.New("unsupported value: " + strconv.FormatFloat(f, 'g', -1, bits))
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b := strconv.AppendFloat(*buf, f, format, -1, bits)
+	if format == 'e' {
+		// e-09 を e-9 に整形する
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	*buf = b
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString は encoding/json と同じ規則で文字列をエスケープして書き込みます
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '\\', '"':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = utf8.AppendRune(b, utf8.RuneError)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 と U+2029 は JavaScript で、U+0085 は一部のツールで改行として扱われるためエスケープする
+		if r == '\u2028' || r == '\u2029' || r == '\u0085' {
+			b = append(b, s[start:i]...)
+			b = appendUnicodeEscape(b, r)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
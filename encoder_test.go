@@ -0,0 +1,216 @@
+package loggo
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+type encoderInner struct {
+	Value string
+	Ptr   *int
+}
+
+type encoderEmbedded struct {
+	ID   int
+	Name string
+}
+
+type encoderOuter struct {
+	encoderEmbedded
+	Name     string `json:"name"`
+	Inner    encoderInner
+	List     []encoderInner
+	Map      map[string]int
+	IntKeys  map[int]string
+	Bytes    []byte
+	Any      any
+	Time     time.Time
+	IP       net.IP
+	Float    float64
+	Small    float32
+	Skipped  string `json:"-"`
+	Empty    string `json:",omitempty"`
+	internal string
+}
+
+type encoderNode struct {
+	Value int
+	Next  *encoderNode
+}
+
+// TestEncoderMatchesJSON はエンコーダーの出力が encoding/json と一致することをテストします
+func TestEncoderMatchesJSON(t *testing.T) {
+	n := 7
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"struct", encoderOuter{
+			encoderEmbedded: encoderEmbedded{ID: 1, Name: "shadowed"},
+			Name:            "outer",
+			Inner:           encoderInner{Value: "v", Ptr: &n},
+			List:            []encoderInner{{Value: "a"}, {Value: "b"}},
+			Map:             map[string]int{"z": 1, "a": 2, "m": 3},
+			IntKeys:         map[int]string{10: "ten", 2: "two"},
+			Bytes:           []byte("hello"),
+			Any:             map[string]any{"nested": []any{1, "two", nil}},
+			Time:            time.Date(2024, 1, 15, 10, 30, 45, 123, time.UTC),
+			IP:              net.ParseIP("192.168.0.1"),
+			Float:           1e21,
+			Small:           0.1,
+			Skipped:         "skip",
+			internal:        "internal",
+		}},
+		{"zero struct", encoderOuter{}},
+		{"escaping", map[string]string{"html": "<a href=\"x\">&</a>", "ctrl": "\x00\x1f\t\n", "unicode": "日本語 ", "invalid": "\xff"}},
+		{"small float", []float64{1e-7, 0.000001, 123456789.125, -0.5}},
+		{"linked list", &encoderNode{Value: 1, Next: &encoderNode{Value: 2}}},
+		{"nil slice", struct{ S []int }{}},
+		{"array", [3]bool{true, false, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf := buffer.New()
+			defer buf.Free()
+			if err := appendReflect(buf, reflect.ValueOf(tt.value)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("output mismatch\n got: %s\nwant: %s", got, want)
+			}
+		})
+	}
+}
+
+// TestEncoderLogTags は log タグが json タグより優先されることをテストします
+func TestEncoderLogTags(t *testing.T) {
+	type Request struct {
+		ID       int    `json:"id" log:"request_id"`
+		Password string `json:"password" log:"-"`
+		Note     string `log:"note,omitempty"`
+		Method   string `json:"method"`
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, nil))
+	logger.Info("test", "req", Request{ID: 1, Password: "secret", Method: "GET"})
+
+	output := buf.String()
+	if !strings.Contains(output, `req={"request_id":1,"method":"GET"}`) {
+		t.Errorf("log tags should be honored, got: %s", output)
+	}
+	if strings.Contains(output, "secret") {
+		t.Errorf("field tagged log:\"-\" should be omitted, got: %s", output)
+	}
+}
+
+// TestEncoderErrors はエンコードできない値でエラーが報告されることをテストします
+func TestEncoderErrors(t *testing.T) {
+	t.Run("cycle", func(t *testing.T) {
+		node := &encoderNode{Value: 1}
+		node.Next = node
+
+		buf := buffer.New()
+		defer buf.Free()
+		if err := appendReflect(buf, reflect.ValueOf(node)); err == nil {
+			t.Error("expected error for cyclic value")
+		}
+	})
+
+	t.Run("NaN", func(t *testing.T) {
+		buf := buffer.New()
+		defer buf.Free()
+		if err := appendReflect(buf, reflect.ValueOf([]float64{math.NaN()})); err == nil {
+			t.Error("expected error for NaN")
+		}
+	})
+
+	t.Run("handler output", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(NewHandler(&buf, nil))
+		logger.Info("test", "value", struct{ F float64 }{math.Inf(1)})
+		if !strings.Contains(buf.String(), `value="!ERROR:`) {
+			t.Errorf("expected error marker, got: %s", buf.String())
+		}
+	})
+}
+
+// TestEncoderUnsupportedTypes は JSON で表現できない値が型名付きの文字列になることをテストします
+func TestEncoderUnsupportedTypes(t *testing.T) {
+	ch := make(chan int)
+	fn := func() {}
+
+	tests := []struct {
+		name  string
+		value any
+		want  *regexp.Regexp
+	}{
+		{"chan", ch, regexp.MustCompile(`^"chan int\(0x[0-9a-f]+\)"$`)},
+		{"func", fn, regexp.MustCompile(`^"func\(\)\(0x[0-9a-f]+\)"$`)},
+		{"nil chan", (chan string)(nil), regexp.MustCompile(`^"chan string\(nil\)"$`)},
+		{"struct field", struct {
+			Name string
+			Done chan bool
+		}{"job", nil}, regexp.MustCompile(`^\{"Name":"job","Done":"chan bool\(nil\)"\}$`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := buffer.New()
+			defer buf.Free()
+			if err := appendReflect(buf, reflect.ValueOf(tt.value)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.want.MatchString(buf.String()) {
+				t.Errorf("unexpected output: %s", buf.String())
+			}
+		})
+	}
+
+	t.Run("handler output", func(t *testing.T) {
+		var buf bytes.Buffer
+		slog.New(NewHandler(&buf, nil)).Info("test", "ch", ch)
+		if !strings.Contains(buf.String(), `ch="chan int(0x`) || strings.Contains(buf.String(), "!ERROR") {
+			t.Errorf("unexpected output: %s", buf.String())
+		}
+	})
+}
+
+// BenchmarkStructEncoding はエンコーダーと encoding/json を比較します
+func BenchmarkStructEncoding(b *testing.B) {
+	value := encoderInner{Value: "value"}
+
+	b.Run("Encoder", func(b *testing.B) {
+		buf := buffer.New()
+		defer buf.Free()
+		rv := reflect.ValueOf(value)
+		b.ReportAllocs()
+		for b.Loop() {
+			buf.Reset()
+			appendReflect(buf, rv)
+		}
+	})
+
+	b.Run("JSONMarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			json.Marshal(value)
+		}
+	})
+}
@@ -0,0 +1,245 @@
+package loggo
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxEncryptedFrameSize は復号する1フレームの最大サイズ。壊れたファイルで巨大な確保を避けるために使う
+const maxEncryptedFrameSize = 64 << 20 // 64MB
+
+// EncryptionOptions は EncryptedWriter の鍵の設定。Key と KeyFunc のどちらかを指定します。
+type EncryptionOptions struct {
+	// Key は AES の鍵（16, 24, 32 バイトでそれぞれ AES-128, AES-192, AES-256）
+	Key []byte
+	// KeyFunc は KMS などから鍵を取得する関数。Key が空の場合に、ライターの作成時に一度だけ呼び出されます。
+	KeyFunc func() ([]byte, error)
+}
+
+// KeyFromEnv は環境変数 name から base64 でエンコードされた鍵を読み取ります
+func KeyFromEnv(name string) ([]byte, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("golog: environment variable %s is not set", name)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+	if err != nil {
+		return nil, fmt.Errorf("golog: environment variable %s: %w", name, err)
+	}
+	return key, nil
+}
+
+// EncryptedWriter は書き込みごとに AES-GCM で暗号化して下位のライターへ書き出すライター。
+// 平文のログを保存できない環境で FileWriter と組み合わせて使います。
+//
+// 1回の Write は「4バイトのビッグエンディアンの長さ、16バイトのストリーム ID、8バイトの通し番号、
+// 暗号文と認証タグ」の1つのフレームになります。ストリーム ID はライターごとにランダムに生成し、
+// 鍵とストリーム ID から導出したストリームごとの鍵で暗号化します。ノンスには通し番号を使うため、
+// ランダムなノンスのように書き込みの回数で衝突の確率が高くなることはありません。
+// ストリーム ID と通し番号は追加認証データとして認証されるため、NewDecryptedReader は
+// フレームの改ざんに加えて、ストリーム内のフレームの欠落、重複、入れ替えを検出します。
+type EncryptedWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	stream [encryptedStreamIDSize]byte
+
+	mu  sync.Mutex
+	seq uint64
+	buf []byte
+}
+
+const (
+	// encryptedStreamIDSize はフレームのストリーム ID のバイト数
+	encryptedStreamIDSize = 16
+	// encryptedHeaderSize はフレームの長さに続くストリーム ID と通し番号のバイト数
+	encryptedHeaderSize = encryptedStreamIDSize + 8
+	// encryptedKeyInfo はストリームごとの鍵の導出に使う HKDF の info
+	encryptedKeyInfo = "golog encrypted log v1"
+)
+
+// NewEncryptedWriter は w へ暗号化して書き出すライターを作成します
+func NewEncryptedWriter(w io.Writer, opts EncryptionOptions) (*EncryptedWriter, error) {
+	key := opts.Key
+	if len(key) == 0 {
+		if opts.KeyFunc == nil {
+			return nil, errors.New("golog: encryption key is not specified")
+		}
+		var err error
+		if key, err = opts.KeyFunc(); err != nil {
+			return nil, fmt.Errorf("golog: failed to get encryption key: %w", err)
+		}
+	}
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, fmt.Errorf("golog: invalid encryption key: %w", err)
+	}
+	ew := &EncryptedWriter{w: w}
+	rand.Read(ew.stream[:])
+	aead, err := newStreamGCM(key, ew.stream[:])
+	if err != nil {
+		return nil, err
+	}
+	ew.aead = aead
+	return ew, nil
+}
+
+// newStreamGCM は key とストリーム ID から導出した鍵で AES-GCM を作成します
+func newStreamGCM(key, stream []byte) (cipher.AEAD, error) {
+	subkey, err := hkdf.Key(sha256.New, key, stream, encryptedKeyInfo, len(key))
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(subkey)
+	if err != nil {
+		return nil, fmt.Errorf("golog: invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// frameNonce はフレームの通し番号からノンスを作ります
+func frameNonce(nonce *[12]byte, seq uint64) []byte {
+	binary.BigEndian.PutUint64(nonce[4:], seq)
+	return nonce[:]
+}
+
+// Write は p を1つのフレームに暗号化して書き出します
+func (w *EncryptedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.seq == math.MaxUint64 {
+		return 0, errors.New("golog: encrypted stream exhausted its frame counter")
+	}
+	size := encryptedHeaderSize + len(p) + w.aead.Overhead()
+	frame := slices.Grow(w.buf[:0], 4+size)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(size))
+	frame = append(frame, w.stream[:]...)
+	frame = binary.BigEndian.AppendUint64(frame, w.seq)
+	var nonce [12]byte
+	frame = w.aead.Seal(frame, frameNonce(&nonce, w.seq), p, frame[4:4+encryptedHeaderSize])
+	// 書き込みに失敗した場合も途中まで書き込まれた可能性があるため、ノンスを再利用しないよう番号を進める
+	w.seq++
+
+	_, err := w.w.Write(frame)
+	if cap(frame) <= maxRetainedBatchSize {
+		w.buf = frame[:0]
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Ping は下位のライターが HealthChecker を実装している場合にその Ping を呼び出します
+func (w *EncryptedWriter) Ping(ctx context.Context) error {
+	if hc, ok := w.w.(HealthChecker); ok {
+		return hc.Ping(ctx)
+	}
+	return ctx.Err()
+}
+
+// Healthy は下位のライターが HealthChecker を実装している場合にその Healthy を返します
+func (w *EncryptedWriter) Healthy() bool {
+	if hc, ok := w.w.(HealthChecker); ok {
+		return hc.Healthy()
+	}
+	return true
+}
+
+// decryptedReader は EncryptedWriter が書き出したフレームを復号するリーダー
+type decryptedReader struct {
+	r       io.Reader
+	key     []byte
+	aead    cipher.AEAD // stream の鍵の AES-GCM
+	stream  [encryptedStreamIDSize]byte
+	next    uint64 // stream で次に期待する通し番号
+	frame   []byte
+	plain   []byte // 未読の平文
+	scratch []byte
+}
+
+// NewDecryptedReader は EncryptedWriter で暗号化されたデータを復号するリーダーを返します。
+// 改ざんされたフレームや鍵が異なる場合、ストリーム内のフレームが欠落、重複、入れ替わっている場合は Read がエラーを返します。
+// ローテーションしたファイルのように、ストリームの途中から始まるデータや、複数のストリームを連結したデータも復号できます。
+func NewDecryptedReader(r io.Reader, key []byte) (io.Reader, error) {
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, fmt.Errorf("golog: invalid encryption key: %w", err)
+	}
+	return &decryptedReader{r: r, key: bytes.Clone(key)}, nil
+}
+
+func (d *decryptedReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if err := d.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// readFrame は次のフレームを読み込んで復号します
+func (d *decryptedReader) readFrame() error {
+	var header [4]byte
+	if _, err := io.ReadFull(d.r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("golog: truncated encrypted frame: %w", err)
+		}
+		return err
+	}
+	size := int(binary.BigEndian.Uint32(header[:]))
+	// 認証タグの長さは AES-GCM の標準の 16 バイト
+	if size < encryptedHeaderSize+16 || size > maxEncryptedFrameSize {
+		return fmt.Errorf("golog: invalid encrypted frame size %d", size)
+	}
+	if cap(d.frame) < size {
+		d.frame = make([]byte, size)
+	}
+	frame := d.frame[:size]
+	if _, err := io.ReadFull(d.r, frame); err != nil {
+		return fmt.Errorf("golog: truncated encrypted frame: %w", err)
+	}
+
+	aad, ciphertext := frame[:encryptedHeaderSize], frame[encryptedHeaderSize:]
+	stream, seq := aad[:encryptedStreamIDSize], binary.BigEndian.Uint64(aad[encryptedStreamIDSize:])
+	aead := d.aead
+	newStream := aead == nil || !bytes.Equal(stream, d.stream[:])
+	if newStream {
+		// 新しいストリームはどの通し番号から始まってもよい
+		var err error
+		if aead, err = newStreamGCM(d.key, stream); err != nil {
+			return err
+		}
+	} else if seq != d.next {
+		return fmt.Errorf("golog: encrypted frame out of sequence: expected %d, got %d", d.next, seq)
+	}
+
+	var nonce [12]byte
+	plain, err := aead.Open(d.scratch[:0], frameNonce(&nonce, seq), ciphertext, aad)
+	if err != nil {
+		return fmt.Errorf("golog: failed to decrypt frame: %w", err)
+	}
+	if newStream {
+		d.aead = aead
+		copy(d.stream[:], stream)
+	}
+	d.next = seq + 1
+	d.scratch = plain
+	d.plain = plain
+	return nil
+}
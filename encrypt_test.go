@@ -0,0 +1,145 @@
+package loggo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// testEncryptionKey はテスト用の AES-256 の鍵
+var testEncryptionKey = bytes.Repeat([]byte{0x42}, 32)
+
+// TestEncryptedWriter は暗号化したログを復号すると元の行に戻ることをテストします
+func TestEncryptedWriter(t *testing.T) {
+	var encrypted bytes.Buffer
+	ew, err := NewEncryptedWriter(&encrypted, EncryptionOptions{
+		KeyFunc: func() ([]byte, error) { return testEncryptionKey, nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(NewHandler(ew, nil))
+	logger.Info("secret", "card", "4111-1111-1111-1111")
+	logger.Warn("second")
+
+	if bytes.Contains(encrypted.Bytes(), []byte("4111")) {
+		t.Fatal("plaintext should not appear in the encrypted output")
+	}
+
+	r, err := NewDecryptedReader(bytes.NewReader(encrypted.Bytes()), testEncryptionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(plain), "\n"), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `msg="secret" card="4111-1111-1111-1111"`) || !strings.Contains(lines[1], `msg="second"`) {
+		t.Errorf("unexpected decrypted output: %q", plain)
+	}
+}
+
+// TestDecryptedReaderTampered は改ざんされたデータや異なる鍵で復号できないことをテストします
+func TestDecryptedReaderTampered(t *testing.T) {
+	var encrypted bytes.Buffer
+	ew, err := NewEncryptedWriter(&encrypted, EncryptionOptions{Key: testEncryptionKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ew.Write([]byte("line\n"))
+
+	tampered := bytes.Clone(encrypted.Bytes())
+	tampered[len(tampered)-1] ^= 1
+	otherKey := bytes.Repeat([]byte{0x24}, 32)
+
+	for name, tc := range map[string]struct {
+		data []byte
+		key  []byte
+	}{
+		"tampered":  {tampered, testEncryptionKey},
+		"wrong key": {encrypted.Bytes(), otherKey},
+		"truncated": {encrypted.Bytes()[:encrypted.Len()-3], testEncryptionKey},
+	} {
+		r, err := NewDecryptedReader(bytes.NewReader(tc.data), tc.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadAll(r); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestDecryptedReaderSequence はフレームの欠落や入れ替えを検出し、
+// ストリームの途中から始まるデータや連結したストリームは復号できることをテストします
+func TestDecryptedReaderSequence(t *testing.T) {
+	// frames は1つのストリームの行ごとのフレームを返します
+	frames := func(lines ...string) [][]byte {
+		var buf bytes.Buffer
+		ew, err := NewEncryptedWriter(&buf, EncryptionOptions{Key: testEncryptionKey})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out [][]byte
+		for _, line := range lines {
+			start := buf.Len()
+			ew.Write([]byte(line))
+			out = append(out, bytes.Clone(buf.Bytes()[start:]))
+		}
+		return out
+	}
+	a := frames("a1\n", "a2\n", "a3\n")
+	b := frames("b1\n", "b2\n")
+
+	for name, tc := range map[string]struct {
+		frames [][]byte
+		want   string // 空の場合はエラーを期待する
+	}{
+		"in order":         {[][]byte{a[0], a[1], a[2]}, "a1\na2\na3\n"},
+		"mid stream":       {[][]byte{a[1], a[2]}, "a2\na3\n"},
+		"concatenated":     {[][]byte{a[0], a[1], b[0], b[1]}, "a1\na2\nb1\nb2\n"},
+		"missing frame":    {[][]byte{a[0], a[2]}, ""},
+		"reordered frames": {[][]byte{a[1], a[0]}, ""},
+		"replayed frame":   {[][]byte{a[0], a[1], a[1]}, ""},
+	} {
+		r, err := NewDecryptedReader(bytes.NewReader(bytes.Join(tc.frames, nil)), testEncryptionKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plain, err := io.ReadAll(r)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			continue
+		}
+		if err != nil || string(plain) != tc.want {
+			t.Errorf("%s: got %q, %v", name, plain, err)
+		}
+	}
+}
+
+// TestKeyFromEnv は環境変数から base64 の鍵を読み取れることをテストします
+func TestKeyFromEnv(t *testing.T) {
+	t.Setenv("GOLOG_TEST_KEY", base64.StdEncoding.EncodeToString(testEncryptionKey))
+	key, err := KeyFromEnv("GOLOG_TEST_KEY")
+	if err != nil || !bytes.Equal(key, testEncryptionKey) {
+		t.Errorf("unexpected key %x (err %v)", key, err)
+	}
+
+	if _, err := KeyFromEnv("GOLOG_TEST_KEY_MISSING"); err == nil {
+		t.Error("expected an error for a missing variable")
+	}
+	if _, err := NewEncryptedWriter(io.Discard, EncryptionOptions{Key: []byte("short")}); err == nil {
+		t.Error("expected an error for an invalid key size")
+	}
+	failing := errors.New("kms unavailable")
+	if _, err := NewEncryptedWriter(io.Discard, EncryptionOptions{KeyFunc: func() ([]byte, error) { return nil, failing }}); !errors.Is(err, failing) {
+		t.Errorf("expected KeyFunc error, got %v", err)
+	}
+}
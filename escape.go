@@ -0,0 +1,122 @@
+package loggo
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Escaper は Handler と同じ規則で属性のキーと文字列の値をエスケープします。
+// 独自のシンクやエンコーダーで、golog の出力と同じ保証を得るために使います。
+//
+// どの設定でも、AppendKey と AppendString が書き込む内容は次を満たします。
+//   - 有効な UTF-8 で、改行（\n, \r, \v, \f, U+0085, U+2028, U+2029）を含まない1行
+//   - クォートした値は JSON では JSON の文字列として、テキスト形式では strconv.Unquote で元の文字列に戻せる
+//     （不正な UTF-8 のバイトは、JSON では U+FFFD に置き換え、テキスト形式では \x でエスケープします）
+//   - クォートしないキーは空白、'='、'"'、制御文字、表示できない文字を含まない
+//
+// ゼロ値は Handler のテキスト形式のデフォルトと同じ規則です。
+type Escaper struct {
+	// JSON は JSON の規則で文字列をエスケープし、キーを常にクォートします（FormatJSON）
+	JSON bool
+	// ASCIIOnly は非 ASCII 文字を \u 形式でエスケープします（Options.ASCIIOnly）
+	ASCIIOnly bool
+	// KeySeparator は空でない場合、これを含むキーをクォートします（Options.QuoteSeparator）
+	KeySeparator string
+}
+
+// KeyNeedsQuoting はキーまたはグループ名にクォートが必要かどうかを返します
+func (e Escaper) KeyNeedsQuoting(key string) bool {
+	return e.JSON || needsQuoting(key) || (e.ASCIIOnly && !isASCII(key)) || (e.KeySeparator != "" && strings.Contains(key, e.KeySeparator))
+}
+
+// AppendKey はキーまたはグループ名を dst に追加します。必要な場合はクォートします。
+func (e Escaper) AppendKey(dst []byte, key string) []byte {
+	if e.KeyNeedsQuoting(key) {
+		return e.AppendString(dst, key)
+	}
+	return append(dst, key...)
+}
+
+// AppendString は s をクォートして dst に追加します
+func (e Escaper) AppendString(dst []byte, s string) []byte {
+	if e.JSON {
+		start := len(dst)
+		dst = appendJSONString(dst, s)
+		if e.ASCIIOnly {
+			dst = escapeNonASCII(dst, start)
+		}
+		return dst
+	}
+	if e.ASCIIOnly {
+		return strconv.AppendQuoteToASCII(dst, s)
+	}
+	return strconv.AppendQuote(dst, s)
+}
+
+// needsQuoting はテキスト形式のキーにクォートが必要かどうかを判定します。
+// 不正な UTF-8 と表示できない文字（U+0085 や U+2028 などの改行を含む）もクォートしてエスケープします。
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f {
+			return true
+		}
+		if r >= utf8.RuneSelf && (r == utf8.RuneError || !unicode.IsPrint(r)) {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeNonASCII は b の start 以降に含まれる非 ASCII 文字を \u 形式でエスケープします。
+// サロゲートペアを用いるため JSON としても有効です。
+func escapeNonASCII(b []byte, start int) []byte {
+	i := start
+	for i < len(b) && b[i] < utf8.RuneSelf {
+		i++
+	}
+	if i == len(b) {
+		return b
+	}
+
+	tail := []byte(string(b[i:]))
+	b = b[:i]
+	for len(tail) > 0 {
+		r, size := utf8.DecodeRune(tail)
+		tail = tail[size:]
+		if r < utf8.RuneSelf {
+			b = append(b, byte(r))
+			continue
+		}
+		if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+			b = appendUnicodeEscape(b, r1)
+			b = appendUnicodeEscape(b, r2)
+		} else {
+			b = appendUnicodeEscape(b, r)
+		}
+	}
+	return b
+}
+
+func appendUnicodeEscape(b []byte, r rune) []byte {
+	b = append(b, `\u`...)
+	for shift := 12; shift >= 0; shift -= 4 {
+		b = append(b, hexDigits[(r>>shift)&0xF])
+	}
+	return b
+}
+
+// isASCII は文字列が ASCII 文字のみで構成されているかを判定します
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package loggo
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// escaperConfigs はテストする Escaper の設定
+var escaperConfigs = []Escaper{
+	{},
+	{ASCIIOnly: true},
+	{KeySeparator: "."},
+	{JSON: true},
+	{JSON: true, ASCIIOnly: true},
+}
+
+// escapeSeeds はファズテストの初期値
+var escapeSeeds = []string{
+	"", "plain", "with space", "a=b", `quote"d`, "line\nbreak", "cr\rlf", "tab\t", "\x00\x1f\x7f",
+	"nel\u0085", "ls\u2028ps\u2029", "日本語", "emoji😀", "invalid\xff\xfe", "\xc3", "user.name", `back\slash`,
+}
+
+// lineTerminators は1行の出力に含まれてはならない文字
+const lineTerminators = "\n\r\v\f\u0085\u2028\u2029"
+
+// checkEscaped は Escaper の出力が1行の有効な UTF-8 で、元の文字列に戻せることを検査します
+func checkEscaped(t *testing.T, e Escaper, s string, out []byte, quoted bool) {
+	t.Helper()
+	if !utf8.Valid(out) {
+		t.Fatalf("%+v: invalid UTF-8 for %q: %q", e, s, out)
+	}
+	if i := bytes.IndexAny(out, lineTerminators); i >= 0 {
+		t.Fatalf("%+v: line terminator in output for %q: %q", e, s, out)
+	}
+	if e.ASCIIOnly && !isASCII(string(out)) {
+		t.Fatalf("%+v: non-ASCII output for %q: %q", e, s, out)
+	}
+	if !quoted {
+		if string(out) != s || needsQuoting(s) {
+			t.Fatalf("%+v: unquoted key %q was written as %q", e, s, out)
+		}
+		return
+	}
+	if e.JSON {
+		var got, want string
+		if err := json.Unmarshal(out, &got); err != nil {
+			t.Fatalf("%+v: output for %q is not a JSON string: %q: %v", e, s, out, err)
+		}
+		expected, _ := json.Marshal(s)
+		json.Unmarshal(expected, &want)
+		if got != want {
+			t.Fatalf("%+v: JSON round trip of %q = %q, want %q", e, s, got, want)
+		}
+		return
+	}
+	got, err := strconv.Unquote(string(out))
+	if err != nil || got != s {
+		t.Fatalf("%+v: Unquote(%q) = %q, %v; want %q", e, out, got, err, s)
+	}
+}
+
+// TestEscaper は代表的な文字列のエスケープをテストします
+func TestEscaper(t *testing.T) {
+	for _, e := range escaperConfigs {
+		for _, s := range escapeSeeds {
+			checkEscaped(t, e, s, e.AppendString(nil, s), true)
+			checkEscaped(t, e, s, e.AppendKey(nil, s), e.KeyNeedsQuoting(s))
+		}
+	}
+
+	tests := []struct {
+		e    Escaper
+		in   string
+		want string
+	}{
+		{Escaper{}, "user_id", "user_id"},
+		{Escaper{}, "user id", `"user id"`},
+		{Escaper{}, "k\u2028", `"k\u2028"`},
+		{Escaper{KeySeparator: "."}, "a.b", `"a.b"`},
+		{Escaper{JSON: true}, "k", `"k"`},
+		{Escaper{JSON: true}, "nel\u0085", `"nel\u0085"`},
+		{Escaper{ASCIIOnly: true}, "日本", `"\u65e5\u672c"`},
+	}
+	for _, tt := range tests {
+		if got := string(tt.e.AppendKey(nil, tt.in)); got != tt.want {
+			t.Errorf("%+v.AppendKey(%q) = %s, want %s", tt.e, tt.in, got, tt.want)
+		}
+	}
+}
+
+// FuzzEscaper は任意の文字列のエスケープが1行の有効な UTF-8 で、元の文字列に戻せることをテストします
+func FuzzEscaper(f *testing.F) {
+	for _, s := range escapeSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, e := range escaperConfigs {
+			checkEscaped(t, e, s, e.AppendString(nil, s), true)
+			checkEscaped(t, e, s, e.AppendKey(nil, s), e.KeyNeedsQuoting(s))
+		}
+	})
+}
+
+// FuzzHandlerSingleLine は任意のメッセージ、キー、値、グループ名のレコードが1行で出力されることをテストします
+func FuzzHandlerSingleLine(f *testing.F) {
+	for _, s := range escapeSeeds {
+		f.Add(s, s, s)
+	}
+	f.Fuzz(func(t *testing.T, msg, key, value string) {
+		for _, format := range []Format{FormatText, FormatJSON} {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, &Options{Format: format}))
+			logger.WithGroup(key).Info(msg, key, value, slog.Group(value, key, []byte(value)))
+
+			out := buf.Bytes()
+			if !bytes.HasSuffix(out, []byte("\n")) {
+				t.Fatalf("%v: output does not end with a newline: %q", format, out)
+			}
+			line := out[:len(out)-1]
+			if !utf8.Valid(line) || bytes.ContainsAny(line, lineTerminators) {
+				t.Fatalf("%v: output is not a single valid line: %q", format, out)
+			}
+			if format == FormatJSON && !json.Valid(line) {
+				t.Fatalf("output is not valid JSON: %q", line)
+			}
+			if format == FormatText && !strings.Contains(string(line), "msg=") {
+				t.Fatalf("missing message: %q", line)
+			}
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package loggo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// EventKey はイベントの名前を出力する属性のキー
+const EventKey = "event"
+
+// ErrInvalidEvent はイベントの属性がスキーマに合わないことを示すエラー
+var ErrInvalidEvent = errors.New("golog: invalid event")
+
+// EventField はイベントのスキーマの1つのフィールド
+type EventField struct {
+	Key string
+	// Kind は値の種類。slog.KindAny（ゼロ値）の場合は種類を問いません。LogValuer は解決した後の種類で判断します。
+	Kind slog.Kind
+	// Required は必須のフィールドであることを表します
+	Required bool
+}
+
+// Required は必須のフィールドを返します
+func Required(key string, kind slog.Kind) EventField {
+	return EventField{Key: key, Kind: kind, Required: true}
+}
+
+// Optional は省略できるフィールドを返します
+func Optional(key string, kind slog.Kind) EventField {
+	return EventField{Key: key, Kind: kind}
+}
+
+// EventOptions はイベントのオプション
+type EventOptions struct {
+	// Level はイベントを記録するレベル。nil の場合は slog.LevelInfo です。
+	Level slog.Leveler
+	// AllowExtra はスキーマに無いフィールドを許可します。デフォルトではエラーになります。
+	AllowExtra bool
+}
+
+// Event はスキーマを持つ構造化イベント。分析や監査のイベントの属性をチーム全体で揃えるために、
+// 必須のフィールドと値の種類を定義しておき、Emit で検証してから記録します。
+// レコードのメッセージはイベントの名前で、EventKey の属性にも名前を出力します。
+//
+//	var OrderPlaced = golog.NewEvent("order_placed", nil,
+//	    golog.Required("order_id", slog.KindString),
+//	    golog.Required("amount", slog.KindInt64),
+//	    golog.Optional("coupon", slog.KindString),
+//	)
+//
+//	err := OrderPlaced.Emit(ctx, logger, "order_id", id, "amount", 1200)
+type Event struct {
+	name       string
+	level      slog.Leveler
+	fields     []EventField
+	allowExtra bool
+}
+
+// NewEvent は name のイベントを定義します
+func NewEvent(name string, opts *EventOptions, fields ...EventField) *Event {
+	e := &Event{name: name, level: slog.LevelInfo, fields: fields}
+	if opts != nil {
+		if opts.Level != nil {
+			e.level = opts.Level
+		}
+		e.allowExtra = opts.AllowExtra
+	}
+	return e
+}
+
+// Name はイベントの名前を返します
+func (e *Event) Name() string {
+	return e.name
+}
+
+// Emit は args（slog.Logger.Info と同じキーと値の組、または slog.Attr）をスキーマで検証し、イベントを記録します。
+// 検証に失敗した場合は記録せず、ErrInvalidEvent をラップしたエラーを返します。
+// ロガーのレベルで記録されない場合も検証は行います。
+func (e *Event) Emit(ctx context.Context, logger *slog.Logger, args ...any) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:]) // runtime.Callers と Emit を飛ばす
+	r := slog.NewRecord(time.Now(), e.level.Level(), e.name, pcs[0])
+	r.Add(args...)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	if err := e.Validate(attrs); err != nil {
+		return err
+	}
+	if !logger.Enabled(ctx, r.Level) {
+		return nil
+	}
+
+	rec := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	rec.AddAttrs(slog.String(EventKey, e.name))
+	rec.AddAttrs(attrs...)
+	return logger.Handler().Handle(ctx, rec)
+}
+
+// Validate は属性がスキーマに合うかを検証します。合わない場合はすべての問題を含むエラーを返します。
+func (e *Event) Validate(attrs []slog.Attr) error {
+	var problems []string
+	seen := make(map[string]bool, len(attrs))
+	for _, a := range attrs {
+		if a.Key == badKey {
+			problems = append(problems, fmt.Sprintf("value %v without key", a.Value))
+			continue
+		}
+		seen[a.Key] = true
+		f, ok := e.field(a.Key)
+		if !ok {
+			if !e.allowExtra {
+				problems = append(problems, fmt.Sprintf("unknown field %q", a.Key))
+			}
+			continue
+		}
+		if kind := a.Value.Resolve().Kind(); f.Kind != slog.KindAny && kind != f.Kind {
+			problems = append(problems, fmt.Sprintf("field %q must be %s, got %s", a.Key, f.Kind, kind))
+		}
+	}
+	for _, f := range e.fields {
+		if f.Required && !seen[f.Key] {
+			problems = append(problems, fmt.Sprintf("missing required field %q", f.Key))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w %s: %s", ErrInvalidEvent, e.name, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// field はキーのフィールドを返します
+func (e *Event) field(key string) (EventField, bool) {
+	for _, f := range e.fields {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return EventField{}, false
+}
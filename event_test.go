@@ -0,0 +1,58 @@
+package loggo
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestEventEmit はスキーマに合うイベントを名前とともに記録することをテストします
+func TestEventEmit(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{Format: FormatJSON, AddSource: true}))
+	orderPlaced := NewEvent("order_placed", nil,
+		Required("order_id", slog.KindString),
+		Required("amount", slog.KindInt64),
+		Optional("coupon", slog.KindString),
+	)
+
+	if err := orderPlaced.Emit(t.Context(), logger, "order_id", "o-1", slog.Int("amount", 1200)); err != nil {
+		t.Fatal(err)
+	}
+	line := buf.String()
+	for _, want := range []string{`"msg":"order_placed"`, `"event":"order_placed","order_id":"o-1","amount":1200`, `event_test.go`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("%s not found in %s", want, line)
+		}
+	}
+}
+
+// TestEventValidate は必須のフィールドの欠落、種類の誤り、スキーマに無いフィールドをエラーにして記録しないことをテストします
+func TestEventValidate(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, nil))
+	ev := NewEvent("login", &EventOptions{Level: slog.LevelWarn},
+		Required("user_id", slog.KindString),
+		Optional("attempts", slog.KindInt64),
+	)
+
+	err := ev.Emit(t.Context(), logger, "attempts", "three", "ip", "192.0.2.1")
+	if !errors.Is(err, ErrInvalidEvent) {
+		t.Fatalf("err = %v, want ErrInvalidEvent", err)
+	}
+	for _, want := range []string{`missing required field "user_id"`, `field "attempts" must be Int64, got String`, `unknown field "ip"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%s not found in %v", want, err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("invalid event should not be logged: %s", buf.String())
+	}
+
+	extra := NewEvent("login", &EventOptions{AllowExtra: true}, Required("user_id", slog.KindAny))
+	if err := extra.Emit(t.Context(), logger, "user_id", 42, "ip", "192.0.2.1"); err != nil {
+		t.Errorf("AllowExtra: %v", err)
+	}
+}
@@ -0,0 +1,564 @@
+package loggo
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultFilePerm はログファイルを作成する際のデフォルトのパーミッション
+const defaultFilePerm = 0o644
+
+// backupTimeFormat はローテーションしたファイル名に付ける時刻の書式
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// ErrQuotaExceeded は MaxTotalSize を超えたため書き込みを停止したことを示すエラー
+var ErrQuotaExceeded = errors.New("golog: disk quota exceeded")
+
+// QuotaPolicy は MaxTotalSize を超えた場合の動作
+type QuotaPolicy int
+
+const (
+	// QuotaDeleteOldest は古いローテーション済みファイルから削除します（デフォルト）
+	QuotaDeleteOldest QuotaPolicy = iota
+	// QuotaStopWriting は書き込みを停止し、ErrQuotaExceeded を返します。
+	// 停止した時点で OnError に通知されます。
+	QuotaStopWriting
+)
+
+// FileWriterOptions は FileWriter のオプション
+type FileWriterOptions struct {
+	Perm os.FileMode // ファイルを作成する際のパーミッション。0 の場合は 0644
+
+	// MaxSize はファイルをローテーションするサイズ（バイト）。0 の場合はローテーションしません。
+	// ローテーションしたファイルは "app-2006-01-02T15-04-05.000.log" の形式の名前に変更されます。
+	MaxSize int64
+
+	// Compressor はローテーションしたファイルの圧縮方式。nil の場合は圧縮しません。
+	// 圧縮はバックグラウンドで行われ、完了後に元のファイルは削除されます。
+	Compressor *Compressor
+
+	// MaxTotalSize は現在のファイルとローテーション済みファイルの合計サイズの上限（バイト）。
+	// 0 の場合は制限しません。MaxSize を指定している場合は、現在のファイルが
+	// MaxSize まで増えても上限を超えないようにローテーション済みファイルを削除します。
+	MaxTotalSize int64
+
+	// QuotaPolicy は MaxTotalSize を超えた場合の動作
+	QuotaPolicy QuotaPolicy
+
+	// OnArchive はローテーションしたファイルが完成した後（Compressor を指定した場合は圧縮の後）に、
+	// そのファイルのパスを伴ってバックグラウンドで呼び出されます。S3Uploader.Upload などで
+	// 外部のストレージへ保管するために使います。エラーは OnError に渡され、Close は完了を待ちます。
+	OnArchive func(path string) error
+
+	// Header は新しい空のファイル（最初に開いた時、ローテーションや Reopen の後）の先頭に書き込む内容を返します。
+	// W3C 拡張ログ形式の #Fields のように、ファイルごとにヘッダーを必要とする形式で使います。
+	// ヘッダーはファイルへの最初の書き込みの直前に書き込むため、レコードの無いファイルにヘッダーだけが残ることはありません。
+	Header func() []byte
+
+	// OnError はシグナルによる開き直しやバックグラウンドでの圧縮など、
+	// 呼び出し元へエラーを返せない処理で発生したエラーを受け取ります
+	OnError func(err error)
+}
+
+// FileWriter はファイルへ追記するライター。
+// logrotate などの外部ツールがファイルを移動した後に Reopen を呼び出すと、
+// 元のパスでファイルを開き直します（copytruncate は不要です）。
+type FileWriter struct {
+	path         string
+	perm         os.FileMode
+	maxSize      int64
+	compressor   *Compressor
+	maxTotalSize int64
+	quotaPolicy  QuotaPolicy
+	onArchive    func(path string) error
+	onError      func(err error)
+
+	mu            sync.Mutex
+	header        func() []byte
+	headerPending bool // 空のファイルを開いた後、まだヘッダーを書き込んでいない
+	file          *os.File
+	size          atomic.Int64 // 書き込みは mu を保持して行う
+	closed        bool
+	quotaExceeded bool
+	lastErr       error     // 直前の書き込みのエラー
+	lastBackup    time.Time // 直前にローテーションしたファイル名の時刻
+
+	quotaMu     sync.Mutex   // ローテーション済みファイルの走査と削除を直列化
+	archiveSize atomic.Int64 // ローテーション済みファイルの合計サイズ
+
+	compressing sync.WaitGroup // 圧縮と OnArchive の完了を待つ
+
+	sigMu   sync.Mutex
+	sigStop chan struct{}
+}
+
+// OpenFile は path のファイルを追記モードで開きます。ファイルが無い場合は作成します。
+func OpenFile(path string, opts *FileWriterOptions) (*FileWriter, error) {
+	w := &FileWriter{path: path, perm: defaultFilePerm}
+	if opts != nil {
+		if opts.Perm != 0 {
+			w.perm = opts.Perm
+		}
+		w.maxSize = opts.MaxSize
+		w.compressor = opts.Compressor
+		w.maxTotalSize = opts.MaxTotalSize
+		w.quotaPolicy = opts.QuotaPolicy
+		w.onArchive = opts.OnArchive
+		w.header = opts.Header
+		w.onError = opts.OnError
+	}
+
+	if err := w.openLocked(); err != nil {
+		return nil, err
+	}
+	w.enforceQuota()
+	return w, nil
+}
+
+// openLocked はファイルを開き、現在のサイズを記録します。ファイルが空の場合は次の書き込みの前にヘッダーを書き込みます。
+// mu を保持して呼び出します。
+func (w *FileWriter) openLocked() error {
+	f, err := w.open()
+	if err != nil {
+		return err
+	}
+	var size int64
+	if fi, err := f.Stat(); err == nil {
+		size = fi.Size()
+	}
+	w.file = f
+	w.size.Store(size)
+	w.headerPending = size == 0
+	return nil
+}
+
+// writeHeaderLocked は未書き込みのヘッダーがあれば書き込みます。mu を保持して呼び出します。
+func (w *FileWriter) writeHeaderLocked() error {
+	// 最初の書き込みの後に SetHeader を呼び出しても、ファイルの途中にヘッダーを書き込まない
+	if !w.headerPending {
+		return nil
+	}
+	w.headerPending = false
+	if w.header == nil {
+		return nil
+	}
+	n, err := w.file.Write(w.header())
+	w.size.Add(int64(n))
+	return err
+}
+
+// SetHeader は FileWriterOptions.Header を設定します。現在のファイルが空の場合は次の書き込みの前にヘッダーを書き込みます。
+func (w *FileWriter) SetHeader(header func() []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	w.header = header
+	return nil
+}
+
+// open は path のファイルを追記モードで開きます
+func (w *FileWriter) open() (*os.File, error) {
+	return os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, w.perm)
+}
+
+// Path はファイルのパスを返します
+func (w *FileWriter) Path() string {
+	return w.path
+}
+
+// Write はファイルへ書き込みます
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	size := w.size.Load()
+	if w.maxSize > 0 && size > 0 && size+int64(len(p)) > w.maxSize {
+		if err := w.rotateLocked(); err != nil {
+			w.reportError(err)
+		}
+	}
+	if w.overQuotaLocked(len(p)) {
+		return 0, ErrQuotaExceeded
+	}
+	if err := w.writeHeaderLocked(); err != nil {
+		w.reportError(err)
+	}
+	n, err := w.file.Write(p)
+	w.size.Add(int64(n))
+	w.lastErr = err
+	return n, err
+}
+
+// Healthy は直前の書き込みが成功したかを返します
+func (w *FileWriter) Healthy() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.closed && w.lastErr == nil
+}
+
+// Ping はファイルへ書き込める状態かを確認します。クローズ済みの場合や直前の書き込みが失敗した場合のほか、
+// 開いているファイルが元のパスから移動・削除されたまま開き直されていない場合もエラーを返します。
+func (w *FileWriter) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	if w.lastErr != nil {
+		return w.lastErr
+	}
+	opened, err := w.file.Stat()
+	if err != nil {
+		return err
+	}
+	current, err := os.Stat(w.path)
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(opened, current) {
+		return fmt.Errorf("golog: %s has been replaced since it was opened", w.path)
+	}
+	return nil
+}
+
+// Reopen は現在のファイルを閉じ、元のパスでファイルを開き直します。
+// 開き直しに失敗した場合は現在のファイルへの書き込みを続けます。
+func (w *FileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	old := w.file
+	if err := w.openLocked(); err != nil {
+		return err
+	}
+	// 外部ツールがファイルを移動・削除した可能性があるため、合計サイズを数え直す
+	w.enforceQuota()
+	return old.Close()
+}
+
+// Rotate は現在のファイルを時刻付きの名前に変更し、新しいファイルを開きます
+func (w *FileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	return w.rotateLocked()
+}
+
+// rotateLocked は現在のファイルをローテーションします。mu を保持して呼び出します。
+func (w *FileWriter) rotateLocked() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	size := w.size.Load()
+	backup := w.nextBackupName()
+	renameErr := os.Rename(w.path, backup)
+	// 名前の変更に失敗した場合も、書き込みを続けられるようにファイルを開き直す
+	if err := w.openLocked(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+	w.archiveSize.Add(size)
+
+	if w.compressor != nil || w.onArchive != nil {
+		w.compressing.Add(1)
+		go func() {
+			defer w.compressing.Done()
+			w.finishArchive(backup)
+		}()
+	} else {
+		w.enforceQuota()
+	}
+	return nil
+}
+
+// finishArchive はローテーションしたファイルを圧縮して OnArchive に渡し、合計サイズの上限を適用します。
+// OnArchive が保管する前に削除されないように、上限の適用は最後に行います。
+func (w *FileWriter) finishArchive(backup string) {
+	if w.compressor != nil {
+		if err := w.compressor.compressFile(backup); err != nil {
+			w.reportError(err)
+		} else {
+			backup += w.compressor.Ext
+		}
+	}
+	if w.onArchive != nil {
+		if err := w.onArchive(backup); err != nil {
+			w.reportError(err)
+		}
+	}
+	w.enforceQuota()
+}
+
+// nextBackupName は既存のファイルと重複しないローテーション後のファイル名を返します。
+// 同じミリ秒に複数回ローテーションした場合は時刻を進め、名前の順序と作成順を一致させます。
+// mu を保持して呼び出します。
+func (w *FileWriter) nextBackupName() string {
+	t := time.Now().Truncate(time.Millisecond)
+	if !t.After(w.lastBackup) {
+		t = w.lastBackup.Add(time.Millisecond)
+	}
+	for {
+		name := backupName(w.path, t)
+		if !fileExists(name) && (w.compressor == nil || !fileExists(name+w.compressor.Ext)) {
+			w.lastBackup = t
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// fileExists は path にファイルが存在するかを返します
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// overQuotaLocked は QuotaStopWriting で n バイトを書き込むと上限を超えるかを返します。
+// 超える状態になった時点で一度だけ OnError に通知します。mu を保持して呼び出します。
+func (w *FileWriter) overQuotaLocked(n int) bool {
+	if w.maxTotalSize <= 0 || w.quotaPolicy != QuotaStopWriting {
+		return false
+	}
+	over := w.archiveSize.Load()+w.size.Load()+int64(n) > w.maxTotalSize
+	if over && !w.quotaExceeded {
+		w.reportError(ErrQuotaExceeded)
+	}
+	w.quotaExceeded = over
+	return over
+}
+
+// archiveFile はローテーション済みのファイル
+type archiveFile struct {
+	path string
+	size int64
+}
+
+// archives はローテーション済みのファイル（圧縮済みを含む）を古い順に返します
+func (w *FileWriter) archives() ([]archiveFile, error) {
+	dir, base := filepath.Split(w.path)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+	matches, err := filepath.Glob(filepath.Join(dir, name+"-*"+ext+"*"))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]archiveFile, 0, len(matches))
+	for _, m := range matches {
+		rest := strings.TrimPrefix(filepath.Base(m), name+"-")
+		if len(rest) < len(backupTimeFormat) || !strings.HasPrefix(rest[len(backupTimeFormat):], ext) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, rest[:len(backupTimeFormat)]); err != nil {
+			continue
+		}
+		fi, err := os.Lstat(m)
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, archiveFile{path: m, size: fi.Size()})
+	}
+	// ファイル名の時刻部分は辞書順と時刻順が一致する
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
+
+// enforceQuota はローテーション済みファイルの合計サイズを数え直し、
+// QuotaDeleteOldest の場合は上限に収まるまで古いファイルを削除します
+func (w *FileWriter) enforceQuota() {
+	if w.maxTotalSize <= 0 {
+		return
+	}
+	w.quotaMu.Lock()
+	defer w.quotaMu.Unlock()
+
+	files, err := w.archives()
+	if err != nil {
+		w.reportError(err)
+		return
+	}
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+
+	if w.quotaPolicy == QuotaDeleteOldest {
+		// 現在のファイルが MaxSize まで増える分を確保する
+		reserved := max(w.maxSize, w.size.Load())
+		for len(files) > 0 && total+reserved > w.maxTotalSize {
+			if err := os.Remove(files[0].path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				w.reportError(err)
+				break
+			}
+			total -= files[0].size
+			files = files[1:]
+		}
+	}
+	w.archiveSize.Store(total)
+}
+
+// reportError は呼び出し元へ返せないエラーを OnError に渡します
+func (w *FileWriter) reportError(err error) {
+	if w.onError != nil {
+		w.onError(err)
+	}
+}
+
+// backupName は path に時刻を付けたローテーション後のファイル名を返します
+func backupName(path string, t time.Time) string {
+	dir, base := filepath.Split(path)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+	return filepath.Join(dir, name+"-"+t.Format(backupTimeFormat)+ext)
+}
+
+// Compressor はローテーションしたファイルの圧縮方式。
+// zstd などを使う場合は NewWriter に圧縮ライターを作成する関数を指定します：
+//
+//	&golog.Compressor{Ext: ".zst", NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+//	    return zstd.NewWriter(w)
+//	}}
+type Compressor struct {
+	Ext       string // 圧縮したファイルに付ける拡張子（".gz" など）
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+}
+
+// GzipCompressor は gzip で圧縮する Compressor
+var GzipCompressor = &Compressor{
+	Ext: ".gz",
+	NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	},
+}
+
+// compressFile は path のファイルを圧縮し、成功した場合は元のファイルを削除します
+func (c *Compressor) compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dstPath := path + c.Ext
+	dst, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(dstPath)
+		}
+	}()
+
+	cw, err := c.NewWriter(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(cw, src); err != nil {
+		return err
+	}
+	if err = cw.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
+
+// ReopenOnSignal はシグナルを受け取るたびに Reopen を呼び出します。
+// シグナルを指定しない場合は SIGHUP を使用します（Unix 以外のプラットフォームでは何もしません）。
+// 開き直しのエラーは OnError に渡されます。
+// 既に監視している場合は、以前の監視を停止してから開始します。監視は Close で停止します。
+func (w *FileWriter) ReopenOnSignal(sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = defaultReopenSignals
+	}
+	if len(sigs) == 0 {
+		return
+	}
+
+	w.sigMu.Lock()
+	defer w.sigMu.Unlock()
+	w.stopSignalLocked()
+
+	ch := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	w.sigStop = stop
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ch:
+				if err := w.Reopen(); err != nil {
+					w.reportError(err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopSignalLocked はシグナルの監視を停止します。sigMu を保持して呼び出します。
+func (w *FileWriter) stopSignalLocked() {
+	if w.sigStop != nil {
+		close(w.sigStop)
+		w.sigStop = nil
+	}
+}
+
+// Close はシグナルの監視を停止し、ファイルを閉じます
+func (w *FileWriter) Close() error {
+	w.sigMu.Lock()
+	w.stopSignalLocked()
+	w.sigMu.Unlock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	err := w.file.Close()
+	w.compressing.Wait()
+	return err
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package loggo
+
+import "os"
+
+// defaultReopenSignals は ReopenOnSignal でシグナルを指定しない場合に監視するシグナル。
+// SIGHUP の無いプラットフォームでは監視しません。
+var defaultReopenSignals []os.Signal
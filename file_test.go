@@ -0,0 +1,321 @@
+package loggo
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestFileWriterReopen は移動されたファイルの代わりに元のパスで開き直すことをテストします
+func TestFileWriterReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := OpenFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("before\n"))
+	// logrotate と同様にファイルを移動する
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("moved\n"))
+	if err := w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("after\n"))
+
+	assertFileContent(t, path+".1", "before\nmoved\n")
+	assertFileContent(t, path, "after\n")
+}
+
+// TestFileWriterClosed はクローズ後の操作がエラーになることをテストします
+func TestFileWriterClosed(t *testing.T) {
+	w, err := OpenFile(filepath.Join(t.TempDir(), "app.log"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("x")); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if err := w.Reopen(); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if err := w.Rotate(); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close should be a no-op, got %v", err)
+	}
+}
+
+// TestFileWriterRotate は MaxSize を超えるとファイルがローテーションされることをテストします
+func TestFileWriterRotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := OpenFile(path, &FileWriterOptions{MaxSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("first\n"))
+	w.Write([]byte("second\n"))
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 backup, got %v", backups)
+	}
+	assertFileContent(t, backups[0], "first\n")
+	assertFileContent(t, path, "second\n")
+}
+
+// TestFileWriterCompress はローテーションしたファイルが圧縮され、元のファイルが削除されることをテストします
+func TestFileWriterCompress(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := OpenFile(path, &FileWriterOptions{
+		Compressor: GzipCompressor,
+		OnError:    func(err error) { t.Error(err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("archived\n"))
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	// Close は圧縮の完了を待つ
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if plain, _ := filepath.Glob(filepath.Join(dir, "app-*.log")); len(plain) != 0 {
+		t.Errorf("original backup should be removed, got %v", plain)
+	}
+	archives, _ := filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+	if len(archives) != 1 {
+		t.Fatalf("expected 1 archive, got %v", archives)
+	}
+	f, err := os.Open(archives[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "archived\n" {
+		t.Errorf("expected %q, got %q", "archived\n", got)
+	}
+}
+
+// TestFileWriterQuotaDeleteOldest は合計サイズが上限を超えないよう古いファイルが削除されることをテストします
+func TestFileWriterQuotaDeleteOldest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := OpenFile(path, &FileWriterOptions{MaxSize: 10, MaxTotalSize: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for i := 1; i <= 5; i++ {
+		fmt.Fprintf(w, "line-%02d\n", i)
+	}
+
+	// 現在のファイルが MaxSize まで増える分を確保するため、残るのは 2 ファイル
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %v", backups)
+	}
+	assertFileContent(t, backups[0], "line-03\n")
+	assertFileContent(t, backups[1], "line-04\n")
+	assertFileContent(t, path, "line-05\n")
+}
+
+// TestFileWriterQuotaStopWriting は上限を超えると書き込みが停止し、一度だけ通知されることをテストします
+func TestFileWriterQuotaStopWriting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	var notified int
+	w, err := OpenFile(path, &FileWriterOptions{
+		MaxTotalSize: 16,
+		QuotaPolicy:  QuotaStopWriting,
+		OnError: func(err error) {
+			if errors.Is(err, ErrQuotaExceeded) {
+				notified++
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("line-01\n"))
+	w.Write([]byte("line-02\n"))
+	for range 2 {
+		if _, err := w.Write([]byte("line-03\n")); !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("expected ErrQuotaExceeded, got %v", err)
+		}
+	}
+	if notified != 1 {
+		t.Errorf("expected 1 notification, got %d", notified)
+	}
+	assertFileContent(t, path, "line-01\nline-02\n")
+}
+
+// TestFileWriterPing はファイルが移動されたまま開き直されていない場合にエラーになることをテストします
+func TestFileWriterPing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := OpenFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := w.Ping(t.Context()); err != nil {
+		t.Fatalf("expected healthy file, got %v", err)
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Ping(t.Context()); err == nil {
+		t.Error("expected an error after the file was moved")
+	}
+	if err := w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Ping(t.Context()); err != nil {
+		t.Errorf("expected healthy file after Reopen, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := w.Ping(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// assertFileContent はファイルの内容を検証します
+func assertFileContent(t *testing.T, path, want string) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("%s: expected %q, got %q", filepath.Base(path), want, got)
+	}
+}
+
+// TestFileWriterOnArchive は圧縮したファイルのパスで OnArchive を呼び出すことをテストします
+func TestFileWriterOnArchive(t *testing.T) {
+	dir := t.TempDir()
+	var (
+		mu       sync.Mutex
+		archived []string
+	)
+	w, err := OpenFile(filepath.Join(dir, "app.log"), &FileWriterOptions{
+		Compressor: GzipCompressor,
+		OnArchive: func(path string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			archived = append(archived, path)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("line\n"))
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close() // OnArchive の完了を待つ
+
+	if len(archived) != 1 || !strings.HasSuffix(archived[0], ".log.gz") {
+		t.Fatalf("archived = %v", archived)
+	}
+	if _, err := os.Stat(archived[0]); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestFileWriterHeader は新しい空のファイルへの最初の書き込みの前にヘッダーを書き込み、既存の内容がある場合は書き込まないことをテストします
+func TestFileWriterHeader(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	header := func() []byte { return []byte("#header\n") }
+
+	w, err := OpenFile(path, &FileWriterOptions{Header: header})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("first\n"))
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	// ヘッダーは最初のレコードの直前に書き込む
+	assertFileContent(t, path, "")
+	w.Write([]byte("second\n"))
+	w.Close()
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 backup, got %v", backups)
+	}
+	assertFileContent(t, backups[0], "#header\nfirst\n")
+	assertFileContent(t, path, "#header\nsecond\n")
+
+	// 内容のあるファイルを開き直した場合や SetHeader の場合は書き込まない
+	w, err = OpenFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := w.SetHeader(header); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("third\n"))
+	assertFileContent(t, path, "#header\nsecond\nthird\n")
+}
+
+// TestFileWriterSetHeaderAfterWrite は空のファイルに書き込んだ後の SetHeader でヘッダーを書き込まないことをテストします
+func TestFileWriterSetHeaderAfterWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := OpenFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("one\n"))
+	if err := w.SetHeader(func() []byte { return []byte("#HDR\n") }); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("two\n"))
+	assertFileContent(t, path, "one\ntwo\n")
+}
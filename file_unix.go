@@ -0,0 +1,11 @@
+//go:build unix
+
+package loggo
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultReopenSignals は ReopenOnSignal でシグナルを指定しない場合に監視するシグナル
+var defaultReopenSignals = []os.Signal{syscall.SIGHUP}
@@ -0,0 +1,46 @@
+//go:build unix
+
+package loggo
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestFileWriterReopenOnSignal は SIGHUP で開き直すことをテストします
+func TestFileWriterReopenOnSignal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := OpenFile(path, &FileWriterOptions{Perm: 0o600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.ReopenOnSignal()
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Skipf("cannot send SIGHUP: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("file was not reopened after SIGHUP")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("reopened file should keep the configured permission, got %v (%v)", fi.Mode().Perm(), err)
+	}
+}
@@ -0,0 +1,317 @@
+package loggo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultFluentTag は FluentWriterOptions.Tag のデフォルト値
+	defaultFluentTag = "golog"
+	// defaultFluentTimeout は FluentWriterOptions.Timeout のデフォルト値
+	defaultFluentTimeout = 5 * time.Second
+)
+
+// FluentWriterOptions は FluentWriter のオプション
+type FluentWriterOptions struct {
+	// Tag はイベントのタグ。空の場合は "golog" です。
+	Tag string
+	// RequireAck は書き込みごとに受信側の ack を待ち、届いたことを確認します（at-least-once）
+	RequireAck bool
+	// Timeout は接続、送信、ack の待機のタイムアウト。0 の場合は 5 秒です。
+	Timeout time.Duration
+	// Network は接続に使うネットワーク。空の場合は "tcp" です。Unix ドメインソケットには "unix" を指定します。
+	Network string
+}
+
+// FluentWriter は Fluentd / Fluent Bit の Forward プロトコルでイベントを送信するライター。
+// 1回の Write に含まれる行を1つのチャンク（Forward モードのメッセージ）として送ります。
+// Format: FormatJSON の行は属性をそのままレコードのフィールドにし、time フィールドをイベントの時刻にします。
+// それ以外の行は {"message": 行} のレコードとして送ります。
+//
+// 送信に失敗した場合は接続し直して一度だけ再送します。
+type FluentWriter struct {
+	addr    string
+	network string
+	tag     string
+	ack     bool
+	timeout time.Duration
+
+	mu      sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+	closed  bool
+	lastErr error // 直前の書き込みのエラー
+}
+
+// DialFluent は addr の Fluentd / Fluent Bit に接続します
+//
+//	w, err := golog.DialFluent("localhost:24224", &golog.FluentWriterOptions{Tag: "app.api", RequireAck: true})
+//	handler := golog.NewHandler(w, &golog.Options{Format: golog.FormatJSON})
+func DialFluent(addr string, opts *FluentWriterOptions) (*FluentWriter, error) {
+	w := &FluentWriter{addr: addr, network: "tcp", tag: defaultFluentTag, timeout: defaultFluentTimeout}
+	if opts != nil {
+		if opts.Tag != "" {
+			w.tag = opts.Tag
+		}
+		if opts.Network != "" {
+			w.network = opts.Network
+		}
+		if opts.Timeout > 0 {
+			w.timeout = opts.Timeout
+		}
+		w.ack = opts.RequireAck
+	}
+	if err := w.connectLocked(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connectLocked は受信側に接続します。mu を保持して呼び出します。
+func (w *FluentWriter) connectLocked() error {
+	conn, err := net.DialTimeout(w.network, w.addr, w.timeout)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	w.reader = bufio.NewReader(conn)
+	return nil
+}
+
+// disconnectLocked は接続を閉じます。mu を保持して呼び出します。
+func (w *FluentWriter) disconnectLocked() {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+		w.reader = nil
+	}
+}
+
+// Write は p の各行をイベントとして送信します
+func (w *FluentWriter) Write(p []byte) (int, error) {
+	msg, chunk := w.encode(p)
+	if msg == nil {
+		return len(p), nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	err := w.sendLocked(msg, chunk)
+	if err != nil {
+		// 接続が切れている可能性があるため、接続し直して一度だけ再送する
+		w.disconnectLocked()
+		err = w.sendLocked(msg, chunk)
+	}
+	w.lastErr = err
+	if err != nil {
+		w.disconnectLocked()
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// sendLocked はメッセージを送信し、RequireAck の場合は ack を待ちます。mu を保持して呼び出します。
+func (w *FluentWriter) sendLocked(msg []byte, chunk string) error {
+	if w.conn == nil {
+		if err := w.connectLocked(); err != nil {
+			return err
+		}
+	}
+	w.conn.SetDeadline(time.Now().Add(w.timeout))
+	if _, err := w.conn.Write(msg); err != nil {
+		return err
+	}
+	if !w.ack {
+		return nil
+	}
+	resp, err := readMsgpackStringMap(w.reader)
+	if err != nil {
+		return err
+	}
+	if resp["ack"] != chunk {
+		return fmt.Errorf("golog: fluent ack mismatch: got %q, want %q", resp["ack"], chunk)
+	}
+	return nil
+}
+
+// encode は p を Forward モードのメッセージ [tag, [[time, record], ...], option] に変換します。
+// RequireAck の場合は option に含めたチャンク ID も返します。イベントが無い場合は nil を返します。
+func (w *FluentWriter) encode(p []byte) ([]byte, string) {
+	var entries []byte
+	n := 0
+	for line := range bytes.Lines(p) {
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			continue
+		}
+		entries = appendMsgpackArrayHeader(entries, 2)
+		entries = appendFluentEntry(entries, line)
+		n++
+	}
+	if n == 0 {
+		return nil, ""
+	}
+
+	msg := appendMsgpackArrayHeader(nil, 3)
+	msg = appendMsgpackString(msg, w.tag)
+	msg = appendMsgpackArrayHeader(msg, n)
+	msg = append(msg, entries...)
+	var chunk string
+	if w.ack {
+		var id [16]byte
+		rand.Read(id[:])
+		chunk = base64.StdEncoding.EncodeToString(id[:])
+		msg = appendMsgpackMapHeader(msg, 2)
+		msg = appendMsgpackString(msg, "chunk")
+		msg = appendMsgpackString(msg, chunk)
+	} else {
+		msg = appendMsgpackMapHeader(msg, 1)
+	}
+	msg = appendMsgpackString(msg, "size")
+	msg = appendMsgpackInt(msg, int64(n))
+	return msg, chunk
+}
+
+// appendFluentEntry は1行を時刻とレコードに変換して書き込みます
+func appendFluentEntry(b []byte, line []byte) []byte {
+	if line[0] == '{' {
+		var fields struct {
+			Time string `json:"time"`
+		}
+		if json.Unmarshal(line, &fields) == nil {
+			t, err := time.Parse(time.RFC3339Nano, fields.Time)
+			if err != nil {
+				t = time.Now()
+			}
+			start := len(b)
+			b = appendMsgpackEventTime(b, t)
+			if b, err = appendMsgpackJSON(b, line); err == nil {
+				return b
+			}
+			b = b[:start]
+		}
+	}
+	b = appendMsgpackEventTime(b, time.Now())
+	b = appendMsgpackMapHeader(b, 1)
+	b = appendMsgpackString(b, slog.MessageKey)
+	return appendMsgpackString(b, string(line))
+}
+
+// Healthy は直前の書き込みが成功したかを返します
+func (w *FluentWriter) Healthy() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.closed && w.lastErr == nil
+}
+
+// Ping は受信側へ接続できるかを確認します。接続が無い場合は接続し直します。
+func (w *FluentWriter) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	if w.conn == nil {
+		if err := w.connectLocked(); err != nil {
+			return err
+		}
+	}
+	return w.lastErr
+}
+
+// Close は接続を閉じます
+func (w *FluentWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.disconnectLocked()
+	return nil
+}
+
+// readMsgpackStringMap は値がすべて文字列の MessagePack のマップを読み取ります。ack の応答の解析に使います。
+func readMsgpackStringMap(r *bufio.Reader) (map[string]string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	switch {
+	case c&0xf0 == 0x80:
+		n = int(c & 0x0f)
+	case c == 0xde:
+		var buf [2]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, err
+		}
+		n = int(binary.BigEndian.Uint16(buf[:]))
+	default:
+		return nil, fmt.Errorf("golog: unexpected msgpack type 0x%02x in fluent response", c)
+	}
+	m := make(map[string]string, n)
+	for range n {
+		key, err := readMsgpackString(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readMsgpackString(r)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+// readMsgpackString は MessagePack の文字列を読み取ります
+func readMsgpackString(r *bufio.Reader) (string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	var n int
+	switch {
+	case c&0xe0 == 0xa0:
+		n = int(c & 0x1f)
+	case c == 0xd9 || c == 0xda || c == 0xdb:
+		size := 1 << (c - 0xd9) // 1, 2, 4 バイトの長さ
+		var buf [4]byte
+		if _, err := io.ReadFull(r, buf[:size]); err != nil {
+			return "", err
+		}
+		for _, b := range buf[:size] {
+			n = n<<8 | int(b)
+		}
+	default:
+		return "", errors.New("golog: expected msgpack string in fluent response")
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
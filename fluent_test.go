@@ -0,0 +1,209 @@
+package loggo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+)
+
+// fluentServer はテスト用の Forward プロトコルの受信側。受信したメッセージを messages に送ります。
+type fluentServer struct {
+	ln       net.Listener
+	messages chan []byte
+	ack      bool
+}
+
+// newFluentServer は 127.0.0.1 で待ち受ける受信側を開始します
+func newFluentServer(t *testing.T, ack bool) *fluentServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fluentServer{ln: ln, messages: make(chan []byte, 16), ack: ack}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(t, conn)
+		}
+	}()
+	return s
+}
+
+// serve は接続からメッセージを読み取り、ack を要求された場合は応答します。
+// 1回の Read で1つのメッセージが届くことを前提にした簡易的な実装です。
+func (s *fluentServer) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		buf := make([]byte, 64*1024)
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		msg := buf[:n]
+		s.messages <- msg
+		if !s.ack {
+			continue
+		}
+		v, _ := decodeMsgpack(t, msg)
+		option := v.([]any)[2].(msgpackMap)
+		resp := appendMsgpackMapHeader(nil, 1)
+		resp = appendMsgpackString(resp, "ack")
+		resp = appendMsgpackString(resp, option.get("chunk").(string))
+		conn.Write(resp)
+	}
+}
+
+// next は次に受信したメッセージを [tag, entries, option] にデコードして返します
+func (s *fluentServer) next(t *testing.T) []any {
+	t.Helper()
+	select {
+	case msg := <-s.messages:
+		v, rest := decodeMsgpack(t, msg)
+		if len(rest) != 0 {
+			t.Fatalf("trailing %d bytes", len(rest))
+		}
+		return v.([]any)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+		return nil
+	}
+}
+
+// TestFluentWriter は JSON のレコードをキーの順序と時刻を保って Forward モードで送信することをテストします
+func TestFluentWriter(t *testing.T) {
+	s := newFluentServer(t, true)
+	w, err := DialFluent(s.ln.Addr().String(), &FluentWriterOptions{Tag: "app.test", RequireAck: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	logger := slog.New(NewHandler(w, &Options{Format: FormatJSON, TimeFormat: time.RFC3339Nano}))
+	logger.Info("hello", "user", "alice", "n", 3)
+
+	msg := s.next(t)
+	if msg[0] != "app.test" {
+		t.Errorf("tag = %v", msg[0])
+	}
+	entries := msg[1].([]any)
+	if len(entries) != 1 {
+		t.Fatalf("entries = %d, want 1", len(entries))
+	}
+	entry := entries[0].([]any)
+	if ts := entry[0].(time.Time); time.Since(ts) > time.Minute || ts.After(time.Now()) {
+		t.Errorf("event time = %v", ts)
+	}
+	record := entry[1].(msgpackMap)
+	if record.get("msg") != "hello" || record.get("user") != "alice" || record.get("n") != int64(3) {
+		t.Errorf("record = %#v", record)
+	}
+	if i := len(record.Keys); i < 3 || record.Keys[i-2] != "user" || record.Keys[i-1] != "n" {
+		t.Errorf("keys = %v", record.Keys)
+	}
+	if !w.Healthy() {
+		t.Error("writer should be healthy")
+	}
+}
+
+// TestFluentWriterPlainLines は JSON でない行を message フィールドとして送信することをテストします
+func TestFluentWriterPlainLines(t *testing.T) {
+	s := newFluentServer(t, false)
+	w, err := DialFluent(s.ln.Addr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte("first\nsecond\n\n")); err != nil {
+		t.Fatal(err)
+	}
+	msg := s.next(t)
+	if msg[0] != defaultFluentTag {
+		t.Errorf("tag = %v", msg[0])
+	}
+	entries := msg[1].([]any)
+	if len(entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(entries))
+	}
+	for i, want := range []string{"first", "second"} {
+		record := entries[i].([]any)[1].(msgpackMap)
+		if record.get("msg") != want {
+			t.Errorf("entry %d = %#v, want %q", i, record, want)
+		}
+	}
+	if option := msg[2].(msgpackMap); option.get("size") != int64(2) || option.get("chunk") != nil {
+		t.Errorf("option = %#v", option)
+	}
+}
+
+// TestFluentWriterReconnect は接続が切れた後に接続し直して送信することをテストします
+func TestFluentWriterReconnect(t *testing.T) {
+	s := newFluentServer(t, true)
+	w, err := DialFluent(s.ln.Addr().String(), &FluentWriterOptions{RequireAck: true, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.mu.Lock()
+	w.conn.Close() // 切断された接続を再現する
+	w.mu.Unlock()
+
+	if _, err := w.Write([]byte("after reconnect\n")); err != nil {
+		t.Fatal(err)
+	}
+	record := s.next(t)[1].([]any)[0].([]any)[1].(msgpackMap)
+	if record.get("msg") != "after reconnect" {
+		t.Errorf("record = %#v", record)
+	}
+}
+
+// TestFluentWriterAckTimeout は ack が届かない場合にエラーを返して異常と報告することをテストします
+func TestFluentWriterAckTimeout(t *testing.T) {
+	s := newFluentServer(t, false) // ack を返さない
+	w, err := DialFluent(s.ln.Addr().String(), &FluentWriterOptions{RequireAck: true, Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte("lost\n")); err == nil {
+		t.Fatal("expected error without ack")
+	}
+	if w.Healthy() {
+		t.Error("writer should be unhealthy")
+	}
+	if err := w.Ping(t.Context()); err == nil {
+		t.Error("Ping should report the last error")
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte("closed\n")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write after Close = %v, want ErrClosed", err)
+	}
+}
+
+// TestReadMsgpackStringMap は ack の応答の解析をテストします
+func TestReadMsgpackStringMap(t *testing.T) {
+	long := string(make([]byte, 40))
+	b := appendMsgpackMapHeader(nil, 1)
+	b = appendMsgpackString(b, "ack")
+	b = appendMsgpackString(b, long)
+	m, err := readMsgpackStringMap(bufio.NewReader(bytes.NewReader(b)))
+	if err != nil || m["ack"] != long {
+		t.Errorf("m = %v, err = %v", m, err)
+	}
+	if _, err := readMsgpackStringMap(bufio.NewReader(bytes.NewReader([]byte{0x91}))); err == nil {
+		t.Error("expected error for array")
+	}
+}
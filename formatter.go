@@ -0,0 +1,66 @@
+package loggo
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// Buffer は RegisterFormatter で登録する関数が書き込むバッファ
+type Buffer = buffer.Buffer
+
+// formatterFunc は登録されたフォーマット関数を型に依存しない形にしたもの
+type formatterFunc func(buf *Buffer, v any) error
+
+var (
+	// formatters は型ごとに登録されたフォーマット関数を保持します
+	formatters sync.Map // map[reflect.Type]formatterFunc
+	// hasFormatters は登録が無い場合に型の検索を省くためのフラグ
+	hasFormatters atomic.Bool
+)
+
+// RegisterFormatter は型 T の値を出力する関数を登録します。
+// uuid.UUID や decimal.Decimal のように、LogFormatter を実装できない外部の型を
+// 中間文字列を作らずに出力するために使います。
+//
+// fn は値を出力する形式のまま buf に書き込みます。文字列として出力する場合はクォートも書き込んでください。
+// fn がエラーを返した場合、書き込んだ内容は取り消され "!ERROR:..." が出力されます。
+// 登録された関数は属性の値そのものに使用され、LogValuer などのインターフェースより優先されます。
+// string や int などの基本型、構造体のフィールドなど JSON の内部の値には使用されません。
+// T がインターフェース型の場合は何も一致しません。同じ型を再登録すると上書きされます。
+func RegisterFormatter[T any](fn func(buf *Buffer, v T) error) {
+	formatters.Store(reflect.TypeFor[T](), formatterFunc(func(buf *Buffer, v any) error {
+		return fn(buf, v.(T))
+	}))
+	hasFormatters.Store(true)
+}
+
+// lookupFormatter は v の型に登録されたフォーマット関数を返します
+func lookupFormatter(v any) (formatterFunc, bool) {
+	if !hasFormatters.Load() {
+		return nil, false
+	}
+	fn, ok := formatters.Load(reflect.TypeOf(v))
+	if !ok {
+		return nil, false
+	}
+	return fn.(formatterFunc), true
+}
+
+// appendRegistered は登録されたフォーマット関数で値を書き込みます。
+// 関数が登録されていない場合は false を返します。
+func (f *valueFormatter) appendRegistered(buf *buffer.Buffer, v any) (bool, error) {
+	fn, ok := lookupFormatter(v)
+	if !ok {
+		return false, nil
+	}
+	start := buf.Len()
+	if err := fn(buf, v); err != nil {
+		buf.SetLen(start)
+		return true, err
+	}
+	f.escapeNonASCII(buf, start)
+	return true, nil
+}
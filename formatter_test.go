@@ -0,0 +1,64 @@
+package loggo
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type testUUID [16]byte
+
+type testDecimal struct {
+	units int64
+	scale int
+}
+
+func (d testDecimal) LogValue() slog.Value { return slog.StringValue("logvaluer") }
+
+type testFailing struct{}
+
+func init() {
+	RegisterFormatter(func(buf *Buffer, u testUUID) error {
+		buf.WriteByte('"')
+		*buf = hex.AppendEncode(*buf, u[:4])
+		buf.WriteByte('-')
+		*buf = hex.AppendEncode(*buf, u[4:6])
+		buf.WriteByte('"')
+		return nil
+	})
+	RegisterFormatter(func(buf *Buffer, d testDecimal) error {
+		buf.WriteString("decimal")
+		return nil
+	})
+	RegisterFormatter(func(buf *Buffer, _ testFailing) error {
+		buf.WriteString("partial")
+		return errors.New("format failed")
+	})
+}
+
+// TestRegisterFormatter は登録したフォーマット関数で値が出力されることをテストします
+func TestRegisterFormatter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{"registered type", testUUID{0xde, 0xad, 0xbe, 0xef, 0x01, 0x02}, `v="deadbeef-0102"`},
+		{"takes precedence over LogValuer", testDecimal{units: 1}, `v=decimal`},
+		{"error", testFailing{}, `v="!ERROR:format failed"`},
+		{"pointer is not matched", &testUUID{}, `v=[0,0,0,0`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf, nil)).Info("test", "v", tt.value)
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("want %q in output, got: %s", tt.want, buf.String())
+			}
+		})
+	}
+}
@@ -1,17 +1,23 @@
 package loggo
 
 import (
+	"bytes"
+	"cmp"
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
-	"path/filepath"
+	"math"
+	"math/big"
 	"reflect"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/f0reth/golog/internal/buffer"
 )
@@ -31,6 +37,13 @@ const (
 	defaultTimeFormat = "2006-01-02 15:04:05.000"
 )
 
+// truncatedMarker は MaxLineBytes で切り詰めた行の末尾に付ける印
+const truncatedMarker = "...[TRUNCATED]"
+
+// foldSentinel は FoldMultiline で折り返す値をレコードの行の中で囲む区切り。
+// 行の他の部分では制御文字はエスケープされるため、そのまま現れることはありません。
+const foldSentinel = 0
+
 // timeFormatterFunc は時刻をバッファにフォーマットする関数型
 type timeFormatterFunc func(*buffer.Buffer, time.Time)
 
@@ -106,16 +119,54 @@ func makeTimeFormatter(format string) timeFormatterFunc {
 
 // Handler は指定されたフォーマットでログを出力するハンドラー
 type Handler struct {
-	out               io.Writer
+	out               output
+	term              *terminal // out の出力先。実際の出力先への書き込みを排他制御する
+	routes            []route   // Options.Routes の出力先。クローンで共有する
 	minLevel          slog.Level
+	verbose           bool           // DebugOnly の属性を出力する（実効レベルが Debug 以下）
+	override          *levelOverride // TemporaryLevel で変更したレベル。クローンで共有する
 	timeFormat        string
 	timeFormatter     timeFormatterFunc
 	groups            []string
 	useColors         bool
 	addSource         bool
+	sourceFormat      SourceFormat
 	replaceAttr       func(groups []string, a slog.Attr) slog.Attr
-	mu                *sync.Mutex
+	vf                valueFormatter
+	keys              *keyCache // クローンで共有する
+	sortAttrs         bool
+	duplicateKeys     DuplicateKeyPolicy
+	groupPrefix       string // groups を "." で連結したもの（末尾に "." を含む）
+	groupKeyPrefix    []byte // groups をエスケープして groupSeparator で連結したもの（WithGroup で計算する）
+	groupSeparator    string
 	preformattedAttrs []byte
+	preformattedSpans []attrSpan // duplicateKeys が有効な場合のみ記録する
+	jsonOpenGroups    int        // FormatJSON で preformattedAttrs の中で開いているグループの数
+	beforeHandle      func(ctx context.Context, r *slog.Record) bool
+	afterWrite        func(ctx context.Context, r slog.Record, n int, err error)
+	onRecord          []RecordCallback
+	runtimeStatsLevel slog.Leveler // nil の場合は RuntimeStats を付加しない
+	stackTraceLevel   slog.Leveler // nil の場合はスタックトレースを付加しない
+	fingerprint       bool
+	devMode           DevMode
+	dropBadKeys       bool
+	lineEnding        string
+	syslog            *syslogHeader // nil の場合は syslog のヘッダーを付けない
+	heroku            *herokuHeader // nil の場合は Logplex 形式のヘッダーを付けない
+	banner            *banner       // nil の場合はバナーを出力しない。クローンで共有する
+	name              string        // 空の場合は LoggerKey の属性を出力しない
+	levelFormat       LevelFormat
+	levelSeverities   *SeverityMap[int] // LevelFormatSyslog で使う変換表
+	maxLineBytes      int
+	foldMultiline     bool
+	highlightValues   bool         // UseColors が無効な場合は常に false
+	highlighter       *highlighter // UseColors が無効な場合は常に nil
+	emphasizeMessage  bool         // UseColors が無効な場合は常に false
+	messageWidth      int
+	autoElapsed       bool
+	elapsedStart      time.Time // With で追加された StartKey の時刻
+	baggageKeys       []string
+	baggageLookup     func(ctx context.Context, key string) (string, bool)
 }
 
 // Options はカスタムハンドラーのオプション
@@ -125,6 +176,200 @@ type Options struct {
 	TimeFormat  string // 空の場合は "2006-01-02 15:04:05.000" を使用
 	AddSource   bool
 	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+
+	// SourceFormat は AddSource で出力するソースコードの場所の形式。
+	// SourceFormatFileURL や SourceFormatVSCode を指定すると、端末でログの行をクリックして呼び出し元へ移動できます。
+	// -trimpath でビルドしたバイナリではファイルのパスが絶対パスにならないため、リンクとして開けません。
+	SourceFormat SourceFormat
+
+	// ReplaceAttrs は ReplaceAttr の後に順番に適用される関数。
+	// 削除、名前の変更、正規化などの処理を再利用可能な関数の組み合わせとして構成できます。
+	// いずれかの関数がキーが空の属性を返した場合、属性は削除され以降の関数は呼び出されません。
+	ReplaceAttrs []func(groups []string, a slog.Attr) slog.Attr
+
+	WriteMode   WriteMode   // 出力先への書き込み方式
+	FloatFormat FloatFormat // 浮動小数点数の属性の出力形式
+
+	// NoLock は出力先への書き込みの排他制御を省略し、ミューテックスの取得と解放を行いません。
+	// ログの記録が常に1つのゴルーチンから行われることをプログラムが保証できる場合（単純な CLI など）にだけ指定してください。
+	// 複数のゴルーチンから記録したり、SetOutput や Progress を別のゴルーチンから呼び出したりすると、
+	// 行が混ざったりデータ競合が発生したりします。指定した場合 WriteMode は無視されます。
+	NoLock bool
+
+	// QueueSize は WriteModeAsync のキュー、WriteModeSerial のチャネルに保持するレコード数。0 の場合は 1024
+	QueueSize int
+	// DropPolicy は WriteModeAsync のキューが満杯の場合の動作。破棄したレコード数は Stats で取得できます。
+	DropPolicy DropPolicy
+	// DropSummary はレコードを破棄した後、キューが空になった時点で破棄した件数を WARN レベルで出力します
+	DropSummary bool
+
+	// DigitSeparator は整数の属性を3桁ごとに区切る文字（'_' や ',' など）。
+	// バイト数や期間を目視で確認しやすくするためのコンソール向けの機能で、0 の場合は区切りません。
+	DigitSeparator rune
+
+	// ASCIIOnly は出力を ASCII 文字のみに制限します。
+	// 文字列やキーに含まれる非 ASCII 文字は \u 形式でエスケープされるため、
+	// UTF-8 を正しく扱えないパイプラインや端末でも安全に出力できます。
+	ASCIIOnly bool
+
+	// MaxLineBytes は改行を含む1行の最大バイト数。超えた行は UTF-8 の文字の境界で切り詰め、
+	// 末尾に "...[TRUNCATED]" を付けます。大きすぎる行を黙って破棄する syslog や UDP の転送経路向けで、
+	// 0 の場合は制限しません。
+	MaxLineBytes int
+
+	// FoldMultiline は改行を含む文字列の属性を \n でエスケープせず、レコードの行の下に
+	// "  key| " で始まる字下げした継続行として出力します。レコードの行には "key=|" と出力されます。
+	// スタックトレースや SQL、YAML をターミナルで読みやすく表示するためのオプションです。
+	FoldMultiline bool
+
+	// HighlightValues は UseColors が有効な場合に、構造体やマップなど JSON で出力される値の
+	// 括弧や区切り、キー、文字列、数値に控えめな色を付けます。大きなペイロードをターミナルで読みやすくします。
+	HighlightValues bool
+
+	// HighlightRules は UseColors が有効な場合に、メッセージと指定したキーの文字列の属性のうち
+	// 一致した部分を強調する規則。レベルに関係なく適用されるため、Info のレコードでも障害の兆候を目立たせられます。
+	HighlightRules []HighlightRule
+
+	// EmphasizeMessage は UseColors が有効な場合に、メッセージ自体をレベルに応じて強調します。
+	// DEBUG 以下は灰色、WARN は黄色、ERROR 以上は赤の太字で、INFO はそのまま出力されます。
+	EmphasizeMessage bool
+
+	// MessageWidth はメッセージの値（クォートを含む）の表示幅。0 より大きい場合、短いメッセージは空白で埋め、
+	// 長いメッセージは末尾を "..." に置き換えて切り詰めるため、属性が毎行同じ列から始まります。
+	// 全角文字は幅2として数えます。0 の場合は揃えません。
+	MessageWidth int
+
+	// AutoElapsed は StartKey の時刻の属性を持つレコードに、その時刻からレコードの時刻までの経過時間を
+	// ElapsedKey の属性として付加します。With で追加された属性も対象です（グループの外のもののみ）。
+	AutoElapsed bool
+
+	// Format は出力形式。デフォルトは FormatText です。
+	// FormatJSON では WithGroup のグループは入れ子のオブジェクトになります。
+	// UseColors, HighlightValues, HighlightRules, EmphasizeMessage, FoldMultiline, MessageWidth,
+	// DigitSeparator, DuplicateKeys はテキスト形式でのみ使用されます。
+	Format Format
+
+	// GroupSeparator はテキスト形式でグループ名とキーを連結する文字列。空の場合は "." です。
+	// 下流のツールが "." をフィールドの階層として解釈する場合に "/" や "::" などに変更します。
+	GroupSeparator string
+
+	// QuoteSeparator は GroupSeparator を含むキーとグループ名をクォートします。
+	// グループの区切りとキーの一部を区別でき、"a.b" というキーとグループ a のキー b が衝突しません。
+	QuoteSeparator bool
+
+	// SortAttrs は属性をキーでソートして出力します。グループの値のメンバーも各階層でソートします。
+	// 差分ベースのテストや、同一行の重複排除を行うコンシューマーで有用です。
+	// With で追加された属性は With の呼び出しごとにソートされ、常にレコードの属性より前に出力されます。
+	SortAttrs bool
+
+	// DuplicateKeys は With で追加された属性とレコードの属性でキーが重複した場合の扱い。
+	// グループの値はメンバーごとに展開し、キーはグループのプレフィックスを含めて比較されます。
+	DuplicateKeys DuplicateKeyPolicy
+
+	// SerializerPrecedence は複数のインターフェースを実装した値で、どのインターフェースを優先するかの順序。
+	// nil の場合は LogValuer, LogFormatter, Stringer, JSONMarshaler, TextMarshaler の順です。
+	// 含まれない LogValuer, LogFormatter, Stringer は使用されません。JSON にフォールバックした場合は
+	// encoding/json と同様に json.Marshaler と encoding.TextMarshaler が使用されます。
+	SerializerPrecedence []Serializer
+
+	// BeforeHandle はレベルの判定後、フォーマットの前にレコードごとに呼び出されます。
+	// r に属性を追加してレコードを補強できます（呼び出し元のレコードには影響しません）。
+	// false を返すとレコードは出力されません。
+	BeforeHandle func(ctx context.Context, r *slog.Record) bool
+
+	// AfterWrite は出力先への書き込みの後に、書き込んだバイト数とエラーを伴って呼び出されます。
+	// 監査やメトリクスの収集に使えます。WriteModeBatched で他のゴルーチンの書き込みにまとめられた場合や
+	// WriteModeSharded, WriteModeAsync の場合は、書き込みが出力先に渡された時点で呼び出され、エラーは Flush から返されます。
+	AfterWrite func(ctx context.Context, r slog.Record, n int, err error)
+
+	// OnRecord はレベルが閾値以上のレコードで呼び出されるコールバック。
+	// エラーの計数やページング、重大なエラー時のヒーププロファイルの取得などの副作用に使います。
+	OnRecord []RecordCallback
+
+	// RuntimeStatsLevel は RuntimeStats（ヒープ使用量、ゴルーチン数、直前の GC の停止時間）を
+	// 付加するレコードの最小レベル。メモリの問題をログだけから調査するために slog.LevelWarn などを指定します。
+	// 取得時に短時間 stop-the-world が発生するため、低いレベルの指定は避けてください。nil の場合は付加しません。
+	RuntimeStatsLevel slog.Leveler
+
+	// StackTraceLevel はレコードを記録した箇所からのスタックトレースを StackKey の属性として付加する
+	// レコードの最小レベル。nil の場合は付加しません。FoldMultiline と組み合わせると読みやすく表示されます。
+	StackTraceLevel slog.Leveler
+	// Fingerprint は StackTraceLevel でスタックトレースを付加したレコードに、メッセージと上位のスタックフレームの
+	// 関数名から計算したハッシュを FingerprintKey の属性として付加します。ファイルのパスや行番号を含めないため、
+	// 同じ箇所で発生した同じエラーはホストやビルドが違っても同じ値になり、集約システムでまとめられます。
+	Fingerprint bool
+
+	// DevMode はログ呼び出しの誤り（値の無いキーや文字列でないキー、空のキー、重複したキー）を検出した場合の動作。
+	// 本番環境で !BADKEY などが黙って出力されるのを防ぐため、開発中やテストで DevModePanic を指定します。
+	// レコードごとに属性を走査するため、本番環境では DevModeOff のままにしてください。
+	DevMode DevMode
+
+	// DropBadKeys は slog が値に対応するキーの無い引数や文字列でないキーに付ける !BADKEY の属性を出力しません。
+	// デフォルトでは slog の組み込みのハンドラーと同じく !BADKEY=値 として出力します。
+	DropBadKeys bool
+
+	// LineEnding は行の終わりに出力する文字列。空の場合は "\n" です。
+	// CRLF を要求する Windows 向けのファイルや一部のアプライアンスの syslog の受信側では "\r\n" を指定します。
+	// FoldMultiline の継続行にも適用されます。
+	LineEnding string
+
+	// Syslog は各行の先頭に RFC 3164（BSD syslog）形式のヘッダー "<PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: " を付けます。
+	// 古い形式の syslog しか受け付けないアプライアンスへ送る場合に使います。PRI の重要度はレベルから決まります。
+	// 時刻はヘッダーに含まれるため、テキスト形式では行の時刻を出力しません。
+	// RFC 3164 はパケットを 1024 バイト以下に制限しているため、MaxLineBytes との併用を推奨します。
+	Syslog *SyslogOptions
+
+	// Heroku は各行を Heroku の router や dyno のログと同じ "2006-01-02T15:04:05.000000+00:00 app[web.1]: " で始め、
+	// テキスト形式ではレベルを "at=info" として logfmt の本文にします。Heroku 上のアプリケーションのログを
+	// プラットフォームのログと同じ形式で Papertrail などのドレインへ送る場合に使います。
+	// 時刻はヘッダーに含まれるため、テキスト形式では行の時刻を出力しません。Syslog と同時には使用できず、Heroku が優先されます。
+	Heroku *HerokuOptions
+
+	// Banner はハンドラーが最初にレコードを書き込む前に、サービス名、バージョン、PID、設定の概要などを含む
+	// 起動時のレコードを一度だけ出力します。どのログファイルやストリームも単独で出所が分かるようになります。
+	// バナーは Options.Level に関わらず INFO で出力し、With や WithGroup で追加した属性は含みません。
+	Banner *BannerOptions
+
+	// Routes は w に加えてレコードを書き込む出力先。出力先ごとに最小レベルを指定でき、
+	// コンソールには Warn 以上、ファイルには Debug 以上、のように振り分けられます。
+	// レコードは1回だけ整形し、最小レベル以上のすべての出力先へ同じ行を書き込みます。
+	// 書き込み方式（WriteMode など）は w と同じです。形式の異なる出力先には MultiHandler を使います。
+	// DebugOnly の属性は出力先ごとの最小レベルで判断し、Debug が有効な出力先にのみ出力します
+	// （その場合は DebugOnly の有無で2通りに整形します）。
+	Routes []Route
+
+	// LevelFormat はレベルの出力形式。LevelFormatNumber や LevelFormatSyslog を指定すると、
+	// レベルを数値で出力し（テキスト形式では "[4]"、JSON 形式では "level":4）、後段で数値の比較で絞り込めます。
+	// ReplaceAttr が slog.Level 以外の値を返した場合は、その値を出力します。
+	LevelFormat LevelFormat
+
+	// Name はロガーの名前。空でない場合、各レコードのメッセージの後に logger="api.server" のように出力します。
+	// 複数のコンポーネントを含むバイナリで、AddSource を使わずに出所を区別するために使います。
+	// WithGroup のグループには入らず、ReplaceAttr には groups が nil で LoggerKey の属性として渡されます。
+	// Registry の名前付きのロガーも WithName で同じ属性を出力します。
+	Name string
+
+	// BaggageKeys は BaggageLookup でコンテキストから取り出し、属性として出力するキー。
+	// テナントや実験の ID などのビジネス上のメタデータをすべてのログ行に結び付けるために使います。
+	BaggageKeys []string
+	// BaggageLookup はコンテキストの OpenTelemetry Baggage から key の値を取り出す関数。
+	// このパッケージは OpenTelemetry に依存しないため、呼び出し側で指定します（README を参照）。
+	BaggageLookup func(ctx context.Context, key string) (value string, ok bool)
+}
+
+// RecordCallback は Level 以上のレコードが書き込まれた後に呼び出されるコールバック
+type RecordCallback struct {
+	Level slog.Leveler // nil の場合は slog.LevelInfo
+	Func  func(ctx context.Context, r slog.Record)
+}
+
+// FloatFormat は浮動小数点数の出力形式。
+// ゼロ値の場合は値を正確に表現できる最小の桁数で 'f' 形式を使用します。
+// Format が 0 で Precision だけを指定した場合は 'f' 形式でその桁数を使用します（ゼロ値と区別できないため、
+// 小数点以下 0 桁にするには Format: 'f' を指定します）。
+type FloatFormat struct {
+	Format    byte // strconv.FormatFloat の書式（'f', 'g', 'e' など）。0 の場合は 'f'
+	Precision int  // 桁数（'f', 'e' は小数点以下の桁数、'g' は有効桁数）。-1 は正確に表現できる最小の桁数
 }
 
 // NewHandler は新しいカスタムハンドラーを作成します
@@ -132,8 +377,41 @@ func NewHandler(w io.Writer, opts *Options) *Handler {
 	var level slog.Level
 	useColors := false
 	addSource := false
+	sourceFormat := SourceFormatBase
 	var replaceAttr func(groups []string, a slog.Attr) slog.Attr
 	timeFormat := "2006-01-02 15:04:05.000"
+	writeMode := WriteModeBatched
+	var outOpts outputOptions
+	dropSummary := false
+	vf := defaultValueFormatter
+	sortAttrs := false
+	duplicateKeys := DuplicateKeysKeepAll
+	var beforeHandle func(ctx context.Context, r *slog.Record) bool
+	var afterWrite func(ctx context.Context, r slog.Record, n int, err error)
+	var onRecord []RecordCallback
+	var runtimeStatsLevel slog.Leveler
+	var stackTraceLevel slog.Leveler
+	fingerprint := false
+	devMode := DevModeOff
+	dropBadKeys := false
+	lineEnding := "\n"
+	var syslog *syslogHeader
+	var heroku *herokuHeader
+	var bnr *banner
+	var routes []Route
+	name := ""
+	levelFormat := LevelFormatName
+	levelSeverities := SyslogSeverities
+	maxLineBytes := 0
+	foldMultiline := false
+	highlightValues := false
+	var hl *highlighter
+	emphasizeMessage := false
+	messageWidth := 0
+	groupSeparator := "."
+	autoElapsed := false
+	var baggageKeys []string
+	var baggageLookup func(ctx context.Context, key string) (string, bool)
 
 	if opts != nil {
 		if opts.Level != nil {
@@ -141,67 +419,444 @@ func NewHandler(w io.Writer, opts *Options) *Handler {
 		}
 		useColors = opts.UseColors
 		addSource = opts.AddSource
-		replaceAttr = opts.ReplaceAttr
+		sourceFormat = opts.SourceFormat
+		replaceAttr = chainReplaceAttr(opts.ReplaceAttr, opts.ReplaceAttrs)
 		if opts.TimeFormat != "" {
 			timeFormat = opts.TimeFormat
 		}
+		writeMode = opts.WriteMode
+		outOpts.queueSize = opts.QueueSize
+		outOpts.noLock = opts.NoLock
+		outOpts.dropPolicy = opts.DropPolicy
+		dropSummary = opts.DropSummary
+		if ff := opts.FloatFormat; ff != (FloatFormat{}) {
+			vf.floatFormat = cmp.Or(ff.Format, 'f')
+			vf.floatPrecision = ff.Precision
+		}
+		vf.digitSeparator = opts.DigitSeparator
+		vf.ASCIIOnly = opts.ASCIIOnly
+		maxLineBytes = max(opts.MaxLineBytes, 0)
+		foldMultiline = opts.FoldMultiline
+		highlightValues = opts.UseColors && opts.HighlightValues
+		if opts.UseColors {
+			hl = newHighlighter(opts.HighlightRules)
+		}
+		emphasizeMessage = opts.UseColors && opts.EmphasizeMessage
+		messageWidth = max(opts.MessageWidth, 0)
+		autoElapsed = opts.AutoElapsed
+		vf.JSON = opts.Format == FormatJSON
+		if opts.GroupSeparator != "" {
+			groupSeparator = opts.GroupSeparator
+		}
+		if opts.QuoteSeparator {
+			vf.KeySeparator = groupSeparator
+		}
+		sortAttrs = opts.SortAttrs
+		duplicateKeys = opts.DuplicateKeys
+		if opts.SerializerPrecedence != nil {
+			vf.precedence = slices.Clone(opts.SerializerPrecedence)
+		}
+		beforeHandle = opts.BeforeHandle
+		runtimeStatsLevel = opts.RuntimeStatsLevel
+		stackTraceLevel = opts.StackTraceLevel
+		fingerprint = opts.Fingerprint
+		devMode = opts.DevMode
+		dropBadKeys = opts.DropBadKeys
+		if opts.LineEnding != "" {
+			lineEnding = opts.LineEnding
+		}
+		if opts.Syslog != nil {
+			syslog = newSyslogHeader(*opts.Syslog)
+		}
+		if opts.Heroku != nil {
+			heroku = newHerokuHeader(*opts.Heroku)
+			syslog = nil
+		}
+		if opts.Banner != nil {
+			bnr = newBanner(*opts.Banner)
+		}
+		name = opts.Name
+		routes = opts.Routes
+		levelFormat = opts.LevelFormat
+		if opts.Syslog != nil && opts.Syslog.Severities != nil {
+			levelSeverities = opts.Syslog.Severities
+		}
+		if opts.BaggageLookup != nil && len(opts.BaggageKeys) > 0 {
+			baggageKeys = slices.Clone(opts.BaggageKeys)
+			baggageLookup = opts.BaggageLookup
+		}
+		afterWrite = opts.AfterWrite
+		for _, cb := range opts.OnRecord {
+			if cb.Func == nil {
+				continue
+			}
+			if cb.Level == nil {
+				cb.Level = slog.LevelInfo
+			}
+			onRecord = append(onRecord, cb)
+		}
+	}
+
+	if vf.JSON {
+		// JSON 形式ではコンソール向けの装飾と、テキスト形式を前提とする設定を使用しない
+		useColors, foldMultiline, highlightValues, emphasizeMessage = false, false, false, false
+		hl = nil
+		messageWidth = 0
+		vf.digitSeparator = 0
+		duplicateKeys = DuplicateKeysKeepAll
+		if opts.TimeFormat == "" {
+			timeFormat = time.RFC3339Nano
+		}
+	}
+
+	h := &Handler{
+		minLevel:          level,
+		verbose:           level <= slog.LevelDebug,
+		override:          &levelOverride{},
+		timeFormat:        timeFormat,
+		timeFormatter:     makeTimeFormatter(timeFormat),
+		groups:            []string{},
+		useColors:         useColors,
+		addSource:         addSource,
+		sourceFormat:      sourceFormat,
+		replaceAttr:       replaceAttr,
+		vf:                vf,
+		keys:              &keyCache{},
+		sortAttrs:         sortAttrs,
+		duplicateKeys:     duplicateKeys,
+		beforeHandle:      beforeHandle,
+		afterWrite:        afterWrite,
+		onRecord:          onRecord,
+		runtimeStatsLevel: runtimeStatsLevel,
+		stackTraceLevel:   stackTraceLevel,
+		fingerprint:       fingerprint,
+		devMode:           devMode,
+		dropBadKeys:       dropBadKeys,
+		lineEnding:        lineEnding,
+		syslog:            syslog,
+		heroku:            heroku,
+		banner:            bnr,
+		name:              name,
+		levelFormat:       levelFormat,
+		levelSeverities:   levelSeverities,
+		maxLineBytes:      maxLineBytes,
+		foldMultiline:     foldMultiline,
+		highlightValues:   highlightValues,
+		highlighter:       hl,
+		emphasizeMessage:  emphasizeMessage,
+		messageWidth:      messageWidth,
+		groupSeparator:    groupSeparator,
+		autoElapsed:       autoElapsed,
+		baggageKeys:       baggageKeys,
+		baggageLookup:     baggageLookup,
+	}
+	if dropSummary {
+		outOpts.summary = h.formatDropSummary
+	}
+	h.term = newTerminal(w, outOpts.noLock)
+	h.out = newOutput(h.term, writeMode, outOpts)
+	h.routes = newRoutes(routes, writeMode, outOpts)
+	if bnr != nil {
+		bnr.root = h
+	}
+	return h
+}
+
+// formatDropSummary は破棄したレコード数を伝えるレコードをフォーマットします
+func (h *Handler) formatDropSummary(dropped uint64) []byte {
+	r := slog.NewRecord(time.Now(), slog.LevelWarn, "golog: dropped log records", 0)
+	r.AddAttrs(slog.Uint64("dropped", dropped))
+
+	buf := buffer.New()
+	defer buf.Free()
+	h.format(buf, r)
+	if h.lineEnding != "\n" {
+		replaceLineEndings(buf, h.lineEnding)
 	}
+	return slices.Clone(*buf)
+}
 
-	return &Handler{
-		out:           w,
-		minLevel:      level,
-		timeFormat:    timeFormat,
-		timeFormatter: makeTimeFormatter(timeFormat),
-		groups:        []string{},
-		useColors:     useColors,
-		addSource:     addSource,
-		replaceAttr:   replaceAttr,
-		mu:            &sync.Mutex{},
+// chainReplaceAttr は first と rest を順番に適用する関数を返します。
+// 関数が1つ以下の場合は合成せずにそのまま返します。
+func chainReplaceAttr(first func([]string, slog.Attr) slog.Attr, rest []func([]string, slog.Attr) slog.Attr) func([]string, slog.Attr) slog.Attr {
+	var fns []func([]string, slog.Attr) slog.Attr
+	if first != nil {
+		fns = append(fns, first)
+	}
+	for _, fn := range rest {
+		if fn != nil {
+			fns = append(fns, fn)
+		}
+	}
+	switch len(fns) {
+	case 0:
+		return nil
+	case 1:
+		return fns[0]
+	}
+	return func(groups []string, a slog.Attr) slog.Attr {
+		for _, fn := range fns {
+			a = fn(groups, a)
+			if a.Key == "" {
+				break
+			}
+		}
+		return a
 	}
 }
 
-// Enabled はログレベルが有効かどうかを判断します
+// Enabled はログレベルが有効かどうかを判断します。
+// コンテキストに WithMinLevel でレベルが設定されている場合は、そのレベルで判断します。
+// Routes を指定している場合は、いずれかの出力先で有効かどうかを判断します。
 func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
-	return level >= h.minLevel
+	return level >= h.enabledLevel(ctx)
+}
+
+// effectiveLevel は ctx で記録するレコードに適用する最小レベルを返します。
+// WithMinLevel で設定したレベル、TemporaryLevel で一時的に下げたレベル、Options.Level の順に優先します。
+func (h *Handler) effectiveLevel(ctx context.Context) slog.Level {
+	if minLevel, ok := MinLevelFromContext(ctx); ok {
+		return minLevel
+	}
+	if level, ok := h.override.get(); ok {
+		return min(level, h.minLevel)
+	}
+	return h.minLevel
 }
 
 // Handle はログレコードを処理します
 func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
-	if !h.Enabled(ctx, r.Level) {
+	level := h.enabledLevel(ctx)
+	if r.Level < level {
 		return nil
 	}
+	if h.devMode != DevModeOff {
+		h.checkRecord(ctx, r)
+	}
+	verboseLevel := level
+	if len(h.routes) > 0 {
+		// Routes の出力先は writeRoutes で出力先ごとに判断する
+		verboseLevel = h.effectiveLevel(ctx)
+	}
+	if (verboseLevel <= slog.LevelDebug) != h.verbose {
+		// WithMinLevel や TemporaryLevel で変更したレベルで DebugOnly の属性を判断する
+		clone := *h
+		clone.verbose = !h.verbose
+		h = &clone
+	}
+
+	if h.beforeHandle != nil {
+		// フックによる属性の追加が呼び出し元のレコードに影響しないように複製する
+		rec := r.Clone()
+		if !h.beforeHandle(ctx, &rec) {
+			return nil
+		}
+		r = rec
+	}
+	if h.runtimeStatsLevel != nil && r.Level >= h.runtimeStatsLevel.Level() {
+		rec := r.Clone()
+		rec.AddAttrs(RuntimeStats())
+		r = rec
+	}
+	if id, ok := RequestIDFromContext(ctx); ok {
+		rec := r.Clone()
+		rec.AddAttrs(slog.String(RequestIDKey, id))
+		r = rec
+	}
+	if h.baggageLookup != nil {
+		r = h.addBaggage(ctx, r)
+	}
+	if h.autoElapsed {
+		r = h.addElapsed(r)
+	}
+	if h.stackTraceLevel != nil && r.Level >= h.stackTraceLevel.Level() {
+		r = h.addStack(r)
+	}
+	if h.banner != nil {
+		h.banner.emit(ctx)
+	}
+	return h.writeRecord(ctx, r, true)
+}
 
+// writeRecord はレコードをフォーマットして出力先へ書き込み、AfterWrite と OnRecord を呼び出します。
+// filter が true の場合、Routes を指定していれば出力先ごとの最小レベルで書き込む出力先を選び、
+// 書き込んだ出力先が無い場合はフックを呼び出しません。
+func (h *Handler) writeRecord(ctx context.Context, r slog.Record, filter bool) error {
 	buf := buffer.New()
 	defer buf.Free()
 
+	var n int
+	var err error
+	if len(h.routes) == 0 {
+		h.render(buf, r)
+		n = buf.Len()
+		err = h.out.write(*buf)
+	} else {
+		var written bool
+		if n, written, err = h.writeRoutes(ctx, buf, r, filter); !written {
+			return nil
+		}
+	}
+	if h.afterWrite != nil {
+		h.afterWrite(ctx, r, n, err)
+	}
+	for _, cb := range h.onRecord {
+		if r.Level >= cb.Level.Level() {
+			cb.Func(ctx, r)
+		}
+	}
+	return err
+}
+
+// render はレコードを改行まで含めた1行（FoldMultiline の場合は継続行を含む）に整形して、空の buf に書き込みます
+func (h *Handler) render(buf *buffer.Buffer, r slog.Record) {
+	if h.syslog != nil {
+		h.syslog.append(buf, r)
+	}
+	if h.heroku != nil {
+		h.heroku.append(buf, r)
+	}
+	h.format(buf, r)
+	if h.foldMultiline {
+		moveFoldedBlocks(buf)
+	}
+	// 改行を置き換えた後の長さで MaxLineBytes に収める
+	extra := len(h.lineEnding) - 1
+	if h.maxLineBytes > 0 && buf.Len()+extra > h.maxLineBytes {
+		truncateLine(buf, h.maxLineBytes-extra)
+	}
+	if h.lineEnding != "\n" {
+		replaceLineEndings(buf, h.lineEnding)
+	}
+}
+
+// addBaggage は BaggageKeys の値をコンテキストから取り出し、属性として追加したレコードを返します。
+// 値が1つも無い場合は r をそのまま返します。
+func (h *Handler) addBaggage(ctx context.Context, r slog.Record) slog.Record {
+	cloned := false
+	for _, key := range h.baggageKeys {
+		v, ok := h.baggageLookup(ctx, key)
+		if !ok {
+			continue
+		}
+		if !cloned {
+			r = r.Clone()
+			cloned = true
+		}
+		r.AddAttrs(slog.String(key, v))
+	}
+	return r
+}
+
+// truncateLine は改行を含めて maxBytes に収まるように行を切り詰めます。
+// UTF-8 の文字の途中では切らず、末尾に truncatedMarker と改行を付けます。
+func truncateLine(buf *buffer.Buffer, maxBytes int) {
+	marker := truncatedMarker
+	if maxBytes <= len(marker)+1 {
+		marker = ""
+	}
+	keep := max(maxBytes-len(marker)-1, 0)
+	for keep > 0 && !utf8.RuneStart((*buf)[keep]) {
+		keep--
+	}
+	*buf = append(append((*buf)[:keep], marker...), '\n')
+}
+
+// replaceLineEndings は buf の改行をすべて ending に置き換えます。
+// 値に含まれる改行はエスケープされるため、buf の改行は行の終わりだけです。
+func replaceLineEndings(buf *buffer.Buffer, ending string) {
+	if bytes.IndexByte(*buf, '\n') < 0 {
+		return
+	}
+	tmp := buffer.New()
+	defer tmp.Free()
+	tmp.Write(*buf)
+	buf.SetLen(0)
+	for line := range bytes.Lines(*tmp) {
+		if line[len(line)-1] == '\n' {
+			buf.Write(line[:len(line)-1])
+			buf.WriteString(ending)
+		} else {
+			buf.Write(line)
+		}
+	}
+}
+
+// format はレコードを出力形式に応じて1行にフォーマットしてバッファに書き込みます
+func (h *Handler) format(buf *buffer.Buffer, r slog.Record) {
+	if h.vf.JSON {
+		h.formatJSON(buf, r)
+		return
+	}
+	h.formatText(buf, r)
+}
+
+// formatText はレコードをテキスト形式の1行にフォーマットしてバッファに書き込みます
+func (h *Handler) formatText(buf *buffer.Buffer, r slog.Record) {
 	timeAttr := slog.Time(slog.TimeKey, r.Time)
 	if h.replaceAttr != nil {
 		timeAttr = h.replaceAttr(nil, timeAttr)
 	}
-	if timeAttr.Key != "" {
+	if timeAttr.Key != "" && h.syslog == nil && h.heroku == nil {
 		buf.WriteByte('[')
-		if t, ok := timeAttr.Value.Any().(time.Time); ok {
-			h.timeFormatter(buf, t)
+		// ReplaceAttr が時刻以外（エポック秒など）に置き換えた場合は、その値をそのまま書き込む
+		if v := h.vf.resolve(timeAttr.Value); v.Kind() == slog.KindTime {
+			h.timeFormatter(buf, v.Time())
 		} else {
-			h.timeFormatter(buf, r.Time)
+			h.appendHeaderValue(buf, v)
 		}
 		buf.WriteString("] ")
 	}
 
-	levelAttr := slog.Any(slog.LevelKey, r.Level)
+	// slog.Any によるボックス化を避けるため、ReplaceAttr が無い場合は属性を作らない
+	level, keepLevel := r.Level, true
+	var levelValue slog.Value // ReplaceAttr が slog.Level 以外に置き換えた値
+	customLevel := false
 	if h.replaceAttr != nil {
-		levelAttr = h.replaceAttr(nil, levelAttr)
+		levelAttr := h.replaceAttr(nil, slog.Any(slog.LevelKey, r.Level))
+		v := h.vf.resolve(levelAttr.Value)
+		if lvl, ok := v.Any().(slog.Level); ok {
+			level = lvl
+		} else {
+			levelValue, customLevel = v, true
+		}
+		keepLevel = levelAttr.Key != ""
 	}
-	if levelAttr.Key != "" {
+	switch {
+	case keepLevel && h.heroku != nil:
+		buf.WriteString("at=")
+		if customLevel {
+			h.vf.appendAttrValue(buf, levelValue)
+		} else if n, ok := h.levelNumber(level); ok {
+			*buf = strconv.AppendInt(*buf, n, 10)
+		} else {
+			buf.WriteString(herokuLevel(level))
+		}
+		buf.WriteByte(' ')
+	case keepLevel && customLevel:
+		// 置き換えた値は元のレベルの色で表示する
 		buf.WriteByte('[')
-		var level slog.Level
-		if lvl, ok := levelAttr.Value.Any().(slog.Level); ok {
-			level = lvl
+		if h.useColors {
+			buf.WriteString(levelColor(r.Level))
+		}
+		h.appendHeaderValue(buf, levelValue)
+		if h.useColors {
+			buf.WriteString(colorReset)
+		}
+		buf.WriteString("] ")
+	case keepLevel:
+		buf.WriteByte('[')
+		if n, ok := h.levelNumber(level); ok {
+			if h.useColors {
+				buf.WriteString(levelColor(level))
+			}
+			*buf = strconv.AppendInt(*buf, n, 10)
+			if h.useColors {
+				buf.WriteString(colorReset)
+			}
 		} else {
-			level = r.Level
+			buf.WriteString(h.formatLevelWithColor(level))
 		}
-		levelStr := h.formatLevelWithColor(level)
-		buf.WriteString(levelStr)
 		buf.WriteString("] ")
 	}
 
@@ -211,203 +866,690 @@ func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
 	}
 	if msgAttr.Key != "" {
 		buf.WriteString("msg=")
-		if msgErr := formatValue(buf, msgAttr.Value.Any()); msgErr != nil {
-			buf.WriteString("\"!ERROR:")
-			buf.WriteString(msgErr.Error())
-			buf.WriteByte('"')
+		var style string
+		if h.emphasizeMessage {
+			style = messageStyle(level)
+			buf.WriteString(style)
+		}
+		msgStart := buf.Len()
+		if h.highlighter != nil && msgAttr.Value.Kind() == slog.KindString {
+			h.vf.appendHighlighted(buf, msgAttr.Value.String(), h.highlighter.message, style)
+		} else {
+			h.vf.appendAttrValue(buf, msgAttr.Value)
+		}
+		if h.messageWidth > 0 {
+			fitMessage(buf, msgStart, h.messageWidth, style)
+		}
+		if style != "" {
+			buf.WriteString(colorReset)
 		}
 	}
 
-	if len(h.preformattedAttrs) > 0 {
+	h.appendName(buf)
+
+	// ソートや重複の排除が必要な場合は、レコードの属性を先に集める
+	var stack [16]slog.Attr
+	var attrs []slog.Attr
+	collect := h.sortAttrs || h.duplicateKeys != DuplicateKeysKeepAll
+	if collect {
+		attrs = h.collectAttrs(stack[:0], r)
+	}
+
+	// 重複の排除はグループを展開したキーで行う
+	var leaves []leafAttr
+	if h.duplicateKeys != DuplicateKeysKeepAll {
+		for _, attr := range attrs {
+			leaves = h.flattenAttr(leaves, nil, attr)
+		}
+		h.appendPreformattedDeduped(buf, leaves)
+	} else if len(h.preformattedAttrs) > 0 {
 		buf.Write(h.preformattedAttrs)
 	}
 
 	if h.addSource {
-		fs := runtime.CallersFrames([]uintptr{r.PC})
-		f, _ := fs.Next()
-		if f.File != "" {
-			file := filepath.Base(f.File)
-			sourceStr := file + ":" + strconv.Itoa(f.Line)
-
-			sourceAttr := slog.String(slog.SourceKey, sourceStr)
-			if h.replaceAttr != nil {
-				sourceAttr = h.replaceAttr(nil, sourceAttr)
-			}
-			if sourceAttr.Key != "" {
-				buf.WriteString(" ")
-				if needsQuoting(sourceAttr.Key) {
-					buf.WriteString(strconv.Quote(sourceAttr.Key))
-				} else {
-					buf.WriteString(sourceAttr.Key)
-				}
-				buf.WriteString("=")
-				formatValue(buf, sourceAttr.Value.Any())
-			}
-		}
+		h.appendSource(buf, r.PC)
 	}
 
-	r.Attrs(func(attr slog.Attr) bool {
-		appendAttr(buf, attr.Key, attr.Value, h.groups, h.replaceAttr)
-		return true
-	})
+	switch {
+	case h.duplicateKeys != DuplicateKeysKeepAll:
+		h.appendAttrsDeduped(buf, leaves)
+	case collect:
+		for _, attr := range attrs {
+			h.writeAttr(buf, nil, attr)
+		}
+	default:
+		r.Attrs(func(attr slog.Attr) bool {
+			h.appendAttr(buf, attr)
+			return true
+		})
+	}
 
 	buf.WriteByte('\n')
+}
 
-	h.mu.Lock()
-	_, err := h.out.Write(*buf)
-	h.mu.Unlock()
-	return err
+// Flush は未書き込みのレコードを出力先へ書き出します
+func (h *Handler) Flush() error {
+	return errors.Join(h.out.flush(), h.flushRoutes())
 }
 
-// needsQuoting はキーにクォートが必要かどうかを判定します
-func needsQuoting(s string) bool {
-	if s == "" {
-		return true
+// SetOutput は出力先を w に置き換えます。Routes の出力先は変更しません。
+// それまでに書き込まれたレコードは元の出力先へ書き出されます。クローンを含むすべてのハンドラーに反映されるため、
+// SIGHUP や logrotate でのファイルの開き直しや、テストでの出力の取得にロガーを作り直す必要がありません。
+// 元の出力先はクローズしません。
+func (h *Handler) SetOutput(w io.Writer) {
+	h.drainOutput()
+	h.term.setWriter(w)
+}
+
+// drainOutput は out に残っているレコードを現在の出力先へ書き出します。
+// flush と異なり、WriteModeAsync で発生した書き込みエラーを消費しません。
+func (h *Handler) drainOutput() {
+	h.out.setWriter(h.term)
+}
+
+// Close は未書き込みのレコードを書き出し、バックグラウンドの書き込み処理を停止します。
+// クローンを含むすべてのハンドラーが出力先を共有しているため、どのハンドラーから呼び出しても同じです。
+// 出力先の io.Writer はクローズしません。
+// WriteModeSharded, WriteModeAsync, WriteModeSerial のハンドラーは Close を呼び出すまで Shutdown の対象として保持され、
+// ガベージコレクションの対象になりません。使い終わったら必ず呼び出してください。
+func (h *Handler) Close() error {
+	return errors.Join(h.out.close(), h.closeRoutes())
+}
+
+// appendSource はソースファイルと行番号をバッファに書き込みます
+func (h *Handler) appendSource(buf *buffer.Buffer, pc uintptr) {
+	fs := runtime.CallersFrames([]uintptr{pc})
+	f, _ := fs.Next()
+	if f.File == "" {
+		return
 	}
-	for _, r := range s {
-		if r <= ' ' || r == '=' || r == '"' || r == 0x7f {
-			return true
-		}
+	file := h.sourceFormat.file(f.File)
+
+	if h.replaceAttr == nil {
+		// 中間文字列を作らずに source="file.go:42" を書き込む
+		h.appendBuiltinKey(buf, slog.SourceKey)
+		h.vf.appendString(buf, file)
+		buf.SetLen(buf.Len() - 1)
+		buf.WriteByte(':')
+		*buf = strconv.AppendInt(*buf, int64(f.Line), 10)
+		buf.WriteByte('"')
+		return
+	}
+
+	sourceAttr := h.replaceAttr(nil, slog.String(slog.SourceKey, file+":"+strconv.Itoa(f.Line)))
+	if sourceAttr.Key == "" {
+		return
+	}
+	h.appendBuiltinKey(buf, sourceAttr.Key)
+	h.vf.appendAttrValue(buf, sourceAttr.Value)
+}
+
+// appendName は Options.Name のロガーの名前を、グループに関係なく最上位の属性として書き込みます
+func (h *Handler) appendName(buf *buffer.Buffer) {
+	if h.name == "" {
+		return
+	}
+	if h.replaceAttr == nil {
+		h.appendBuiltinKey(buf, LoggerKey)
+		h.vf.appendString(buf, h.name)
+		return
+	}
+	attr := h.replaceAttr(nil, slog.String(LoggerKey, h.name))
+	if attr.Key == "" {
+		return
+	}
+	h.appendBuiltinKey(buf, attr.Key)
+	h.vf.appendAttrValue(buf, attr.Value)
+}
+
+// Name は Options.Name または WithName で設定したロガーの名前を返します
+func (h *Handler) Name() string {
+	return h.name
+}
+
+// WithName はロガーの名前を name に置き換えたハンドラーを返します。name が空の場合は名前を出力しません。
+// "api" のハンドラーから "api.server" のように、ドットで階層化した名前を付けることを推奨します（Registry と同じ規則）。
+func (h *Handler) WithName(name string) *Handler {
+	newHandler := *h
+	newHandler.name = name
+	return &newHandler
+}
+
+// appendBuiltinKey は source などの組み込みの属性のキーを出力形式に応じて書き込みます
+func (h *Handler) appendBuiltinKey(buf *buffer.Buffer, key string) {
+	if h.vf.JSON {
+		h.appendJSONKey(buf, key)
+		return
+	}
+	if key == slog.SourceKey {
+		buf.WriteString(" source=")
+		return
+	}
+	buf.WriteByte(' ')
+	h.appendCachedKey(buf, key)
+}
+
+// appendAttr は属性をグループのプレフィックス付きでバッファに書き込みます
+func (h *Handler) appendAttr(buf *buffer.Buffer, attr slog.Attr) {
+	if attr, ok := h.replace(nil, attr); ok {
+		h.writeAttr(buf, nil, attr)
 	}
-	return false
 }
 
-func appendAttr(buf *buffer.Buffer, key string, value slog.Value, groups []string, replaceAttr func(groups []string, a slog.Attr) slog.Attr) {
-	attr := slog.Attr{Key: key, Value: value}
-	if replaceAttr != nil {
-		attr = replaceAttr(groups, attr)
+// replace は値を解決して ReplaceAttr を適用します。
+// 属性を出力しない場合（空の属性、空のグループ、ReplaceAttr で削除された属性、出力しない DebugOnly）は false を返します。
+// グループの属性自体には ReplaceAttr を適用せず、メンバーの書き込み時に適用します。
+func (h *Handler) replace(nested []string, attr slog.Attr) (slog.Attr, bool) {
+	if attr.Value.Kind() == slog.KindLogValuer && !h.verbose {
+		if _, ok := attr.Value.LogValuer().(debugOnlyValue); ok {
+			return attr, false
+		}
+	}
+	attr.Value = h.vf.resolve(attr.Value)
+	if attr.Key == "" && attr.Value.Kind() == slog.KindAny && attr.Value.Any() == nil {
+		return attr, false
+	}
+	if h.dropBadKeys && attr.Key == badKey {
+		return attr, false
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if h.sortAttrs {
+			attr.Value = sortGroup(attr.Value)
+		}
+		return attr, len(attr.Value.Group()) > 0
+	}
+	if h.replaceAttr != nil {
+		groups := h.groups
+		if len(nested) > 0 {
+			groups = append(slices.Clip(h.groups), nested...)
+		}
+		attr = h.replaceAttr(groups, attr)
+		attr.Value = h.vf.resolve(attr.Value)
 		if attr.Key == "" {
-			return
+			return attr, false
+		}
+	}
+	return attr, true
+}
+
+// sortAttrsByKey は属性をキーでソートします。同じキーの属性は元の順序を保持します。
+func sortAttrsByKey(attrs []slog.Attr) {
+	slices.SortStableFunc(attrs, compareAttrKeys)
+}
+
+func compareAttrKeys(a, b slog.Attr) int {
+	return strings.Compare(a.Key, b.Key)
+}
+
+// sortGroup はグループのメンバーをキーでソートした値を返します。ソート済みの場合はそのまま返します。
+func sortGroup(v slog.Value) slog.Value {
+	members := v.Group()
+	if slices.IsSortedFunc(members, compareAttrKeys) {
+		return v
+	}
+	members = slices.Clone(members)
+	sortAttrsByKey(members)
+	return slog.GroupValue(members...)
+}
+
+// replaceWithAttrs は With の属性に ReplaceAttr を適用し、SortAttrs が有効な場合はキーでソートします
+func (h *Handler) replaceWithAttrs(attrs []slog.Attr) []slog.Attr {
+	kept := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		if attr, ok := h.replace(nil, attr); ok {
+			kept = append(kept, attr)
 		}
 	}
+	if h.sortAttrs {
+		sortAttrsByKey(kept)
+	}
+	return kept
+}
+
+// writeAttr は ReplaceAttr を適用せずに属性を書き込みます。
+// グループの値はメンバーごとに展開し、キーが空のグループはプレフィックスを付けずにインライン化します。
+// nested はハンドラーのグループに続く、属性の値によるグループです。
+func (h *Handler) writeAttr(buf *buffer.Buffer, nested []string, attr slog.Attr) {
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			nested = append(slices.Clip(nested), attr.Key)
+		}
+		for _, member := range attr.Value.Group() {
+			if member, ok := h.replace(nested, member); ok {
+				h.writeAttr(buf, nested, member)
+			}
+		}
+		return
+	}
 
 	buf.WriteByte(' ')
 
-	if len(groups) > 0 {
-		for _, group := range groups {
-			if needsQuoting(group) {
-				buf.WriteString(strconv.Quote(group))
-			} else {
-				buf.WriteString(group)
+	keyStart := buf.Len()
+	buf.Write(h.groupKeyPrefix)
+	for _, group := range nested {
+		h.vf.appendKey(buf, group)
+		buf.WriteString(h.groupSeparator)
+	}
+
+	h.appendCachedKey(buf, attr.Key)
+	if h.foldMultiline && attr.Value.Kind() == slog.KindString && strings.Contains(attr.Value.String(), "\n") {
+		buf.SetLen(buf.Len() - 1) // '=' を取り除く
+		h.appendFolded(buf, (*buf)[keyStart:], attr.Value.String())
+		return
+	}
+	if h.highlighter != nil && attr.Value.Kind() == slog.KindString {
+		if rules, ok := h.highlighter.byKey[attr.Key]; ok {
+			h.vf.appendHighlighted(buf, attr.Value.String(), rules, "")
+			return
+		}
+	}
+	start := buf.Len()
+	h.vf.appendAttrValue(buf, attr.Value)
+	if h.highlightValues {
+		highlightJSON(buf, start)
+	}
+}
+
+// appendFolded は "=|" に続けて、折り返した値の継続行を foldSentinel で囲んで書き込みます。
+// 継続行は format の最後に moveFoldedBlocks でレコードの行の後ろへ移動されます。
+func (h *Handler) appendFolded(buf *buffer.Buffer, key []byte, s string) {
+	buf.WriteString("=|")
+	buf.WriteByte(foldSentinel)
+	for line := range strings.Lines(s) {
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		buf.WriteString("  ")
+		buf.Write(key)
+		buf.WriteString("| ")
+		start := buf.Len()
+		for i := 0; i < len(line); i++ {
+			c := line[i]
+			if (c < 0x20 && c != '\t') || c == 0x7f {
+				buf.WriteString(`\x`)
+				buf.WriteByte(hexDigits[c>>4])
+				buf.WriteByte(hexDigits[c&0xf])
+				continue
 			}
-			buf.WriteByte('.')
+			buf.WriteByte(c)
 		}
+		h.vf.escapeNonASCII(buf, start)
+		buf.WriteByte('\n')
 	}
+	buf.WriteByte(foldSentinel)
+}
 
-	if needsQuoting(attr.Key) {
-		buf.WriteString(strconv.Quote(attr.Key))
-	} else {
-		buf.WriteString(attr.Key)
+// moveFoldedBlocks は foldSentinel で囲まれた継続行をレコードの行から取り除き、行の後ろへ移動します
+func moveFoldedBlocks(buf *buffer.Buffer) {
+	line := *buf
+	if bytes.IndexByte(line, foldSentinel) < 0 {
+		return
 	}
-	buf.WriteByte('=')
-	if err := formatValue(buf, attr.Value.Any()); err != nil {
-		buf.WriteString("\"!ERROR:")
-		buf.WriteString(err.Error())
-		buf.WriteByte('"')
+	out := buffer.New()
+	defer out.Free()
+
+	// 1回目はレコードの行、2回目は継続行を書き出す
+	for _, folded := range [...]bool{false, true} {
+		rest := line
+		for len(rest) > 0 {
+			i := bytes.IndexByte(rest, foldSentinel)
+			if i < 0 {
+				if !folded {
+					out.Write(rest)
+				}
+				break
+			}
+			j := i + 1 + bytes.IndexByte(rest[i+1:], foldSentinel)
+			if folded {
+				out.Write(rest[i+1 : j])
+			} else {
+				out.Write(rest[:i])
+			}
+			rest = rest[j+1:]
+		}
 	}
+	buf.SetLen(0)
+	buf.Write(*out)
 }
 
-// formatLevelWithColor はログレベルを色付きでフォーマットします
+// formatLevelWithColor はログレベルを色付きでフォーマットします。
+// 標準のレベルは連結済みの定数を返すため、アロケーションが発生しません。
 func (h *Handler) formatLevelWithColor(level slog.Level) string {
-	levelStr := formatLevel(level)
-
 	if !h.useColors {
-		return levelStr
+		return formatLevel(level)
 	}
 
-	var colorCode string
 	switch level {
 	case slog.LevelDebug:
-		colorCode = colorCyan
+		return colorCyan + "DEBUG" + colorReset
 	case slog.LevelInfo:
-		colorCode = colorGreen
+		return colorGreen + " INFO" + colorReset
 	case slog.LevelWarn:
-		colorCode = colorYellow
+		return colorYellow + " WARN" + colorReset
 	case slog.LevelError:
-		colorCode = colorRed
+		return colorRed + "ERROR" + colorReset
 	default:
-		colorCode = colorWhite
+		return colorWhite + formatLevel(level) + colorReset
+	}
+}
+
+// levelNumber は LevelFormat が数値の形式の場合に、出力するレベルの数値を返します
+func (h *Handler) levelNumber(level slog.Level) (int64, bool) {
+	switch h.levelFormat {
+	case LevelFormatNumber:
+		return int64(level), true
+	case LevelFormatSyslog:
+		return int64(h.levelSeverities.Lookup(level)), true
+	}
+	return 0, false
+}
+
+// levelColor はレベルの表示に使う色を返します（formatLevelWithColor と同じ色）
+func levelColor(level slog.Level) string {
+	switch level {
+	case slog.LevelDebug:
+		return colorCyan
+	case slog.LevelInfo:
+		return colorGreen
+	case slog.LevelWarn:
+		return colorYellow
+	case slog.LevelError:
+		return colorRed
+	default:
+		return colorWhite
+	}
+}
+
+// appendHeaderValue は ReplaceAttr で置き換えた時刻やレベルの値を "[...]" の中に書き込みます。
+// 文字列は括弧の中に1行で収まる場合はクォートせずに、それ以外の値は属性の値と同じ形式で書き込みます。
+func (h *Handler) appendHeaderValue(buf *buffer.Buffer, v slog.Value) {
+	if v.Kind() == slog.KindString && headerSafe(v.String(), h.vf.ASCIIOnly) {
+		buf.WriteString(v.String())
+		return
+	}
+	h.vf.appendAttrValue(buf, v)
+}
+
+// headerSafe は s をクォートせずに "[...]" の中に書き込めるかどうかを判定します
+func headerSafe(s string, asciiOnly bool) bool {
+	if s == "" || s[0] == ' ' || s[len(s)-1] == ' ' {
+		return false
+	}
+	for _, r := range s {
+		if r < ' ' || r == ']' || r == '"' || r == 0x7f {
+			return false
+		}
+		if r >= utf8.RuneSelf && (asciiOnly || r == utf8.RuneError || !unicode.IsPrint(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// valueFormatter は値をバッファに書き込む際の設定
+type valueFormatter struct {
+	floatFormat    byte
+	floatPrecision int
+	digitSeparator rune
+	precedence     []Serializer
+	Escaper        // キーと文字列のエスケープ。JSON は FormatJSON の場合に true、KeySeparator は Options.QuoteSeparator
+}
+
+// defaultValueFormatter はデフォルト設定の valueFormatter
+var defaultValueFormatter = valueFormatter{
+	floatFormat:    'f',
+	floatPrecision: -1,
+	precedence:     defaultPrecedence,
+}
+
+// appendKey はキーまたはグループ名を書き込みます。必要な場合はクォートします。
+func (f *valueFormatter) appendKey(buf *buffer.Buffer, key string) {
+	*buf = f.AppendKey(*buf, key)
+}
+
+// keyNeedsQuoting はキーまたはグループ名にクォートが必要かどうかを判定します
+func (f *valueFormatter) keyNeedsQuoting(key string) bool {
+	return f.KeyNeedsQuoting(key)
+}
+
+// appendString は文字列をクォートして書き込みます
+func (f *valueFormatter) appendString(buf *buffer.Buffer, s string) {
+	*buf = f.AppendString(*buf, s)
+}
+
+// escapeNonASCII は ASCIIOnly が設定されている場合に、buf の start 以降に含まれる非 ASCII 文字を \u 形式でエスケープします。
+// JSON や LogFormatter の出力に使用し、サロゲートペアを用いるため JSON としても有効です。
+func (f *valueFormatter) escapeNonASCII(buf *buffer.Buffer, start int) {
+	if f.ASCIIOnly {
+		*buf = escapeNonASCII(*buf, start)
+	}
+}
+
+// appendInt は整数を書き込みます。digitSeparator が設定されている場合は3桁ごとに区切ります。
+func (f *valueFormatter) appendInt(buf *buffer.Buffer, n int64) {
+	if f.digitSeparator == 0 || (n > -1000 && n < 1000) {
+		*buf = strconv.AppendInt(*buf, n, 10)
+		return
+	}
+	u := uint64(n)
+	if n < 0 {
+		buf.WriteByte('-')
+		u = -u
+	}
+	var digits [20]byte
+	f.appendGroupedDigits(buf, strconv.AppendUint(digits[:0], u, 10))
+}
+
+// appendUint は符号なし整数を書き込みます。digitSeparator が設定されている場合は3桁ごとに区切ります。
+func (f *valueFormatter) appendUint(buf *buffer.Buffer, n uint64) {
+	if f.digitSeparator == 0 || n < 1000 {
+		*buf = strconv.AppendUint(*buf, n, 10)
+		return
+	}
+	var digits [20]byte
+	f.appendGroupedDigits(buf, strconv.AppendUint(digits[:0], n, 10))
+}
+
+// appendGroupedDigits は10進数の数字列を3桁ごとに区切って書き込みます
+func (f *valueFormatter) appendGroupedDigits(buf *buffer.Buffer, d []byte) {
+	for i, c := range d {
+		if i > 0 && (len(d)-i)%3 == 0 {
+			*buf = utf8.AppendRune(*buf, f.digitSeparator)
+		}
+		buf.WriteByte(c)
+	}
+}
+
+// appendBigInt は多倍長整数を書き込みます。digitSeparator が設定されている場合は3桁ごとに区切ります。
+func (f *valueFormatter) appendBigInt(buf *buffer.Buffer, n *big.Int) {
+	if f.digitSeparator == 0 {
+		*buf = n.Append(*buf, 10)
+		return
+	}
+	d := n.Append(nil, 10)
+	if d[0] == '-' {
+		buf.WriteByte('-')
+		d = d[1:]
+	}
+	f.appendGroupedDigits(buf, d)
+}
+
+// appendBigFloat は多倍長浮動小数点数を FloatFormat の書式で書き込みます。
+// 桁数が -1 の場合は、値の精度で一意に表現できる最小の桁数になります。
+func (f *valueFormatter) appendBigFloat(buf *buffer.Buffer, n *big.Float) {
+	*buf = n.Append(*buf, f.floatFormat, f.floatPrecision)
+}
+
+// appendFloat は浮動小数点数を書き込みます。
+// JSON 形式では数値として表せない NaN と無限大を文字列にします。
+func (f *valueFormatter) appendFloat(buf *buffer.Buffer, v float64, bitSize int) {
+	if f.JSON && (math.IsNaN(v) || math.IsInf(v, 0)) {
+		buf.WriteByte('"')
+		*buf = strconv.AppendFloat(*buf, v, f.floatFormat, f.floatPrecision, bitSize)
+		buf.WriteByte('"')
+		return
 	}
+	*buf = strconv.AppendFloat(*buf, v, f.floatFormat, f.floatPrecision, bitSize)
+}
 
-	return colorCode + levelStr + colorReset
+// appendComplex は複素数を "(1+2i)" の形式で書き込みます。JSON 形式では文字列にします。
+func (f *valueFormatter) appendComplex(buf *buffer.Buffer, c complex128, bitSize int) {
+	if f.JSON {
+		buf.WriteByte('"')
+		defer buf.WriteByte('"')
+	}
+	buf.WriteByte('(')
+	*buf = strconv.AppendFloat(*buf, real(c), f.floatFormat, f.floatPrecision, bitSize/2)
+	im := strconv.AppendFloat(nil, imag(c), f.floatFormat, f.floatPrecision, bitSize/2)
+	if im[0] != '+' && im[0] != '-' {
+		buf.WriteByte('+')
+	}
+	buf.Write(im)
+	buf.WriteString("i)")
+}
+
+// appendAttrValue は属性の値を書き込みます。
+// エラーは "!ERROR:..." として、String や MarshalJSON などのパニックは "!PANIC: ..." として書き込み、
+// ログの呼び出し元へは伝播させません。
+func (f *valueFormatter) appendAttrValue(buf *buffer.Buffer, v slog.Value) {
+	start := buf.Len()
+	defer func() {
+		if r := recover(); r != nil {
+			buf.SetLen(start)
+			f.appendString(buf, fmt.Sprintf("!PANIC: %v", r))
+		}
+	}()
+	if err := f.appendValue(buf, v); err != nil {
+		f.appendString(buf, "!ERROR:"+err.Error())
+	}
+}
+
+// appendValue は slog.Value をボックス化せずにバッファへ書き込みます。
+// 基本的な Kind は直接書き込み、それ以外は formatValue に委譲します。
+func (f *valueFormatter) appendValue(buf *buffer.Buffer, v slog.Value) error {
+	switch v.Kind() {
+	case slog.KindString:
+		f.appendString(buf, v.String())
+	case slog.KindInt64:
+		f.appendInt(buf, v.Int64())
+	case slog.KindUint64:
+		f.appendUint(buf, v.Uint64())
+	case slog.KindFloat64:
+		f.appendFloat(buf, v.Float64(), 64)
+	case slog.KindBool:
+		*buf = strconv.AppendBool(*buf, v.Bool())
+	case slog.KindDuration:
+		f.appendInt(buf, int64(v.Duration()))
+	case slog.KindTime:
+		buf.WriteByte('"')
+		*buf = v.Time().AppendFormat(*buf, time.RFC3339Nano)
+		buf.WriteByte('"')
+	case slog.KindLogValuer:
+		if resolved := f.resolve(v); resolved.Kind() != slog.KindLogValuer {
+			return f.appendValue(buf, resolved)
+		}
+		return f.formatValue(buf, v.Any())
+	default:
+		if u, ok := v.Any().(unitValue); ok {
+			f.appendUnit(buf, u)
+			return nil
+		}
+		return f.formatValue(buf, v.Any())
+	}
+	return nil
 }
 
 // formatValue は値を適切な形式に変換してバッファに書き込みます
-func formatValue(buf *buffer.Buffer, v any) error {
+func (f *valueFormatter) formatValue(buf *buffer.Buffer, v any) error {
 	if v == nil {
 		buf.WriteString("null")
 		return nil
 	}
 
-	if lv, ok := v.(slog.LogValuer); ok {
-		return formatValue(buf, lv.LogValue().Any())
-	}
-
 	if s, ok := v.(string); ok {
-		buf.WriteString(strconv.Quote(s))
+		f.appendString(buf, s)
 		return nil
 	}
 
 	switch v := v.(type) {
 	case int:
-		*buf = strconv.AppendInt(*buf, int64(v), 10)
+		f.appendInt(buf, int64(v))
 		return nil
 	case int8:
-		*buf = strconv.AppendInt(*buf, int64(v), 10)
+		f.appendInt(buf, int64(v))
 		return nil
 	case int16:
-		*buf = strconv.AppendInt(*buf, int64(v), 10)
+		f.appendInt(buf, int64(v))
 		return nil
 	case int32:
-		*buf = strconv.AppendInt(*buf, int64(v), 10)
+		f.appendInt(buf, int64(v))
 		return nil
 	case int64:
-		*buf = strconv.AppendInt(*buf, v, 10)
+		f.appendInt(buf, v)
 		return nil
 	case uint:
-		*buf = strconv.AppendUint(*buf, uint64(v), 10)
+		f.appendUint(buf, uint64(v))
 		return nil
 	case uint8:
-		*buf = strconv.AppendUint(*buf, uint64(v), 10)
+		f.appendUint(buf, uint64(v))
 		return nil
 	case uint16:
-		*buf = strconv.AppendUint(*buf, uint64(v), 10)
+		f.appendUint(buf, uint64(v))
 		return nil
 	case uint32:
-		*buf = strconv.AppendUint(*buf, uint64(v), 10)
+		f.appendUint(buf, uint64(v))
 		return nil
 	case uint64:
-		*buf = strconv.AppendUint(*buf, v, 10)
+		f.appendUint(buf, v)
 		return nil
 	case float32:
-		*buf = strconv.AppendFloat(*buf, float64(v), 'f', -1, 32)
+		f.appendFloat(buf, float64(v), 32)
 		return nil
 	case float64:
-		*buf = strconv.AppendFloat(*buf, v, 'f', -1, 64)
+		f.appendFloat(buf, v, 64)
 		return nil
 	case bool:
 		*buf = strconv.AppendBool(*buf, v)
 		return nil
-	case LogFormatter:
-		s, err := v.FormatForLog()
-		if err != nil {
-			return err
+	case complex64:
+		f.appendComplex(buf, complex128(v), 64)
+		return nil
+	case complex128:
+		f.appendComplex(buf, v, 128)
+		return nil
+	case *big.Int:
+		if v == nil {
+			buf.WriteString("null")
+		} else {
+			f.appendBigInt(buf, v)
+		}
+		return nil
+	case *big.Float:
+		if v == nil {
+			buf.WriteString("null")
+		} else {
+			f.appendBigFloat(buf, v)
 		}
-		buf.WriteString(s)
 		return nil
 	}
 
+	if ok, err := f.appendRegistered(buf, v); ok {
+		return err
+	}
+
+	if ok, err := f.appendSerialized(buf, v); ok {
+		return err
+	}
+
 	rv := reflect.ValueOf(v)
 	if rv.Kind() == reflect.Pointer && rv.IsNil() {
 		buf.WriteString("null")
 		return nil
 	}
 
-	b, err := json.Marshal(v)
-	if err != nil {
+	start := buf.Len()
+	if err := appendReflect(buf, rv); err != nil {
 		return err
 	}
-	buf.Write(b)
+	f.escapeNonASCII(buf, start)
 	return nil
 }
 
@@ -427,6 +1569,14 @@ func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	newHandler.groups = make([]string, len(h.groups))
 	copy(newHandler.groups, h.groups)
 
+	if h.autoElapsed && len(h.groups) == 0 {
+		for _, attr := range attrs {
+			if attr.Key == StartKey && attr.Value.Kind() == slog.KindTime {
+				newHandler.elapsedStart = attr.Value.Time()
+			}
+		}
+	}
+
 	buf := buffer.New()
 	defer buf.Free()
 
@@ -434,8 +1584,28 @@ func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 		buf.Write(h.preformattedAttrs)
 	}
 
+	attrs = h.replaceWithAttrs(attrs)
+	if h.vf.JSON {
+		h.withAttrsJSON(&newHandler, buf, attrs)
+		return &newHandler
+	}
+
 	for _, attr := range attrs {
-		appendAttr(buf, attr.Key, attr.Value, h.groups, h.replaceAttr)
+		if h.duplicateKeys == DuplicateKeysKeepAll {
+			h.writeAttr(buf, nil, attr)
+			continue
+		}
+		// 重複を判定できるよう、グループはメンバーごとに位置を記録する
+		for _, leaf := range h.flattenAttr(nil, nil, attr) {
+			start := buf.Len()
+			h.writeAttr(buf, leaf.nested, leaf.attr)
+			newHandler.preformattedSpans = append(slices.Clip(newHandler.preformattedSpans), attrSpan{
+				prefix: h.groupPrefix,
+				key:    leaf.key,
+				start:  start,
+				end:    buf.Len(),
+			})
+		}
 	}
 
 	newHandler.preformattedAttrs = make([]byte, buf.Len())
@@ -460,6 +1630,13 @@ func (h *Handler) WithGroup(name string) slog.Handler {
 	newHandler.groups = make([]string, len(h.groups)+1)
 	copy(newHandler.groups, h.groups)
 	newHandler.groups[len(h.groups)] = name
+	newHandler.groupPrefix = h.groupPrefix + name + "."
+
+	// レコードごとにグループ名をエスケープし直さないよう、出力する形式のプレフィックスを作っておく
+	prefix := buffer.Buffer(slices.Clip(h.groupKeyPrefix))
+	h.vf.appendKey(&prefix, name)
+	prefix.WriteString(h.groupSeparator)
+	newHandler.groupKeyPrefix = prefix
 
 	return &newHandler
 }
@@ -476,6 +1653,9 @@ func formatLevel(level slog.Level) string {
 	case slog.LevelError:
 		return "ERROR"
 	default:
+		if c, ok := lookupLevel(level); ok {
+			return c.padded
+		}
 		s := level.String()
 		if len(s) < 5 {
 			return strings.Repeat(" ", 5-len(s)) + s
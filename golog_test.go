@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"context"
 	"log/slog"
+	"math/big"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/f0reth/golog/internal/buffer"
 )
@@ -194,6 +197,72 @@ func TestGroupWithAttrs(t *testing.T) {
 	}
 }
 
+// TestGroupValues はグループ値の属性の展開をテストします
+func TestGroupValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		logFunc func(l *slog.Logger)
+		want    string
+	}{
+		{"group", func(l *slog.Logger) {
+			l.Info("test", slog.Group("req", "method", "GET", "status", 200))
+		}, `msg="test" req.method="GET" req.status=200`},
+		{"nested", func(l *slog.Logger) {
+			l.Info("test", slog.Group("a", slog.Group("b", "c", 1)))
+		}, `msg="test" a.b.c=1`},
+		{"inline", func(l *slog.Logger) {
+			l.Info("test", "k", 1, slog.Attr{Key: "", Value: slog.GroupValue(slog.Int("x", 2), slog.Int("y", 3))})
+		}, `msg="test" k=1 x=2 y=3`},
+		{"inline in group", func(l *slog.Logger) {
+			l.WithGroup("g").Info("test", slog.Group("", "x", 1))
+		}, `msg="test" g.x=1`},
+		{"empty group", func(l *slog.Logger) {
+			l.Info("test", "a", 1, slog.Group("empty"), "b", 2)
+		}, `msg="test" a=1 b=2`},
+		{"nested empty group", func(l *slog.Logger) {
+			l.Info("test", slog.Group("outer", slog.Group("inner")))
+		}, `msg="test"`},
+		{"zero attr", func(l *slog.Logger) {
+			l.Info("test", slog.Attr{}, "a", 1)
+		}, `msg="test" a=1`},
+		{"with attrs", func(l *slog.Logger) {
+			l.With(slog.Group("svc", "name", "api"), slog.Group("none")).Info("test")
+		}, `msg="test" svc.name="api"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.logFunc(slog.New(NewHandler(&buf, &Options{TimeFormat: ""})))
+			got := strings.TrimSuffix(buf.String(), "\n")
+			if !strings.HasSuffix(got, tt.want) {
+				t.Errorf("want suffix %q, got: %q", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("ReplaceAttr receives nested groups", func(t *testing.T) {
+		var buf bytes.Buffer
+		var gotGroups []string
+		h := NewHandler(&buf, &Options{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == "id" {
+					gotGroups = groups
+				}
+				return a
+			},
+		})
+		slog.New(h).WithGroup("g").Info("test", slog.Group("req", slog.Group("", "id", 1)))
+
+		if !slices.Equal(gotGroups, []string{"g", "req"}) {
+			t.Errorf("want groups [g req], got %v", gotGroups)
+		}
+		if !strings.Contains(buf.String(), "g.req.id=1") {
+			t.Errorf("unexpected output: %s", buf.String())
+		}
+	})
+}
+
 // TestColors はカラー出力をテストします
 func TestColors(t *testing.T) {
 	var buf bytes.Buffer
@@ -214,7 +283,7 @@ func TestColors(t *testing.T) {
 	}
 }
 
-// TestFormatValue は formatValue 関数をテストします
+// TestFormatValue は formatValue メソッドをテストします
 func TestFormatValue(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -245,7 +314,7 @@ func TestFormatValue(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			buf := buffer.New()
 			defer buf.Free()
-			err := formatValue(buf, tt.input)
+			err := defaultValueFormatter.formatValue(buf, tt.input)
 			if (err != nil) != tt.hasError {
 				t.Errorf("expected error=%v, got error=%v", tt.hasError, err)
 			}
@@ -257,6 +326,62 @@ func TestFormatValue(t *testing.T) {
 	}
 }
 
+type testColor int
+
+func (c testColor) String() string {
+	return [...]string{"red", "green", "blue"}[c]
+}
+
+type testUserID struct {
+	id int
+}
+
+func (u *testUserID) String() string {
+	return "user-" + strconv.Itoa(u.id)
+}
+
+// TestStringer は fmt.Stringer を実装した値が String の結果で出力されることをテストします
+func TestStringer(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    any
+		expected string
+	}{
+		{"enum", testColor(1), `"green"`},
+		{"pointer receiver", &testUserID{id: 42}, `"user-42"`},
+		{"nil pointer", (*testUserID)(nil), "null"},
+		{"value without pointer receiver", testUserID{id: 42}, "{}"},
+		{"escaped", testStringerFunc("a\"b"), `"a\"b"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := buffer.New()
+			defer buf.Free()
+			if err := defaultValueFormatter.formatValue(buf, tt.input); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("handler output", func(t *testing.T) {
+		var buf bytes.Buffer
+		slog.New(NewHandler(&buf, nil)).Info("test", "color", testColor(2))
+		if !strings.Contains(buf.String(), `color="blue"`) {
+			t.Errorf("expected Stringer output, got: %s", buf.String())
+		}
+	})
+}
+
+type testStringerFunc string
+
+func (s testStringerFunc) String() string {
+	return string(s)
+}
+
 // TestNilPointer は nil ポインタの処理をテストします
 func TestNilPointer(t *testing.T) {
 	type TestStruct struct {
@@ -266,7 +391,7 @@ func TestNilPointer(t *testing.T) {
 	var nilPtr *TestStruct
 	formatBuf := buffer.New()
 	defer formatBuf.Free()
-	err := formatValue(formatBuf, nilPtr)
+	err := defaultValueFormatter.formatValue(formatBuf, nilPtr)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -829,6 +954,52 @@ func TestLogFormatterError(t *testing.T) {
 	}
 }
 
+type panicStringer struct{}
+
+func (panicStringer) String() string { panic("stringer failed") }
+
+type panicLogValuer struct{}
+
+func (panicLogValuer) LogValue() slog.Value { panic("logvaluer failed") }
+
+type panicMarshaler struct{}
+
+func (panicMarshaler) MarshalJSON() ([]byte, error) { panic("marshal failed") }
+
+type valueReceiverLogValuer struct{ name string }
+
+func (v valueReceiverLogValuer) LogValue() slog.Value { return slog.StringValue(v.name) }
+
+// TestPanicRecovery は値のフォーマット中のパニックが属性の値として出力されることをテストします
+func TestPanicRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		logFunc func(l *slog.Logger)
+		want    string
+	}{
+		{"Stringer", func(l *slog.Logger) { l.Info("test", "v", panicStringer{}) }, `v="!PANIC: stringer failed"`},
+		{"LogValuer", func(l *slog.Logger) { l.Info("test", "v", panicLogValuer{}) }, `v="!PANIC: logvaluer failed"`},
+		{"nested MarshalJSON", func(l *slog.Logger) {
+			l.Info("test", "v", map[string]any{"m": panicMarshaler{}})
+		}, `v="!PANIC: marshal failed"`},
+		{"nil value receiver", func(l *slog.Logger) {
+			l.Info("test", "v", (*valueReceiverLogValuer)(nil))
+		}, `v="!PANIC: `},
+		{"WithAttrs", func(l *slog.Logger) { l.With("v", panicStringer{}).Info("test") }, `v="!PANIC: stringer failed"`},
+		{"following attrs", func(l *slog.Logger) { l.Info("test", "v", panicStringer{}, "next", 1) }, `v="!PANIC: stringer failed" next=1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.logFunc(slog.New(NewHandler(&buf, nil)))
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("want %q in output, got: %s", tt.want, buf.String())
+			}
+		})
+	}
+}
+
 // TestAllColorLevels はすべてのログレベルの色をテストします
 func TestAllColorLevels(t *testing.T) {
 	tests := []struct {
@@ -884,7 +1055,7 @@ func TestVariousNumericTypes(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			buf := buffer.New()
 			defer buf.Free()
-			err := formatValue(buf, tt.value)
+			err := defaultValueFormatter.formatValue(buf, tt.value)
 			if err != nil {
 				t.Errorf("unexpected error: %v", err)
 			}
@@ -896,6 +1067,148 @@ func TestVariousNumericTypes(t *testing.T) {
 	}
 }
 
+// TestComplexAndBigNumbers は複素数と math/big の型をテストします
+func TestComplexAndBigNumbers(t *testing.T) {
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+
+	tests := []struct {
+		name     string
+		opts     *Options
+		value    any
+		expected string
+	}{
+		{"complex128", nil, complex(1.5, -2), "(1.5-2i)"},
+		{"complex64", nil, complex64(complex(0.1, 3)), "(0.1+3i)"},
+		{"complex precision", &Options{FloatFormat: FloatFormat{Format: 'f', Precision: 2}}, complex(1.0/3, 2.0/3), "(0.33+0.67i)"},
+		{"big.Int", nil, huge, "-123456789012345678901234567890"},
+		{"big.Int separator", &Options{DigitSeparator: '_'}, huge, "-123_456_789_012_345_678_901_234_567_890"},
+		{"big.Float", nil, big.NewFloat(1.25), "1.25"},
+		{"big.Float precision", &Options{FloatFormat: FloatFormat{Format: 'e', Precision: 3}}, new(big.Float).SetPrec(200).SetInt(huge), "-1.235e+29"},
+		{"nil big.Int", nil, (*big.Int)(nil), "null"},
+		{"complex in struct", nil, struct{ Z complex128 }{complex(1, 2)}, `{"Z":"(1+2i)"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf, tt.opts)).Info("test", "v", tt.value)
+			if want := " v=" + tt.expected + "\n"; !strings.HasSuffix(buf.String(), want) {
+				t.Errorf("want suffix %q, got: %q", want, buf.String())
+			}
+		})
+	}
+}
+
+// TestFloatFormat は FloatFormat オプションをテストします
+func TestFloatFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		format FloatFormat
+		value  any
+		want   string
+	}{
+		{"default", FloatFormat{}, 3.14159, "value=3.14159"},
+		{"fixed 2", FloatFormat{Format: 'f', Precision: 2}, 3.14159, "value=3.14"},
+		{"precision only", FloatFormat{Precision: 2}, 3.14159, "value=3.14"},
+		{"fixed pads", FloatFormat{Format: 'f', Precision: 3}, 2.5, "value=2.500"},
+		{"g shortest", FloatFormat{Format: 'g', Precision: -1}, 1e21, "value=1e+21"},
+		{"g precision", FloatFormat{Format: 'g', Precision: 3}, 123456.0, "value=1.23e+05"},
+		{"float32", FloatFormat{Format: 'f', Precision: 1}, float32(0.25), "value=0.2"},
+		{"int unaffected", FloatFormat{Format: 'f', Precision: 2}, 42, "value=42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, &Options{FloatFormat: tt.format}))
+			logger.Info("test", "value", tt.value)
+
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("want %q in output, got: %s", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+// TestDigitSeparator は DigitSeparator オプションをテストします
+func TestDigitSeparator(t *testing.T) {
+	tests := []struct {
+		name  string
+		sep   rune
+		value any
+		want  string
+	}{
+		{"disabled", 0, 1234567, "value=1234567"},
+		{"underscore", '_', 1234567, "value=1_234_567"},
+		{"comma", ',', int64(1000), "value=1,000"},
+		{"small", ',', 999, "value=999"},
+		{"negative", ',', -1234567, "value=-1,234,567"},
+		{"min int64", ',', int64(-9223372036854775808), "value=-9,223,372,036,854,775,808"},
+		{"uint64", '_', uint64(18446744073709551615), "value=18_446_744_073_709_551_615"},
+		{"duration", '_', 1500 * time.Millisecond, "value=1_500_000_000"},
+		{"multibyte", '\u202f', 12345, "value=12\u202f345"},
+		{"float unaffected", ',', 12345.5, "value=12345.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, &Options{DigitSeparator: tt.sep}))
+			logger.Info("test", "value", tt.value)
+
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("want %q in output, got: %s", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+// TestASCIIOnly は ASCIIOnly オプションをテストします
+func TestASCIIOnly(t *testing.T) {
+	type Payload struct {
+		Name string
+	}
+
+	tests := []struct {
+		name string
+		log  func(*slog.Logger)
+		want string
+	}{
+		{"message", func(l *slog.Logger) { l.Info("こんにちは") }, `msg="\u3053\u3093\u306b\u3061\u306f"`},
+		{"string value", func(l *slog.Logger) { l.Info("test", "city", "東京") }, `city="\u6771\u4eac"`},
+		{"emoji", func(l *slog.Logger) { l.Info("test", "face", "😀") }, `face="\U0001f600"`},
+		{"key", func(l *slog.Logger) { l.Info("test", "名前", "x") }, `"\u540d\u524d"="x"`},
+		{"group", func(l *slog.Logger) { l.WithGroup("グループ").Info("test", "k", 1) }, `"\u30b0\u30eb\u30fc\u30d7".k=1`},
+		{"json", func(l *slog.Logger) { l.Info("test", "p", Payload{Name: "日本😀"}) }, `p={"Name":"\u65e5\u672c\ud83d\ude00"}`},
+		{"log formatter", func(l *slog.Logger) { l.Info("test", "c", CustomType{Value: "値"}) }, `c="custom:\u5024"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewHandler(&buf, &Options{ASCIIOnly: true})))
+
+			output := buf.String()
+			if !strings.Contains(output, tt.want) {
+				t.Errorf("want %q in output, got: %s", tt.want, output)
+			}
+			for _, c := range []byte(output) {
+				if c >= 0x80 {
+					t.Fatalf("output should be ASCII only, got: %s", output)
+				}
+			}
+		})
+	}
+
+	t.Run("default passes UTF-8 through", func(t *testing.T) {
+		var buf bytes.Buffer
+		slog.New(NewHandler(&buf, nil)).Info("こんにちは", "city", "東京")
+		if !strings.Contains(buf.String(), `msg="こんにちは" city="東京"`) {
+			t.Errorf("UTF-8 should pass through by default, got: %s", buf.String())
+		}
+	})
+}
+
 // TestHandlerIndependence は複数のハンドラーの独立性をテストします
 func TestHandlerIndependence(t *testing.T) {
 	var buf bytes.Buffer
@@ -1149,6 +1462,77 @@ func TestAttributeOrder(t *testing.T) {
 	}
 }
 
+// TestSortAttrs は SortAttrs オプションで属性がキー順に出力されることをテストします
+func TestSortAttrs(t *testing.T) {
+	t.Run("sorted", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(NewHandler(&buf, &Options{SortAttrs: true}))
+		logger.Info("test", "zeta", 1, "alpha", 2, "mid", 3, "alpha", 4)
+
+		if !strings.Contains(buf.String(), `msg="test" alpha=2 alpha=4 mid=3 zeta=1`) {
+			t.Errorf("attributes should be sorted by key (stable), got: %s", buf.String())
+		}
+	})
+
+	t.Run("with groups and preformatted attrs", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(NewHandler(&buf, &Options{SortAttrs: true})).With("z", "pre").WithGroup("g")
+		logger.Info("test", "b", 1, "a", 2)
+
+		if !strings.Contains(buf.String(), `z="pre" g.a=2 g.b=1`) {
+			t.Errorf("record attributes should be sorted after preformatted attrs, got: %s", buf.String())
+		}
+	})
+
+	t.Run("sorted after ReplaceAttr", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(NewHandler(&buf, &Options{
+			SortAttrs: true,
+			ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+				if a.Key == "b" {
+					a.Key = "0"
+				}
+				return a
+			},
+		}))
+		logger.Info("test", "a", 1, "b", 2)
+
+		if !strings.Contains(buf.String(), `0=2 a=1`) {
+			t.Errorf("attributes should be sorted by replaced key, got: %s", buf.String())
+		}
+	})
+
+	t.Run("nested groups and With attrs", func(t *testing.T) {
+		for _, format := range []Format{FormatText, FormatJSON} {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, &Options{SortAttrs: true, Format: format})).With("y", 1, "x", 2)
+			logger.Info("test", slog.Group("g", "b", 1, slog.Group("h", "d", 3, "c", 4), "a", 2))
+
+			want := `x=2 y=1 g.a=2 g.b=1 g.h.c=4 g.h.d=3`
+			if format == FormatJSON {
+				want = `"x":2,"y":1,"g":{"a":2,"b":1,"h":{"c":4,"d":3}}`
+			}
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%v: With attrs and group members should be sorted, got: %s", format, buf.String())
+			}
+		}
+	})
+
+	t.Run("many attributes", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(NewHandler(&buf, &Options{SortAttrs: true}))
+		var args []any
+		for i := 30; i > 0; i-- {
+			args = append(args, "k"+strconv.Itoa(100+i), i)
+		}
+		logger.Info("test", args...)
+
+		if !strings.Contains(buf.String(), `k101=1 k102=2`) || !strings.HasSuffix(buf.String(), "k130=30\n") {
+			t.Errorf("attributes should be sorted, got: %s", buf.String())
+		}
+	})
+}
+
 // TestPreformattedAttrsWithMultipleWithAttrs は複数のWithAttrsで事前フォーマットをテストします
 func TestPreformattedAttrsWithMultipleWithAttrs(t *testing.T) {
 	var buf bytes.Buffer
@@ -1411,6 +1795,438 @@ func TestReplaceAttrModifiesBuiltInValues(t *testing.T) {
 			t.Errorf("output should not contain original level INFO, got: %s", output)
 		}
 	})
+
+	t.Run("replace time and level with other types", func(t *testing.T) {
+		toEpoch := func(groups []string, a slog.Attr) slog.Attr {
+			switch {
+			case groups != nil:
+			case a.Key == slog.TimeKey:
+				return slog.Int64(slog.TimeKey, a.Value.Time().Unix())
+			case a.Key == slog.LevelKey:
+				return slog.String(slog.LevelKey, strings.ToLower(a.Value.Any().(slog.Level).String()))
+			}
+			return a
+		}
+		now := time.Unix(1705314645, 0)
+		tests := []struct {
+			name string
+			opts Options
+			want string
+		}{
+			{"text", Options{}, `[1705314645] [info] msg="m" g.k="v"`},
+			{"colors", Options{UseColors: true}, "[1705314645] [" + colorGreen + "info" + colorReset + `] msg="m" g.k="v"`},
+			{"json", Options{Format: FormatJSON}, `{"time":1705314645,"level":"info","msg":"m","g":{"k":"v"}}`},
+			{"heroku", Options{Heroku: &HerokuOptions{}}, `at="info" msg="m" g.k="v"`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				tt.opts.ReplaceAttr = toEpoch
+				h := NewHandler(&buf, &tt.opts).WithGroup("g")
+				r := slog.NewRecord(now, slog.LevelInfo, "m", 0)
+				r.AddAttrs(slog.String("k", "v"))
+				if err := h.Handle(context.Background(), r); err != nil {
+					t.Fatal(err)
+				}
+				if got := strings.TrimSuffix(buf.String(), "\n"); !strings.HasSuffix(got, tt.want) {
+					t.Errorf("got  %q\nwant suffix %q", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("quote header values that would break the line", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := NewHandler(&buf, &Options{ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.LevelKey {
+				return slog.String(slog.LevelKey, "a]\nb")
+			}
+			if a.Key == slog.TimeKey {
+				return slog.String(slog.TimeKey, "today 10:00")
+			}
+			return a
+		}})
+		slog.New(handler).Info("m")
+		if want := "[today 10:00] [\"a]\\nb\"] msg=\"m\"\n"; buf.String() != want {
+			t.Errorf("got %q, want %q", buf.String(), want)
+		}
+	})
+}
+
+// TestReplaceAttrs は複数の ReplaceAttr 関数が順番に適用されることをテストします
+func TestReplaceAttrs(t *testing.T) {
+	redact := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == "password" {
+			return slog.String(a.Key, "***")
+		}
+		return a
+	}
+	rename := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == "password" {
+			a.Key = "pw"
+		}
+		return a
+	}
+	drop := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == "internal" {
+			return slog.Attr{}
+		}
+		return a
+	}
+	var calledAfterDrop bool
+	after := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == "" || a.Key == "internal" {
+			calledAfterDrop = true
+		}
+		return a
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{
+		ReplaceAttr:  redact,
+		ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{rename, nil, drop, after},
+	}))
+	logger.Info("login", "password", "secret", "internal", 1, "user", "alice")
+
+	output := buf.String()
+	if !strings.Contains(output, `pw="***"`) {
+		t.Errorf("functions should be applied in order, got: %s", output)
+	}
+	if strings.Contains(output, "internal") || strings.Contains(output, "secret") {
+		t.Errorf("unexpected attribute in output: %s", output)
+	}
+	if !strings.Contains(output, `user="alice"`) {
+		t.Errorf("other attributes should be kept, got: %s", output)
+	}
+	if calledAfterDrop {
+		t.Error("functions after a removal should not be called")
+	}
+}
+
+// TestHooks は BeforeHandle と AfterWrite フックをテストします
+func TestHooks(t *testing.T) {
+	t.Run("enrich and observe", func(t *testing.T) {
+		var buf bytes.Buffer
+		var gotMsg string
+		var gotN int
+		var gotErr error
+		logger := slog.New(NewHandler(&buf, &Options{
+			BeforeHandle: func(ctx context.Context, r *slog.Record) bool {
+				r.AddAttrs(slog.String("host", "web-1"))
+				return true
+			},
+			AfterWrite: func(ctx context.Context, r slog.Record, n int, err error) {
+				gotMsg, gotN, gotErr = r.Message, n, err
+			},
+		}))
+		logger.Info("request", "status", 200)
+
+		if !strings.Contains(buf.String(), `status=200 host="web-1"`) {
+			t.Errorf("BeforeHandle should enrich the record, got: %s", buf.String())
+		}
+		if gotMsg != "request" || gotN != buf.Len() || gotErr != nil {
+			t.Errorf("unexpected AfterWrite arguments: msg=%q n=%d (want %d) err=%v", gotMsg, gotN, buf.Len(), gotErr)
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		var buf bytes.Buffer
+		called := false
+		logger := slog.New(NewHandler(&buf, &Options{
+			BeforeHandle: func(ctx context.Context, r *slog.Record) bool {
+				return r.Message != "noisy"
+			},
+			AfterWrite: func(ctx context.Context, r slog.Record, n int, err error) {
+				called = true
+			},
+		}))
+		logger.Info("noisy")
+
+		if buf.Len() != 0 || called {
+			t.Errorf("record should be dropped, got: %q (AfterWrite called: %v)", buf.String(), called)
+		}
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		var gotErr error
+		h := NewHandler(errorWriter{}, &Options{
+			AfterWrite: func(ctx context.Context, r slog.Record, n int, err error) {
+				gotErr = err
+			},
+		})
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "test", 0)
+		if err := h.Handle(t.Context(), r); err == nil || gotErr != err {
+			t.Errorf("AfterWrite should receive the write error, got %v (Handle returned %v)", gotErr, err)
+		}
+	})
+
+	t.Run("caller record is not modified", func(t *testing.T) {
+		h := NewHandler(&bytes.Buffer{}, &Options{
+			BeforeHandle: func(ctx context.Context, r *slog.Record) bool {
+				r.AddAttrs(slog.Int("extra", 1))
+				return true
+			},
+		})
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "test", 0)
+		r.AddAttrs(slog.Int("a", 1))
+		h.Handle(t.Context(), r)
+		if r.NumAttrs() != 1 {
+			t.Errorf("caller record should keep 1 attr, got %d", r.NumAttrs())
+		}
+	})
+}
+
+// TestOnRecord はレベルの閾値以上のレコードでコールバックが呼び出されることをテストします
+func TestOnRecord(t *testing.T) {
+	var errors, warnings []string
+	threshold := new(slog.LevelVar)
+	threshold.Set(slog.LevelWarn)
+
+	logger := slog.New(NewHandler(&bytes.Buffer{}, &Options{
+		Level: slog.LevelDebug,
+		OnRecord: []RecordCallback{
+			{Level: slog.LevelError, Func: func(ctx context.Context, r slog.Record) {
+				errors = append(errors, r.Message)
+			}},
+			{Level: threshold, Func: func(ctx context.Context, r slog.Record) {
+				warnings = append(warnings, r.Message)
+			}},
+			{Level: slog.LevelDebug}, // Func が nil のコールバックは無視される
+		},
+	}))
+
+	logger.Debug("debug")
+	logger.Warn("warn")
+	logger.Error("error")
+	threshold.Set(slog.LevelError)
+	logger.Warn("warn after change")
+
+	if !slices.Equal(errors, []string{"error"}) {
+		t.Errorf("unexpected error callbacks: %v", errors)
+	}
+	if !slices.Equal(warnings, []string{"warn", "error"}) {
+		t.Errorf("unexpected warning callbacks: %v", warnings)
+	}
+}
+
+// TestBaggage は BaggageKeys の値がコンテキストから属性として出力されることをテストします
+func TestBaggage(t *testing.T) {
+	type baggageKey struct{}
+	lookup := func(ctx context.Context, key string) (string, bool) {
+		m, _ := ctx.Value(baggageKey{}).(map[string]string)
+		v, ok := m[key]
+		return v, ok
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{BaggageKeys: []string{"tenant", "experiment"}, BaggageLookup: lookup}))
+
+	ctx := context.WithValue(t.Context(), baggageKey{}, map[string]string{"tenant": "acme", "other": "ignored"})
+	logger.InfoContext(ctx, "with baggage")
+	logger.Info("without baggage")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if !strings.HasSuffix(lines[0], `msg="with baggage" tenant="acme"`) {
+		t.Errorf("expected only selected baggage keys, got %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], `msg="without baggage"`) {
+		t.Errorf("expected no baggage attributes, got %s", lines[1])
+	}
+}
+
+// TestMaxLineBytes は長い行が UTF-8 の文字の境界で切り詰められることをテストします
+func TestMaxLineBytes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{
+		MaxLineBytes: 60,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	logger.Info("short")
+	logger.Info("long", "text", strings.Repeat("あ", 40))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if lines[0] != `[ INFO] msg="short"` {
+		t.Errorf("short line should not be truncated: %q", lines[0])
+	}
+	if len(lines[1])+1 > 60 || !strings.HasSuffix(lines[1], "...[TRUNCATED]") {
+		t.Errorf("expected truncated line within 60 bytes, got %d bytes: %q", len(lines[1])+1, lines[1])
+	}
+	if !utf8.ValidString(lines[1]) {
+		t.Errorf("truncated line should be valid UTF-8: %q", lines[1])
+	}
+}
+
+// TestFoldMultiline は改行を含む値が字下げした継続行として出力されることをテストします
+func TestFoldMultiline(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{
+		FoldMultiline: true,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})).With("svc", "api")
+
+	logger.Error("query failed", "sql", "SELECT *\r\nFROM t\n\tWHERE x\x00", "n", 1, slog.Group("err", "stack", "main.go:1\nlib.go:2\n"))
+	logger.Info("plain", "text", "one line")
+
+	want := "[ERROR] msg=\"query failed\" svc=\"api\" sql=| n=1 err.stack=|\n" +
+		"  sql| SELECT *\n" +
+		"  sql| FROM t\n" +
+		"  sql| \tWHERE x\\x00\n" +
+		"  err.stack| main.go:1\n" +
+		"  err.stack| lib.go:2\n" +
+		"[ INFO] msg=\"plain\" svc=\"api\" text=\"one line\"\n"
+	if buf.String() != want {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+// TestGroupSeparator はグループの区切り文字の変更と、区切りを含むキーのクォートをテストします
+func TestGroupSeparator(t *testing.T) {
+	noTime := func(_ []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		return a
+	}
+	tests := []struct {
+		name string
+		opts *Options
+		want string
+	}{
+		{"default", &Options{ReplaceAttr: noTime}, `req.id=1 req.db.rows=2 req.a::b=3 req.x.y=4`},
+		{"custom", &Options{GroupSeparator: "::", ReplaceAttr: noTime}, `req::id=1 req::db::rows=2 req::a::b=3 req::x.y=4`},
+		{"quoted", &Options{GroupSeparator: "::", QuoteSeparator: true, ReplaceAttr: noTime}, `req::id=1 req::db::rows=2 req::"a::b"=3 req::x.y=4`},
+		{"quoted default", &Options{QuoteSeparator: true, ReplaceAttr: noTime}, `req.id=1 req.db.rows=2 req.a::b=3 req."x.y"=4`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, tt.opts)).WithGroup("req").With("id", 1)
+			logger.Info("m", slog.Group("db", "rows", 2), "a::b", 3, "x.y", 4)
+			want := `[ INFO] msg="m" ` + tt.want + "\n"
+			if buf.String() != want {
+				t.Errorf("got  %q\nwant %q", buf.String(), want)
+			}
+		})
+	}
+}
+
+// TestNoLock は NoLock で排他制御を省略しても出力と出力先の置き換えが動作することをテストします
+func TestNoLock(t *testing.T) {
+	var first, second bytes.Buffer
+	h := NewHandler(&first, &Options{NoLock: true, WriteMode: WriteModeAsync})
+	if _, ok := h.out.(*directOutput); !ok {
+		t.Fatalf("expected directOutput, got %T", h.out)
+	}
+	logger := slog.New(h)
+
+	logger.Info("first", "i", 1)
+	if !strings.Contains(first.String(), `msg="first" i=1`) {
+		t.Errorf("expected record to be written without Flush, got %q", first.String())
+	}
+	h.SetOutput(&second)
+	logger.Info("second")
+	if strings.Contains(first.String(), "second") || !strings.Contains(second.String(), `msg="second"`) {
+		t.Error("records after SetOutput should go to the new writer")
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestBadKey は値に対応するキーの無い引数と文字列でないキーが slog と同じく !BADKEY として出力されることをテストします
+func TestBadKey(t *testing.T) {
+	// go vet の printf 風の検査を避けるため、引数はスライスで渡す
+	odd := []any{"a", 1, "b"}
+	nonString := []any{42, "x", "y"}
+	tests := []struct {
+		name string
+		opts *Options
+		want []string
+	}{
+		{"text", nil, []string{
+			`msg="m" a=1 !BADKEY="b"`,
+			`msg="m" !BADKEY=42 x="y"`,
+			`msg="m" !BADKEY="z" !BADKEY="q"`,
+		}},
+		{"json", &Options{Format: FormatJSON}, []string{
+			`"msg":"m","a":1,"!BADKEY":"b"}`,
+			`"msg":"m","!BADKEY":42,"x":"y"}`,
+			`"msg":"m","!BADKEY":"z","!BADKEY":"q"}`,
+		}},
+		{"last wins", &Options{DuplicateKeys: DuplicateKeysLastWins}, []string{
+			`msg="m" a=1 !BADKEY="b"`,
+			`msg="m" !BADKEY=42 x="y"`,
+			`msg="m" !BADKEY="z" !BADKEY="q"`,
+		}},
+		{"first wins", &Options{DuplicateKeys: DuplicateKeysFirstWins}, []string{
+			`msg="m" a=1 !BADKEY="b"`,
+			`msg="m" !BADKEY=42 x="y"`,
+			`msg="m" !BADKEY="z" !BADKEY="q"`,
+		}},
+		{"drop", &Options{DropBadKeys: true}, []string{
+			`msg="m" a=1`,
+			`msg="m" x="y"`,
+			`msg="m"`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, tt.opts))
+			logger.Info("m", odd...)
+			logger.Info("m", nonString...)
+			logger.With([]any{"z"}...).Info("m", []any{"q"}...)
+
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("expected %d lines, got %d:\n%s", len(tt.want), len(lines), buf.String())
+			}
+			for i, line := range lines {
+				if !strings.HasSuffix(line, tt.want[i]) {
+					t.Errorf("line %d: want suffix %q, got %q", i, tt.want[i], line)
+				}
+			}
+		})
+	}
+}
+
+// TestLineEnding は LineEnding で行の終わりが置き換えられることをテストします
+func TestLineEnding(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *Options
+		want string
+	}{
+		{"default", &Options{}, "msg=\"a\" v=\"x\\ny\"\n"},
+		{"crlf", &Options{LineEnding: "\r\n"}, "msg=\"a\" v=\"x\\ny\"\r\n"},
+		{"json", &Options{LineEnding: "\r\n", Format: FormatJSON}, `"msg":"a","v":"x\ny"}` + "\r\n"},
+		{"fold", &Options{LineEnding: "\r\n", FoldMultiline: true}, "msg=\"a\" v=|\r\n  v| x\r\n  v| y\r\n"},
+		{"truncate", &Options{LineEnding: "\r\n", MaxLineBytes: 30}, "[ I...[TRUNCATED]\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.opts.TimeFormat = "15:04:05"
+			slog.New(NewHandler(&buf, tt.opts)).Info("a", "v", "x\ny")
+			if !strings.HasSuffix(buf.String(), tt.want) {
+				t.Errorf("want suffix %q, got %q", tt.want, buf.String())
+			}
+			if tt.opts.MaxLineBytes > 0 && buf.Len() != tt.opts.MaxLineBytes {
+				t.Errorf("expected line of %d bytes including CRLF, got %d", tt.opts.MaxLineBytes, buf.Len())
+			}
+		})
+	}
 }
 
 // TestKeyEscaping はキーのエスケープ処理をテストします
@@ -1593,6 +2409,48 @@ func TestProductionScenarios(t *testing.T) {
 	})
 }
 
+// TestHandleZeroAllocs は基本的な Kind の属性でアロケーションが発生しないことをテストします
+func TestHandleZeroAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("race detector affects allocation counts")
+	}
+
+	tests := []struct {
+		name string
+		opts *Options
+		args []any
+	}{
+		{"no attrs", nil, nil},
+		{"string", nil, []any{"key", "value"}},
+		{"int", nil, []any{"key", 42}},
+		{"uint", nil, []any{"key", uint64(42)}},
+		{"float", nil, []any{"key", 3.14}},
+		{"bool", nil, []any{"key", true}},
+		{"duration", nil, []any{"key", time.Second}},
+		{"time", nil, []any{"key", time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC)}},
+		{"colors", &Options{UseColors: true}, []any{"key", "value"}},
+		{"grouped", nil, []any{"key", "value"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h slog.Handler = NewHandler(discardWriter{}, tt.opts)
+			if tt.name == "grouped" {
+				h = h.WithGroup("g").WithAttrs([]slog.Attr{slog.Int("pre", 1)})
+			}
+			logger := slog.New(h)
+			ctx := context.Background()
+
+			allocs := testing.AllocsPerRun(100, func() {
+				logger.Log(ctx, slog.LevelInfo, "message", tt.args...)
+			})
+			if allocs != 0 {
+				t.Errorf("expected 0 allocs, got %v", allocs)
+			}
+		})
+	}
+}
+
 // BenchmarkHandle はログ出力のベンチマークです
 func BenchmarkHandle(b *testing.B) {
 	var buf bytes.Buffer
@@ -1609,6 +2467,17 @@ func BenchmarkHandle(b *testing.B) {
 	}
 }
 
+// BenchmarkHandleGrouped はグループ内でのログ出力のベンチマークです
+func BenchmarkHandleGrouped(b *testing.B) {
+	handler := NewHandler(discardWriter{}, &Options{Level: slog.LevelInfo})
+	logger := slog.New(handler).WithGroup("service").WithGroup("http handler").WithGroup("request")
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		logger.Info("benchmark test", "iteration", i, "method", "GET", "path", "/api/users", "status", 200)
+	}
+}
+
 // BenchmarkHandleConcurrent は並行ログ出力のベンチマークです
 func BenchmarkHandleConcurrent(b *testing.B) {
 	var buf bytes.Buffer
@@ -1728,3 +2597,29 @@ func BenchmarkTimeFormatting(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkHandleAttrKinds は属性の Kind ごとのアロケーションを測定します
+func BenchmarkHandleAttrKinds(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		attr slog.Attr
+	}{
+		{"String", slog.String("key", "value")},
+		{"Int", slog.Int("key", 42)},
+		{"Float", slog.Float64("key", 3.14)},
+		{"Bool", slog.Bool("key", true)},
+		{"Duration", slog.Duration("key", time.Second)},
+		{"Time", slog.Time("key", time.Now())},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			logger := slog.New(NewHandler(discardWriter{}, nil))
+			ctx := context.Background()
+			b.ReportAllocs()
+			for b.Loop() {
+				logger.LogAttrs(ctx, slog.LevelInfo, "benchmark", bm.attr)
+			}
+		})
+	}
+}
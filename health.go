@@ -0,0 +1,105 @@
+package loggo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// HealthChecker は配送の状態を確認できる出力先。
+// FileWriter、FluentWriter、WebhookWriter、NATSWriter、NetWriter、Handler が実装しています。
+type HealthChecker interface {
+	// Ping は出力先へ配送できる状態かを能動的に確認します
+	Ping(ctx context.Context) error
+	// Healthy は直前の配送が成功したかを返します。I/O を伴わないため頻繁に呼び出せます。
+	Healthy() bool
+}
+
+// SinkHealth は1つの出力先の状態
+type SinkHealth struct {
+	Name    string
+	Healthy bool
+	Err     error // Ping が返したエラー
+}
+
+// HealthReport は CheckHealth の結果
+type HealthReport struct {
+	Healthy bool         // すべての出力先が正常な場合は true
+	Sinks   []SinkHealth // 名前の順
+}
+
+// Err は正常でない出力先のエラーをまとめて返します。すべて正常な場合は nil を返します。
+func (r HealthReport) Err() error {
+	var errs []error
+	for _, s := range r.Sinks {
+		switch {
+		case s.Err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name, s.Err))
+		case !s.Healthy:
+			errs = append(errs, fmt.Errorf("%s: last delivery failed", s.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckHealth はすべての出力先の Ping を並行して呼び出し、状態をまとめて返します。
+// Kubernetes の readiness probe などで、ログの配送が止まっていることをデータが失われる前に検出できます。
+func CheckHealth(ctx context.Context, sinks map[string]HealthChecker) HealthReport {
+	report := HealthReport{Healthy: true, Sinks: make([]SinkHealth, 0, len(sinks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, sink := range sinks {
+		wg.Go(func() {
+			err := sink.Ping(ctx)
+			s := SinkHealth{Name: name, Healthy: err == nil && sink.Healthy(), Err: err}
+			mu.Lock()
+			defer mu.Unlock()
+			report.Sinks = append(report.Sinks, s)
+			report.Healthy = report.Healthy && s.Healthy
+		})
+	}
+	wg.Wait()
+
+	sort.Slice(report.Sinks, func(i, j int) bool { return report.Sinks[i].Name < report.Sinks[j].Name })
+	return report
+}
+
+// Ping は出力先（Routes の出力先を含む）のうち HealthChecker を実装しているものの Ping を呼び出し、
+// エラーをまとめて返します。実装している出力先が無い場合は nil を返します。
+func (h *Handler) Ping(ctx context.Context) error {
+	var errs []error
+	for _, w := range h.healthWriters() {
+		if hc, ok := w.(HealthChecker); ok {
+			errs = append(errs, hc.Ping(ctx))
+		}
+	}
+	if len(errs) == 0 {
+		return ctx.Err()
+	}
+	return errors.Join(errs...)
+}
+
+// Healthy は出力先（Routes の出力先を含む）のうち HealthChecker を実装しているものがすべて正常な場合に true を返します。
+// 実装している出力先が無い場合は true を返します。
+func (h *Handler) Healthy() bool {
+	for _, w := range h.healthWriters() {
+		if hc, ok := w.(HealthChecker); ok && !hc.Healthy() {
+			return false
+		}
+	}
+	return true
+}
+
+// healthWriters は w と Routes の出力先を返します
+func (h *Handler) healthWriters() []io.Writer {
+	ws := make([]io.Writer, 0, 1+len(h.routes))
+	ws = append(ws, h.term.writer())
+	for _, r := range h.routes {
+		ws = append(ws, r.w)
+	}
+	return ws
+}
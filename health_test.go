@@ -0,0 +1,50 @@
+package loggo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCheckHealth は出力先の状態がまとめて報告されることをテストします
+func TestCheckHealth(t *testing.T) {
+	dir := t.TempDir()
+	healthy, err := OpenFile(filepath.Join(dir, "healthy.log"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer healthy.Close()
+	closed, err := OpenFile(filepath.Join(dir, "closed.log"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	report := CheckHealth(t.Context(), map[string]HealthChecker{
+		"file":    healthy,
+		"handler": NewHandler(healthy, nil),
+		"closed":  closed,
+		"stdout":  NewHandler(os.Stdout, nil),
+		// Routes の出力先も確認する
+		"routed": NewHandler(healthy, &Options{Routes: []Route{{Writer: closed}}}),
+	})
+
+	if report.Healthy {
+		t.Error("report should be unhealthy when a sink is closed")
+	}
+	var names []string
+	for _, s := range report.Sinks {
+		names = append(names, s.Name)
+		if s.Healthy != (s.Name != "closed" && s.Name != "routed") {
+			t.Errorf("%s: unexpected health %v (err %v)", s.Name, s.Healthy, s.Err)
+		}
+	}
+	if strings.Join(names, ",") != "closed,file,handler,routed,stdout" {
+		t.Errorf("sinks should be sorted by name, got %v", names)
+	}
+	if err := report.Err(); !errors.Is(err, ErrClosed) || !strings.Contains(err.Error(), "closed: ") || !strings.Contains(err.Error(), "routed: ") {
+		t.Errorf("expected ErrClosed for the closed sink, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package loggo
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// herokuTimeFormat は Logplex が付ける時刻の書式（マイクロ秒まで、UTC のオフセット付き）
+const herokuTimeFormat = "2006-01-02T15:04:05.000000-07:00"
+
+// HerokuOptions は Heroku の Logplex 形式のヘッダーの設定
+type HerokuOptions struct {
+	// Source はログの発生元。空の場合はアプリケーションのログを表す "app" です。
+	Source string
+	// Dyno は dyno の名前（"web.1" など）。空の場合は環境変数 DYNO の値で、それも空の場合は "web.1" です。
+	Dyno string
+}
+
+// herokuHeader は Logplex 形式のヘッダーのうち時刻以外の部分。NewHandler で計算します。
+type herokuHeader struct {
+	suffix string // " SOURCE[DYNO]: "
+}
+
+// newHerokuHeader は opts からヘッダーの値を計算します
+func newHerokuHeader(opts HerokuOptions) *herokuHeader {
+	source := opts.Source
+	if source == "" {
+		source = "app"
+	}
+	dyno := opts.Dyno
+	if dyno == "" {
+		dyno = os.Getenv("DYNO")
+		if dyno == "" {
+			dyno = "web.1"
+		}
+	}
+	return &herokuHeader{suffix: " " + source + "[" + dyno + "]: "}
+}
+
+// append は "2006-01-02T15:04:05.000000+00:00 SOURCE[DYNO]: " を書き込みます
+func (h *herokuHeader) append(buf *buffer.Buffer, r slog.Record) {
+	*buf = r.Time.UTC().AppendFormat(*buf, herokuTimeFormat)
+	buf.WriteString(h.suffix)
+}
+
+// herokuLevel は "at=" に続けて出力する小文字のレベルの名前を返します
+func herokuLevel(level slog.Level) string {
+	switch level {
+	case slog.LevelDebug:
+		return "debug"
+	case slog.LevelInfo:
+		return "info"
+	case slog.LevelWarn:
+		return "warn"
+	case slog.LevelError:
+		return "error"
+	default:
+		return strings.ToLower(LevelName(level))
+	}
+}
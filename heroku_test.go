@@ -0,0 +1,47 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHerokuFormat は Logplex 形式のヘッダーと at= のレベルで行を出力することをテストします
+func TestHerokuFormat(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(&buf, &Options{
+		Heroku: &HerokuOptions{Dyno: "worker.2"},
+	})
+	ts := time.Date(2024, time.March, 5, 9, 4, 7, 123456000, time.FixedZone("JST", 9*60*60))
+	r := slog.NewRecord(ts, slog.LevelWarn, "queue backed up", 0)
+	r.AddAttrs(slog.Int("depth", 120))
+	if err := h.Handle(t.Context(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "2024-03-05T00:04:07.123456+00:00 app[worker.2]: at=warn msg=\"queue backed up\" depth=120\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
+
+// TestHerokuFormatDefaults は発生元と dyno のデフォルト値と、Syslog より優先されることをテストします
+func TestHerokuFormatDefaults(t *testing.T) {
+	t.Setenv("DYNO", "web.3")
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{
+		Heroku: &HerokuOptions{},
+		Syslog: &SyslogOptions{},
+	}))
+	logger.Log(t.Context(), slog.LevelError+2, "boom")
+
+	line := buf.String()
+	if strings.HasPrefix(line, "<") {
+		t.Errorf("syslog header should not be written: %q", line)
+	}
+	if _, body, ok := strings.Cut(line, " app[web.3]: "); !ok || body != "at=error+2 msg=\"boom\"\n" {
+		t.Errorf("unexpected line: %q", line)
+	}
+}
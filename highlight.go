@@ -0,0 +1,258 @@
+package loggo
+
+import (
+	"cmp"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// Color は ANSI のエスケープシーケンスで表した文字の色
+type Color string
+
+// HighlightRule などで使用できる色
+const (
+	ColorRed     Color = colorRed
+	ColorGreen   Color = colorGreen
+	ColorYellow  Color = colorYellow
+	ColorBlue    Color = "\033[34m"
+	ColorMagenta Color = "\033[35m"
+	ColorCyan    Color = colorCyan
+	ColorWhite   Color = colorWhite
+)
+
+// 強調に使うエスケープシーケンス
+const (
+	colorBold = "\033[1m"
+	colorGray = "\033[90m" // 明るい黒
+)
+
+// HighlightRule はメッセージと属性の値のうち、一致した部分を強調する規則。
+// Options.HighlightRules に指定し、UseColors が有効な場合にだけ適用されます。
+//
+//	golog.HighlightRule{Match: regexp.MustCompile(`timeout|refused`), Color: golog.ColorRed, Bold: true}
+type HighlightRule struct {
+	// Match は強調する部分に一致する正規表現
+	Match *regexp.Regexp
+	// Substring は強調する部分文字列。Match が nil の場合に使用されます。
+	Substring string
+	// Color は一致した部分の色。空の場合は色を変えません。
+	Color Color
+	// Bold は一致した部分を太字にします
+	Bold bool
+	// Keys はメッセージに加えて規則を適用する文字列の属性のキー。
+	// グループ内の属性はグループ名を含まないキーで比較します。
+	Keys []string
+}
+
+// highlightRule は HighlightRule のエスケープシーケンスを連結済みのもの
+type highlightRule struct {
+	match     *regexp.Regexp
+	substring string
+	style     string
+}
+
+// highlighter は HighlightRule を適用先ごとにまとめたもの
+type highlighter struct {
+	message []highlightRule
+	byKey   map[string][]highlightRule
+}
+
+// newHighlighter は rules から highlighter を作成します。有効な規則が無い場合は nil を返します。
+func newHighlighter(rules []HighlightRule) *highlighter {
+	var hl highlighter
+	for _, rule := range rules {
+		if rule.Match == nil && rule.Substring == "" {
+			continue
+		}
+		style := string(rule.Color)
+		if rule.Bold {
+			style += colorBold
+		}
+		r := highlightRule{match: rule.Match, substring: rule.Substring, style: style}
+		hl.message = append(hl.message, r)
+		for _, key := range rule.Keys {
+			if hl.byKey == nil {
+				hl.byKey = make(map[string][]highlightRule)
+			}
+			hl.byKey[key] = append(hl.byKey[key], r)
+		}
+	}
+	if len(hl.message) == 0 {
+		return nil
+	}
+	return &hl
+}
+
+// highlightSpan は強調する範囲
+type highlightSpan struct {
+	start, end int
+	rule       int
+}
+
+// appendHighlighted は s をクォートして書き込み、rules に一致した部分にエスケープシーケンスを付けます。
+// 一致した範囲が重なる場合は、先に始まるもの、同じ位置では先に指定された規則を優先します。
+// base は値全体に適用されているスタイルで、一致した部分の後に付け直します。
+func (f *valueFormatter) appendHighlighted(buf *buffer.Buffer, s string, rules []highlightRule, base string) {
+	var stack [8]highlightSpan
+	spans := stack[:0]
+	for i, rule := range rules {
+		if rule.match != nil {
+			for _, m := range rule.match.FindAllStringIndex(s, -1) {
+				if m[0] < m[1] {
+					spans = append(spans, highlightSpan{m[0], m[1], i})
+				}
+			}
+			continue
+		}
+		for off := 0; ; {
+			j := strings.Index(s[off:], rule.substring)
+			if j < 0 {
+				break
+			}
+			start := off + j
+			spans = append(spans, highlightSpan{start, start + len(rule.substring), i})
+			off = start + len(rule.substring)
+		}
+	}
+	if len(spans) == 0 {
+		f.appendString(buf, s)
+		return
+	}
+	slices.SortFunc(spans, func(a, b highlightSpan) int {
+		return cmp.Or(cmp.Compare(a.start, b.start), cmp.Compare(a.rule, b.rule))
+	})
+
+	buf.WriteByte('"')
+	pos := 0
+	for _, span := range spans {
+		if span.start < pos {
+			continue
+		}
+		f.appendQuotedInner(buf, s[pos:span.start])
+		buf.WriteString(rules[span.rule].style)
+		f.appendQuotedInner(buf, s[span.start:span.end])
+		buf.WriteString(colorReset)
+		buf.WriteString(base)
+		pos = span.end
+	}
+	f.appendQuotedInner(buf, s[pos:])
+	buf.WriteByte('"')
+}
+
+// appendQuotedInner は s をクォートした結果を、前後の '"' を除いて書き込みます
+func (f *valueFormatter) appendQuotedInner(buf *buffer.Buffer, s string) {
+	if s == "" {
+		return
+	}
+	start := buf.Len()
+	f.appendString(buf, s)
+	end := buf.Len() - 1
+	copy((*buf)[start:], (*buf)[start+1:end])
+	buf.SetLen(end - 1)
+}
+
+// JSON のシンタックスハイライトに使う控えめな色
+const (
+	colorJSONPunct  = colorGray
+	colorJSONKey    = colorCyan
+	colorJSONString = colorGreen
+	colorJSONNumber = "\033[35m" // マゼンタ。true, false, null にも使う
+)
+
+// highlightJSON は buf の start 以降に書き込まれた JSON の値に色を付けます。
+// 値が '{' または '[' で始まらない場合は何もしません。
+// JSON として不正な部分は色を付けずにそのまま残します。
+func highlightJSON(buf *buffer.Buffer, start int) {
+	if start >= buf.Len() || ((*buf)[start] != '{' && (*buf)[start] != '[') {
+		return
+	}
+	src := buffer.New()
+	defer src.Free()
+	src.Write((*buf)[start:])
+	buf.SetLen(start)
+
+	s := *src
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"':
+			end := jsonStringEnd(s, i)
+			color := colorJSONString
+			if isJSONKey(s, end) {
+				color = colorJSONKey
+			}
+			buf.WriteString(color)
+			buf.Write(s[i:end])
+			buf.WriteString(colorReset)
+			i = end
+		case c == '{' || c == '}' || c == '[' || c == ']' || c == ',' || c == ':':
+			buf.WriteString(colorJSONPunct)
+			buf.WriteByte(c)
+			buf.WriteString(colorReset)
+			i++
+		case c == '-' || c == 't' || c == 'f' || c == 'n' || ('0' <= c && c <= '9'):
+			end := i + 1
+			for end < len(s) && isJSONLiteralByte(s[end]) {
+				end++
+			}
+			buf.WriteString(colorJSONNumber)
+			buf.Write(s[i:end])
+			buf.WriteString(colorReset)
+			i = end
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+}
+
+// jsonStringEnd は s[i] の '"' で始まる文字列の終わりの次の位置を返します
+func jsonStringEnd(s []byte, i int) int {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// isJSONKey は s[i] 以降で空白を除いた次の文字が ':' かどうかを判定します
+func isJSONKey(s []byte, i int) bool {
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case ':':
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// isJSONLiteralByte は数値、true, false, null を構成する文字かどうかを判定します
+func isJSONLiteralByte(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || c == '.' || c == '+' || c == '-' || c == 'E'
+}
+
+// messageStyle は EmphasizeMessage でメッセージに付けるレベルごとのスタイルを返します。
+// INFO はそのまま、DEBUG 以下は灰色、WARN は黄色、ERROR 以上は赤の太字にします。
+func messageStyle(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return colorRed + colorBold
+	case level >= slog.LevelWarn:
+		return colorYellow
+	case level >= slog.LevelInfo:
+		return ""
+	default:
+		return colorGray
+	}
+}
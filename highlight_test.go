@@ -0,0 +1,141 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// ansiPattern は ANSI のエスケープシーケンスに一致します
+var ansiPattern = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// TestHighlightJSON は JSON の各要素に色が付くことをテストします
+func TestHighlightJSON(t *testing.T) {
+	buf := buffer.New()
+	defer buf.Free()
+	buf.WriteString("v=")
+	highlightJSON(buf, buf.Len())
+	if buf.String() != "v=" {
+		t.Errorf("empty value should not change: %q", buf.String())
+	}
+
+	buf.WriteString(`{"a\"b":[1.5e3,-2,true,null],"s":"x,y"}`)
+	highlightJSON(buf, 2)
+
+	want := "v=" +
+		colorJSONPunct + "{" + colorReset +
+		colorJSONKey + `"a\"b"` + colorReset +
+		colorJSONPunct + ":" + colorReset +
+		colorJSONPunct + "[" + colorReset +
+		colorJSONNumber + "1.5e3" + colorReset +
+		colorJSONPunct + "," + colorReset +
+		colorJSONNumber + "-2" + colorReset +
+		colorJSONPunct + "," + colorReset +
+		colorJSONNumber + "true" + colorReset +
+		colorJSONPunct + "," + colorReset +
+		colorJSONNumber + "null" + colorReset +
+		colorJSONPunct + "]" + colorReset +
+		colorJSONPunct + "," + colorReset +
+		colorJSONKey + `"s"` + colorReset +
+		colorJSONPunct + ":" + colorReset +
+		colorJSONString + `"x,y"` + colorReset +
+		colorJSONPunct + "}" + colorReset
+	if buf.String() != want {
+		t.Errorf("unexpected highlight:\ngot:  %q\nwant: %q", buf.String(), want)
+	}
+}
+
+// TestHighlightValues は HighlightValues が UseColors と組み合わせた場合だけ有効になることをテストします
+func TestHighlightValues(t *testing.T) {
+	payload := map[string]any{"id": 1, "tags": []string{"a"}}
+
+	var colored bytes.Buffer
+	slog.New(NewHandler(&colored, &Options{UseColors: true, HighlightValues: true})).Info("req", "body", payload, "name", "alice")
+	out := colored.String()
+	if !strings.Contains(out, colorJSONKey+`"id"`+colorReset) {
+		t.Errorf("expected highlighted key in output: %q", out)
+	}
+	if !strings.Contains(out, `name="alice"`) {
+		t.Errorf("plain string values should not be highlighted: %q", out)
+	}
+	if plain := ansiPattern.ReplaceAllString(out, ""); !strings.Contains(plain, `body={"id":1,"tags":["a"]}`) {
+		t.Errorf("removing colors should give the original JSON: %q", plain)
+	}
+
+	var noColor bytes.Buffer
+	slog.New(NewHandler(&noColor, &Options{HighlightValues: true})).Info("req", "body", payload)
+	if strings.Contains(noColor.String(), "\033[") {
+		t.Errorf("HighlightValues without UseColors should not emit colors: %q", noColor.String())
+	}
+}
+
+// TestHighlightRules はメッセージと指定したキーの属性の一致した部分が強調されることをテストします
+func TestHighlightRules(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{
+		UseColors: true,
+		HighlightRules: []HighlightRule{
+			{Match: regexp.MustCompile(`timeout|refused`), Color: ColorRed, Bold: true, Keys: []string{"err"}},
+			{Substring: "db", Color: ColorYellow},
+			{Substring: "out", Color: ColorBlue}, // "timeout" と重なるため適用されない
+		},
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	logger.Info("db \"timeout\"", "err", "connection refused", "other", "timeout")
+
+	red := string(ColorRed) + colorBold
+	want := `msg="` + string(ColorYellow) + "db" + colorReset + ` \"` + red + "timeout" + colorReset + `\""` +
+		` err="connection ` + red + "refused" + colorReset + `"` +
+		` other="timeout"`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("unexpected output:\ngot:  %q\nwant: %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	slog.New(NewHandler(&buf, &Options{
+		HighlightRules: []HighlightRule{{Substring: "timeout", Color: ColorRed}},
+	})).Info("timeout")
+	if strings.Contains(buf.String(), string(ColorRed)) {
+		t.Errorf("rules should not apply without UseColors: %q", buf.String())
+	}
+}
+
+// TestEmphasizeMessage はメッセージがレベルに応じて強調されることをテストします
+func TestEmphasizeMessage(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{
+		Level:            slog.LevelDebug,
+		UseColors:        true,
+		EmphasizeMessage: true,
+		HighlightRules:   []HighlightRule{{Substring: "disk", Color: ColorCyan}},
+	}))
+
+	logger.Debug("d")
+	logger.Info("i")
+	logger.Warn("w")
+	logger.Error("disk full")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	wants := []string{
+		`msg=` + colorGray + `"d"` + colorReset,
+		`msg="i"`,
+		`msg=` + colorYellow + `"w"` + colorReset,
+		// 強調ルールの後にメッセージのスタイルが付け直される
+		`msg=` + colorRed + colorBold + `"` + colorCyan + "disk" + colorReset + colorRed + colorBold + ` full"` + colorReset,
+	}
+	for i, want := range wants {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("line %d: want suffix %q, got %q", i, want, lines[i])
+		}
+	}
+}
@@ -5,7 +5,10 @@
 // Package buffer provides a pool-allocated byte buffer.
 package buffer
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+)
 
 // Buffer is a byte buffer.
 //
@@ -13,27 +16,134 @@ import "sync"
 // in go/src/log/slog/internal/buffer/buffer.go.
 type Buffer []byte
 
+// Default sizes used when SetSizes has not been called.
+const (
+	DefaultInitialSize     = 1024     // 1KB
+	DefaultMaxRetainedSize = 16 << 10 // 16KB
+)
+
+var (
+	initialSize     atomic.Int64
+	maxRetainedSize atomic.Int64
+
+	// The counters below are updated only while statsEnabled is true.
+	statsEnabled atomic.Bool
+	gets         atomic.Uint64
+	frees        atomic.Uint64
+	misses       atomic.Uint64
+	discarded    atomic.Uint64
+	peakSize     atomic.Int64
+)
+
+// Stats holds counters describing pool usage while stats collection was enabled.
+type Stats struct {
+	Gets      uint64 // calls to New
+	Frees     uint64 // calls to Free, including discarded buffers
+	Misses    uint64 // calls to New that allocated a new buffer
+	Discarded uint64 // buffers dropped by Free because they exceeded the maximum retained size
+	PeakSize  int    // largest buffer capacity passed to Free
+}
+
+// ReadStats returns the current pool counters.
+func ReadStats() Stats {
+	return Stats{
+		Gets:      gets.Load(),
+		Frees:     frees.Load(),
+		Misses:    misses.Load(),
+		Discarded: discarded.Load(),
+		PeakSize:  int(peakSize.Load()),
+	}
+}
+
+// EnableStats turns collection of the pool counters on or off and returns the
+// previous setting. Collection is off by default so that New and Free do not
+// touch shared counters on the hot path.
+func EnableStats(on bool) bool {
+	return statsEnabled.Swap(on)
+}
+
+// StatsEnabled reports whether the pool counters are being collected.
+func StatsEnabled() bool {
+	return statsEnabled.Load()
+}
+
+func init() {
+	initialSize.Store(DefaultInitialSize)
+	maxRetainedSize.Store(DefaultMaxRetainedSize)
+}
+
+// SetSizes sets the capacity of newly allocated buffers and the largest
+// capacity that Free returns to the pool. Non-positive values restore the
+// defaults. Buffers already in the pool are not affected.
+func SetSizes(initial, maxRetained int) {
+	if initial <= 0 {
+		initial = DefaultInitialSize
+	}
+	if maxRetained <= 0 {
+		maxRetained = DefaultMaxRetainedSize
+	}
+	initialSize.Store(int64(initial))
+	maxRetainedSize.Store(int64(maxRetained))
+}
+
+// Sizes returns the current initial and maximum retained buffer capacities.
+func Sizes() (initial, maxRetained int) {
+	return int(initialSize.Load()), int(maxRetainedSize.Load())
+}
+
 // Having an initial size gives a dramatic speedup.
 var bufPool = sync.Pool{
 	New: func() any {
-		b := make([]byte, 0, 1024)
+		if statsEnabled.Load() {
+			misses.Add(1)
+		}
+		b := make([]byte, 0, initialSize.Load())
 		return (*Buffer)(&b)
 	},
 }
 
+// Preallocate allocates n buffers with the current initial size and puts
+// them into the pool, so that the first calls to New do not allocate.
+// Like everything in a sync.Pool, the buffers may be released by the
+// garbage collector if they are not used.
+func Preallocate(n int) {
+	size := initialSize.Load()
+	for range n {
+		b := make([]byte, 0, size)
+		bufPool.Put((*Buffer)(&b))
+	}
+}
+
 // New returns a buffer from the pool.
 func New() *Buffer {
+	if statsEnabled.Load() {
+		gets.Add(1)
+	}
 	return bufPool.Get().(*Buffer)
 }
 
 // Free returns the buffer to the pool.
 // To reduce peak allocation, return only smaller buffers to the pool.
 func (b *Buffer) Free() {
-	const maxBufferSize = 16 << 10 // 16KB
-	if cap(*b) <= maxBufferSize {
-		*b = (*b)[:0]
-		bufPool.Put(b)
+	size := int64(cap(*b))
+	stats := statsEnabled.Load()
+	if stats {
+		frees.Add(1)
+		for {
+			peak := peakSize.Load()
+			if size <= peak || peakSize.CompareAndSwap(peak, size) {
+				break
+			}
+		}
+	}
+	if size > maxRetainedSize.Load() {
+		if stats {
+			discarded.Add(1)
+		}
+		return
 	}
+	*b = (*b)[:0]
+	bufPool.Put(b)
 }
 
 // Reset resets the buffer to be empty.
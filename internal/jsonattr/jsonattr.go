@@ -0,0 +1,188 @@
+// Package jsonattr は JSON のオブジェクトを、キーの順序を保ったまま slog の属性に変換します。
+// ルートパッケージの ZerologWriter と parse パッケージの JSON 形式の解析で共有します。
+package jsonattr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+)
+
+// SyntaxError は JSON の解析のエラー
+type SyntaxError struct {
+	Offset int // 入力の中のバイト位置
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("golog: offset %d: %s", e.Offset, e.Msg)
+}
+
+// Raw は配列と空のオブジェクトの元の JSON。ハンドラーに渡すと元と同じ JSON が出力されます。
+type Raw []byte
+
+// MarshalJSON は元の JSON を返します
+func (r Raw) MarshalJSON() ([]byte, error) {
+	if len(r) == 0 {
+		return []byte("null"), nil
+	}
+	return r, nil
+}
+
+// Decoder は1つの JSON オブジェクトのメンバーを、先頭から順に属性として読みます。
+//
+// ネストしたオブジェクトはグループ、配列と空のオブジェクト（slog.GroupValue は空のグループを取り除くため）は
+// 元の JSON の Raw、数値は整数として表せる場合は Int64 または Uint64、
+// それ以外は Float64 の値になります。
+type Decoder struct {
+	dec     *json.Decoder
+	base    int // dec の入力の先頭の、元の入力の中での位置
+	started bool
+}
+
+// NewDecoder は data の JSON オブジェクトを読む Decoder を作成します
+func NewDecoder(data []byte) *Decoder {
+	return newDecoder(data, 0)
+}
+
+func newDecoder(data []byte, base int) *Decoder {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return &Decoder{dec: dec, base: base}
+}
+
+// Decode は data の JSON オブジェクトのすべてのメンバーを属性に変換します
+func Decode(data []byte) ([]slog.Attr, error) {
+	return NewDecoder(data).decodeAll()
+}
+
+// Offset は次に読む位置を返します
+func (d *Decoder) Offset() int {
+	return d.base + int(d.dec.InputOffset())
+}
+
+// Next は次のメンバーを返します。オブジェクトの終わりでは、後に続くデータが無いことを確認して false を返します。
+func (d *Decoder) Next() (slog.Attr, bool, error) {
+	if !d.started {
+		d.started = true
+		if err := d.delim('{'); err != nil {
+			return slog.Attr{}, false, err
+		}
+	}
+	if !d.dec.More() {
+		if err := d.delim('}'); err != nil {
+			return slog.Attr{}, false, err
+		}
+		if _, err := d.dec.Token(); err != io.EOF {
+			return slog.Attr{}, false, d.errorf("unexpected data after object")
+		}
+		return slog.Attr{}, false, nil
+	}
+
+	tok, err := d.dec.Token()
+	if err != nil {
+		return slog.Attr{}, false, d.wrap(err)
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return slog.Attr{}, false, d.errorf("expected object key")
+	}
+	v, err := d.value()
+	if err != nil {
+		return slog.Attr{}, false, err
+	}
+	return slog.Attr{Key: key, Value: v}, true, nil
+}
+
+// decodeAll は残りのメンバーをすべて読みます
+func (d *Decoder) decodeAll() ([]slog.Attr, error) {
+	var attrs []slog.Attr
+	for {
+		a, ok, err := d.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return attrs, nil
+		}
+		attrs = append(attrs, a)
+	}
+}
+
+// value は次の値を slog.Value に変換します
+func (d *Decoder) value() (slog.Value, error) {
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		return slog.Value{}, d.wrap(err)
+	}
+	// Decode は値の前の空白を読み飛ばすため、値の位置は末尾から求める
+	start := d.Offset() - len(raw)
+
+	switch raw[0] {
+	case '{':
+		attrs, err := newDecoder(raw, start).decodeAll()
+		if err != nil {
+			return slog.Value{}, err
+		}
+		if len(attrs) == 0 {
+			return slog.AnyValue(Raw(raw)), nil
+		}
+		return slog.GroupValue(attrs...), nil
+	case '[':
+		return slog.AnyValue(Raw(raw)), nil
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return slog.Value{}, &SyntaxError{Offset: start, Msg: err.Error()}
+		}
+		return slog.StringValue(s), nil
+	}
+	return scalar(string(raw)), nil
+}
+
+// scalar は文字列以外のスカラー値を変換します
+func scalar(token string) slog.Value {
+	switch token {
+	case "null":
+		return slog.AnyValue(nil)
+	case "true":
+		return slog.BoolValue(true)
+	case "false":
+		return slog.BoolValue(false)
+	}
+	if n, err := strconv.ParseInt(token, 10, 64); err == nil {
+		return slog.Int64Value(n)
+	}
+	if n, err := strconv.ParseUint(token, 10, 64); err == nil {
+		return slog.Uint64Value(n)
+	}
+	f, _ := strconv.ParseFloat(token, 64)
+	return slog.Float64Value(f)
+}
+
+// delim は区切り文字 c を読みます
+func (d *Decoder) delim(c json.Delim) error {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return d.wrap(err)
+	}
+	if tok != c {
+		return d.errorf("expected %q", rune(c))
+	}
+	return nil
+}
+
+func (d *Decoder) errorf(format string, args ...any) error {
+	return &SyntaxError{Offset: d.Offset(), Msg: fmt.Sprintf(format, args...)}
+}
+
+// wrap は encoding/json のエラーを SyntaxError に変換します
+func (d *Decoder) wrap(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return d.errorf("%v", err)
+}
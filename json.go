@@ -0,0 +1,206 @@
+package loggo
+
+import (
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// Format はレコードの出力形式
+type Format int
+
+const (
+	// FormatText は "[時刻] [レベル] msg=... key=value" のテキスト形式です（デフォルト）。
+	// グループはキーを Options.GroupSeparator（デフォルトは "."）で連結して表します。
+	FormatText Format = iota
+	// FormatJSON は1行に1つの JSON オブジェクトを出力します。
+	// グループは入れ子のオブジェクトになり、時刻はデフォルトで RFC3339Nano です。
+	FormatJSON
+)
+
+// formatNames は Format のテキスト表現
+var formatNames = []string{
+	FormatText: "text",
+	FormatJSON: "json",
+}
+
+// String は出力形式の名前を返します
+func (f Format) String() string {
+	if f >= 0 && int(f) < len(formatNames) {
+		return formatNames[f]
+	}
+	return "Format(" + strconv.Itoa(int(f)) + ")"
+}
+
+// MarshalText は出力形式の名前を返します
+func (f Format) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText は "text", "json" を解析します
+func (f *Format) UnmarshalText(text []byte) error {
+	i, err := parseEnumName("format", formatNames, string(text))
+	if err != nil {
+		return err
+	}
+	*f = Format(i)
+	return nil
+}
+
+// formatJSON はレコードを JSON のオブジェクトにフォーマットしてバッファに書き込みます。
+// With で追加された属性と開いたグループは preformattedAttrs に書き込み済みで、
+// まだ属性が無いグループはレコードの属性を書き込む際に開きます。
+func (h *Handler) formatJSON(buf *buffer.Buffer, r slog.Record) {
+	buf.WriteByte('{')
+
+	timeAttr := slog.Time(slog.TimeKey, r.Time)
+	if h.replaceAttr != nil {
+		timeAttr = h.replaceAttr(nil, timeAttr)
+	}
+	if timeAttr.Key != "" {
+		h.appendJSONKey(buf, timeAttr.Key)
+		if timeAttr.Value.Kind() == slog.KindTime {
+			buf.WriteByte('"')
+			h.timeFormatter(buf, timeAttr.Value.Time())
+			buf.WriteByte('"')
+		} else {
+			h.vf.appendAttrValue(buf, timeAttr.Value)
+		}
+	}
+
+	levelAttr := slog.Any(slog.LevelKey, r.Level)
+	if h.replaceAttr != nil {
+		levelAttr = h.replaceAttr(nil, levelAttr)
+	}
+	if levelAttr.Key != "" {
+		h.appendJSONKey(buf, levelAttr.Key)
+		level, ok := levelAttr.Value.Any().(slog.Level)
+		if n, numeric := h.levelNumber(level); ok && numeric {
+			*buf = strconv.AppendInt(*buf, n, 10)
+		} else if ok {
+			buf.WriteByte('"')
+			buf.WriteString(strings.TrimLeft(formatLevel(level), " "))
+			buf.WriteByte('"')
+		} else {
+			h.vf.appendAttrValue(buf, levelAttr.Value)
+		}
+	}
+
+	msgAttr := slog.String(slog.MessageKey, r.Message)
+	if h.replaceAttr != nil {
+		msgAttr = h.replaceAttr(nil, msgAttr)
+	}
+	if msgAttr.Key != "" {
+		h.appendJSONKey(buf, msgAttr.Key)
+		h.vf.appendAttrValue(buf, msgAttr.Value)
+	}
+
+	h.appendName(buf)
+	if h.addSource {
+		h.appendSource(buf, r.PC)
+	}
+
+	pre := h.preformattedAttrs
+	if len(pre) > 0 && (*buf)[buf.Len()-1] == '{' {
+		pre = pre[1:] // 先頭の ',' を除く
+	}
+	buf.Write(pre)
+
+	// 属性が1つも無い場合に空のオブジェクトを出力しないよう、グループは最初の属性の前に開く
+	open := h.jsonOpenGroups
+	pending := h.groups[h.jsonOpenGroups:]
+	// attr は ReplaceAttr を適用済みの属性
+	writeAttr := func(attr slog.Attr) {
+		for _, group := range pending {
+			h.appendJSONKey(buf, group)
+			buf.WriteByte('{')
+			open++
+		}
+		pending = nil
+		h.writeJSONAttr(buf, nil, attr)
+	}
+	if h.sortAttrs {
+		var stack [16]slog.Attr
+		for _, attr := range h.collectAttrs(stack[:0], r) {
+			writeAttr(attr)
+		}
+	} else {
+		r.Attrs(func(attr slog.Attr) bool {
+			if attr, ok := h.replace(nil, attr); ok {
+				writeAttr(attr)
+			}
+			return true
+		})
+	}
+
+	for range open {
+		buf.WriteByte('}')
+	}
+	buf.WriteString("}\n")
+}
+
+// withAttrsJSON は ReplaceAttr を適用済みの attrs を JSON の形式で buf に追加し、newHandler の preformattedAttrs にします。
+// 属性を1つも書き込まなかった場合は、まだ開いていないグループを開きません。
+func (h *Handler) withAttrsJSON(newHandler *Handler, buf *buffer.Buffer, attrs []slog.Attr) {
+	mark := buf.Len()
+	for _, group := range h.groups[h.jsonOpenGroups:] {
+		h.appendJSONKey(buf, group)
+		buf.WriteByte('{')
+	}
+	opened := buf.Len()
+
+	for _, attr := range attrs {
+		h.writeJSONAttr(buf, nil, attr)
+	}
+
+	if buf.Len() == opened {
+		buf.SetLen(mark)
+	} else {
+		newHandler.jsonOpenGroups = len(h.groups)
+	}
+	newHandler.preformattedAttrs = make([]byte, buf.Len())
+	copy(newHandler.preformattedAttrs, *buf)
+}
+
+// writeJSONAttr は ReplaceAttr を適用せずに属性を書き込みます。
+// グループの値は入れ子のオブジェクトにし、キーが空のグループは現在のオブジェクトにインライン化します。
+// メンバーがすべて削除されたグループは出力しません。
+func (h *Handler) writeJSONAttr(buf *buffer.Buffer, nested []string, attr slog.Attr) {
+	if attr.Value.Kind() != slog.KindGroup {
+		h.appendJSONKey(buf, attr.Key)
+		h.vf.appendAttrValue(buf, attr.Value)
+		return
+	}
+
+	start := buf.Len()
+	if attr.Key != "" {
+		nested = append(slices.Clip(nested), attr.Key)
+		h.appendJSONKey(buf, attr.Key)
+		buf.WriteByte('{')
+	}
+	members := buf.Len()
+	for _, member := range attr.Value.Group() {
+		if member, ok := h.replace(nested, member); ok {
+			h.writeJSONAttr(buf, nested, member)
+		}
+	}
+	if attr.Key == "" {
+		return
+	}
+	if buf.Len() == members {
+		buf.SetLen(start)
+		return
+	}
+	buf.WriteByte('}')
+}
+
+// appendJSONKey は必要な場合は区切りの ',' を付けて、"key": を書き込みます
+func (h *Handler) appendJSONKey(buf *buffer.Buffer, key string) {
+	if buf.Len() == 0 || (*buf)[buf.Len()-1] != '{' {
+		buf.WriteByte(',')
+	}
+	h.appendCachedKey(buf, key)
+}
@@ -0,0 +1,120 @@
+package loggo
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// removeJSONTime は JSON 形式のテストで時刻を出力しないための ReplaceAttr
+func removeJSONTime(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.TimeKey {
+		return slog.Attr{}
+	}
+	return a
+}
+
+// TestFormatJSON は JSON 形式でグループが入れ子のオブジェクトになることをテストします
+func TestFormatJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l *slog.Logger)
+		want string
+	}{
+		{
+			"basic",
+			func(l *slog.Logger) { l.Info("hello", "n", 1, "s", "a\"b\x00") },
+			`{"level":"INFO","msg":"hello","n":1,"s":"a\"b\u0000"}`,
+		},
+		{
+			"nested groups",
+			func(l *slog.Logger) {
+				l.With("app", "api").WithGroup("req").With("id", 7).WithGroup("db").Info("query", "rows", 3, slog.Group("conn", "host", "x"))
+			},
+			`{"level":"INFO","msg":"query","app":"api","req":{"id":7,"db":{"rows":3,"conn":{"host":"x"}}}}`,
+		},
+		{
+			"empty groups are omitted",
+			func(l *slog.Logger) { l.WithGroup("a").WithGroup("b").Info("none", slog.Group("g")) },
+			`{"level":"INFO","msg":"none"}`,
+		},
+		{
+			"inline group",
+			func(l *slog.Logger) { l.Info("m", slog.Group("", "x", 1), "y", 2) },
+			`{"level":"INFO","msg":"m","x":1,"y":2}`,
+		},
+		{
+			"special values",
+			func(l *slog.Logger) {
+				l.Warn("v", "nan", math.NaN(), "c", complex(1, 2), "m", map[string]int{"k": 1})
+			},
+			`{"level":"WARN","msg":"v","nan":"NaN","c":"(1+2i)","m":{"k":1}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewHandler(&buf, &Options{Format: FormatJSON, ReplaceAttr: removeJSONTime})))
+			got := strings.TrimSuffix(buf.String(), "\n")
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+			if !json.Valid([]byte(got)) {
+				t.Errorf("output is not valid JSON: %s", got)
+			}
+		})
+	}
+}
+
+// TestFormatJSONBuiltins は JSON 形式の時刻、ソース、ReplaceAttr による組み込みの属性の変更をテストします
+func TestFormatJSONBuiltins(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(&buf, &Options{
+		Format:           FormatJSON,
+		AddSource:        true,
+		UseColors:        true, // JSON 形式では無視される
+		DigitSeparator:   '_',
+		EmphasizeMessage: true,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.MessageKey {
+				a.Key = "message"
+			}
+			return a
+		},
+	})
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	r := slog.NewRecord(time.Date(2024, 1, 15, 10, 30, 0, 5, time.UTC), slog.LevelError, "m", pcs[0])
+	r.AddAttrs(slog.Int("big", 1000000))
+	h.Handle(t.Context(), r)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if got["time"] != "2024-01-15T10:30:00.000000005Z" || got["level"] != "ERROR" || got["message"] != "m" || got["big"] != 1e6 {
+		t.Errorf("unexpected builtins: %v", got)
+	}
+	if src, _ := got["source"].(string); !strings.HasPrefix(src, "json_test.go:") {
+		t.Errorf("unexpected source: %v", got["source"])
+	}
+}
+
+// TestFormatText は Format のテキスト表現をテストします
+func TestFormatText(t *testing.T) {
+	var f Format
+	if err := f.UnmarshalText([]byte("JSON")); err != nil || f != FormatJSON {
+		t.Errorf("UnmarshalText: %v, %v", f, err)
+	}
+	if b, _ := FormatText.MarshalText(); string(b) != "text" {
+		t.Errorf("MarshalText: %s", b)
+	}
+	if err := f.UnmarshalText([]byte("xml")); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,72 @@
+package loggo
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+const (
+	// maxCachedKeys は keyCache に保持するキーの最大数。これを超えたキーは毎回フォーマットします。
+	maxCachedKeys = 1024
+	// maxCachedKeyLen はキャッシュするキーの最大のバイト数
+	maxCachedKeyLen = 64
+)
+
+// keyCache はクォートが必要な属性のキーを出力する形式（"key"= や JSON の "key":）にフォーマットした結果を保持するキャッシュ。
+// ハンドラーとそのクローンで共有され、同じキーを記録するたびにエスケープとクォートをやり直さずに済みます。
+// キーの出力形式は valueFormatter の設定に依存するため、ハンドラーの作成ごとに作ります。
+type keyCache struct {
+	m sync.Map // map[string][]byte。値はエスケープ済みのキーと区切り文字
+	n atomic.Int32
+}
+
+// lookup は key をフォーマットした結果を返します。
+// キャッシュに無い場合はフォーマットして追加します。キャッシュが満杯の場合や key が長すぎる場合は nil を返します。
+func (c *keyCache) lookup(f *valueFormatter, key string) []byte {
+	if v, ok := c.m.Load(key); ok {
+		return v.([]byte)
+	}
+	if len(key) > maxCachedKeyLen || c.n.Load() >= maxCachedKeys {
+		return nil
+	}
+
+	buf := buffer.New()
+	defer buf.Free()
+	f.appendKey(buf, key)
+	buf.WriteByte(keySeparatorByte(f))
+	// 呼び出し元の文字列を保持し続けないようにコピーしたものをキーにする
+	formatted := slices.Clone([]byte(*buf))
+	if v, loaded := c.m.LoadOrStore(strings.Clone(key), formatted); loaded {
+		return v.([]byte)
+	}
+	c.n.Add(1)
+	return formatted
+}
+
+// appendCachedKey は key とキーの後の区切り文字（テキストでは '='、JSON では ':'）をキャッシュを使って書き込みます
+// クォートが不要なキーはそのまま書き込む方が速いため、キャッシュを使いません。
+func (h *Handler) appendCachedKey(buf *buffer.Buffer, key string) {
+	if !h.vf.keyNeedsQuoting(key) {
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		return
+	}
+	if formatted := h.keys.lookup(&h.vf, key); formatted != nil {
+		buf.Write(formatted)
+		return
+	}
+	h.vf.appendKey(buf, key)
+	buf.WriteByte(keySeparatorByte(&h.vf))
+}
+
+// keySeparatorByte はキーと値の間の区切り文字を返します
+func keySeparatorByte(f *valueFormatter) byte {
+	if f.JSON {
+		return ':'
+	}
+	return '='
+}
@@ -0,0 +1,64 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestKeyCache はクォートが必要なキーをキャッシュしても毎回同じ形式で出力されることをテストします
+func TestKeyCache(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *Options
+		want string
+	}{
+		{"text", nil, `"user id"=1 plain=2`},
+		{"json", &Options{Format: FormatJSON}, `"user id":1,"plain":2`},
+		{"separator", &Options{QuoteSeparator: true}, `"user.id"=1 plain=2`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := NewHandler(&buf, tt.opts)
+			logger := slog.New(h).With("with", true)
+
+			key := "user id"
+			if tt.name == "separator" {
+				key = "user.id"
+			}
+			for range 3 {
+				logger.Info("test", key, 1, "plain", 2)
+			}
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if len(lines) != 3 {
+				t.Fatalf("expected 3 lines, got %d", len(lines))
+			}
+			for _, line := range lines {
+				if !strings.Contains(line, tt.want) {
+					t.Errorf("expected %q in %q", tt.want, line)
+				}
+			}
+		})
+	}
+}
+
+// TestKeyCacheLimit はキャッシュするキーの数が制限され、制限を超えたキーも正しく出力されることをテストします
+func TestKeyCacheLimit(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(&buf, nil)
+	logger := slog.New(h)
+
+	for i := range maxCachedKeys + 10 {
+		logger.Info("test", "key "+strconv.Itoa(i), i)
+	}
+	if n := h.keys.n.Load(); n != maxCachedKeys {
+		t.Errorf("expected %d cached keys, got %d", maxCachedKeys, n)
+	}
+	last := maxCachedKeys + 9
+	if want := `"key ` + strconv.Itoa(last) + `"=` + strconv.Itoa(last); !strings.Contains(buf.String(), want) {
+		t.Errorf("expected %q in output", want)
+	}
+}
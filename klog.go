@@ -0,0 +1,203 @@
+package loggo
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// V は glog/klog の V(n) に対応するレベルを返します。
+// V(0) が slog.LevelInfo で、n が1増えるごとにレベルが1下がります（V(4) が slog.LevelDebug）。
+func V(n int) slog.Level {
+	return slog.LevelInfo - slog.Level(n)
+}
+
+// vmoduleRule は -vmodule の1つの指定
+type vmoduleRule struct {
+	pattern string
+	level   slog.Level
+}
+
+// vmoduleConfig は -vmodule の設定と、PC ごとの判定結果のキャッシュ
+type vmoduleConfig struct {
+	spec     string
+	rules    []vmoduleRule
+	minLevel slog.Level
+	cache    sync.Map // uintptr -> vmoduleMatch
+}
+
+// vmoduleMatch は PC の呼び出し元のファイルに一致した -vmodule のレベル
+type vmoduleMatch struct {
+	level   slog.Level
+	matched bool // false の場合は -v が変更されても追従できるよう、レベルを記録しない
+}
+
+// Verbosity は glog/klog の -v と -vmodule の設定。ゼロ値は -v=0 で使用できます。
+// AddFlags でフラグを登録し、NewVerbosityHandler で Handler に適用します。
+type Verbosity struct {
+	v       atomic.Int32
+	vmodule atomic.Pointer[vmoduleConfig]
+}
+
+// AddFlags は fs に -v と -vmodule を登録します
+func (v *Verbosity) AddFlags(fs *flag.FlagSet) {
+	fs.Var(verbosityFlag{v}, "v", "number for the log level verbosity")
+	fs.Var(vmoduleFlag{v}, "vmodule", "comma-separated list of pattern=N settings for file-filtered logging")
+}
+
+// SetV は -v の値を設定します
+func (v *Verbosity) SetV(n int) {
+	v.v.Store(int32(n))
+}
+
+// SetVModule は "pattern=N,..." の形式の -vmodule の値を設定します。
+// pattern はファイル名（.go を除く）に対するグロブで、"/" を含む場合はパス全体と比較します。
+func (v *Verbosity) SetVModule(spec string) error {
+	cfg := &vmoduleConfig{spec: spec}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		pattern, n, ok := strings.Cut(part, "=")
+		if !ok || pattern == "" {
+			return fmt.Errorf("golog: invalid vmodule %q: expected pattern=N", part)
+		}
+		level, err := strconv.Atoi(n)
+		if err != nil {
+			return fmt.Errorf("golog: invalid vmodule %q: %w", part, err)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("golog: invalid vmodule pattern %q: %w", pattern, err)
+		}
+		cfg.rules = append(cfg.rules, vmoduleRule{pattern: pattern, level: V(level)})
+		cfg.minLevel = min(cfg.minLevel, V(level))
+	}
+	v.vmodule.Store(cfg)
+	return nil
+}
+
+// Level は -v に対応するレベルを返します。slog.Leveler として使えます。
+func (v *Verbosity) Level() slog.Level {
+	return V(int(v.v.Load()))
+}
+
+// levelFor は pc の呼び出し元のファイルに適用されるレベルを返します。
+// -vmodule に一致するパターンがある場合は最初に一致したものを使い、無い場合は -v を使います。
+func (v *Verbosity) levelFor(pc uintptr) slog.Level {
+	cfg := v.vmodule.Load()
+	if cfg == nil || len(cfg.rules) == 0 || pc == 0 {
+		return v.Level()
+	}
+	if cached, ok := cfg.cache.Load(pc); ok {
+		if m := cached.(vmoduleMatch); m.matched {
+			return m.level
+		}
+		return v.Level()
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	path := strings.TrimSuffix(frame.File, ".go")
+	module := filepath.Base(path)
+	for _, rule := range cfg.rules {
+		target := module
+		if strings.Contains(rule.pattern, "/") {
+			target = path
+		}
+		if ok, _ := filepath.Match(rule.pattern, target); ok {
+			cfg.cache.Store(pc, vmoduleMatch{level: rule.level, matched: true})
+			return rule.level
+		}
+	}
+	cfg.cache.Store(pc, vmoduleMatch{})
+	return v.Level()
+}
+
+// minLevel は -v と -vmodule のうち最も低いレベルを返します
+func (v *Verbosity) minLevel() slog.Level {
+	level := v.Level()
+	if cfg := v.vmodule.Load(); cfg != nil && len(cfg.rules) > 0 {
+		level = min(level, cfg.minLevel)
+	}
+	return level
+}
+
+// verbosityFlag は -v の flag.Value
+type verbosityFlag struct{ v *Verbosity }
+
+func (f verbosityFlag) String() string {
+	if f.v == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(f.v.v.Load()))
+}
+
+func (f verbosityFlag) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	f.v.SetV(n)
+	return nil
+}
+
+// vmoduleFlag は -vmodule の flag.Value
+type vmoduleFlag struct{ v *Verbosity }
+
+func (f vmoduleFlag) String() string {
+	if f.v == nil {
+		return ""
+	}
+	if cfg := f.v.vmodule.Load(); cfg != nil {
+		return cfg.spec
+	}
+	return ""
+}
+
+func (f vmoduleFlag) Set(s string) error {
+	return f.v.SetVModule(s)
+}
+
+// verbosityHandler は Verbosity に従ってレコードを絞り込むハンドラー
+type verbosityHandler struct {
+	next slog.Handler
+	v    *Verbosity
+}
+
+// NewVerbosityHandler は -v と -vmodule に従ってレコードを絞り込むハンドラーを返します。
+// 絞り込みはこのハンドラーが行うため、next のレベルは V で指定する最も詳細なレベル以下にしてください。
+//
+//	var verbosity golog.Verbosity
+//	verbosity.AddFlags(flag.CommandLine)
+//	flag.Parse()
+//	handler := golog.NewVerbosityHandler(golog.NewHandler(os.Stderr, &golog.Options{Level: golog.V(10)}), &verbosity)
+//	logger.Log(ctx, golog.V(2), "detailed message")
+func NewVerbosityHandler(next slog.Handler, v *Verbosity) slog.Handler {
+	return &verbosityHandler{next: next, v: v}
+}
+
+func (h *verbosityHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.v.minLevel() && h.next.Enabled(ctx, level)
+}
+
+func (h *verbosityHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < h.v.levelFor(r.PC) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *verbosityHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &verbosityHandler{next: h.next.WithAttrs(attrs), v: h.v}
+}
+
+func (h *verbosityHandler) WithGroup(name string) slog.Handler {
+	return &verbosityHandler{next: h.next.WithGroup(name), v: h.v}
+}
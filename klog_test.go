@@ -0,0 +1,61 @@
+package loggo
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestVerbosityFlags は -v と -vmodule によってレコードが絞り込まれることをテストします
+func TestVerbosityFlags(t *testing.T) {
+	var verbosity Verbosity
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	verbosity.AddFlags(fs)
+	if err := fs.Parse([]string{"-v=1", "-vmodule=klog_test=3,other*=5"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(NewVerbosityHandler(NewHandler(&buf, &Options{Level: V(10)}), &verbosity))
+
+	// このファイルには klog_test=3 が適用される
+	for n := range 5 {
+		logger.Log(t.Context(), V(n), "verbose", "n", n)
+	}
+	if got := strings.Count(buf.String(), "\n"); got != 4 {
+		t.Errorf("expected V(0)..V(3) to be logged, got %d lines:\n%s", got, buf.String())
+	}
+
+	buf.Reset()
+	if err := verbosity.SetVModule(""); err != nil {
+		t.Fatal(err)
+	}
+	for n := range 5 {
+		logger.Log(t.Context(), V(n), "verbose", "n", n)
+	}
+	if got := strings.Count(buf.String(), "\n"); got != 2 {
+		t.Errorf("expected V(0)..V(1) after clearing vmodule, got %d lines:\n%s", got, buf.String())
+	}
+	if fs.Lookup("v").Value.String() != "1" {
+		t.Errorf("unexpected -v value %q", fs.Lookup("v").Value.String())
+	}
+}
+
+// TestVerbosityInvalidVModule は不正な -vmodule がエラーになることをテストします
+func TestVerbosityInvalidVModule(t *testing.T) {
+	var verbosity Verbosity
+	for _, spec := range []string{"noequals", "=3", "file=x", "[=1"} {
+		if err := verbosity.SetVModule(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+// TestV は V(n) のレベルの対応をテストします
+func TestV(t *testing.T) {
+	if V(0) != slog.LevelInfo || V(4) != slog.LevelDebug {
+		t.Errorf("unexpected levels: V(0)=%v V(4)=%v", V(0), V(4))
+	}
+}
@@ -0,0 +1,23 @@
+package loggo
+
+import "log/slog"
+
+// lazyValue は値の計算を出力時まで遅らせる slog.LogValuer
+type lazyValue func() slog.Value
+
+// LogValue は関数を呼び出して値を返します
+func (f lazyValue) LogValue() slog.Value {
+	return f()
+}
+
+// Lazy は fn をレコードが実際に出力されるときにのみ呼び出す値を返します。
+// 大きなダンプやデータベースの参照など、計算コストの高い値を無効なレベルで計算しないために使います。
+//
+//	logger.Debug("状態", "dump", golog.Lazy(func() slog.Value {
+//	    return slog.StringValue(expensiveDump())
+//	}))
+//
+// fn はハンドラーごとに Handle の中で呼び出されます。With に渡した場合は With の呼び出し時に評価されます。
+func Lazy(fn func() slog.Value) slog.LogValuer {
+	return lazyValue(fn)
+}
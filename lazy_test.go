@@ -0,0 +1,40 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestLazy は Lazy の関数がレコードを出力する場合にのみ呼び出されることをテストします
+func TestLazy(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{Level: slog.LevelInfo}))
+
+	calls := 0
+	value := Lazy(func() slog.Value {
+		calls++
+		return slog.IntValue(42)
+	})
+
+	logger.Debug("disabled", "v", value)
+	if calls != 0 {
+		t.Errorf("function should not be called at a disabled level, called %d times", calls)
+	}
+
+	logger.Info("enabled", "v", value)
+	if calls != 1 {
+		t.Errorf("function should be called once, called %d times", calls)
+	}
+	if !strings.Contains(buf.String(), "v=42") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+
+	sampled := slog.New(NewSampler(NewHandler(&buf, nil), SamplingOptions{First: 1}))
+	sampled.Info("sampled", "v", value)
+	sampled.Info("sampled", "v", value)
+	if calls != 2 {
+		t.Errorf("function should not be called for dropped records, called %d times", calls)
+	}
+}
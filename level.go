@@ -0,0 +1,182 @@
+package loggo
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// customLevel は登録されたカスタムレベルの名前
+type customLevel struct {
+	name   string
+	padded string // 出力用に5文字幅に揃えた名前
+}
+
+var (
+	levelNamesMu sync.Mutex
+	// levelNames は Handle でロックせずに参照できるよう、登録のたびに作り直すマップ
+	levelNames atomic.Pointer[map[slog.Level]customLevel]
+)
+
+// RegisterLevel はカスタムレベルの名前を登録します。
+// 登録した名前はログの出力、ParseLevel、Level のテキスト変換で使用されます。
+// 標準のレベル（DEBUG, INFO, WARN, ERROR）の名前は変更できません。
+//
+//	const LevelTrace = slog.Level(-8)
+//	golog.RegisterLevel(LevelTrace, "TRACE")
+func RegisterLevel(level slog.Level, name string) {
+	switch level {
+	case slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError:
+		return
+	}
+	name = strings.ToUpper(name)
+	padded := name
+	if len(padded) < 5 {
+		padded = strings.Repeat(" ", 5-len(padded)) + padded
+	}
+
+	levelNamesMu.Lock()
+	defer levelNamesMu.Unlock()
+	names := make(map[slog.Level]customLevel)
+	if old := levelNames.Load(); old != nil {
+		maps.Copy(names, *old)
+	}
+	names[level] = customLevel{name: name, padded: padded}
+	levelNames.Store(&names)
+}
+
+// lookupLevel は登録されたカスタムレベルを返します
+func lookupLevel(level slog.Level) (customLevel, bool) {
+	names := levelNames.Load()
+	if names == nil {
+		return customLevel{}, false
+	}
+	c, ok := (*names)[level]
+	return c, ok
+}
+
+// LevelName はレベルの名前を返します。
+// カスタムレベルは登録された名前を、それ以外は slog.Level.String と同じ形式（"WARN", "ERROR+2" など）を返します。
+func LevelName(level slog.Level) string {
+	if c, ok := lookupLevel(level); ok {
+		return c.name
+	}
+	return level.String()
+}
+
+// ParseLevel はレベルの名前を解析します。大文字と小文字は区別しません。
+// "debug", "info", "warn"（"warning"）, "error"、"warn+2" のようなオフセット付きの名前、
+// RegisterLevel で登録した名前、"-4" のような数値を受け付けます。
+func ParseLevel(s string) (slog.Level, error) {
+	name := strings.TrimSpace(s)
+	if names := levelNames.Load(); names != nil {
+		for level, c := range *names {
+			if strings.EqualFold(c.name, name) {
+				return level, nil
+			}
+		}
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		return slog.Level(n), nil
+	}
+	if strings.EqualFold(name, "warning") {
+		return slog.LevelWarn, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("golog: unknown level %q", s)
+	}
+	return level, nil
+}
+
+// Level は設定ファイルやフラグとテキストで相互に変換できるログレベル。
+// slog.Leveler を実装しているため、Options.Level にそのまま指定できます。
+type Level slog.Level
+
+// Level は slog.Level を返します
+func (l Level) Level() slog.Level {
+	return slog.Level(l)
+}
+
+// String はレベルの名前を返します
+func (l Level) String() string {
+	return LevelName(slog.Level(l))
+}
+
+// MarshalText はレベルの名前を返します
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText は ParseLevel でレベルの名前を解析します
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = Level(level)
+	return nil
+}
+
+// Set は flag.Value を実装し、コマンドラインフラグからレベルを設定できるようにします
+func (l *Level) Set(s string) error {
+	return l.UnmarshalText([]byte(s))
+}
+
+// LevelFormat はレベルの出力形式
+type LevelFormat int
+
+const (
+	// LevelFormatName は "INFO" のような名前を出力します。テキスト形式では5文字の幅に揃えます（デフォルト）。
+	LevelFormatName LevelFormat = iota
+	// LevelFormatNumber は slog.Level の数値（DEBUG は -4、INFO は 0、WARN は 4、ERROR は 8）を出力します
+	LevelFormatNumber
+	// LevelFormatSyslog は syslog の重要度の数値（ERROR は 3、INFO は 6 など）を出力します。
+	// 変換には Options.Syslog の Severities を、指定が無い場合は SyslogSeverities を使います。
+	LevelFormatSyslog
+)
+
+// levelFormatNames は LevelFormat のテキスト表現
+var levelFormatNames = []string{
+	LevelFormatName:   "name",
+	LevelFormatNumber: "number",
+	LevelFormatSyslog: "syslog",
+}
+
+// String はレベルの出力形式の名前を返します
+func (f LevelFormat) String() string {
+	if f >= 0 && int(f) < len(levelFormatNames) {
+		return levelFormatNames[f]
+	}
+	return "LevelFormat(" + strconv.Itoa(int(f)) + ")"
+}
+
+// MarshalText はレベルの出力形式の名前を返します
+func (f LevelFormat) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText は "name", "number", "syslog" を解析します
+func (f *LevelFormat) UnmarshalText(text []byte) error {
+	i, err := parseEnumName("level format", levelFormatNames, string(text))
+	if err != nil {
+		return err
+	}
+	*f = LevelFormat(i)
+	return nil
+}
+
+// parseEnumName は names の中から大文字と小文字を区別せずに name を探し、その位置を返します
+func parseEnumName(kind string, names []string, name string) (int, error) {
+	name = strings.TrimSpace(name)
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("golog: unknown %s %q", kind, name)
+}
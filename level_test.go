@@ -0,0 +1,173 @@
+package loggo
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+const (
+	testLevelTrace = slog.Level(-8)
+	testLevelFatal = slog.Level(16)
+)
+
+func init() {
+	RegisterLevel(testLevelTrace, "trace")
+	RegisterLevel(testLevelFatal, "FATAL")
+}
+
+// TestParseLevel はレベルの名前の解析をテストします
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{" warn ", slog.LevelWarn, false},
+		{"Warning", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"warn+2", slog.LevelWarn + 2, false},
+		{"INFO-4", slog.LevelDebug, false},
+		{"trace", testLevelTrace, false},
+		{"Fatal", testLevelFatal, false},
+		{"-3", slog.Level(-3), false},
+		{"verbose", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseLevel(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestLevelText はレベルのテキスト変換が往復できることをテストします
+func TestLevelText(t *testing.T) {
+	type config struct {
+		Level      Level              `json:"level"`
+		WriteMode  WriteMode          `json:"write_mode"`
+		Duplicates DuplicateKeyPolicy `json:"duplicates"`
+	}
+
+	in := config{Level: Level(testLevelTrace), WriteMode: WriteModeSharded, Duplicates: DuplicateKeysLastWins}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"level":"TRACE","write_mode":"sharded","duplicates":"last-wins"}` {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+
+	var out config
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("round trip mismatch: %+v != %+v", out, in)
+	}
+
+	if err := json.Unmarshal([]byte(`{"write_mode":"parallel"}`), &out); err == nil {
+		t.Error("expected error for unknown write mode")
+	}
+	if got := WriteMode(9).String(); got != "WriteMode(9)" {
+		t.Errorf("unexpected name: %s", got)
+	}
+}
+
+// TestLevelFlag は Level をコマンドラインフラグとして使えることをテストします
+func TestLevelFlag(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	level := Level(slog.LevelInfo)
+	fs.Var(&level, "level", "log level")
+	if err := fs.Parse([]string{"-level", "warn+1"}); err != nil {
+		t.Fatal(err)
+	}
+	if level.Level() != slog.LevelWarn+1 || level.String() != "WARN+1" {
+		t.Errorf("unexpected level: %v", level)
+	}
+}
+
+// TestRegisterLevelOutput は登録したレベルの名前が出力されることをテストします
+func TestRegisterLevelOutput(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{Level: Level(testLevelTrace)}))
+	logger.Log(t.Context(), testLevelTrace, "tracing")
+	logger.Log(t.Context(), testLevelFatal, "fatal")
+
+	if !strings.Contains(buf.String(), "[TRACE] msg=\"tracing\"") || !strings.Contains(buf.String(), "[FATAL] msg=\"fatal\"") {
+		t.Errorf("custom level names should be used, got: %s", buf.String())
+	}
+
+	RegisterLevel(slog.LevelInfo, "NOTICE")
+	if LevelName(slog.LevelInfo) != "INFO" {
+		t.Error("standard level names should not be overridden")
+	}
+}
+
+// TestLevelFormat はレベルを slog の数値や syslog の重要度の数値で出力することをテストします
+func TestLevelFormat(t *testing.T) {
+	noTime := func(_ []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		return a
+	}
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"number", Options{LevelFormat: LevelFormatNumber}, "[-4] msg=\"m\"\n[0] msg=\"m\"\n[8] msg=\"m\"\n"},
+		{"syslog", Options{LevelFormat: LevelFormatSyslog}, "[7] msg=\"m\"\n[6] msg=\"m\"\n[3] msg=\"m\"\n"},
+		{"syslog severities", Options{LevelFormat: LevelFormatSyslog, Syslog: &SyslogOptions{
+			Severities: SyslogSeverities.With(slog.LevelInfo, 5),
+		}}, "[7] msg=\"m\"\n[5] msg=\"m\"\n[3] msg=\"m\"\n"},
+		{"colors", Options{LevelFormat: LevelFormatNumber, UseColors: true},
+			"[" + colorCyan + "-4" + colorReset + "] msg=\"m\"\n[" + colorGreen + "0" + colorReset + "] msg=\"m\"\n[" + colorRed + "8" + colorReset + "] msg=\"m\"\n"},
+		{"json", Options{LevelFormat: LevelFormatNumber, Format: FormatJSON},
+			"{\"level\":-4,\"msg\":\"m\"}\n{\"level\":0,\"msg\":\"m\"}\n{\"level\":8,\"msg\":\"m\"}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.opts.Level = slog.LevelDebug
+			tt.opts.ReplaceAttr = noTime
+			logger := slog.New(NewHandler(&buf, &tt.opts))
+			logger.Debug("m")
+			logger.Info("m")
+			logger.Error("m")
+			got := buf.String()
+			if tt.opts.Syslog != nil {
+				// syslog のヘッダーを除く
+				var lines []string
+				for line := range strings.Lines(got) {
+					lines = append(lines, line[strings.Index(line, ": ")+2:])
+				}
+				got = strings.Join(lines, "")
+			}
+			if got != tt.want {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+
+	var f LevelFormat
+	if err := f.UnmarshalText([]byte("Syslog")); err != nil || f != LevelFormatSyslog {
+		t.Errorf("UnmarshalText = %v, %v", f, err)
+	}
+	if text, _ := LevelFormatNumber.MarshalText(); string(text) != "number" {
+		t.Errorf("MarshalText = %q", text)
+	}
+}
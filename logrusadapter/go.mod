@@ -0,0 +1,10 @@
+module github.com/f0reth/golog/logrusadapter
+
+go 1.25.6
+
+require (
+	github.com/f0reth/golog v0.1.0
+	github.com/sirupsen/logrus v1.9.3
+)
+
+require golang.org/x/sys v0.9.0 // indirect
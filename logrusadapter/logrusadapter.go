@@ -0,0 +1,135 @@
+// Package logrusadapter は logrus のエントリーを golog（slog.Handler）へ転送するアダプター。
+// logrus から golog へ段階的に移行する際に、両方のログを同じ形式で出力するために使います。
+//
+// ルートのモジュールを外部依存の無い状態に保つため、独立したモジュールとして提供しています。
+package logrusadapter
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	loggo "github.com/f0reth/golog"
+	"github.com/sirupsen/logrus"
+)
+
+// Level は logrus のレベルを slog のレベルに変換します。
+// Trace は slog.LevelDebug-4、Fatal は slog.LevelError+4、Panic は slog.LevelError+8 になります。
+func Level(l logrus.Level) slog.Level {
+	switch l {
+	case logrus.PanicLevel:
+		return slog.LevelError + 8
+	case logrus.FatalLevel:
+		return slog.LevelError + 4
+	case logrus.ErrorLevel:
+		return slog.LevelError
+	case logrus.WarnLevel:
+		return slog.LevelWarn
+	case logrus.InfoLevel:
+		return slog.LevelInfo
+	case logrus.DebugLevel:
+		return slog.LevelDebug
+	default:
+		return slog.LevelDebug - 4
+	}
+}
+
+// Record はエントリーを slog.Record に変換します。
+// フィールドはキーの順に属性になり、エラーの値はメッセージの文字列になります。ReportCaller が有効な場合は呼び出し元の PC を引き継ぎます。
+func Record(e *logrus.Entry) slog.Record {
+	var pc uintptr
+	if e.Caller != nil {
+		pc = e.Caller.PC
+	}
+	r := slog.NewRecord(e.Time, Level(e.Level), strings.TrimSuffix(e.Message, "\n"), pc)
+
+	keys := make([]string, 0, len(e.Data))
+	for k := range e.Data {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		// logrus の JSONFormatter と同様に、エラーはメッセージの文字列として出力する
+		if err, ok := e.Data[k].(error); ok {
+			r.AddAttrs(slog.String(k, err.Error()))
+			continue
+		}
+		r.AddAttrs(slog.Any(k, e.Data[k]))
+	}
+	return r
+}
+
+// entryContext はエントリーのコンテキストを返します
+func entryContext(e *logrus.Entry) context.Context {
+	if e.Context != nil {
+		return e.Context
+	}
+	return context.Background()
+}
+
+// Hook は logrus のエントリーを slog.Handler へ転送する logrus.Hook。
+// logrus 側の出力が不要な場合は logger.SetOutput(io.Discard) と組み合わせます。
+type Hook struct {
+	handler slog.Handler
+	levels  []logrus.Level
+}
+
+// NewHook は handler へ転送する Hook を作成します。levels を省略した場合はすべてのレベルを転送します。
+func NewHook(handler slog.Handler, levels ...logrus.Level) *Hook {
+	if len(levels) == 0 {
+		levels = logrus.AllLevels
+	}
+	return &Hook{handler: handler, levels: levels}
+}
+
+// Levels は転送するレベルを返します
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire はエントリーを slog.Handler へ転送します
+func (h *Hook) Fire(e *logrus.Entry) error {
+	ctx := entryContext(e)
+	level := Level(e.Level)
+	if !h.handler.Enabled(ctx, level) {
+		return nil
+	}
+	return h.handler.Handle(ctx, Record(e))
+}
+
+// Formatter は golog の形式でエントリーを整形する logrus.Formatter
+type Formatter struct {
+	mu      sync.Mutex // buf と handler の書き込みを保護
+	buf     bytes.Buffer
+	handler *loggo.Handler
+}
+
+// NewFormatter は opts の設定で整形する Formatter を作成します。
+// レベルによる絞り込みは logrus 側で行うため、opts.Level は無視されます。
+func NewFormatter(opts *loggo.Options) *Formatter {
+	var o loggo.Options
+	if opts != nil {
+		o = *opts
+	}
+	o.Level = slog.Level(-1 << 20)
+	// 書き込みは mu で直列化するため、ハンドラー側では排他制御を行わない
+	o.WriteMode = loggo.WriteModeBatched
+	o.NoLock = true
+	f := &Formatter{}
+	f.handler = loggo.NewHandler(&f.buf, &o)
+	return f
+}
+
+// Format はエントリーを1行に整形します
+func (f *Formatter) Format(e *logrus.Entry) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.buf.Reset()
+	if err := f.handler.Handle(entryContext(e), Record(e)); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(f.buf.Bytes()), nil
+}
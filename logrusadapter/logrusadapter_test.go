@@ -0,0 +1,94 @@
+package logrusadapter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	loggo "github.com/f0reth/golog"
+	"github.com/sirupsen/logrus"
+)
+
+// TestHook はエントリーのフィールドとレベルが golog へ転送されることをテストします
+func TestHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.TraceLevel)
+	logger.AddHook(NewHook(loggo.NewHandler(&buf, nil)))
+
+	logger.WithFields(logrus.Fields{"user": "alice", "attempt": 2}).Warn("login failed")
+	logger.WithError(errors.New("timeout")).Error("request failed")
+	logger.Debug("filtered by handler level")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	if !strings.HasSuffix(lines[0], `[ WARN] msg="login failed" attempt=2 user="alice"`) {
+		t.Errorf("unexpected warn line: %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], `[ERROR] msg="request failed" error="timeout"`) {
+		t.Errorf("unexpected error line: %s", lines[1])
+	}
+}
+
+// TestFormatter は logrus の出力が golog の形式で整形されることをテストします
+func TestFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(NewFormatter(&loggo.Options{Level: slog.LevelError}))
+
+	logger.WithField("k", "v").Info("formatted")
+
+	if !strings.HasSuffix(buf.String(), "[ INFO] msg=\"formatted\" k=\"v\"\n") {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+// TestFormatterConcurrent は同じ Formatter を並行して使用しても行が混ざらないことをテストします
+func TestFormatterConcurrent(t *testing.T) {
+	f := NewFormatter(nil)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			msg := fmt.Sprintf("entry %d", i)
+			for range 100 {
+				line, err := f.Format(&logrus.Entry{Level: logrus.InfoLevel, Message: msg})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !strings.HasSuffix(string(line), fmt.Sprintf("msg=%q\n", msg)) {
+					t.Errorf("unexpected line: %q", line)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// TestLevel は logrus のレベルの変換をテストします
+func TestLevel(t *testing.T) {
+	tests := map[logrus.Level]slog.Level{
+		logrus.TraceLevel: slog.LevelDebug - 4,
+		logrus.DebugLevel: slog.LevelDebug,
+		logrus.InfoLevel:  slog.LevelInfo,
+		logrus.WarnLevel:  slog.LevelWarn,
+		logrus.ErrorLevel: slog.LevelError,
+		logrus.FatalLevel: slog.LevelError + 4,
+		logrus.PanicLevel: slog.LevelError + 8,
+	}
+	for in, want := range tests {
+		if got := Level(in); got != want {
+			t.Errorf("Level(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
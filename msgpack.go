@@ -0,0 +1,154 @@
+package loggo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+// MessagePack のエンコードに必要な最小限の関数。Fluent Forward プロトコルで使用します。
+
+func appendMsgpackNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+func appendMsgpackBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+func appendMsgpackInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v <= 0x7f:
+		return append(b, byte(v))
+	case v < 0 && v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+	}
+}
+
+func appendMsgpackFloat(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n <= 31:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendMsgpackArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+func appendMsgpackMapHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+// appendMsgpackEventTime は Fluent の EventTime（拡張型 0、秒とナノ秒の 32 ビット整数）を書き込みます
+func appendMsgpackEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, 0x00)
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Unix()))
+	return binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+}
+
+// appendMsgpackJSON は1つの JSON の値を、キーの順序を保ったまま MessagePack に変換して書き込みます
+func appendMsgpackJSON(b []byte, data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	b, err := appendMsgpackJSONValue(b, dec)
+	if err != nil {
+		return b, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return b, errors.New("golog: unexpected data after JSON value")
+	}
+	return b, nil
+}
+
+// appendMsgpackJSONValue は dec の次の値を変換します。
+// オブジェクトと配列は要素の数が先頭に必要なため、要素を変換した後にヘッダーを挿入します。
+func appendMsgpackJSONValue(b []byte, dec *json.Decoder) ([]byte, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return b, err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		start := len(b)
+		n := 0
+		for dec.More() {
+			if v == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return b, err
+				}
+				b = appendMsgpackString(b, key.(string))
+			}
+			if b, err = appendMsgpackJSONValue(b, dec); err != nil {
+				return b, err
+			}
+			n++
+		}
+		if _, err := dec.Token(); err != nil { // 閉じる括弧
+			return b, err
+		}
+		var header []byte
+		if v == '{' {
+			header = appendMsgpackMapHeader(nil, n)
+		} else {
+			header = appendMsgpackArrayHeader(nil, n)
+		}
+		b = append(b, header...)
+		copy(b[start+len(header):], b[start:len(b)-len(header)])
+		copy(b[start:], header)
+		return b, nil
+	case string:
+		return appendMsgpackString(b, v), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendMsgpackInt(b, i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return b, err
+		}
+		return appendMsgpackFloat(b, f), nil
+	case bool:
+		return appendMsgpackBool(b, v), nil
+	default:
+		return appendMsgpackNil(b), nil
+	}
+}
@@ -0,0 +1,169 @@
+package loggo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// msgpackMap はテストでキーの順序を確認するためのデコード結果のマップ
+type msgpackMap struct {
+	Keys   []string
+	Values []any
+}
+
+// get は key の値を返します
+func (m msgpackMap) get(key string) any {
+	for i, k := range m.Keys {
+		if k == key {
+			return m.Values[i]
+		}
+	}
+	return nil
+}
+
+// decodeMsgpack はテスト用に MessagePack の値を1つデコードし、残りのバイト列を返します。
+// 整数は int64、マップは msgpackMap、EventTime は time.Time になります。
+func decodeMsgpack(t *testing.T, b []byte) (any, []byte) {
+	t.Helper()
+	if len(b) == 0 {
+		t.Fatal("unexpected end of msgpack data")
+	}
+	c := b[0]
+	b = b[1:]
+	readLen := func(size int) int {
+		n := 0
+		for _, x := range b[:size] {
+			n = n<<8 | int(x)
+		}
+		b = b[size:]
+		return n
+	}
+	str := func(n int) string {
+		s := string(b[:n])
+		b = b[n:]
+		return s
+	}
+	array := func(n int) []any {
+		values := make([]any, n)
+		for i := range values {
+			values[i], b = decodeMsgpack(t, b)
+		}
+		return values
+	}
+	object := func(n int) msgpackMap {
+		var m msgpackMap
+		for range n {
+			var k, v any
+			k, b = decodeMsgpack(t, b)
+			v, b = decodeMsgpack(t, b)
+			m.Keys = append(m.Keys, k.(string))
+			m.Values = append(m.Values, v)
+		}
+		return m
+	}
+	switch {
+	case c <= 0x7f:
+		return int64(c), b
+	case c >= 0xe0:
+		return int64(int8(c)), b
+	case c&0xf0 == 0x80:
+		return object(int(c & 0x0f)), b
+	case c&0xf0 == 0x90:
+		return array(int(c & 0x0f)), b
+	case c&0xe0 == 0xa0:
+		return str(int(c & 0x1f)), b
+	}
+	switch c {
+	case 0xc0:
+		return nil, b
+	case 0xc2, 0xc3:
+		return c == 0xc3, b
+	case 0xd2:
+		return int64(int32(readLen(4))), b
+	case 0xd3:
+		v := int64(binary.BigEndian.Uint64(b))
+		return v, b[8:]
+	case 0xcb:
+		v := math.Float64frombits(binary.BigEndian.Uint64(b))
+		return v, b[8:]
+	case 0xd7:
+		if b[0] != 0 {
+			t.Fatalf("unexpected ext type %d", b[0])
+		}
+		v := time.Unix(int64(binary.BigEndian.Uint32(b[1:])), int64(binary.BigEndian.Uint32(b[5:])))
+		return v, b[9:]
+	case 0xd9:
+		return str(readLen(1)), b
+	case 0xda:
+		return str(readLen(2)), b
+	case 0xdb:
+		return str(readLen(4)), b
+	case 0xdc:
+		return array(readLen(2)), b
+	case 0xde:
+		return object(readLen(2)), b
+	}
+	t.Fatalf("unsupported msgpack type 0x%02x", c)
+	return nil, nil
+}
+
+// TestMsgpackJSON は JSON を MessagePack に変換し、キーの順序と値の型を保つことをテストします
+func TestMsgpackJSON(t *testing.T) {
+	input := `{"z":1,"a":"x","neg":-5,"big":5000000000,"f":1.5,"ok":true,"nil":null,"arr":[1,"two",{"k":"v"}]}`
+	b, err := appendMsgpackJSON(nil, []byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, rest := decodeMsgpack(t, b)
+	if len(rest) != 0 {
+		t.Errorf("trailing %d bytes", len(rest))
+	}
+	m := v.(msgpackMap)
+	wantKeys := []string{"z", "a", "neg", "big", "f", "ok", "nil", "arr"}
+	if !reflect.DeepEqual(m.Keys, wantKeys) {
+		t.Errorf("keys = %v, want %v", m.Keys, wantKeys)
+	}
+	wantValues := []any{int64(1), "x", int64(-5), int64(5000000000), 1.5, true, nil,
+		[]any{int64(1), "two", msgpackMap{Keys: []string{"k"}, Values: []any{"v"}}}}
+	if !reflect.DeepEqual(m.Values, wantValues) {
+		t.Errorf("values = %#v, want %#v", m.Values, wantValues)
+	}
+
+	if _, err := appendMsgpackJSON(nil, []byte(`{"a":1} {}`)); err == nil {
+		t.Error("expected error for trailing data")
+	}
+	if _, err := appendMsgpackJSON(nil, []byte(`{"a":`)); err == nil {
+		t.Error("expected error for truncated JSON")
+	}
+}
+
+// TestMsgpackLengths は長さに応じたヘッダーの形式をテストします
+func TestMsgpackLengths(t *testing.T) {
+	for _, n := range []int{0, 31, 32, 255, 256, 70000} {
+		s := fmt.Sprintf("%0*d", n, 0)[:n]
+		v, rest := decodeMsgpack(t, appendMsgpackString(nil, s))
+		if v != s || len(rest) != 0 {
+			t.Errorf("string of length %d did not round-trip", n)
+		}
+	}
+	for _, n := range []int{15, 16} {
+		b := appendMsgpackArrayHeader(nil, n)
+		for range n {
+			b = appendMsgpackNil(b)
+		}
+		v, _ := decodeMsgpack(t, b)
+		if len(v.([]any)) != n {
+			t.Errorf("array of length %d did not round-trip", n)
+		}
+	}
+	for _, i := range []int64{-1, -32, -33, 127, 128, math.MinInt32, math.MaxInt64} {
+		v, _ := decodeMsgpack(t, appendMsgpackInt(nil, i))
+		if v != i {
+			t.Errorf("int %d decoded as %v", i, v)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package loggo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+)
+
+// Sink は MultiHandler の出力先の1つ
+type Sink struct {
+	// Handler はレコードを出力するハンドラー
+	Handler slog.Handler
+	// ReplaceAttrs はこの出力先に渡す前に、順番に属性へ適用する関数。
+	// 他の出力先には影響しないため、ファイルにはすべての属性を、コンソールにはマスクした属性を出力する、
+	// といった使い分けができます。slog.HandlerOptions.ReplaceAttr と同じく、グループの属性自体には適用せず
+	// メンバーに適用し、キーを空にした属性は取り除きます。時刻やレベルなどの組み込みの属性には適用しません。
+	ReplaceAttrs []func(groups []string, a slog.Attr) slog.Attr
+}
+
+// multiSink は MultiHandler の出力先と、WithGroup で開いたグループ
+type multiSink struct {
+	handler     slog.Handler
+	replaceAttr func(groups []string, a slog.Attr) slog.Attr // nil の場合は変換しない
+	groups      []string
+}
+
+// MultiHandler はレコードを複数の出力先へ振り分けるハンドラー。
+// 出力先ごとに ReplaceAttrs を指定でき、属性の変換は出力先ごとに独立して行われます。
+//
+//	mask := golog.NewKeyTransforms().Register("email", golog.MaskEmailDomain)
+//	handler := golog.NewMultiHandler(
+//	    golog.Sink{Handler: golog.NewHandler(file, &golog.Options{Format: golog.FormatJSON})},
+//	    golog.Sink{
+//	        Handler:      golog.NewHandler(os.Stderr, nil),
+//	        ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{mask.ReplaceAttr},
+//	    },
+//	)
+type MultiHandler struct {
+	sinks []multiSink
+}
+
+// NewMultiHandler は sinks へレコードを振り分けるハンドラーを作成します
+func NewMultiHandler(sinks ...Sink) *MultiHandler {
+	h := &MultiHandler{sinks: make([]multiSink, len(sinks))}
+	for i, s := range sinks {
+		h.sinks[i] = multiSink{handler: s.Handler, replaceAttr: chainReplaceAttr(nil, s.ReplaceAttrs)}
+	}
+	return h
+}
+
+// Enabled はいずれかの出力先でレベルが有効かどうかを返します
+func (h *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, s := range h.sinks {
+		if s.handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle はレベルが有効なすべての出力先へレコードを渡します。出力先のエラーはまとめて返します。
+func (h *MultiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, s := range h.sinks {
+		if !s.handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		var rec slog.Record
+		if s.replaceAttr == nil {
+			rec = r.Clone()
+		} else {
+			rec = slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+			var attrs []slog.Attr
+			r.Attrs(func(a slog.Attr) bool {
+				attrs = append(attrs, a)
+				return true
+			})
+			rec.AddAttrs(replaceAttrs(s.groups, attrs, s.replaceAttr)...)
+		}
+		if err := s.handler.Handle(ctx, rec); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs は出力先ごとに属性を変換して追加したハンドラーを返します
+func (h *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	sinks := make([]multiSink, len(h.sinks))
+	for i, s := range h.sinks {
+		sinkAttrs := attrs
+		if s.replaceAttr != nil {
+			sinkAttrs = replaceAttrs(s.groups, attrs, s.replaceAttr)
+		}
+		s.handler = s.handler.WithAttrs(sinkAttrs)
+		sinks[i] = s
+	}
+	return &MultiHandler{sinks: sinks}
+}
+
+// WithGroup はすべての出力先でグループを開いたハンドラーを返します
+func (h *MultiHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	sinks := make([]multiSink, len(h.sinks))
+	for i, s := range h.sinks {
+		s.handler = s.handler.WithGroup(name)
+		s.groups = append(slices.Clip(s.groups), name)
+		sinks[i] = s
+	}
+	return &MultiHandler{sinks: sinks}
+}
+
+// Close は出力先のうち Close を実装するハンドラーを閉じます。エラーはまとめて返します。
+func (h *MultiHandler) Close() error {
+	var errs []error
+	for _, s := range h.sinks {
+		if c, ok := s.handler.(interface{ Close() error }); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// replaceAttrs は attrs に fn を適用した属性を返します。
+// グループはメンバーに再帰的に適用し、キーが空になった属性と空になったグループは取り除きます。
+func replaceAttrs(groups []string, attrs []slog.Attr, fn func([]string, slog.Attr) slog.Attr) []slog.Attr {
+	out := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindGroup {
+			nested := groups
+			if a.Key != "" {
+				nested = append(slices.Clip(groups), a.Key)
+			}
+			members := replaceAttrs(nested, a.Value.Group(), fn)
+			if len(members) > 0 {
+				out = append(out, slog.Attr{Key: a.Key, Value: slog.GroupValue(members...)})
+			}
+			continue
+		}
+		if a = fn(groups, a); a.Key != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package loggo
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMultiHandlerReplaceAttrs は出力先ごとの ReplaceAttrs が他の出力先に影響しないことをテストします
+func TestMultiHandlerReplaceAttrs(t *testing.T) {
+	var file, console bytes.Buffer
+	redact := func(groups []string, a slog.Attr) slog.Attr {
+		switch a.Key {
+		case "email":
+			return slog.String(a.Key, "***")
+		case "token":
+			return slog.Attr{}
+		}
+		return a
+	}
+	var gotGroups []string
+	recordGroups := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == "id" {
+			gotGroups = groups
+		}
+		return a
+	}
+	logger := slog.New(NewMultiHandler(
+		Sink{Handler: NewHandler(&file, nil)},
+		Sink{Handler: NewHandler(&console, nil), ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{redact, recordGroups}},
+	))
+
+	logger.With("email", "a@example.com").WithGroup("req").
+		Info("login", "token", "secret", slog.Group("user", "id", 7, "email", "b@example.com"))
+
+	if want := `msg="login" email="a@example.com" req.token="secret" req.user.id=7 req.user.email="b@example.com"`; !strings.Contains(file.String(), want) {
+		t.Errorf("file = %s", file.String())
+	}
+	if want := `msg="login" email="***" req.user.id=7 req.user.email="***"` + "\n"; !strings.HasSuffix(console.String(), want) {
+		t.Errorf("console = %s", console.String())
+	}
+	if strings.Join(gotGroups, ".") != "req.user" {
+		t.Errorf("groups = %v, want [req user]", gotGroups)
+	}
+}
+
+// TestMultiHandlerLevels は出力先ごとのレベルとエラーの扱いをテストします
+func TestMultiHandlerLevels(t *testing.T) {
+	var debug, warn bytes.Buffer
+	h := NewMultiHandler(
+		Sink{Handler: NewHandler(&debug, &Options{Level: slog.LevelDebug})},
+		Sink{Handler: NewHandler(&warn, &Options{Level: slog.LevelWarn})},
+	)
+	logger := slog.New(h)
+	logger.Debug("details")
+	logger.Warn("careful")
+	if !strings.Contains(debug.String(), "details") || !strings.Contains(debug.String(), "careful") {
+		t.Errorf("debug = %s", debug.String())
+	}
+	if strings.Contains(warn.String(), "details") || !strings.Contains(warn.String(), "careful") {
+		t.Errorf("warn = %s", warn.String())
+	}
+	if h.Enabled(context.Background(), slog.LevelDebug-1) {
+		t.Error("level below every sink should be disabled")
+	}
+
+	failing := NewMultiHandler(Sink{Handler: NewHandler(errorWriter{}, nil)}, Sink{Handler: NewHandler(&warn, nil)})
+	if err := failing.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "x", 0)); err == nil || !strings.Contains(err.Error(), "write failed") {
+		t.Errorf("Handle = %v, want write error", err)
+	}
+	if !strings.Contains(warn.String(), `msg="x"`) {
+		t.Error("other sinks should receive the record when one fails")
+	}
+}
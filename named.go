@@ -0,0 +1,208 @@
+package loggo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// LoggerKey は Options.Name と Registry のロガーの名前を出力する属性のキー
+	LoggerKey = "logger"
+	// rootLoggerName は Registry.Configure と String でルートのロガーを表す名前
+	rootLoggerName = "<root>"
+)
+
+// Registry は "app.http.client" のようにドットで階層化した名前付きのロガーを管理します。
+// レベルを設定していないロガーは、最も近い親（"app.http"、"app"、ルートの順）のレベルを引き継ぎます。
+// juju/loggo や log4j のように、パッケージやコンポーネントごとのレベルを実行中に変更できます。
+//
+//	registry := golog.NewRegistry(golog.NewHandler(os.Stderr, &golog.Options{Level: slog.LevelDebug}), slog.LevelInfo)
+//	client := registry.Logger("app.http.client")
+//	registry.SetLevel("app.http", slog.LevelDebug) // app.http.client も Debug になる
+//	client.Debug("request sent")                  // logger="app.http.client" が付く
+//
+// 絞り込みは Registry のロガーが行うため、next のレベルは設定する最も詳細なレベル以下にしてください。
+type Registry struct {
+	next slog.Handler
+
+	mu      sync.Mutex
+	levels  map[string]slog.Level // SetLevel で設定したレベル。"" はルート
+	loggers map[string]namedLogger
+}
+
+// namedLogger は Logger で作成したロガーとその実効レベル
+type namedLogger struct {
+	logger *slog.Logger
+	level  *slog.LevelVar
+}
+
+// NewRegistry は next に出力するロガーを管理する Registry を作成します。level はルートのレベルです。
+func NewRegistry(next slog.Handler, level slog.Level) *Registry {
+	return &Registry{
+		next:    next,
+		levels:  map[string]slog.Level{"": level},
+		loggers: make(map[string]namedLogger),
+	}
+}
+
+// Logger は name のロガーを返します。同じ名前では同じロガーを返します。
+// name が空の場合はルートのロガーを返し、それ以外のロガーは LoggerKey の属性に名前を付加します。
+// next が *Handler の場合は WithName で名前を置き換えるため、Options.Name と重複して出力しません。
+func (r *Registry) Logger(name string) *slog.Logger {
+	name = strings.TrimSpace(name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if l, ok := r.loggers[name]; ok {
+		return l.logger
+	}
+	level := new(slog.LevelVar)
+	level.Set(r.effectiveLocked(name))
+	next := r.next
+	if h, ok := next.(*Handler); ok && name != "" {
+		next = h.WithName(name)
+	} else if name != "" {
+		next = next.WithAttrs([]slog.Attr{slog.String(LoggerKey, name)})
+	}
+	logger := slog.New(&namedHandler{next: next, level: level})
+	r.loggers[name] = namedLogger{logger: logger, level: level}
+	return logger
+}
+
+// SetLevel は name とその子孫のうちレベルを設定していないロガーのレベルを設定します。
+// name が空の場合はルートのレベルを設定します。
+func (r *Registry) SetLevel(name string, level slog.Level) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.levels[strings.TrimSpace(name)] = level
+	r.updateLocked()
+}
+
+// UnsetLevel は name に設定したレベルを取り消し、親のレベルを引き継ぐようにします。
+// ルートのレベルは取り消せません。
+func (r *Registry) UnsetLevel(name string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.levels, name)
+	r.updateLocked()
+}
+
+// Level は name のロガーに適用されるレベルを返します
+func (r *Registry) Level(name string) slog.Level {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.effectiveLocked(strings.TrimSpace(name))
+}
+
+// Configure は "<root>=WARN;app.http=DEBUG" の形式の設定でレベルを設定します。
+// 区切りには ";" と "," を使え、"<root>" はルートを表します。レベルは ParseLevel で解析します。
+// 設定に含まれないロガーのレベルは変更しません。1つでも不正な指定がある場合は何も変更しません。
+func (r *Registry) Configure(spec string) error {
+	levels := make(map[string]slog.Level)
+	for _, part := range strings.FieldsFunc(spec, func(c rune) bool { return c == ';' || c == ',' }) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("golog: invalid logger level %q: expected name=LEVEL", part)
+		}
+		name = strings.TrimSpace(name)
+		if name == rootLoggerName {
+			name = ""
+		}
+		level, err := ParseLevel(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("golog: invalid logger level %q: %w", part, err)
+		}
+		levels[name] = level
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, level := range levels {
+		r.levels[name] = level
+	}
+	r.updateLocked()
+	return nil
+}
+
+// String は設定したレベルを Configure の形式で返します
+func (r *Registry) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.levels))
+	for name := range r.levels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(';')
+		}
+		if name == "" {
+			b.WriteString(rootLoggerName)
+		} else {
+			b.WriteString(name)
+		}
+		b.WriteByte('=')
+		b.WriteString(LevelName(r.levels[name]))
+	}
+	return b.String()
+}
+
+// effectiveLocked は name に最も近い、レベルを設定したロガーのレベルを返します。mu を保持して呼び出します。
+func (r *Registry) effectiveLocked(name string) slog.Level {
+	for name != "" {
+		if level, ok := r.levels[name]; ok {
+			return level
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return r.levels[""]
+}
+
+// updateLocked は作成したロガーの実効レベルを計算し直します。mu を保持して呼び出します。
+func (r *Registry) updateLocked() {
+	for name, l := range r.loggers {
+		l.level.Set(r.effectiveLocked(name))
+	}
+}
+
+// namedHandler は Registry のロガーのレベルに従ってレコードを絞り込むハンドラー
+type namedHandler struct {
+	next  slog.Handler
+	level *slog.LevelVar
+}
+
+func (h *namedHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.next.Enabled(ctx, level)
+}
+
+func (h *namedHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < h.level.Level() {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *namedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &namedHandler{next: h.next.WithAttrs(attrs), level: h.level}
+}
+
+func (h *namedHandler) WithGroup(name string) slog.Handler {
+	return &namedHandler{next: h.next.WithGroup(name), level: h.level}
+}
@@ -0,0 +1,125 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestRegistryInheritance は子のロガーが親のレベルを引き継ぎ、設定したレベルで上書きできることをテストします
+func TestRegistryInheritance(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRegistry(NewHandler(&buf, &Options{Level: slog.LevelDebug}), slog.LevelInfo)
+	client := r.Logger("app.http.client")
+	db := r.Logger("app.db")
+
+	client.Debug("hidden")
+	r.SetLevel("app.http", slog.LevelDebug)
+	client.Debug("shown")
+	db.Debug("hidden")
+	if r.Logger("app.http.client") != client {
+		t.Error("Logger should return the same logger for the same name")
+	}
+
+	r.SetLevel("app.http.client", slog.LevelError)
+	client.Warn("hidden")
+	r.UnsetLevel("app.http.client")
+	client.Debug("inherited again")
+
+	r.SetLevel("", slog.LevelError)
+	db.Warn("hidden")
+	r.Logger("").Error("root")
+
+	got := buf.String()
+	if strings.Contains(got, "hidden") {
+		t.Errorf("unexpected record: %s", got)
+	}
+	for _, want := range []string{
+		`msg="shown" logger="app.http.client"`,
+		`msg="inherited again" logger="app.http.client"`,
+		`msg="root"` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in %s", want, got)
+		}
+	}
+	if level := r.Level("app.http.client.retry"); level != slog.LevelDebug {
+		t.Errorf("Level = %v, want DEBUG", level)
+	}
+}
+
+// TestRegistryConfigure は設定文字列によるレベルの設定と String をテストします
+func TestRegistryConfigure(t *testing.T) {
+	r := NewRegistry(slog.NewTextHandler(&bytes.Buffer{}, nil), slog.LevelInfo)
+	if err := r.Configure("<root>=WARN; app.http=debug, app.db=-2"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.String(), "<root>=WARN;app.db=DEBUG+2;app.http=DEBUG"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+	if r.Level("other") != slog.LevelWarn || r.Level("app.http.client") != slog.LevelDebug {
+		t.Errorf("unexpected levels: %v %v", r.Level("other"), r.Level("app.http.client"))
+	}
+
+	for _, spec := range []string{"app", "app=LOUD"} {
+		if err := r.Configure(spec); err == nil {
+			t.Errorf("Configure(%q) should fail", spec)
+		}
+	}
+	if got := r.String(); got != "<root>=WARN;app.db=DEBUG+2;app.http=DEBUG" {
+		t.Errorf("invalid spec should not change levels: %s", got)
+	}
+}
+
+// TestHandlerName は Options.Name の名前がグループに関係なく最上位に出力されることをテストします
+func TestHandlerName(t *testing.T) {
+	noTime := func(_ []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		return a
+	}
+	tests := []struct {
+		name string
+		opts *Options
+		want string
+	}{
+		{"text", &Options{Name: "api.server", ReplaceAttr: noTime},
+			`[ INFO] msg="hi" logger="api.server" svc="x" g.n=1`},
+		{"json", &Options{Name: "api.server", Format: FormatJSON, ReplaceAttr: noTime},
+			`{"level":"INFO","msg":"hi","logger":"api.server","svc":"x","g":{"n":1}}`},
+		{"replace", &Options{Name: "api", ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == LoggerKey && groups == nil {
+				return slog.String("component", strings.ToUpper(a.Value.String()))
+			}
+			return noTime(groups, a)
+		}}, `[ INFO] msg="hi" component="API" svc="x" g.n=1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := NewHandler(&buf, tt.opts)
+			if h.Name() != tt.opts.Name {
+				t.Errorf("Name() = %q", h.Name())
+			}
+			slog.New(h).With("svc", "x").WithGroup("g").Info("hi", "n", 1)
+			if got := strings.TrimSuffix(buf.String(), "\n"); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRegistryWithName は Registry のロガーが Options.Name を置き換え、名前を重複して出力しないことをテストします
+func TestRegistryWithName(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRegistry(NewHandler(&buf, &Options{Name: "api"}), slog.LevelInfo)
+	r.Logger("").Info("root")
+	r.Logger("api.db").Info("db")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], `msg="root" logger="api"`) || !strings.HasSuffix(lines[1], `msg="db" logger="api.db"`) {
+		t.Errorf("got %q", lines)
+	}
+}
@@ -0,0 +1,397 @@
+package loggo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultNATSTimeout は NATSWriterOptions.Timeout のデフォルト値
+const defaultNATSTimeout = 5 * time.Second
+
+// natsAckQueueSize は JetStream の ack を受け取るチャネルの容量
+const natsAckQueueSize = 1024
+
+// errNATSServer はサーバーが -ERR または JetStream のエラーで応答したことを示すエラー。
+// 接続し直しても解決しないため再送しません。
+var errNATSServer = errors.New("golog: NATS server error")
+
+// NATSWriterOptions は NATSWriter のオプション
+type NATSWriterOptions struct {
+	// JetStream は JetStream のストリームに保存されたことを ack で確認します。
+	// Subject を含むストリームが作成されている必要があります。
+	JetStream bool
+	// Name はサーバーに通知する接続の名前
+	Name string
+	// User と Password はユーザー名とパスワードによる認証に使います
+	User     string
+	Password string
+	// Token はトークンによる認証に使います
+	Token string
+	// Timeout は接続、送信、JetStream の ack の待機のタイムアウト。0 の場合は 5 秒です。
+	Timeout time.Duration
+}
+
+// NATSWriter は各行を NATS のサブジェクトへメッセージとして発行するライター。
+// 内部のログバスとして NATS を使う場合に、集約側は Subject を購読するだけで記録を受け取れます。
+// クライアントライブラリに依存せず、NATS のテキストプロトコルを直接話します。
+//
+// 送信に失敗した場合は接続し直して一度だけ再送します。
+type NATSWriter struct {
+	addr      string
+	subject   string
+	jetStream bool
+	connect   []byte // CONNECT コマンド
+	timeout   time.Duration
+
+	mu      sync.Mutex
+	conn    *natsConn
+	batch   uint64 // JetStream の ack を Write ごとに区別する番号
+	closed  bool
+	lastErr error // 直前の書き込みのエラー
+}
+
+// DialNATS は addr の NATS サーバーに接続し、subject へ発行するライターを作成します
+//
+//	w, err := golog.DialNATS("localhost:4222", "logs.api", &golog.NATSWriterOptions{JetStream: true})
+//	handler := golog.NewHandler(w, &golog.Options{Format: golog.FormatJSON})
+func DialNATS(addr, subject string, opts *NATSWriterOptions) (*NATSWriter, error) {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return nil, fmt.Errorf("golog: invalid NATS subject %q", subject)
+	}
+	w := &NATSWriter{addr: addr, subject: subject, timeout: defaultNATSTimeout}
+	connect := map[string]any{
+		"verbose":  false,
+		"pedantic": false,
+		"lang":     "go",
+		"version":  "golog",
+		"protocol": 1,
+	}
+	if opts != nil {
+		w.jetStream = opts.JetStream
+		if opts.Timeout > 0 {
+			w.timeout = opts.Timeout
+		}
+		if opts.Name != "" {
+			connect["name"] = opts.Name
+		}
+		if opts.User != "" {
+			connect["user"] = opts.User
+			connect["pass"] = opts.Password
+		}
+		if opts.Token != "" {
+			connect["auth_token"] = opts.Token
+		}
+	}
+	b, err := json.Marshal(connect)
+	if err != nil {
+		return nil, err
+	}
+	w.connect = append(append([]byte("CONNECT "), b...), "\r\nPING\r\n"...)
+
+	if err := w.connectLocked(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// natsConn は1つの NATS の接続。受信はゴルーチンが行い、PING への応答と JetStream の ack の受け渡しを行います。
+type natsConn struct {
+	c     net.Conn
+	inbox string // JetStream の ack を受け取るサブジェクトの接頭辞
+
+	wmu       sync.Mutex // 書き込みを直列化
+	acks      chan natsMsg
+	done      chan struct{} // 受信のゴルーチンが終了するとクローズされる
+	err       error         // 受信のエラー。done のクローズ後に読み取る
+	serverErr atomic.Pointer[string]
+}
+
+// natsMsg は受信したメッセージ
+type natsMsg struct {
+	subject string
+	data    []byte
+}
+
+// connectLocked はサーバーに接続してハンドシェイクを行います。mu を保持して呼び出します。
+func (w *NATSWriter) connectLocked() error {
+	c, err := net.DialTimeout("tcp", w.addr, w.timeout)
+	if err != nil {
+		return err
+	}
+	c.SetDeadline(time.Now().Add(w.timeout))
+	r := bufio.NewReader(c)
+	if line, err := readNATSLine(r); err != nil {
+		c.Close()
+		return err
+	} else if !strings.HasPrefix(line, "INFO ") {
+		c.Close()
+		return fmt.Errorf("golog: unexpected NATS greeting %q", line)
+	}
+	if _, err := c.Write(w.connect); err != nil {
+		c.Close()
+		return err
+	}
+	// CONNECT の後の PING に PONG が返れば接続が受け入れられている
+	for {
+		line, err := readNATSLine(r)
+		if err != nil {
+			c.Close()
+			return err
+		}
+		if line == "PONG" {
+			break
+		}
+		if after, ok := strings.CutPrefix(line, "-ERR "); ok {
+			c.Close()
+			return fmt.Errorf("golog: NATS connect: %s", strings.Trim(after, "'"))
+		}
+	}
+
+	nc := &natsConn{c: c, acks: make(chan natsMsg, natsAckQueueSize), done: make(chan struct{})}
+	if w.jetStream {
+		var id [8]byte
+		rand.Read(id[:])
+		nc.inbox = "_INBOX." + hex.EncodeToString(id[:])
+		if _, err := c.Write([]byte("SUB " + nc.inbox + ".> 1\r\n")); err != nil {
+			c.Close()
+			return err
+		}
+	}
+	c.SetDeadline(time.Time{})
+	go nc.readLoop(r)
+	w.conn = nc
+	return nil
+}
+
+// ensureConnLocked は接続が無い場合やサーバーが接続を閉じた場合に接続し直します。mu を保持して呼び出します。
+func (w *NATSWriter) ensureConnLocked() error {
+	if w.conn != nil {
+		select {
+		case <-w.conn.done:
+			w.disconnectLocked()
+		default:
+			return nil
+		}
+	}
+	return w.connectLocked()
+}
+
+// disconnectLocked は接続を閉じます。mu を保持して呼び出します。
+func (w *NATSWriter) disconnectLocked() {
+	if w.conn != nil {
+		w.conn.c.Close()
+		<-w.conn.done
+		w.conn = nil
+	}
+}
+
+// readLoop はサーバーからのコマンドを処理します
+func (nc *natsConn) readLoop(r *bufio.Reader) {
+	defer close(nc.done)
+	for {
+		line, err := readNATSLine(r)
+		if err != nil {
+			nc.err = err
+			return
+		}
+		switch {
+		case line == "PING":
+			nc.wmu.Lock()
+			_, err = nc.c.Write([]byte("PONG\r\n"))
+			nc.wmu.Unlock()
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			n, perr := strconv.Atoi(fields[len(fields)-1])
+			if perr != nil || len(fields) < 4 {
+				err = fmt.Errorf("golog: malformed NATS message %q", line)
+				break
+			}
+			data := make([]byte, n+2)
+			if _, err = io.ReadFull(r, data); err != nil {
+				break
+			}
+			select {
+			case nc.acks <- natsMsg{subject: fields[1], data: data[:n]}:
+			default: // 待っている Write が無い古い ack は捨てる
+			}
+		case strings.HasPrefix(line, "-ERR "):
+			msg := strings.Trim(strings.TrimPrefix(line, "-ERR "), "'")
+			nc.serverErr.Store(&msg)
+		}
+		if err != nil {
+			nc.err = err
+			nc.c.Close()
+			return
+		}
+	}
+}
+
+// readNATSLine は CRLF で終わる1行を読み取ります
+func readNATSLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Write は p の各行を1つのメッセージとして発行します
+func (w *NATSWriter) Write(p []byte) (int, error) {
+	var lines [][]byte
+	for line := range bytes.Lines(p) {
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return len(p), nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	err := w.publishLocked(lines)
+	if err != nil && !errors.Is(err, errNATSServer) {
+		// 接続が切れている可能性があるため、接続し直して一度だけ再送する
+		w.disconnectLocked()
+		err = w.publishLocked(lines)
+	}
+	w.lastErr = err
+	if err != nil {
+		if !errors.Is(err, errNATSServer) {
+			w.disconnectLocked()
+		}
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// publishLocked は各行を発行し、JetStream の場合はすべての ack を待ちます。mu を保持して呼び出します。
+func (w *NATSWriter) publishLocked(lines [][]byte) error {
+	if err := w.ensureConnLocked(); err != nil {
+		return err
+	}
+	nc := w.conn
+	w.batch++
+	prefix := nc.inbox + "." + strconv.FormatUint(w.batch, 10) + "."
+
+	var buf []byte
+	for i, line := range lines {
+		buf = append(buf, "PUB "...)
+		buf = append(buf, w.subject...)
+		if w.jetStream {
+			buf = append(buf, ' ')
+			buf = append(buf, prefix...)
+			buf = strconv.AppendInt(buf, int64(i), 10)
+		}
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, int64(len(line)), 10)
+		buf = append(buf, "\r\n"...)
+		buf = append(buf, line...)
+		buf = append(buf, "\r\n"...)
+	}
+	nc.wmu.Lock()
+	nc.c.SetWriteDeadline(time.Now().Add(w.timeout))
+	_, err := nc.c.Write(buf)
+	nc.wmu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if w.jetStream {
+		if err := w.waitAcks(nc, prefix, len(lines)); err != nil {
+			return err
+		}
+	}
+	if msg := nc.serverErr.Swap(nil); msg != nil {
+		return fmt.Errorf("%w: %s", errNATSServer, *msg)
+	}
+	return nil
+}
+
+// waitAcks は prefix で始まるサブジェクトへの n 個の JetStream の ack を待ちます
+func (w *NATSWriter) waitAcks(nc *natsConn, prefix string, n int) error {
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+	for n > 0 {
+		select {
+		case msg := <-nc.acks:
+			if !strings.HasPrefix(msg.subject, prefix) {
+				continue // タイムアウトした以前の Write の ack
+			}
+			var ack struct {
+				Error *struct {
+					Code        int    `json:"code"`
+					Description string `json:"description"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(msg.data, &ack); err != nil {
+				return fmt.Errorf("golog: invalid JetStream ack %q: %w", msg.data, err)
+			}
+			if ack.Error != nil {
+				return fmt.Errorf("%w: JetStream %d: %s", errNATSServer, ack.Error.Code, ack.Error.Description)
+			}
+			n--
+		case <-nc.done:
+			return nc.err
+		case <-timer.C:
+			return fmt.Errorf("golog: timed out waiting for %d JetStream acks", n)
+		}
+	}
+	return nil
+}
+
+// Healthy は直前の書き込みが成功したかを返します
+func (w *NATSWriter) Healthy() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.closed && w.lastErr == nil
+}
+
+// Ping はサーバーへ接続できるかを確認します。接続が無い場合は接続し直します。
+func (w *NATSWriter) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	if err := w.ensureConnLocked(); err != nil {
+		return err
+	}
+	return w.lastErr
+}
+
+// Close は接続を閉じます
+func (w *NATSWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.disconnectLocked()
+	return nil
+}
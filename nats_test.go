@@ -0,0 +1,199 @@
+package loggo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// natsServer はテスト用の最小限の NATS サーバー。PUB されたメッセージを messages に送ります。
+type natsServer struct {
+	ln       net.Listener
+	messages chan string
+	connects chan string
+
+	mu       sync.Mutex
+	ackError string // 空でない場合は JetStream の ack の代わりにエラーを返す
+	conns    []net.Conn
+}
+
+// newNATSServer は 127.0.0.1 で待ち受けるサーバーを開始します。jetStream が true の場合は ack を返します。
+func newNATSServer(t *testing.T, jetStream bool) *natsServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &natsServer{ln: ln, messages: make(chan string, 16), connects: make(chan string, 4)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn, jetStream)
+		}
+	}()
+	return s
+}
+
+// closeConns はすべての接続をサーバー側から閉じます
+func (s *natsServer) closeConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+func (s *natsServer) serve(conn net.Conn, jetStream bool) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"test\"}\r\n")
+	sid, seq := "", 0
+	for {
+		line, err := readNATSLine(r)
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "CONNECT":
+			s.connects <- strings.TrimPrefix(line, "CONNECT ")
+		case "PING":
+			io.WriteString(conn, "PONG\r\n")
+		case "SUB":
+			sid = fields[len(fields)-1]
+		case "PUB":
+			n, _ := strconv.Atoi(fields[len(fields)-1])
+			data := make([]byte, n+2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return
+			}
+			s.messages <- fields[1] + " " + string(data[:n])
+			if jetStream && len(fields) == 4 {
+				s.mu.Lock()
+				ack := fmt.Sprintf(`{"stream":"LOGS","seq":%d}`, seq+1)
+				if s.ackError != "" {
+					ack = `{"error":{"code":503,"description":"` + s.ackError + `"}}`
+				}
+				s.mu.Unlock()
+				seq++
+				fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", fields[2], sid, len(ack), ack)
+			}
+		}
+	}
+}
+
+// next は次に受信した "subject payload" を返します
+func (s *natsServer) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+		return ""
+	}
+}
+
+// TestNATSWriter は各行を1つのメッセージとして発行し、認証情報を CONNECT で送ることをテストします
+func TestNATSWriter(t *testing.T) {
+	s := newNATSServer(t, false)
+	w, err := DialNATS(s.ln.Addr().String(), "logs.api", &NATSWriterOptions{Name: "api", Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if connect := <-s.connects; !strings.Contains(connect, `"auth_token":"secret"`) || !strings.Contains(connect, `"name":"api"`) {
+		t.Errorf("CONNECT = %s", connect)
+	}
+	if _, err := w.Write([]byte("first\nsecond\n")); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"logs.api first", "logs.api second"} {
+		if got := s.next(t); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	if !w.Healthy() || w.Ping(t.Context()) != nil {
+		t.Error("writer should be healthy")
+	}
+}
+
+// TestNATSWriterJetStream は JetStream の ack を待ち、エラーの ack を書き込みのエラーとして返すことをテストします
+func TestNATSWriterJetStream(t *testing.T) {
+	s := newNATSServer(t, true)
+	w, err := DialNATS(s.ln.Addr().String(), "logs.api", &NATSWriterOptions{JetStream: true, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	logger := slog.New(NewHandler(w, &Options{Format: FormatJSON}))
+	logger.Info("stored")
+	if got := s.next(t); !strings.Contains(got, `"msg":"stored"`) {
+		t.Errorf("got %q", got)
+	}
+	if !w.Healthy() {
+		t.Error("writer should be healthy")
+	}
+
+	s.mu.Lock()
+	s.ackError = "no stream"
+	s.mu.Unlock()
+	_, err = w.Write([]byte("rejected\n"))
+	if !errors.Is(err, errNATSServer) || !strings.Contains(err.Error(), "no stream") {
+		t.Errorf("Write = %v, want JetStream error", err)
+	}
+	if w.Healthy() {
+		t.Error("writer should be unhealthy")
+	}
+}
+
+// TestNATSWriterReconnect はサーバーが接続を閉じた後に接続し直して発行することをテストします
+func TestNATSWriterReconnect(t *testing.T) {
+	s := newNATSServer(t, true)
+	w, err := DialNATS(s.ln.Addr().String(), "logs", &NATSWriterOptions{JetStream: true, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	<-s.connects
+
+	s.closeConns()
+	if _, err := w.Write([]byte("after reconnect\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.next(t); got != "logs after reconnect" {
+		t.Errorf("got %q", got)
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte("closed\n")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write after Close = %v, want ErrClosed", err)
+	}
+}
+
+// TestDialNATSInvalidSubject は不正なサブジェクトを拒否することをテストします
+func TestDialNATSInvalidSubject(t *testing.T) {
+	for _, subject := range []string{"", "a b"} {
+		if _, err := DialNATS("127.0.0.1:1", subject, nil); err == nil {
+			t.Errorf("DialNATS(%q) should fail", subject)
+		}
+	}
+}
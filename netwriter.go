@@ -0,0 +1,290 @@
+package loggo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultNetTimeout は NetWriterOptions.DialTimeout と WriteTimeout のデフォルト値
+	defaultNetTimeout = 5 * time.Second
+	// defaultNetMinBackoff は NetWriterOptions.MinBackoff のデフォルト値
+	defaultNetMinBackoff = 100 * time.Millisecond
+	// defaultNetMaxBackoff は NetWriterOptions.MaxBackoff のデフォルト値
+	defaultNetMaxBackoff = 30 * time.Second
+	// defaultNetMaxBufferBytes は NetWriterOptions.MaxBufferBytes のデフォルト値
+	defaultNetMaxBufferBytes = 1 << 20
+)
+
+// NetWriterOptions は NetWriter のオプション
+type NetWriterOptions struct {
+	// DialTimeout は接続のタイムアウト。0 の場合は 5 秒です。
+	DialTimeout time.Duration
+	// WriteTimeout は書き込みのタイムアウト。0 の場合は 5 秒です。
+	WriteTimeout time.Duration
+	// MinBackoff と MaxBackoff は再接続を試みる間隔の最小値と最大値。失敗するたびに間隔を2倍にします。
+	// 0 の場合はそれぞれ 100 ミリ秒と 30 秒です。
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxBufferBytes は切断中にバッファする最大のバイト数。超えた行は破棄して OnError に通知します。
+	// 0 の場合は 1MB です。
+	MaxBufferBytes int
+	// OnError は接続や書き込みのエラーと、切断中に破棄した行の数を受け取ります
+	OnError func(err error)
+}
+
+// NetWriter は TCP または UDP のエンドポイント（Logstash や rsyslog の入力など）へ行を送信するライター。
+// 接続が切れた場合はバックグラウンドで間隔を広げながら再接続し、その間の行を MaxBufferBytes までバッファして
+// 再接続後に送信します。切断中の Write はバッファに追加するだけでエラーを返しません。
+//
+// UDP では1行を1つのデータグラムとして送信します。TCP で書き込みの途中で切断された場合、
+// その書き込みは再接続後にすべて再送するため、受信側で重複することがあります。
+type NetWriter struct {
+	network      string
+	addr         string
+	udp          bool
+	dialTimeout  time.Duration
+	writeTimeout time.Duration
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	maxBuffer    int
+	onError      func(err error)
+
+	mu           sync.Mutex
+	conn         net.Conn
+	pending      []byte // 切断中にバッファした行
+	dropped      int    // 切断中に破棄した行の数
+	reconnecting bool
+	closed       bool
+	lastErr      error
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewNetWriter は network（"tcp", "udp" など）の addr へ送信するライターを作成します。
+// 最初の接続に失敗した場合もエラーを返さず、バックグラウンドで再接続を試みます。
+//
+//	w := golog.NewNetWriter("tcp", "logstash:5000", &golog.NetWriterOptions{
+//	    OnError: func(err error) { fmt.Fprintln(os.Stderr, err) },
+//	})
+//	handler := golog.NewHandler(w, &golog.Options{Format: golog.FormatJSON})
+func NewNetWriter(network, addr string, opts *NetWriterOptions) *NetWriter {
+	w := &NetWriter{
+		network:      network,
+		addr:         addr,
+		udp:          network == "udp" || network == "udp4" || network == "udp6" || network == "unixgram",
+		dialTimeout:  defaultNetTimeout,
+		writeTimeout: defaultNetTimeout,
+		minBackoff:   defaultNetMinBackoff,
+		maxBackoff:   defaultNetMaxBackoff,
+		maxBuffer:    defaultNetMaxBufferBytes,
+		stop:         make(chan struct{}),
+	}
+	if opts != nil {
+		if opts.DialTimeout > 0 {
+			w.dialTimeout = opts.DialTimeout
+		}
+		if opts.WriteTimeout > 0 {
+			w.writeTimeout = opts.WriteTimeout
+		}
+		if opts.MinBackoff > 0 {
+			w.minBackoff = opts.MinBackoff
+		}
+		if opts.MaxBackoff > 0 {
+			w.maxBackoff = opts.MaxBackoff
+		}
+		if opts.MaxBufferBytes > 0 {
+			w.maxBuffer = opts.MaxBufferBytes
+		}
+		w.onError = opts.OnError
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	conn, err := net.DialTimeout(w.network, w.addr, w.dialTimeout)
+	if err != nil {
+		w.lastErr = err
+		w.reportError(err)
+		w.startReconnectLocked()
+	} else {
+		w.conn = conn
+	}
+	return w
+}
+
+// Write は p を送信します。切断中や送信に失敗した場合は p をバッファし、再接続後に送信します。
+func (w *NetWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if w.conn != nil {
+		err := w.sendLocked(p)
+		if err == nil {
+			return len(p), nil
+		}
+		w.disconnectLocked(err)
+	}
+	w.bufferLocked(p)
+	return len(p), nil
+}
+
+// sendLocked は接続へ p を書き込みます。UDP では行ごとに書き込みます。mu を保持して呼び出します。
+func (w *NetWriter) sendLocked(p []byte) error {
+	w.conn.SetWriteDeadline(time.Now().Add(w.writeTimeout))
+	if !w.udp {
+		_, err := w.conn.Write(p)
+		return err
+	}
+	for line := range bytes.Lines(p) {
+		if _, err := w.conn.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bufferLocked は p をバッファに追加します。MaxBufferBytes を超える場合は破棄して数えます。mu を保持して呼び出します。
+func (w *NetWriter) bufferLocked(p []byte) {
+	if len(w.pending)+len(p) > w.maxBuffer {
+		if w.dropped == 0 {
+			w.reportError(fmt.Errorf("golog: buffer for %s is full, dropping records until reconnected", w.addr))
+		}
+		w.dropped += max(bytes.Count(p, []byte{'\n'}), 1)
+		return
+	}
+	w.pending = append(w.pending, p...)
+}
+
+// disconnectLocked は接続を閉じて再接続を開始します。mu を保持して呼び出します。
+func (w *NetWriter) disconnectLocked(err error) {
+	w.conn.Close()
+	w.conn = nil
+	w.lastErr = err
+	w.reportError(err)
+	w.startReconnectLocked()
+}
+
+// startReconnectLocked は再接続のゴルーチンを開始します。mu を保持して呼び出します。
+func (w *NetWriter) startReconnectLocked() {
+	if w.reconnecting || w.closed {
+		return
+	}
+	w.reconnecting = true
+	w.wg.Add(1)
+	go w.reconnect()
+}
+
+// reconnect は接続できるまで間隔を2倍にしながら再接続を試み、接続後にバッファした行を送信します
+func (w *NetWriter) reconnect() {
+	defer w.wg.Done()
+	backoff := w.minBackoff
+	for {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-w.stop:
+			timer.Stop()
+			return
+		}
+		backoff = min(backoff*2, w.maxBackoff)
+
+		conn, err := net.DialTimeout(w.network, w.addr, w.dialTimeout)
+		w.mu.Lock()
+		if w.closed {
+			w.mu.Unlock()
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+		if err != nil {
+			w.lastErr = err
+			w.mu.Unlock()
+			continue
+		}
+		w.conn = conn
+		if len(w.pending) > 0 {
+			if err := w.sendLocked(w.pending); err != nil {
+				conn.Close()
+				w.conn = nil
+				w.lastErr = err
+				w.mu.Unlock()
+				continue
+			}
+		}
+		if cap(w.pending) > maxRetainedBatchSize {
+			w.pending = nil
+		} else {
+			w.pending = w.pending[:0]
+		}
+		if w.dropped > 0 {
+			w.reportError(fmt.Errorf("golog: dropped %d records while disconnected from %s", w.dropped, w.addr))
+			w.dropped = 0
+		}
+		w.lastErr = nil
+		w.reconnecting = false
+		w.mu.Unlock()
+		return
+	}
+}
+
+// reportError は呼び出し元へ返せないエラーを OnError に渡します
+func (w *NetWriter) reportError(err error) {
+	if w.onError != nil {
+		w.onError(err)
+	}
+}
+
+// Healthy は接続しているかを返します
+func (w *NetWriter) Healthy() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.closed && w.conn != nil
+}
+
+// Ping は接続していない場合に直前のエラーを返します
+func (w *NetWriter) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	if w.conn == nil {
+		return fmt.Errorf("golog: not connected to %s (%d bytes buffered): %w", w.addr, len(w.pending), w.lastErr)
+	}
+	return nil
+}
+
+// Close は再接続を停止して接続を閉じます。切断中にバッファした行は破棄し、その数をエラーとして返します。
+func (w *NetWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.stop)
+	var err error
+	if w.conn != nil {
+		err = w.conn.Close()
+		w.conn = nil
+	}
+	if n := bytes.Count(w.pending, []byte{'\n'}) + w.dropped; n > 0 {
+		err = errors.Join(err, fmt.Errorf("golog: %d records to %s were not delivered", n, w.addr))
+	}
+	w.mu.Unlock()
+	w.wg.Wait()
+	return err
+}
@@ -0,0 +1,166 @@
+package loggo
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// freeAddr は使われていない TCP のアドレスを返します
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+// acceptLines は ln で最初の接続を受け付け、受信した行を返すチャネルを返します
+func acceptLines(t *testing.T, ln net.Listener) <-chan string {
+	t.Helper()
+	lines := make(chan string, 16)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s := bufio.NewScanner(conn)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+	}()
+	return lines
+}
+
+// nextLine は次に受信した行を返します
+func nextLine(t *testing.T, lines <-chan string) string {
+	t.Helper()
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for line")
+		return ""
+	}
+}
+
+// TestNetWriterTCP は TCP で行を送信することをテストします
+func TestNetWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := acceptLines(t, ln)
+
+	w := NewNetWriter("tcp", ln.Addr().String(), nil)
+	defer w.Close()
+	if _, err := w.Write([]byte("hello\nworld\n")); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"hello", "world"} {
+		if got := nextLine(t, lines); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	if !w.Healthy() || w.Ping(t.Context()) != nil {
+		t.Error("writer should be healthy")
+	}
+}
+
+// TestNetWriterReconnect は接続できない間の行をバッファし、接続後に送信して破棄した数を通知することをテストします
+func TestNetWriterReconnect(t *testing.T) {
+	addr := freeAddr(t)
+	var (
+		mu   sync.Mutex
+		errs []string
+	)
+	w := NewNetWriter("tcp", addr, &NetWriterOptions{
+		MinBackoff:     5 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+		MaxBufferBytes: 16,
+		OnError: func(err error) {
+			mu.Lock()
+			errs = append(errs, err.Error())
+			mu.Unlock()
+		},
+	})
+	defer w.Close()
+
+	if w.Healthy() || w.Ping(t.Context()) == nil {
+		t.Error("writer should be unhealthy before connecting")
+	}
+	for _, line := range []string{"line-1\n", "line-2\n", "line-3\n"} { // 3 行目はバッファに収まらない
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("address was taken: %v", err)
+	}
+	defer ln.Close()
+	lines := acceptLines(t, ln)
+	for _, want := range []string{"line-1", "line-2"} {
+		if got := nextLine(t, lines); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	w.Write([]byte("line-4\n"))
+	if got := nextLine(t, lines); got != "line-4" {
+		t.Errorf("got %q, want line-4", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	joined := strings.Join(errs, "\n")
+	if !strings.Contains(joined, "dropping records") || !strings.Contains(joined, "dropped 1 records") {
+		t.Errorf("errors = %s", joined)
+	}
+}
+
+// TestNetWriterUDP は UDP で1行を1つのデータグラムとして送信することをテストします
+func TestNetWriterUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	w := NewNetWriter("udp", pc.LocalAddr().String(), nil)
+	defer w.Close()
+	w.Write([]byte("first\nsecond\n"))
+
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	for _, want := range []string{"first\n", "second\n"} {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf[:n]); got != want {
+			t.Errorf("datagram = %q, want %q", got, want)
+		}
+	}
+}
+
+// TestNetWriterClose はクローズ時に未送信の行をエラーとして報告することをテストします
+func TestNetWriterClose(t *testing.T) {
+	w := NewNetWriter("tcp", freeAddr(t), &NetWriterOptions{MinBackoff: time.Hour})
+	w.Write([]byte("lost\n"))
+	if err := w.Close(); err == nil || !strings.Contains(err.Error(), "1 records") {
+		t.Errorf("Close = %v", err)
+	}
+	if _, err := w.Write([]byte("late\n")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write after Close = %v, want ErrClosed", err)
+	}
+}
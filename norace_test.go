@@ -0,0 +1,5 @@
+//go:build !race
+
+package loggo
+
+const raceEnabled = false
@@ -0,0 +1,399 @@
+package loggo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// defaultNotifyInterval は NotifierOptions.MinInterval のデフォルト値
+	defaultNotifyInterval = 10 * time.Second
+	// defaultNotifyDedupeWindow は NotifierOptions.DedupeWindow のデフォルト値
+	defaultNotifyDedupeWindow = 5 * time.Minute
+	// defaultNotifyTimeout は NotifierOptions.Client が nil の場合の送信のタイムアウト
+	defaultNotifyTimeout = 10 * time.Second
+	// maxNotifyAttrs は NotifierOptions.Attrs が nil の場合に含める属性の最大数
+	maxNotifyAttrs = 10
+	// maxNotifyValueLen は通知に含める属性の値の最大のバイト数
+	maxNotifyValueLen = 200
+	// notifyQueueSize は送信を待つ通知の最大数。超えた通知は破棄します。
+	notifyQueueSize = 16
+	// maxSlackHeaderLen は Slack の header ブロックのテキストの最大文字数
+	maxSlackHeaderLen = 150
+	// maxDiscordTitleLen は Discord の embed のタイトルの最大文字数
+	maxDiscordTitleLen = 256
+	// maxTeamsTitleLen は Teams の MessageCard のタイトルと概要の最大文字数。
+	// 上限は公開されていないため、通知の一覧で読める長さとして Slack に合わせます。
+	maxTeamsTitleLen = maxSlackHeaderLen
+)
+
+// ChatService は通知を送るチャットサービスの種類。Webhook のペイロードの形式が決まります。
+type ChatService int
+
+const (
+	// ChatSlack は Slack の Incoming Webhook（Block Kit）です
+	ChatSlack ChatService = iota
+	// ChatDiscord は Discord の Webhook（Embed）です
+	ChatDiscord
+	// ChatTeams は Microsoft Teams の Incoming Webhook（MessageCard）です
+	ChatTeams
+)
+
+// chatServiceNames は ChatService のテキスト表現
+var chatServiceNames = []string{
+	ChatSlack:   "slack",
+	ChatDiscord: "discord",
+	ChatTeams:   "teams",
+}
+
+// String はサービスの名前を返します
+func (s ChatService) String() string {
+	if s >= 0 && int(s) < len(chatServiceNames) {
+		return chatServiceNames[s]
+	}
+	return "ChatService(" + strconv.Itoa(int(s)) + ")"
+}
+
+// MarshalText はサービスの名前を返します
+func (s ChatService) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText は "slack", "discord", "teams" を解析します
+func (s *ChatService) UnmarshalText(text []byte) error {
+	i, err := parseEnumName("chat service", chatServiceNames, string(text))
+	if err != nil {
+		return err
+	}
+	*s = ChatService(i)
+	return nil
+}
+
+// NotifierOptions は Notifier のオプション
+type NotifierOptions struct {
+	// Service はペイロードの形式
+	Service ChatService
+	// Attrs は通知に含める属性のキー（グループの属性は "." で連結したキー）。
+	// nil の場合は先頭から最大 10 個の属性を含めます。
+	Attrs []string
+	// MinInterval は通知を送る最小の間隔。間隔内のレコードは送らずに数え、次の通知に件数を含めます。
+	// 0 の場合は 10 秒です。
+	MinInterval time.Duration
+	// DedupeWindow はレベルとメッセージが同じレコードを重複として送らない期間。0 の場合は 5 分です。
+	DedupeWindow time.Duration
+	// Client は送信に使うクライアント。nil の場合はタイムアウトが 10 秒のクライアントです。
+	Client *http.Client
+	// OnError は送信のエラーを受け取ります
+	OnError func(err error)
+}
+
+// Notifier は重大なレコードをチャットの Webhook へ通知します。
+// メッセージ、主要な属性、ホスト名をサービスごとのリッチな形式で送ります。
+// 送信はバックグラウンドのゴルーチンで行うため、レコードの記録は待たされません。
+//
+//	notifier := golog.NewNotifier(webhookURL, &golog.NotifierOptions{Service: golog.ChatSlack})
+//	defer notifier.Close()
+//	handler := golog.NewHandler(os.Stdout, &golog.Options{
+//	    OnRecord: []golog.RecordCallback{notifier.Callback(slog.LevelError)},
+//	})
+type Notifier struct {
+	url          string
+	service      ChatService
+	attrs        []string
+	minInterval  time.Duration
+	dedupeWindow time.Duration
+	client       *http.Client
+	onError      func(err error)
+	hostname     string
+
+	mu         sync.Mutex
+	lastSent   time.Time
+	suppressed int                  // MinInterval のために送らなかったレコードの数
+	seen       map[string]time.Time // レベルとメッセージごとの最後に通知した時刻
+	closed     bool                 // Close の後は通知を受け付けない
+
+	queue chan []byte
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewNotifier は url の Webhook へ通知する Notifier を作成します
+func NewNotifier(url string, opts *NotifierOptions) *Notifier {
+	n := &Notifier{
+		url:          url,
+		minInterval:  defaultNotifyInterval,
+		dedupeWindow: defaultNotifyDedupeWindow,
+		client:       &http.Client{Timeout: defaultNotifyTimeout},
+		seen:         make(map[string]time.Time),
+		queue:        make(chan []byte, notifyQueueSize),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	if opts != nil {
+		n.service = opts.Service
+		n.attrs = opts.Attrs
+		if opts.MinInterval > 0 {
+			n.minInterval = opts.MinInterval
+		}
+		if opts.DedupeWindow > 0 {
+			n.dedupeWindow = opts.DedupeWindow
+		}
+		if opts.Client != nil {
+			n.client = opts.Client
+		}
+		n.onError = opts.OnError
+	}
+	n.hostname, _ = os.Hostname()
+	go n.loop()
+	return n
+}
+
+// Callback は level 以上のレコードを通知する Options.OnRecord 用のコールバックを返します
+func (n *Notifier) Callback(level slog.Leveler) RecordCallback {
+	return RecordCallback{Level: level, Func: n.Notify}
+}
+
+// Notify はレコードを通知します。MinInterval と DedupeWindow で抑制された場合や、
+// 送信待ちの通知が多すぎる場合は送りません。Close の後の通知は破棄して OnError に通知します。
+func (n *Notifier) Notify(ctx context.Context, r slog.Record) {
+	now := time.Now()
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		n.reportError(errNotifierClosed)
+		return
+	}
+	key := r.Level.String() + "\x00" + r.Message
+	if last, ok := n.seen[key]; ok && now.Sub(last) < n.dedupeWindow {
+		n.mu.Unlock()
+		return
+	}
+	if now.Sub(n.lastSent) < n.minInterval {
+		n.suppressed++
+		n.mu.Unlock()
+		return
+	}
+	suppressed := n.suppressed
+	n.suppressed = 0
+	n.lastSent = now
+	n.seen[key] = now
+	for k, t := range n.seen {
+		if now.Sub(t) >= n.dedupeWindow {
+			delete(n.seen, k)
+		}
+	}
+	n.mu.Unlock()
+
+	payload, err := json.Marshal(n.payload(r, n.collectAttrs(r), suppressed))
+	if err != nil {
+		n.reportError(err)
+		return
+	}
+	// Close が停止を通知した後にキューへ追加しないよう、closed の確認と追加は mu を保持して行う
+	n.mu.Lock()
+	closed := n.closed
+	queued := false
+	if !closed {
+		select {
+		case n.queue <- payload:
+			queued = true
+		default:
+		}
+	}
+	n.mu.Unlock()
+	switch {
+	case closed:
+		n.reportError(errNotifierClosed)
+	case !queued:
+		n.reportError(fmt.Errorf("golog: notification dropped: queue is full"))
+	}
+}
+
+// errNotifierClosed は Close の後に Notify を呼び出した場合に OnError に渡すエラー
+var errNotifierClosed = fmt.Errorf("golog: notification dropped: %w", ErrClosed)
+
+// notifyField は通知に含める属性
+type notifyField struct {
+	Key, Value string
+}
+
+// collectAttrs は通知に含める属性を集めます
+func (n *Notifier) collectAttrs(r slog.Record) []notifyField {
+	var all []notifyField
+	var walk func(prefix string, a slog.Attr)
+	walk = func(prefix string, a slog.Attr) {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindGroup {
+			if a.Key != "" {
+				prefix += a.Key + "."
+			}
+			for _, member := range a.Value.Group() {
+				walk(prefix, member)
+			}
+			return
+		}
+		if a.Key == "" {
+			return
+		}
+		v := a.Value.String()
+		if len(v) > maxNotifyValueLen {
+			keep := maxNotifyValueLen
+			for keep > 0 && !utf8.RuneStart(v[keep]) {
+				keep--
+			}
+			v = v[:keep] + messageEllipsis
+		}
+		all = append(all, notifyField{prefix + a.Key, v})
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		walk("", a)
+		return true
+	})
+
+	if n.attrs == nil {
+		return all[:min(len(all), maxNotifyAttrs)]
+	}
+	fields := make([]notifyField, 0, len(n.attrs))
+	for _, key := range n.attrs {
+		for _, f := range all {
+			if f.Key == key {
+				fields = append(fields, f)
+				break
+			}
+		}
+	}
+	return fields
+}
+
+// payload はサービスごとの Webhook のペイロードを作成します
+func (n *Notifier) payload(r slog.Record, fields []notifyField, suppressed int) any {
+	title := r.Level.String() + ": " + r.Message
+	footer := "host: " + n.hostname
+	if suppressed > 0 {
+		footer += " (" + strconv.Itoa(suppressed) + " more suppressed)"
+	}
+	switch n.service {
+	case ChatDiscord:
+		embedFields := make([]map[string]any, len(fields))
+		for i, f := range fields {
+			embedFields[i] = map[string]any{"name": f.Key, "value": f.Value, "inline": true}
+		}
+		return map[string]any{"embeds": []map[string]any{{
+			"title":     truncateChars(title, maxDiscordTitleLen),
+			"color":     0xE01E5A,
+			"fields":    embedFields,
+			"footer":    map[string]any{"text": footer},
+			"timestamp": r.Time.Format(time.RFC3339),
+		}}}
+	case ChatTeams:
+		facts := make([]map[string]any, len(fields))
+		for i, f := range fields {
+			facts[i] = map[string]any{"name": f.Key, "value": f.Value}
+		}
+		return map[string]any{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    truncateChars(title, maxTeamsTitleLen),
+			"themeColor": "E01E5A",
+			"title":      truncateChars(title, maxTeamsTitleLen),
+			"sections":   []map[string]any{{"facts": facts, "text": footer}},
+		}
+	default:
+		blocks := []map[string]any{{
+			"type": "header",
+			"text": map[string]any{"type": "plain_text", "text": truncateChars(title, maxSlackHeaderLen)},
+		}}
+		if len(fields) > 0 {
+			sectionFields := make([]map[string]any, len(fields))
+			for i, f := range fields {
+				sectionFields[i] = map[string]any{"type": "mrkdwn", "text": "*" + f.Key + "*\n" + f.Value}
+			}
+			blocks = append(blocks, map[string]any{"type": "section", "fields": sectionFields})
+		}
+		blocks = append(blocks, map[string]any{
+			"type":     "context",
+			"elements": []map[string]any{{"type": "mrkdwn", "text": footer + " | " + r.Time.Format(time.RFC3339)}},
+		})
+		return map[string]any{"text": title, "blocks": blocks}
+	}
+}
+
+// truncateChars は s が n 文字を超える場合に、末尾の messageEllipsis を含めて n 文字に切り詰めます。
+// チャットサービスの上限を超えるとメッセージ全体が拒否されるため、タイトルに使います。
+func truncateChars(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	keep := n - utf8.RuneCountInString(messageEllipsis)
+	for i := range s {
+		if keep == 0 {
+			return s[:i] + messageEllipsis
+		}
+		keep--
+	}
+	return s
+}
+
+// loop は送信待ちの通知を送信します
+func (n *Notifier) loop() {
+	defer close(n.done)
+	for {
+		select {
+		case payload := <-n.queue:
+			n.send(payload)
+		case <-n.stop:
+			for {
+				select {
+				case payload := <-n.queue:
+					n.send(payload)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send は通知を1件送信します
+func (n *Notifier) send(payload []byte) {
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		n.reportError(err)
+		return
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		n.reportError(fmt.Errorf("golog: notification webhook returned %s", resp.Status))
+	}
+}
+
+// reportError は送信のエラーを OnError に渡します
+func (n *Notifier) reportError(err error) {
+	if n.onError != nil {
+		n.onError(err)
+	}
+}
+
+// Close は送信待ちの通知を送信してバックグラウンドのゴルーチンを停止します
+func (n *Notifier) Close() error {
+	n.once.Do(func() {
+		n.mu.Lock()
+		n.closed = true
+		n.mu.Unlock()
+		close(n.stop)
+	})
+	<-n.done
+	return nil
+}
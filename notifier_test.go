@@ -0,0 +1,169 @@
+package loggo
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// newNotifyServer は受け取ったペイロードを記録するサーバーを開始します
+func newNotifyServer(t *testing.T) (*httptest.Server, func() []map[string]any) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		payloads []map[string]any
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		var payload map[string]any
+		if err := json.Unmarshal(b, &payload); err != nil {
+			t.Errorf("invalid payload %q: %v", b, err)
+		}
+		mu.Lock()
+		payloads = append(payloads, payload)
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]map[string]any(nil), payloads...)
+	}
+}
+
+// TestNotifier は ERROR 以上のレコードを Slack の形式で通知し、属性を含めることをテストします
+func TestNotifier(t *testing.T) {
+	srv, payloads := newNotifyServer(t)
+	n := NewNotifier(srv.URL, &NotifierOptions{Attrs: []string{"user", "req.path"}})
+	logger := slog.New(NewHandler(io.Discard, &Options{
+		OnRecord: []RecordCallback{n.Callback(slog.LevelError)},
+	}))
+	logger.Info("ignored")
+	logger.Error("payment failed", "user", "alice", "other", 1, slog.Group("req", "path", "/pay"))
+	n.Close()
+
+	got := payloads()
+	if len(got) != 1 {
+		t.Fatalf("payloads = %d, want 1", len(got))
+	}
+	b, _ := json.Marshal(got[0])
+	s := string(b)
+	for _, want := range []string{`"text":"ERROR: payment failed"`, `*user*\nalice`, `*req.path*\n/pay`, `host: `} {
+		if !strings.Contains(s, want) {
+			t.Errorf("payload does not contain %q: %s", want, s)
+		}
+	}
+	if strings.Contains(s, "other") {
+		t.Errorf("payload should contain only the configured attrs: %s", s)
+	}
+}
+
+// TestNotifierRateLimit は重複したレコードを送らず、間隔内のレコードを次の通知で件数として報告することをテストします
+func TestNotifierRateLimit(t *testing.T) {
+	srv, payloads := newNotifyServer(t)
+	n := NewNotifier(srv.URL, &NotifierOptions{Service: ChatDiscord, MinInterval: 50 * time.Millisecond})
+	now := time.Now()
+	n.Notify(t.Context(), slog.NewRecord(now, slog.LevelError, "a", 0))
+	n.Notify(t.Context(), slog.NewRecord(now, slog.LevelError, "a", 0)) // 重複
+	n.Notify(t.Context(), slog.NewRecord(now, slog.LevelError, "b", 0)) // 間隔内
+	n.Notify(t.Context(), slog.NewRecord(now, slog.LevelError, "c", 0)) // 間隔内
+	time.Sleep(60 * time.Millisecond)
+	n.Notify(t.Context(), slog.NewRecord(now, slog.LevelError, "a", 0)) // 重複
+	n.Notify(t.Context(), slog.NewRecord(now, slog.LevelError, "d", 0))
+	n.Close()
+
+	got := payloads()
+	if len(got) != 2 {
+		t.Fatalf("payloads = %d, want 2", len(got))
+	}
+	embed := got[1]["embeds"].([]any)[0].(map[string]any)
+	if embed["title"] != "ERROR: d" {
+		t.Errorf("title = %v", embed["title"])
+	}
+	if footer := embed["footer"].(map[string]any)["text"].(string); !strings.Contains(footer, "(2 more suppressed)") {
+		t.Errorf("footer = %q", footer)
+	}
+}
+
+// TestNotifierTeams は Teams の MessageCard の形式をテストします
+func TestNotifierTeams(t *testing.T) {
+	n := &Notifier{service: ChatTeams, hostname: "web1"}
+	r := slog.NewRecord(time.Now(), slog.LevelError, "boom", 0)
+	r.AddAttrs(slog.String("long", strings.Repeat("あ", 100)))
+	p := n.payload(r, n.collectAttrs(r), 0).(map[string]any)
+	if p["@type"] != "MessageCard" || p["title"] != "ERROR: boom" {
+		t.Errorf("unexpected payload: %v", p)
+	}
+	facts := p["sections"].([]map[string]any)[0]["facts"].([]map[string]any)
+	value := facts[0]["value"].(string)
+	if !strings.HasSuffix(value, messageEllipsis) || len(value) > maxNotifyValueLen+len(messageEllipsis) {
+		t.Errorf("long value was not truncated: %d bytes", len(value))
+	}
+}
+
+// TestNotifierTitleLimit は長いメッセージのタイトルがサービスごとの上限の文字数に切り詰められることをテストします
+func TestNotifierTitleLimit(t *testing.T) {
+	r := slog.NewRecord(time.Now(), slog.LevelError, strings.Repeat("あ", 300), 0)
+
+	slack := (&Notifier{service: ChatSlack}).payload(r, nil, 0).(map[string]any)
+	header := slack["blocks"].([]map[string]any)[0]["text"].(map[string]any)["text"].(string)
+	if n := utf8.RuneCountInString(header); n != maxSlackHeaderLen || !strings.HasSuffix(header, messageEllipsis) {
+		t.Errorf("Slack header has %d characters: %q", n, header)
+	}
+
+	discord := (&Notifier{service: ChatDiscord}).payload(r, nil, 0).(map[string]any)
+	title := discord["embeds"].([]map[string]any)[0]["title"].(string)
+	if n := utf8.RuneCountInString(title); n != maxDiscordTitleLen || !strings.HasSuffix(title, messageEllipsis) {
+		t.Errorf("Discord title has %d characters: %q", n, title)
+	}
+
+	teams := (&Notifier{service: ChatTeams}).payload(r, nil, 0).(map[string]any)
+	for _, key := range []string{"title", "summary"} {
+		v := teams[key].(string)
+		if n := utf8.RuneCountInString(v); n != maxTeamsTitleLen || !strings.HasSuffix(v, messageEllipsis) {
+			t.Errorf("Teams %s has %d characters: %q", key, n, v)
+		}
+	}
+
+	if got := truncateChars("short", maxSlackHeaderLen); got != "short" {
+		t.Errorf("short title should not be truncated, got %q", got)
+	}
+}
+
+// TestNotifierClosed は Close の後の通知を送らず、OnError に ErrClosed を渡すことをテストします
+func TestNotifierClosed(t *testing.T) {
+	srv, payloads := newNotifyServer(t)
+	var errs []error
+	n := NewNotifier(srv.URL, &NotifierOptions{OnError: func(err error) { errs = append(errs, err) }})
+	n.Close()
+	n.Notify(t.Context(), slog.NewRecord(time.Now(), slog.LevelError, "late", 0))
+
+	if got := payloads(); len(got) != 0 {
+		t.Errorf("expected no payloads after Close, got %v", got)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrClosed) {
+		t.Errorf("expected ErrClosed to be reported, got %v", errs)
+	}
+}
+
+// TestChatServiceText は ChatService のテキスト表現をテストします
+func TestChatServiceText(t *testing.T) {
+	var s ChatService
+	if err := s.UnmarshalText([]byte("Teams")); err != nil || s != ChatTeams {
+		t.Errorf("UnmarshalText = %v, %v", s, err)
+	}
+	if err := s.UnmarshalText([]byte("irc")); err == nil {
+		t.Error("expected error for unknown service")
+	}
+	if ChatDiscord.String() != "discord" {
+		t.Errorf("String() = %q", ChatDiscord.String())
+	}
+}
@@ -0,0 +1,123 @@
+package loggo
+
+import (
+	"container/list"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// onceKey はログを抑制する単位（呼び出し位置とメッセージ）
+type onceKey struct {
+	pc  uintptr
+	msg string
+}
+
+// maxOnceKeys は onceSeen に保持する呼び出し位置とメッセージの最大数。
+// メッセージに可変の値を含めた場合でもメモリが増え続けないよう、超えた場合は最も長く使われていないものを破棄します。
+// 破棄したものは次に記録した時点で再び出力されます。
+const maxOnceKeys = 4096
+
+// onceSeen は呼び出し位置ごとに最後に出力した時刻を保持します。
+// Once(logger) を呼び出すたびにロガーを作り直しても抑制されるよう、プロセス全体で共有します。
+var onceSeen = newOnceCache(maxOnceKeys)
+
+// onceCache は onceKey ごとに最後に出力した時刻を、最大 size 件まで保持する LRU キャッシュ
+type onceCache struct {
+	mu    sync.Mutex
+	size  int
+	items map[onceKey]*list.Element // 値は *onceEntry
+	order list.List                 // 先頭ほど最近使われた
+}
+
+// onceEntry は onceCache の要素
+type onceEntry struct {
+	key  onceKey
+	last time.Time
+}
+
+func newOnceCache(size int) *onceCache {
+	return &onceCache{size: size, items: make(map[onceKey]*list.Element)}
+}
+
+// allow はレコードを出力すべきかを判定し、出力する場合は時刻を記録します。
+// interval が 0 以下の場合は、キャッシュに残っている限り二度目以降を抑制します。
+func (c *onceCache) allow(key onceKey, now time.Time, interval time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.order.MoveToFront(e)
+		entry := e.Value.(*onceEntry)
+		if interval <= 0 || now.Sub(entry.last) < interval {
+			return false
+		}
+		entry.last = now
+		return true
+	}
+
+	c.items[key] = c.order.PushFront(&onceEntry{key: key, last: now})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*onceEntry).key)
+	}
+	return true
+}
+
+// len はキャッシュに保持している件数を返します
+func (c *onceCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Once は同じ呼び出し位置とメッセージのログをプロセス中で一度だけ出力するロガーを返します。
+// 非推奨の機能の警告などに使います。記録した呼び出し位置とメッセージは最大 4096 件まで保持し、
+// それを超えて破棄したものは再び出力されることがあります。
+//
+//	golog.Once(logger).Warn("Config.Timeout は非推奨です")
+func Once(logger *slog.Logger) *slog.Logger {
+	return Every(logger, 0)
+}
+
+// Every は同じ呼び出し位置とメッセージのログを interval ごとに一度だけ出力するロガーを返します。
+// interval が 0 以下の場合は Once と同じです。
+func Every(logger *slog.Logger, interval time.Duration) *slog.Logger {
+	return slog.New(&onceHandler{next: logger.Handler(), interval: interval})
+}
+
+// onceHandler は繰り返されるレコードを抑制するハンドラー
+type onceHandler struct {
+	next     slog.Handler
+	interval time.Duration
+}
+
+// Enabled は次のハンドラーの判定を返します
+func (h *onceHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle は初回、または前回の出力から interval が経過したレコードのみを次のハンドラーに渡します
+func (h *onceHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.allow(onceKey{pc: r.PC, msg: r.Message}, r.Time) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// allow はレコードを出力すべきかを判定し、出力する場合は時刻を記録します
+func (h *onceHandler) allow(key onceKey, now time.Time) bool {
+	return onceSeen.allow(key, now, h.interval)
+}
+
+// WithAttrs は属性を追加した次のハンドラーを持つハンドラーを返します
+func (h *onceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &onceHandler{next: h.next.WithAttrs(attrs), interval: h.interval}
+}
+
+// WithGroup はグループを追加した次のハンドラーを持つハンドラーを返します
+func (h *onceHandler) WithGroup(name string) slog.Handler {
+	return &onceHandler{next: h.next.WithGroup(name), interval: h.interval}
+}
@@ -0,0 +1,81 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOnce は同じ呼び出し位置のログが一度だけ出力されることをテストします
+func TestOnce(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, nil))
+
+	for range 3 {
+		Once(logger).Warn("deprecated option", "name", "Timeout")
+	}
+	Once(logger).Warn("deprecated option", "name", "other call site")
+	for i := range 2 {
+		Once(logger.With("i", i)).Warn("another message")
+	}
+
+	output := buf.String()
+	if n := strings.Count(output, `msg="deprecated option"`); n != 2 {
+		t.Errorf("expected 2 lines (one per call site), got %d: %s", n, output)
+	}
+	if n := strings.Count(output, `msg="another message"`); n != 1 {
+		t.Errorf("expected 1 line, got %d: %s", n, output)
+	}
+	if !strings.Contains(output, `msg="another message" i=0`) {
+		t.Errorf("attributes should be passed through, got: %s", output)
+	}
+}
+
+// TestEvery は interval ごとに一度だけ出力されることをテストします
+func TestEvery(t *testing.T) {
+	h := &onceHandler{interval: time.Minute}
+	key := onceKey{pc: 1, msg: "TestEvery"}
+	start := time.Now()
+
+	steps := []struct {
+		offset time.Duration
+		want   bool
+	}{
+		{0, true},
+		{30 * time.Second, false},
+		{time.Minute, true},
+		{90 * time.Second, false},
+		{3 * time.Minute, true},
+	}
+	for _, s := range steps {
+		if got := h.allow(key, start.Add(s.offset)); got != s.want {
+			t.Errorf("at +%v: expected %v, got %v", s.offset, s.want, got)
+		}
+	}
+}
+
+// TestOnceCacheBounded は保持する件数が上限を超えず、最も長く使われていないものから破棄されることをテストします
+func TestOnceCacheBounded(t *testing.T) {
+	c := newOnceCache(3)
+	now := time.Now()
+	for i := range 3 {
+		c.allow(onceKey{pc: uintptr(i)}, now, 0)
+	}
+	// pc=0 を使うと、次に追加した時点で pc=1 が破棄される
+	if c.allow(onceKey{pc: 0}, now, 0) {
+		t.Error("cached key should be suppressed")
+	}
+	c.allow(onceKey{pc: 3}, now, 0)
+
+	if n := c.len(); n != 3 {
+		t.Errorf("expected 3 cached keys, got %d", n)
+	}
+	if c.allow(onceKey{pc: 0}, now, 0) {
+		t.Error("recently used key should not be evicted")
+	}
+	if !c.allow(onceKey{pc: 1}, now, 0) {
+		t.Error("least recently used key should be evicted and allowed again")
+	}
+}
@@ -0,0 +1,236 @@
+package loggo
+
+import (
+	"io"
+	"strconv"
+	"sync"
+)
+
+// maxRetainedBatchSize はバッチ用バッファとして再利用する最大容量
+const maxRetainedBatchSize = 256 << 10 // 256KB
+
+// WriteMode は出力先への書き込み方式
+type WriteMode int
+
+const (
+	// WriteModeBatched は並行する書き込みを1回の Write にまとめます（デフォルト）
+	WriteModeBatched WriteMode = iota
+	// WriteModeSharded は書き込みを CPU 数分のシャードに分散し、専用のゴルーチンが
+	// まとめて書き出します。多コア環境でのミューテックス競合を解消します。
+	// レコードは書き込んだ順に出力されます。使用後は Handler.Close を呼び出してください。
+	WriteModeSharded
+	// WriteModeAsync はレコードを固定長のキューに追加し、専用のゴルーチンが書き出します。
+	// 遅い出力先に Handle が引きずられません。キューが満杯の場合の動作は Options.DropPolicy で指定します。
+	// 使用後は Handler.Close を呼び出してください。
+	WriteModeAsync
+	// WriteModeSerial はレコードを容量のあるチャネルに送り、1つのゴルーチンがすべての書き込みを行います。
+	// Handle の経路に共有のミューテックスが無く、溜まったレコードは1回の Write にまとめられます。
+	// チャネルの容量は Options.QueueSize で指定し、満杯の場合は Handle が待機します。
+	// 使用後は Handler.Close を呼び出してください。
+	WriteModeSerial
+)
+
+// writeModeNames は WriteMode のテキスト表現
+var writeModeNames = []string{
+	WriteModeBatched: "batched",
+	WriteModeSharded: "sharded",
+	WriteModeAsync:   "async",
+	WriteModeSerial:  "serial",
+}
+
+// String は書き込み方式の名前を返します
+func (m WriteMode) String() string {
+	if m >= 0 && int(m) < len(writeModeNames) {
+		return writeModeNames[m]
+	}
+	return "WriteMode(" + strconv.Itoa(int(m)) + ")"
+}
+
+// MarshalText は書き込み方式の名前を返します
+func (m WriteMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText は "batched", "sharded", "async", "serial" を解析します
+func (m *WriteMode) UnmarshalText(text []byte) error {
+	i, err := parseEnumName("write mode", writeModeNames, string(text))
+	if err != nil {
+		return err
+	}
+	*m = WriteMode(i)
+	return nil
+}
+
+// output はハンドラーとそのクローン間で共有される出力先
+type output interface {
+	// write は整形済みのレコードを書き込みます。
+	// p は呼び出しから戻った後に再利用されても構いません。
+	write(p []byte) error
+	// flush は未書き込みのデータを出力先へ書き出します
+	flush() error
+	// close は未書き込みのデータを書き出し、バックグラウンド処理を停止します
+	close() error
+	// setWriter は書き込み済みのデータを現在の出力先へ書き出した後、出力先を w に置き換えます
+	setWriter(w io.Writer)
+}
+
+// outputOptions は output の作成に使う WriteMode 以外の設定
+type outputOptions struct {
+	queueSize  int
+	dropPolicy DropPolicy
+	summary    func(dropped uint64) []byte
+	noLock     bool // Options.NoLock。WriteMode より優先される
+}
+
+// newOutput は WriteMode に応じた output を作成します
+func newOutput(w io.Writer, mode WriteMode, opts outputOptions) output {
+	if opts.noLock {
+		return &directOutput{w: w}
+	}
+	switch mode {
+	case WriteModeSharded:
+		return newShardedOutput(w)
+	case WriteModeAsync:
+		return newAsyncOutput(w, opts.queueSize, opts.dropPolicy, opts.summary)
+	case WriteModeSerial:
+		return newSerialOutput(w, opts.queueSize)
+	default:
+		return newBatchOutput(w)
+	}
+}
+
+// batchOutput は並行する Handle 呼び出しの書き込みを1回の Write にまとめる output。
+//
+// 書き込み中のゴルーチン（リーダー）が存在する場合、他のゴルーチンは自分の行を
+// pending のバッチに追加し、そのバッチが書き出されるまで待機して、その書き込みエラーを返します。
+// リーダーは自分の行に続けて pending のバッチを1つだけ書き出し、残りは待機中のゴルーチンに引き継ぐため、
+// 後から来る書き込みによって1つのゴルーチンが書き出し続けることはありません。
+type batchOutput struct {
+	mu      sync.Mutex    // 以下のフィールドを保護
+	cond    sync.Cond     // バッチの書き出しの完了と、writing が false になったことを通知
+	w       io.Writer     // writing が false の間のみ置き換えられる
+	writing bool          // リーダーが書き込み中
+	pending *pendingBatch // 次に書き出すバッチ
+	spare   []byte
+}
+
+// pendingBatch はリーダーの書き込み中に追加された行のバッチ
+type pendingBatch struct {
+	buf  []byte
+	err  error
+	done bool // 書き出しが完了した
+}
+
+func newBatchOutput(w io.Writer) *batchOutput {
+	o := &batchOutput{w: w}
+	o.cond.L = &o.mu
+	return o
+}
+
+func (o *batchOutput) write(p []byte) error {
+	o.mu.Lock()
+	if !o.writing {
+		o.writing = true
+		w := o.w
+		o.mu.Unlock()
+
+		// 競合が無い場合はコピーせずにそのまま書き出す
+		_, err := w.Write(p)
+
+		o.mu.Lock()
+		o.writePending()
+		o.writing = false
+		o.cond.Broadcast()
+		o.mu.Unlock()
+		return err
+	}
+
+	b := o.pending
+	if b == nil {
+		b = &pendingBatch{buf: o.spare[:0]}
+		o.spare = nil
+		o.pending = b
+	}
+	b.buf = append(b.buf, p...)
+	for !b.done {
+		if !o.writing {
+			// リーダーが書き出しを終えたため、このゴルーチンが引き継いで自分のバッチを書き出す
+			o.writing = true
+			o.writePending()
+			o.writing = false
+			o.cond.Broadcast()
+			break
+		}
+		o.cond.Wait()
+	}
+	err := b.err
+	o.mu.Unlock()
+	return err
+}
+
+// writePending は mu と writing を保持した状態で pending のバッチを書き出します
+func (o *batchOutput) writePending() {
+	b := o.pending
+	if b == nil {
+		return
+	}
+	o.pending = nil
+	w := o.w
+	o.mu.Unlock()
+
+	_, err := w.Write(b.buf)
+
+	o.mu.Lock()
+	b.err = err
+	b.done = true
+	if cap(b.buf) <= maxRetainedBatchSize {
+		o.spare = b.buf[:0]
+	}
+	b.buf = nil
+}
+
+// wait は書き込み中のリーダーと pending のバッチが無くなるまで待機します
+func (o *batchOutput) wait() {
+	for o.writing || o.pending != nil {
+		o.cond.Wait()
+	}
+}
+
+// flush はすべてのバッチが書き出されるまで待機します
+func (o *batchOutput) flush() error {
+	o.mu.Lock()
+	o.wait()
+	o.mu.Unlock()
+	return nil
+}
+
+func (o *batchOutput) close() error {
+	return o.flush()
+}
+
+// setWriter はすべてのバッチが書き出されるのを待ってから出力先を置き換えます
+func (o *batchOutput) setWriter(w io.Writer) {
+	o.mu.Lock()
+	o.wait()
+	o.w = w
+	o.mu.Unlock()
+}
+
+// directOutput は排他制御を行わずに出力先へそのまま書き込む output。
+// Options.NoLock で、ログの記録が1つのゴルーチンからに限られる場合にだけ使用します。
+type directOutput struct {
+	w io.Writer
+}
+
+func (o *directOutput) write(p []byte) error {
+	_, err := o.w.Write(p)
+	return err
+}
+
+func (o *directOutput) flush() error { return nil }
+
+func (o *directOutput) close() error { return nil }
+
+func (o *directOutput) setWriter(w io.Writer) {
+	o.w = w
+}
@@ -0,0 +1,182 @@
+package loggo
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowWriter は書き込みごとに待機する io.Writer です
+type slowWriter struct {
+	countingWriter
+	delay time.Duration
+}
+
+func (s *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.countingWriter.Write(p)
+}
+
+// errorWriter は常にエラーを返す io.Writer です
+type errorWriter struct{}
+
+func (errorWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+// TestOutputBatching は並行書き込みが1回の Write にまとめられることをテストします
+func TestOutputBatching(t *testing.T) {
+	out := &slowWriter{delay: time.Millisecond}
+	logger := slog.New(NewHandler(out, nil))
+
+	const goroutines = 50
+	const iterations = 20
+
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for i := range iterations {
+				logger.Info("batched", "goroutine", id, "iteration", i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != goroutines*iterations {
+		t.Fatalf("expected %d lines, got %d", goroutines*iterations, len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "[") || !strings.Contains(line, `msg="batched"`) {
+			t.Fatalf("line was corrupted: %q", line)
+		}
+	}
+	if out.Writes() >= goroutines*iterations {
+		t.Errorf("expected concurrent records to be coalesced, got %d writes for %d records", out.Writes(), len(lines))
+	}
+}
+
+// TestOutputOrder は単一ゴルーチンからの書き込み順序が保持されることをテストします
+func TestOutputOrder(t *testing.T) {
+	var out countingWriter
+	o := newBatchOutput(&out)
+
+	for _, s := range []string{"a\n", "b\n", "c\n"} {
+		if err := o.write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out.String() != "a\nb\nc\n" {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+	if out.Writes() != 3 {
+		t.Errorf("uncontended writes should not be batched, got %d writes", out.Writes())
+	}
+}
+
+// TestOutputError は書き込みエラーが Handle から返されることをテストします
+func TestOutputError(t *testing.T) {
+	h := NewHandler(errorWriter{}, nil)
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "test", 0)
+	if err := h.Handle(t.Context(), r); err == nil {
+		t.Error("expected write error to be returned")
+	}
+}
+
+// blockingWriter は最初の書き込みを release が閉じられるまで止め、常に err を返す io.Writer です
+type blockingWriter struct {
+	countingWriter
+	entered chan struct{}
+	release chan struct{}
+	once    sync.Once
+	err     error
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() {
+		close(w.entered)
+		<-w.release
+	})
+	w.countingWriter.Write(p)
+	return len(p), w.err
+}
+
+// TestOutputFollowerWaits はリーダーの書き込み中に追加した行が、書き出されてから
+// その書き込みエラーと共に返されることをテストします
+func TestOutputFollowerWaits(t *testing.T) {
+	w := &blockingWriter{entered: make(chan struct{}), release: make(chan struct{}), err: errors.New("write failed")}
+	o := newBatchOutput(w)
+
+	go o.write([]byte("leader\n"))
+	<-w.entered
+
+	done := make(chan error, 1)
+	go func() { done <- o.write([]byte("follower\n")) }()
+	for {
+		o.mu.Lock()
+		queued := o.pending != nil
+		o.mu.Unlock()
+		if queued {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case <-done:
+		t.Fatal("follower returned before its line was written")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(w.release)
+	if err := <-done; err == nil || err.Error() != "write failed" {
+		t.Errorf("expected the batch's write error, got %v", err)
+	}
+	if !strings.Contains(w.String(), "follower\n") {
+		t.Errorf("follower line was not written: %q", w.String())
+	}
+}
+
+// TestSetOutput は出力先の置き換えが全てのクローンに反映され、レコードが失われないことをテストします
+func TestSetOutput(t *testing.T) {
+	for _, mode := range []WriteMode{WriteModeBatched, WriteModeSharded, WriteModeSerial} {
+		t.Run(mode.String(), func(t *testing.T) {
+			var first, second countingWriter
+			h := NewHandler(&first, &Options{WriteMode: mode})
+			defer h.Close()
+			logger := slog.New(h).With("clone", true)
+
+			const goroutines = 8
+			const iterations = 100
+			var wg sync.WaitGroup
+			for g := range goroutines {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range iterations {
+						logger.Info("swap", "goroutine", g, "iteration", i)
+					}
+				}()
+			}
+			h.SetOutput(&second)
+			wg.Wait()
+			logger.Info("after swap")
+			if err := h.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			total := strings.Count(first.String(), "\n") + strings.Count(second.String(), "\n")
+			if total != goroutines*iterations+1 {
+				t.Errorf("expected %d records across both writers, got %d", goroutines*iterations+1, total)
+			}
+			if strings.Contains(first.String(), "after swap") || !strings.Contains(second.String(), "after swap") {
+				t.Error("records after SetOutput should go to the new writer")
+			}
+		})
+	}
+}
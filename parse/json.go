@@ -0,0 +1,98 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	loggo "github.com/f0reth/golog"
+	"github.com/f0reth/golog/internal/jsonattr"
+)
+
+// ParseJSON はデフォルト設定で JSON 形式の1行を解析します
+func ParseJSON(line string) (Record, error) {
+	return defaultParser.ParseJSON(line)
+}
+
+// ParseJSON は golog または slog.JSONHandler の JSON 形式の1行を解析します。
+// 最上位の "time"、"level"、"msg" は Record の Time、Level、Message に、それ以外のキーは属性になります。
+// 時刻は Options.TimeFormat と RFC 3339 の順に解析します。
+//
+// ネストしたオブジェクトはグループとして展開するため、JSON で出力した構造体やマップの値も
+// フィールドごとの属性になります。配列は JSON の値のまま保持します。
+func (p *Parser) ParseJSON(line string) (Record, error) {
+	dec := jsonattr.NewDecoder([]byte(line))
+	var rec Record
+	for {
+		a, ok, err := dec.Next()
+		if err != nil {
+			return Record{}, jsonError(err)
+		}
+		if !ok {
+			return rec, nil
+		}
+		switch a.Key {
+		case slog.TimeKey, slog.LevelKey, slog.MessageKey:
+			if a.Value.Kind() != slog.KindString {
+				return Record{}, &SyntaxError{Offset: dec.Offset(), Msg: fmt.Sprintf("%s must be a string", a.Key)}
+			}
+			if err := p.header(&rec, a.Key, a.Value.String()); err != nil {
+				return Record{}, &SyntaxError{Offset: dec.Offset(), Msg: err.Error()}
+			}
+		default:
+			appendJSONAttrs(&rec.Attrs, nil, a)
+		}
+	}
+}
+
+// header は JSON の時刻、レベル、メッセージの値を rec に設定します
+func (p *Parser) header(rec *Record, key, value string) error {
+	switch key {
+	case slog.TimeKey:
+		t, err := time.ParseInLocation(p.timeFormat, value, p.location)
+		if err != nil {
+			if t, err = time.Parse(time.RFC3339Nano, value); err != nil {
+				return fmt.Errorf("invalid time %q", value)
+			}
+		}
+		rec.Time = t
+	case slog.LevelKey:
+		level, err := loggo.ParseLevel(value)
+		if err != nil {
+			return fmt.Errorf("invalid level %q", value)
+		}
+		rec.Level = level
+	case slog.MessageKey:
+		rec.Message = value
+	}
+	return nil
+}
+
+// appendJSONAttrs は a を out に追加します。グループはメンバーごとの属性に展開します。
+func appendJSONAttrs(out *[]Attr, groups []string, a slog.Attr) {
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindGroup:
+		groups = append(slices.Clip(groups), a.Key)
+		for _, m := range v.Group() {
+			appendJSONAttrs(out, groups, m)
+		}
+		return
+	case slog.KindAny:
+		if raw, ok := v.Any().(jsonattr.Raw); ok {
+			v = slog.AnyValue(JSON(raw))
+		}
+	}
+	*out = append(*out, Attr{Groups: groups, Key: a.Key, Value: v})
+}
+
+// jsonError は jsonattr のエラーを SyntaxError に変換します
+func jsonError(err error) error {
+	var se *jsonattr.SyntaxError
+	if errors.As(err, &se) {
+		return &SyntaxError{Offset: se.Offset, Msg: se.Msg}
+	}
+	return err
+}
@@ -0,0 +1,465 @@
+// Package parse は golog のテキスト形式と JSON 形式の行を構造化したレコードに戻します。
+// ログを集計や変換するツールや、出力を検証するラウンドトリップのテストに使います。
+//
+//	r := parse.NewReader(file, nil)
+//	for {
+//	    rec, err := r.Next()
+//	    if err == io.EOF {
+//	        break
+//	    }
+//	    ...
+//	    if v, ok := rec.Lookup("http", "status"); ok && v.Int64() >= 500 { ... }
+//	}
+//
+// クォートしたキーとグループ名、エスケープした文字列、JSON で出力した構造体や配列、色付きの出力（ANSI エスケープシーケンス）に
+// 対応します。FoldMultiline の継続行、Syslog と Heroku のヘッダーには対応しません。
+// QuoteSeparator を指定していないハンドラーの出力では、グループの区切りを含むキーはグループと区別できません。
+package parse
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	loggo "github.com/f0reth/golog"
+)
+
+const (
+	// defaultTimeFormat は Options.TimeFormat のデフォルト値（golog.Options.TimeFormat のデフォルトと同じ）
+	defaultTimeFormat = "2006-01-02 15:04:05.000"
+	// defaultGroupSeparator は Options.GroupSeparator のデフォルト値
+	defaultGroupSeparator = "."
+	// maxLineSize は Reader が読み込む1行の最大のバイト数
+	maxLineSize = 16 << 20
+)
+
+// Options はパーサーのオプション。出力したハンドラーの golog.Options と同じ値を指定します。
+type Options struct {
+	// TimeFormat は時刻の形式。空の場合は golog のデフォルトの "2006-01-02 15:04:05.000" です。
+	TimeFormat string
+	// GroupSeparator はグループ名とキーの区切り。空の場合は "." です。
+	GroupSeparator string
+	// Location はタイムゾーンを含まない時刻の形式で使うタイムゾーン。nil の場合は time.Local です。
+	Location *time.Location
+}
+
+// JSON は JSON で出力された値（構造体、マップ、スライスなど）の元の JSON です。
+// Attr.Value の Any() がこの型になります。値を取り出すには json.Unmarshal を使います。
+type JSON []byte
+
+// MarshalJSON は元の JSON を返します。ハンドラーに渡すと元と同じ JSON が出力されます。
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// Attr は行の1つの属性
+type Attr struct {
+	Groups []string // 属性を囲むグループ名（外側から順）
+	Key    string
+	Value  slog.Value
+}
+
+// Record は1行を解析したレコード
+type Record struct {
+	Time    time.Time // 行に時刻が無い場合はゼロ値
+	Level   slog.Level
+	Message string
+	Attrs   []Attr
+}
+
+// Lookup はグループ名とキーの path に一致する最初の属性の値を返します
+//
+//	rec.Lookup("http", "status") // http.status=200
+func (r Record) Lookup(path ...string) (slog.Value, bool) {
+	if len(path) == 0 {
+		return slog.Value{}, false
+	}
+	groups, key := path[:len(path)-1], path[len(path)-1]
+	for _, a := range r.Attrs {
+		if a.Key == key && slices.Equal(a.Groups, groups) {
+			return a.Value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+// Slog は slog.Record に変換します。連続する同じグループの属性は slog.Group にまとめます。
+// 変換したレコードをハンドラーに渡すと、ログを別の形式に変換したり再生したりできます。
+func (r Record) Slog() slog.Record {
+	rec := slog.NewRecord(r.Time, r.Level, r.Message, 0)
+	rec.AddAttrs(groupAttrs(r.Attrs, 0)...)
+	return rec
+}
+
+// groupAttrs は attrs を depth 番目以降のグループでまとめた属性を返します
+func groupAttrs(attrs []Attr, depth int) []slog.Attr {
+	var out []slog.Attr
+	for i := 0; i < len(attrs); {
+		a := attrs[i]
+		if len(a.Groups) <= depth {
+			out = append(out, slog.Attr{Key: a.Key, Value: a.Value})
+			i++
+			continue
+		}
+		name := a.Groups[depth]
+		j := i + 1
+		for j < len(attrs) && len(attrs[j].Groups) > depth && attrs[j].Groups[depth] == name {
+			j++
+		}
+		out = append(out, slog.Attr{Key: name, Value: slog.GroupValue(groupAttrs(attrs[i:j], depth+1)...)})
+		i = j
+	}
+	return out
+}
+
+// SyntaxError は行の解析のエラー
+type SyntaxError struct {
+	Line   int // Reader で読み込んだ場合の行番号（1 から）。ParseLine では 0
+	Offset int // 行の中のバイト位置
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("golog: parse: line %d, offset %d: %s", e.Line, e.Offset, e.Msg)
+	}
+	return fmt.Sprintf("golog: parse: offset %d: %s", e.Offset, e.Msg)
+}
+
+// Parser は golog のテキスト形式の行を解析します。複数のゴルーチンから使用できます。
+type Parser struct {
+	timeFormat string
+	separator  string
+	location   *time.Location
+}
+
+// NewParser は opts の設定で出力された行を解析する Parser を作成します
+func NewParser(opts *Options) *Parser {
+	p := &Parser{timeFormat: defaultTimeFormat, separator: defaultGroupSeparator, location: time.Local}
+	if opts != nil {
+		if opts.TimeFormat != "" {
+			p.timeFormat = opts.TimeFormat
+		}
+		if opts.GroupSeparator != "" {
+			p.separator = opts.GroupSeparator
+		}
+		if opts.Location != nil {
+			p.location = opts.Location
+		}
+	}
+	return p
+}
+
+// defaultParser は ParseLine で使うデフォルト設定の Parser
+var defaultParser = NewParser(nil)
+
+// Parse はデフォルト設定で1行を解析します
+func Parse(line string) (Record, error) {
+	return defaultParser.Parse(line)
+}
+
+// Parse は行の形式を判定し、'{' で始まる行を ParseJSON で、それ以外の行を ParseLine で解析します。
+// テキスト形式と JSON 形式が混在したログを読み込むために使います。
+func (p *Parser) Parse(line string) (Record, error) {
+	if strings.HasPrefix(strings.TrimLeft(line, " \t"), "{") {
+		return p.ParseJSON(line)
+	}
+	return p.ParseLine(line)
+}
+
+// ParseLine はデフォルト設定のハンドラーが出力した1行を解析します
+func ParseLine(line string) (Record, error) {
+	return defaultParser.ParseLine(line)
+}
+
+// ParseLine は1行を解析します。末尾の改行は取り除きます。
+func (p *Parser) ParseLine(line string) (Record, error) {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.Contains(line, "\x1b[") {
+		line = stripANSI(line)
+	}
+	s := scanner{line: line, sep: p.separator}
+	var rec Record
+
+	// "[時刻] [レベル] " のヘッダー。ReplaceAttr でどちらかが削除されている場合もある
+	for range 2 {
+		if !strings.HasPrefix(s.rest(), "[") {
+			break
+		}
+		end := strings.Index(s.rest(), "] ")
+		if end < 0 {
+			if !strings.HasSuffix(s.rest(), "]") {
+				return Record{}, s.errorf("unterminated header")
+			}
+			end = len(s.rest()) - 1
+		}
+		field := s.rest()[1:end]
+		if level, err := loggo.ParseLevel(strings.TrimSpace(field)); err == nil {
+			rec.Level = level
+		} else if t, err := time.ParseInLocation(p.timeFormat, field, p.location); err == nil {
+			rec.Time = t
+		} else {
+			return Record{}, s.errorf("invalid time or level %q", field)
+		}
+		s.pos += min(end+2, len(s.rest()))
+	}
+
+	// msg はヘッダーの直後にある場合のみメッセージとして扱う
+	first := true
+	for s.skipSpaces() {
+		groups, key, err := s.key()
+		if err != nil {
+			return Record{}, err
+		}
+		value, err := s.value()
+		if err != nil {
+			return Record{}, err
+		}
+		if first && len(groups) == 0 && key == slog.MessageKey && value.Kind() == slog.KindString {
+			rec.Message = value.String()
+			first = false
+			continue
+		}
+		first = false
+		rec.Attrs = append(rec.Attrs, Attr{Groups: groups, Key: key, Value: value})
+	}
+	return rec, nil
+}
+
+// scanner は1行を先頭から読み進めます
+type scanner struct {
+	line string
+	pos  int
+	sep  string // グループの区切り
+}
+
+func (s *scanner) rest() string { return s.line[s.pos:] }
+
+func (s *scanner) errorf(format string, args ...any) error {
+	return &SyntaxError{Offset: s.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// skipSpaces は空白を読み飛ばし、続きがあるかどうかを返します
+func (s *scanner) skipSpaces() bool {
+	for s.pos < len(s.line) && s.line[s.pos] == ' ' {
+		s.pos++
+	}
+	return s.pos < len(s.line)
+}
+
+// key は "=" までのキーを読み、グループ名とキーに分けて返します
+func (s *scanner) key() ([]string, string, error) {
+	var parts []string
+	for {
+		if s.pos >= len(s.line) {
+			return nil, "", s.errorf("missing '=' after key")
+		}
+		if s.line[s.pos] == '"' {
+			str, err := s.quoted()
+			if err != nil {
+				return nil, "", err
+			}
+			parts = append(parts, str)
+		} else {
+			end := s.pos
+			for end < len(s.line) && s.line[end] != '=' && s.line[end] != '"' && s.line[end] != ' ' &&
+				!strings.HasPrefix(s.line[end:], s.sep) {
+				end++
+			}
+			if end < len(s.line) && s.line[end] == ' ' {
+				s.pos = end
+				return nil, "", s.errorf("unexpected space in key")
+			}
+			if end < len(s.line) && s.line[end] == '"' && end > s.pos {
+				s.pos = end
+				return nil, "", s.errorf("unexpected quote in key")
+			}
+			if end > s.pos {
+				parts = append(parts, s.line[s.pos:end])
+			}
+			s.pos = end
+		}
+		switch {
+		case strings.HasPrefix(s.rest(), "="):
+			s.pos++
+			if len(parts) == 0 {
+				return nil, "", s.errorf("empty key")
+			}
+			return parts[:len(parts)-1], parts[len(parts)-1], nil
+		case strings.HasPrefix(s.rest(), s.sep):
+			s.pos += len(s.sep)
+		case s.pos < len(s.line) && s.line[s.pos] == '"':
+		default:
+			return nil, "", s.errorf("unexpected character in key")
+		}
+	}
+}
+
+// quoted はダブルクォートで囲まれた文字列を読み、エスケープを戻した文字列を返します
+func (s *scanner) quoted() (string, error) {
+	end := s.pos + 1
+	for end < len(s.line) && s.line[end] != '"' {
+		if s.line[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end >= len(s.line) {
+		return "", s.errorf("unterminated quoted string")
+	}
+	str, err := strconv.Unquote(s.line[s.pos : end+1])
+	if err != nil {
+		return "", s.errorf("invalid quoted string: %v", err)
+	}
+	s.pos = end + 1
+	return str, nil
+}
+
+// value は "=" の後の値を読みます
+func (s *scanner) value() (slog.Value, error) {
+	if s.pos >= len(s.line) || s.line[s.pos] == ' ' {
+		return slog.StringValue(""), nil
+	}
+	switch s.line[s.pos] {
+	case '"':
+		str, err := s.quoted()
+		if err != nil {
+			return slog.Value{}, err
+		}
+		return slog.StringValue(str), nil
+	case '{', '[':
+		raw, err := s.json()
+		if err != nil {
+			return slog.Value{}, err
+		}
+		return slog.AnyValue(JSON(raw)), nil
+	}
+	end := strings.IndexByte(s.rest(), ' ')
+	if end < 0 {
+		end = len(s.rest())
+	}
+	token := s.rest()[:end]
+	s.pos += end
+	return bareValue(token), nil
+}
+
+// json は JSON のオブジェクトまたは配列を読みます
+func (s *scanner) json() (string, error) {
+	depth := 0
+	inString := false
+	for i := s.pos; i < len(s.line); i++ {
+		c := s.line[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				raw := s.line[s.pos : i+1]
+				if !json.Valid([]byte(raw)) {
+					return "", s.errorf("invalid JSON value")
+				}
+				s.pos = i + 1
+				return raw, nil
+			}
+		}
+	}
+	return "", s.errorf("unterminated JSON value")
+}
+
+// bareValue はクォートされていない値を、null、真偽値、整数、浮動小数点数、文字列の順に解釈します
+func bareValue(token string) slog.Value {
+	switch token {
+	case "null":
+		return slog.AnyValue(nil)
+	case "true":
+		return slog.BoolValue(true)
+	case "false":
+		return slog.BoolValue(false)
+	}
+	if n, err := strconv.ParseInt(token, 10, 64); err == nil {
+		return slog.Int64Value(n)
+	}
+	if n, err := strconv.ParseUint(token, 10, 64); err == nil {
+		return slog.Uint64Value(n)
+	}
+	if f, err := strconv.ParseFloat(token, 64); err == nil {
+		return slog.Float64Value(f)
+	}
+	return slog.StringValue(token)
+}
+
+// stripANSI は ANSI の CSI エスケープシーケンス（色など）を取り除きます
+func stripANSI(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x1b && i+1 < len(s) && s[i+1] == '[' {
+			j := i + 2
+			for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+				j++
+			}
+			i = j
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// Reader は golog のログを1行ずつ解析します。各行の形式は Parser.Parse と同じ方法で判定します。
+type Reader struct {
+	p    *Parser
+	s    *bufio.Scanner
+	line int
+}
+
+// NewReader は r から読み込む Reader を作成します
+func NewReader(r io.Reader, opts *Options) *Reader {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxLineSize)
+	return &Reader{p: NewParser(opts), s: s}
+}
+
+// Next は次の行のレコードを返します。空行は読み飛ばします。すべて読み込んだ場合は io.EOF を返します。
+// 解析できない行では、行番号を設定した *SyntaxError を返し、続けて Next を呼び出すと次の行を解析します。
+func (r *Reader) Next() (Record, error) {
+	for r.s.Scan() {
+		r.line++
+		line := r.s.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		rec, err := r.p.Parse(line)
+		var se *SyntaxError
+		if errors.As(err, &se) {
+			se.Line = r.line
+		}
+		return rec, err
+	}
+	if err := r.s.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
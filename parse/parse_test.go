@@ -0,0 +1,196 @@
+package parse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	loggo "github.com/f0reth/golog"
+)
+
+// TestParseLine は時刻、レベル、メッセージ、属性の解析をテストします
+func TestParseLine(t *testing.T) {
+	line := `[2024-01-15 10:30:45.123] [ WARN] msg="disk \"almost\" full" path="/var/log" used=0.93 free=-12 big=18446744073709551615 ok=true none=null user.id=42 user."display name"="A B" tags=["a","b"] "my key"="v"` + "\n"
+	rec, err := ParseLine(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 1, 15, 10, 30, 45, 123e6, time.Local); !rec.Time.Equal(want) {
+		t.Errorf("Time = %v, want %v", rec.Time, want)
+	}
+	if rec.Level != slog.LevelWarn || rec.Message != `disk "almost" full` {
+		t.Errorf("Level = %v, Message = %q", rec.Level, rec.Message)
+	}
+
+	tests := []struct {
+		path []string
+		want any
+	}{
+		{[]string{"path"}, "/var/log"},
+		{[]string{"used"}, 0.93},
+		{[]string{"free"}, int64(-12)},
+		{[]string{"big"}, uint64(18446744073709551615)},
+		{[]string{"ok"}, true},
+		{[]string{"none"}, nil},
+		{[]string{"user", "id"}, int64(42)},
+		{[]string{"user", "display name"}, "A B"},
+		{[]string{"my key"}, "v"},
+	}
+	for _, tt := range tests {
+		v, ok := rec.Lookup(tt.path...)
+		if !ok || v.Any() != tt.want {
+			t.Errorf("Lookup(%v) = %v (%v), want %v", tt.path, v, ok, tt.want)
+		}
+	}
+	if v, _ := rec.Lookup("tags"); string(v.Any().(JSON)) != `["a","b"]` {
+		t.Errorf("tags = %v", v)
+	}
+}
+
+// TestParseLineHeaders は時刻やレベルが無い行、色付きの行、独自の設定の行の解析をテストします
+func TestParseLineHeaders(t *testing.T) {
+	rec, err := ParseLine(`[DEBUG+2] msg="no time"`)
+	if err != nil || !rec.Time.IsZero() || rec.Level != slog.LevelDebug+2 || rec.Message != "no time" {
+		t.Errorf("got %+v, %v", rec, err)
+	}
+
+	rec, err = ParseLine("[2024-01-15 10:30:45.123] [\x1b[31mERROR\x1b[0m] msg=\"colored\" n=1")
+	if err != nil || rec.Level != slog.LevelError || rec.Message != "colored" {
+		t.Errorf("got %+v, %v", rec, err)
+	}
+
+	p := NewParser(&Options{TimeFormat: time.RFC3339, GroupSeparator: "/", Location: time.UTC})
+	rec, err = p.ParseLine(`[2024-01-15T10:30:45Z] [ INFO] msg="custom" http/"a/b"=1 http/path="/"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := rec.Lookup("http", "a/b"); !ok || v.Int64() != 1 {
+		t.Errorf("http/a/b = %v, %v", v, ok)
+	}
+	if v, ok := rec.Lookup("http", "path"); !ok || v.String() != "/" {
+		t.Errorf("http/path = %v, %v", v, ok)
+	}
+}
+
+// TestParseLineErrors は不正な行でオフセット付きのエラーを返すことをテストします
+func TestParseLineErrors(t *testing.T) {
+	for _, line := range []string{
+		`[not a time] msg="x"`,
+		`msg="unterminated`,
+		`key without equals`,
+		`obj={"a":1`,
+		`=1`,
+	} {
+		_, err := ParseLine(line)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("ParseLine(%q) = %v, want *SyntaxError", line, err)
+		}
+	}
+}
+
+// TestRoundTrip は golog の出力を解析して同じ設定のハンドラーで出力し直すと、元の行と一致することをテストします
+func TestRoundTrip(t *testing.T) {
+	var original bytes.Buffer
+	opts := &loggo.Options{Level: slog.LevelDebug}
+	logger := slog.New(loggo.NewHandler(&original, opts))
+	logger.Info("user login", "user", "alice", "attempt", 3, "ratio", 0.25, "admin", false)
+	logger.With("svc", "api").WithGroup("http").Warn("slow request", "path", "/api/users", "status", 200, "my key", "a\tb\n")
+	logger.Debug("nested", slog.Group("a", slog.Group("b", "c", 1), "d", "e"), "tail", nil)
+	logger.Error(`quote "and" escape \`, "items", []string{"x", "y"})
+
+	var replayed bytes.Buffer
+	h := loggo.NewHandler(&replayed, opts)
+	want := original.String()
+	r := NewReader(&original, nil)
+	n := 0
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.Handle(context.Background(), rec.Slog()); err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 4 {
+		t.Fatalf("parsed %d records, want 4", n)
+	}
+	if replayed.String() != want {
+		t.Errorf("round trip mismatch:\n got: %s\nwant: %s", replayed.String(), want)
+	}
+}
+
+// TestReaderLineNumber は Reader のエラーに行番号が含まれ、続けて読み込めることをテストします
+func TestReaderLineNumber(t *testing.T) {
+	r := NewReader(strings.NewReader("msg=\"a\"\n\nbroken\nmsg=\"b\"\n"), nil)
+	if rec, err := r.Next(); err != nil || rec.Message != "a" {
+		t.Fatalf("first = %+v, %v", rec, err)
+	}
+	_, err := r.Next()
+	var se *SyntaxError
+	if !errors.As(err, &se) || se.Line != 3 || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("second = %v", err)
+	}
+	if rec, err := r.Next(); err != nil || rec.Message != "b" {
+		t.Errorf("third = %+v, %v", rec, err)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("last = %v, want io.EOF", err)
+	}
+}
+
+// TestParseJSON は golog と slog.JSONHandler の JSON 形式の行の解析をテストします
+func TestParseJSON(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).WithGroup("http").Warn("slow", "status", 503, "tags", []string{"a"}, "empty", struct{}{})
+	slog.New(loggo.NewHandler(&buf, &loggo.Options{Format: loggo.FormatJSON})).Info("hi", slog.Group("g", "n", -1, "ok", true))
+
+	r := NewReader(&buf, nil)
+	rec, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Time.IsZero() || rec.Level != slog.LevelWarn || rec.Message != "slow" {
+		t.Errorf("got %+v", rec)
+	}
+	if v, ok := rec.Lookup("http", "status"); !ok || v.Int64() != 503 {
+		t.Errorf("http.status = %v, %v", v, ok)
+	}
+	if v, _ := rec.Lookup("http", "tags"); v.Kind() != slog.KindAny || string(v.Any().(JSON)) != `["a"]` {
+		t.Errorf("http.tags = %v", v)
+	}
+	if v, _ := rec.Lookup("http", "empty"); v.Kind() != slog.KindAny || string(v.Any().(JSON)) != `{}` {
+		t.Errorf("http.empty = %v", v)
+	}
+
+	rec, err = r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Time.IsZero() || rec.Level != slog.LevelInfo || rec.Message != "hi" {
+		t.Errorf("got %+v", rec)
+	}
+	if v, ok := rec.Lookup("g", "n"); !ok || v.Int64() != -1 {
+		t.Errorf("g.n = %v, %v", v, ok)
+	}
+	if v, ok := rec.Lookup("g", "ok"); !ok || !v.Bool() {
+		t.Errorf("g.ok = %v, %v", v, ok)
+	}
+
+	for _, line := range []string{`{"msg":1}`, `{"level":"LOUD"}`, `{"a":1`, `{"a":1} x`} {
+		var se *SyntaxError
+		if _, err := ParseJSON(line); !errors.As(err, &se) {
+			t.Errorf("ParseJSON(%q) = %v, want *SyntaxError", line, err)
+		}
+	}
+}
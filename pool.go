@@ -0,0 +1,60 @@
+package loggo
+
+import "github.com/f0reth/golog/internal/buffer"
+
+// BufferPoolOptions はレコードのフォーマットに使うバッファのプールの設定
+type BufferPoolOptions struct {
+	// InitialSize は新しく確保するバッファの容量。0 の場合は 1KB
+	InitialSize int
+	// MaxRetainedSize はプールに戻すバッファの最大の容量。これを超えたバッファは破棄されます。0 の場合は 16KB
+	MaxRetainedSize int
+	// CollectStats は Handler.Stats のプールの統計情報を集計するかどうか。
+	// 集計はバッファの取得と返却のたびに共有のカウンターを更新するため、デフォルトでは無効です。
+	CollectStats bool
+}
+
+// SetBufferPoolOptions はバッファのプールの設定を変更します。
+// 4〜32KB の行を日常的に出力するサービスでは、両方の値を大きくすることでアロケーションを減らせます。
+// プールはすべてのハンドラーで共有されるため、ハンドラーを作成する前のプログラムの初期化時に呼び出してください。
+func SetBufferPoolOptions(opts BufferPoolOptions) {
+	buffer.SetSizes(opts.InitialSize, opts.MaxRetainedSize)
+	buffer.EnableStats(opts.CollectStats)
+}
+
+// CurrentBufferPoolOptions は現在のバッファのプールの設定を返します
+func CurrentBufferPoolOptions() BufferPoolOptions {
+	initial, maxRetained := buffer.Sizes()
+	return BufferPoolOptions{InitialSize: initial, MaxRetainedSize: maxRetained, CollectStats: buffer.StatsEnabled()}
+}
+
+// Stats はハンドラーの統計情報
+type Stats struct {
+	// PoolHits はバッファのプールから再利用できた回数。
+	// プールの値は BufferPoolOptions.CollectStats が有効な間のみ集計されます。
+	PoolHits uint64
+	// PoolMisses はプールが空で新しくバッファを確保した回数
+	PoolMisses uint64
+	// PoolDiscarded は MaxRetainedSize を超えたためプールに戻さずに破棄したバッファの数
+	PoolDiscarded uint64
+	// PeakBufferSize はこれまでに使用したバッファの最大の容量
+	PeakBufferSize int
+	// Dropped は WriteModeAsync のキューが満杯のため破棄したレコード数
+	Dropped uint64
+}
+
+// Stats はハンドラーの統計情報を返します。
+// バッファのプールはすべてのハンドラーで共有されているため、プールの値はプロセス全体の累計です。
+// BufferPoolOptions の調整に使います。
+func (h *Handler) Stats() Stats {
+	ps := buffer.ReadStats()
+	s := Stats{
+		PoolHits:       ps.Gets - ps.Misses,
+		PoolMisses:     ps.Misses,
+		PoolDiscarded:  ps.Discarded,
+		PeakBufferSize: ps.PeakSize,
+	}
+	if o, ok := h.out.(*asyncOutput); ok {
+		s.Dropped = o.dropped.Load()
+	}
+	return s
+}
@@ -0,0 +1,69 @@
+package loggo
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// TestSetBufferPoolOptions はバッファのプールの設定をテストします
+func TestSetBufferPoolOptions(t *testing.T) {
+	t.Cleanup(func() { SetBufferPoolOptions(BufferPoolOptions{}) })
+
+	SetBufferPoolOptions(BufferPoolOptions{InitialSize: 4 << 10, MaxRetainedSize: 64 << 10})
+	if got := CurrentBufferPoolOptions(); got.InitialSize != 4<<10 || got.MaxRetainedSize != 64<<10 {
+		t.Errorf("unexpected options: %+v", got)
+	}
+
+	// 32KB のバッファは新しい上限ではプールに戻される
+	buf := buffer.New()
+	buf.WriteString(strings.Repeat("x", 32<<10))
+	large := cap(*buf)
+	buf.Free()
+	reused := false
+	for range 10 {
+		b := buffer.New()
+		if cap(*b) == large {
+			reused = true
+		}
+		defer b.Free()
+	}
+	if !reused {
+		// sync.Pool は再利用を保証しない
+		t.Logf("buffer was not reused (this is not necessarily an error)")
+	}
+
+	SetBufferPoolOptions(BufferPoolOptions{})
+	if got := CurrentBufferPoolOptions(); got.InitialSize != 1<<10 || got.MaxRetainedSize != 16<<10 {
+		t.Errorf("zero options should restore defaults, got %+v", got)
+	}
+}
+
+// TestHandlerStats はバッファのプールの統計情報をテストします
+func TestHandlerStats(t *testing.T) {
+	SetBufferPoolOptions(BufferPoolOptions{CollectStats: true})
+	t.Cleanup(func() { SetBufferPoolOptions(BufferPoolOptions{}) })
+
+	h := NewHandler(discardWriter{}, nil)
+	logger := slog.New(h)
+	before := h.Stats()
+
+	for range 10 {
+		logger.Info("small")
+	}
+	logger.Info("large", "data", strings.Repeat("x", 32<<10))
+
+	after := h.Stats()
+	gets := (after.PoolHits + after.PoolMisses) - (before.PoolHits + before.PoolMisses)
+	if gets < 11 {
+		t.Errorf("expected at least 11 buffer gets, got %d", gets)
+	}
+	if after.PoolDiscarded <= before.PoolDiscarded {
+		t.Errorf("oversized buffer should be counted as discarded: before=%d after=%d", before.PoolDiscarded, after.PoolDiscarded)
+	}
+	if after.PeakBufferSize < 32<<10 {
+		t.Errorf("peak buffer size should be at least 32KB, got %d", after.PeakBufferSize)
+	}
+}
@@ -0,0 +1,158 @@
+package loggo
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// Serializer は値を文字列化するためのインターフェースの種類。
+// 複数のインターフェースを実装した型で、どれを使用するかを決めるために使います。
+type Serializer int
+
+const (
+	// SerializerLogValuer は slog.LogValuer の LogValue の結果を出力します
+	SerializerLogValuer Serializer = iota
+	// SerializerLogFormatter は LogFormatter の FormatForLog の結果をそのまま出力します
+	SerializerLogFormatter
+	// SerializerStringer は fmt.Stringer の String の結果をクォートして出力します
+	SerializerStringer
+	// SerializerJSONMarshaler は json.Marshaler の MarshalJSON の結果を出力します
+	SerializerJSONMarshaler
+	// SerializerTextMarshaler は encoding.TextMarshaler の MarshalText の結果をクォートして出力します
+	SerializerTextMarshaler
+)
+
+// defaultPrecedence はデフォルトの優先順位
+var defaultPrecedence = []Serializer{
+	SerializerLogValuer,
+	SerializerLogFormatter,
+	SerializerStringer,
+	SerializerJSONMarshaler,
+	SerializerTextMarshaler,
+}
+
+// String は Serializer の名前を返します
+func (s Serializer) String() string {
+	switch s {
+	case SerializerLogValuer:
+		return "LogValuer"
+	case SerializerLogFormatter:
+		return "LogFormatter"
+	case SerializerStringer:
+		return "Stringer"
+	case SerializerJSONMarshaler:
+		return "JSONMarshaler"
+	case SerializerTextMarshaler:
+		return "TextMarshaler"
+	default:
+		return fmt.Sprintf("Serializer(%d)", int(s))
+	}
+}
+
+// implementedBy は v が s のインターフェースを実装しているかを判定します
+func (s Serializer) implementedBy(v any) bool {
+	switch s {
+	case SerializerLogValuer:
+		_, ok := v.(slog.LogValuer)
+		return ok
+	case SerializerLogFormatter:
+		_, ok := v.(LogFormatter)
+		return ok
+	case SerializerStringer:
+		_, ok := v.(fmt.Stringer)
+		return ok && !isNilPointer(v)
+	case SerializerJSONMarshaler:
+		_, ok := v.(json.Marshaler)
+		return ok
+	case SerializerTextMarshaler:
+		_, ok := v.(encoding.TextMarshaler)
+		return ok
+	}
+	return false
+}
+
+// isNilPointer は v が nil ポインタかどうかを判定します
+func isNilPointer(v any) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+// resolve は slog.LogValuer を解決します。
+// LogValuer が優先順位に含まれない場合や、より前のインターフェースを実装している値は解決せずに返します。
+func (f *valueFormatter) resolve(v slog.Value) slog.Value {
+	if v.Kind() != slog.KindLogValuer {
+		return v
+	}
+	if _, ok := lookupFormatter(v.Any()); ok {
+		return v
+	}
+	for _, s := range f.precedence {
+		if s == SerializerLogValuer {
+			return resolveLogValuer(v)
+		}
+		if s.implementedBy(v.Any()) {
+			return v
+		}
+	}
+	return v
+}
+
+// maxResolveDepth は LogValuer を解決する最大の回数（slog.Value.Resolve と同じ）
+const maxResolveDepth = 100
+
+// resolveLogValuer は LogValuer を再帰的に解決します。
+// LogValue がパニックした場合は、パニックの値を "!PANIC: ..." の文字列として返します。
+func resolveLogValuer(v slog.Value) (resolved slog.Value) {
+	defer func() {
+		if r := recover(); r != nil {
+			resolved = slog.StringValue(fmt.Sprintf("!PANIC: %v", r))
+		}
+	}()
+	for range maxResolveDepth {
+		if v.Kind() != slog.KindLogValuer {
+			return v
+		}
+		v = v.LogValuer().LogValue()
+	}
+	return slog.StringValue(fmt.Sprintf("!ERROR:LogValue called too many times on value of type %T", v.Any()))
+}
+
+// appendSerialized は優先順位に従い、最初に実装されているインターフェースで値を書き込みます。
+// いずれも実装されていない場合は false を返します。
+func (f *valueFormatter) appendSerialized(buf *buffer.Buffer, v any) (bool, error) {
+	for _, s := range f.precedence {
+		if !s.implementedBy(v) {
+			continue
+		}
+		start := buf.Len()
+		var err error
+		switch s {
+		case SerializerLogValuer:
+			return true, f.appendValue(buf, v.(slog.LogValuer).LogValue())
+		case SerializerLogFormatter:
+			var str string
+			if str, err = v.(LogFormatter).FormatForLog(); err == nil {
+				buf.WriteString(str)
+			}
+		case SerializerStringer:
+			f.appendString(buf, v.(fmt.Stringer).String())
+			return true, nil
+		case SerializerJSONMarshaler:
+			err = marshalerEncoder(buf, reflect.ValueOf(v), 0)
+		case SerializerTextMarshaler:
+			err = textMarshalerEncoder(buf, reflect.ValueOf(v), 0)
+		}
+		if err != nil {
+			buf.SetLen(start)
+			return true, err
+		}
+		f.escapeNonASCII(buf, start)
+		return true, nil
+	}
+	return false, nil
+}
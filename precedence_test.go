@@ -0,0 +1,79 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// allSerializers はすべてのインターフェースを実装した型です
+type allSerializers struct{}
+
+func (allSerializers) LogValue() slog.Value          { return slog.StringValue("logvaluer") }
+func (allSerializers) FormatForLog() (string, error) { return "formatter", nil }
+func (allSerializers) String() string                { return "stringer" }
+func (allSerializers) MarshalJSON() ([]byte, error)  { return []byte(`{"json":true}`), nil }
+func (allSerializers) MarshalText() ([]byte, error)  { return []byte("text"), nil }
+
+// TestSerializerPrecedence は SerializerPrecedence の順序で値が出力されることをテストします
+func TestSerializerPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		precedence []Serializer
+		want       string
+	}{
+		{"default", nil, `v="logvaluer"`},
+		{"formatter first", []Serializer{SerializerLogFormatter, SerializerLogValuer}, `v=formatter`},
+		{"stringer first", []Serializer{SerializerStringer, SerializerLogValuer}, `v="stringer"`},
+		{"json first", []Serializer{SerializerJSONMarshaler, SerializerStringer}, `v={"json":true}`},
+		{"text first", []Serializer{SerializerTextMarshaler, SerializerJSONMarshaler}, `v="text"`},
+		{"empty falls back to JSON", []Serializer{}, `v={"json":true}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, &Options{SerializerPrecedence: tt.precedence}))
+			logger.Info("test", "v", allSerializers{})
+
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("want %q in output, got: %s", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+// TestSerializerPrecedenceWithReplaceAttr は LogValuer が優先されない場合に
+// ReplaceAttr へ未解決の値が渡されることをテストします
+func TestSerializerPrecedenceWithReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	var got any
+	logger := slog.New(NewHandler(&buf, &Options{
+		SerializerPrecedence: []Serializer{SerializerStringer, SerializerLogValuer},
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "v" {
+				got = a.Value.Any()
+			}
+			return a
+		},
+	}))
+	logger.Info("test", "v", allSerializers{})
+
+	if _, ok := got.(allSerializers); !ok {
+		t.Errorf("ReplaceAttr should receive the unresolved value, got %T", got)
+	}
+	if !strings.Contains(buf.String(), `v="stringer"`) {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
+
+// TestSerializerString は Serializer の名前をテストします
+func TestSerializerString(t *testing.T) {
+	if got := SerializerJSONMarshaler.String(); got != "JSONMarshaler" {
+		t.Errorf("unexpected name: %s", got)
+	}
+	if got := Serializer(99).String(); got != "Serializer(99)" {
+		t.Errorf("unexpected name: %s", got)
+	}
+}
@@ -0,0 +1,157 @@
+package loggo
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultProgressInterval は出力先が端末でない場合に進捗のレコードを出力するデフォルトの間隔
+	defaultProgressInterval = 5 * time.Second
+	// progressRefresh は端末の進捗の行を描き直す最短の間隔
+	progressRefresh = 100 * time.Millisecond
+	// progressBarWidth は進捗バーの幅
+	progressBarWidth = 20
+)
+
+// Progress は CLI ツールの処理の進捗を表示します。Handler.Progress で作成します。
+//
+// 出力先が端末の場合は、復帰文字で1行の進捗バーを描き直します。ログの行は進捗の行を消してから書き込まれ、
+// 進捗の行はその下に表示し直されるため、表示が崩れません。端末でない場合は Interval ごとに
+// current, total, percent の属性を持つ INFO のレコードを出力します。
+type Progress struct {
+	// Interval は出力先が端末でない場合に進捗のレコードを出力する間隔。0 の場合は5秒です。
+	// 最初の Add または Set の前に設定してください。
+	Interval time.Duration
+
+	h     *Handler
+	msg   string
+	total int64
+	start time.Time
+
+	mu         sync.Mutex // 以下のフィールドを保護
+	current    int64
+	lastDraw   time.Time
+	lastRecord time.Time
+	done       bool
+	line       []byte
+}
+
+// Progress は msg の処理の進捗を表示する Progress を作成します。
+// total は処理の総数で、0 以下の場合は進捗バーを表示せず件数のみを表示します。
+//
+//	p := handler.Progress("downloading", int64(len(files)))
+//	for _, f := range files {
+//		download(f)
+//		p.Add(1)
+//	}
+//	p.Done()
+func (h *Handler) Progress(msg string, total int64) *Progress {
+	now := time.Now()
+	return &Progress{h: h, msg: msg, total: total, start: now, lastRecord: now}
+}
+
+// Add は進捗を n だけ進めます
+func (p *Progress) Add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current += n
+	p.updateLocked()
+}
+
+// Set は進捗を n に設定します
+func (p *Progress) Set(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = n
+	p.updateLocked()
+}
+
+// Done は進捗の行を消去し、最終的な件数と経過時間を持つ INFO のレコードを出力します。
+// 2回目以降の呼び出しは何もしません。
+func (p *Progress) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return
+	}
+	p.done = true
+	p.h.term.clearStatus()
+	p.logLocked(slog.Duration(ElapsedKey, time.Since(p.start)))
+}
+
+// updateLocked は間隔に応じて進捗の行を描き直すか、進捗のレコードを出力します
+func (p *Progress) updateLocked() {
+	if p.done {
+		return
+	}
+	now := time.Now()
+	if p.h.term.isTTY() {
+		if now.Sub(p.lastDraw) < progressRefresh && (p.total <= 0 || p.current < p.total) {
+			return
+		}
+		p.lastDraw = now
+		p.line = p.appendLine(p.line[:0])
+		p.h.term.setStatus(p.line)
+		return
+	}
+
+	interval := p.Interval
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	if now.Sub(p.lastRecord) >= interval {
+		p.lastRecord = now
+		p.logLocked()
+	}
+}
+
+// logLocked は現在の進捗を属性に持つ INFO のレコードを出力します
+func (p *Progress) logLocked(attrs ...slog.Attr) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, p.msg, 0)
+	r.AddAttrs(slog.Int64("current", p.current))
+	if p.total > 0 {
+		r.AddAttrs(slog.Int64("total", p.total), slog.Int("percent", p.percent()))
+	}
+	r.AddAttrs(attrs...)
+	ctx := context.Background()
+	if p.h.Enabled(ctx, r.Level) {
+		p.h.Handle(ctx, r)
+	}
+}
+
+// appendLine は "msg [#####...............]  25% 250/1000" の形式の進捗の行を書き込みます
+func (p *Progress) appendLine(b []byte) []byte {
+	b = append(b, p.msg...)
+	b = append(b, ' ')
+	if p.total <= 0 {
+		return strconv.AppendInt(b, p.current, 10)
+	}
+	percent := p.percent()
+	filled := percent * progressBarWidth / 100
+	b = append(b, '[')
+	for i := range progressBarWidth {
+		if i < filled {
+			b = append(b, '#')
+		} else {
+			b = append(b, '.')
+		}
+	}
+	b = append(b, "] "...)
+	for range 3 - len(strconv.Itoa(percent)) {
+		b = append(b, ' ')
+	}
+	b = strconv.AppendInt(b, int64(percent), 10)
+	b = append(b, "% "...)
+	b = strconv.AppendInt(b, p.current, 10)
+	b = append(b, '/')
+	return strconv.AppendInt(b, p.total, 10)
+}
+
+// percent は進捗の割合を 0 から 100 の範囲で返します
+func (p *Progress) percent() int {
+	return int(min(max(p.current*100/p.total, 0), 100))
+}
@@ -0,0 +1,66 @@
+package loggo
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestProgressTerminal は端末で進捗の行が描き直され、ログの行と混ざらないことをテストします
+func TestProgressTerminal(t *testing.T) {
+	orig := isTerminal
+	t.Cleanup(func() { isTerminal = orig })
+	isTerminal = func(io.Writer) bool { return true }
+
+	var buf bytes.Buffer
+	h := NewHandler(&buf, &Options{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == ElapsedKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	p := h.Progress("copy", 4)
+	p.Add(1)
+	slog.New(h).Info("file copied")
+	p.Add(3) // 完了時は間隔に関係なく描き直す
+	p.Done()
+	p.Done()
+
+	want := clearLine + "copy [#####...............]  25% 1/4" +
+		clearLine + "[ INFO] msg=\"file copied\"\n" + "copy [#####...............]  25% 1/4" +
+		clearLine + "copy [####################] 100% 4/4" +
+		clearLine +
+		"[ INFO] msg=\"copy\" current=4 total=4 percent=100\n"
+	if buf.String() != want {
+		t.Errorf("unexpected output:\ngot:  %q\nwant: %q", buf.String(), want)
+	}
+}
+
+// TestProgressNotTerminal は端末でない場合に一定間隔で進捗のレコードが出力されることをテストします
+func TestProgressNotTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(&buf, nil)
+	p := h.Progress("import", 0)
+	p.Interval = 20 * time.Millisecond
+
+	p.Add(10) // 間隔が経過していないため出力しない
+	time.Sleep(30 * time.Millisecond)
+	p.Add(5)
+	p.Done()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 || strings.Contains(buf.String(), "\r") {
+		t.Fatalf("expected 2 plain records, got: %q", buf.String())
+	}
+	if !strings.HasSuffix(lines[0], `msg="import" current=15`) {
+		t.Errorf("unexpected progress record: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `msg="import" current=15 elapsed=`) {
+		t.Errorf("unexpected done record: %q", lines[1])
+	}
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package loggo
+
+// raceEnabled は race detector が有効かどうかを示します。
+// race detector はアロケーション数に影響するため、アロケーションのテストで参照します。
+const raceEnabled = true
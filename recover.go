@@ -0,0 +1,98 @@
+package loggo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PanicKey は RecoverAndLog で記録するパニックの値の属性のキー
+const PanicKey = "panic"
+
+// RecoverOptions は RecoverAndLogWith の設定
+type RecoverOptions struct {
+	// Level はパニックを記録するレベル。nil の場合は slog.LevelError です。
+	// プロセスを終了させるパニックを区別する場合は、RegisterLevel で登録した FATAL などのレベルを指定します。
+	Level slog.Leveler
+	// Repanic は記録した後に同じ値で再びパニックを起こします。
+	// パニックでプロセスを終了させたいが、その前にログの形式で記録しておきたい場合に使います。
+	Repanic bool
+}
+
+// RecoverAndLog はパニックから回復し、パニックの値とスタックトレースを ERROR のレコードとして記録します。
+// defer で直接呼び出してください。パニックが発生していない場合は何もしません。
+//
+//	func worker(logger *slog.Logger) {
+//		defer golog.RecoverAndLog(logger)
+//		...
+//	}
+func RecoverAndLog(logger *slog.Logger) {
+	if v := recover(); v != nil {
+		logPanic(logger, v, RecoverOptions{})
+	}
+}
+
+// RecoverAndLogWith は opts に従ってパニックを記録する RecoverAndLog です。defer で直接呼び出してください。
+//
+//	defer golog.RecoverAndLogWith(logger, golog.RecoverOptions{Repanic: true})
+func RecoverAndLogWith(logger *slog.Logger, opts RecoverOptions) {
+	if v := recover(); v != nil {
+		logPanic(logger, v, opts)
+		if opts.Repanic {
+			panic(v)
+		}
+	}
+}
+
+// logPanic はパニックの値と、パニックが発生した箇所からのスタックトレースを記録します。
+// レコードの PC はパニックが発生した箇所を指すため、AddSource ではその位置が出力されます。
+func logPanic(logger *slog.Logger, v any, opts RecoverOptions) {
+	level := slog.LevelError
+	if opts.Level != nil {
+		level = opts.Level.Level()
+	}
+	ctx := context.Background()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	pcs := panicStack()
+	var pc uintptr
+	if len(pcs) > 0 {
+		pc = pcs[0]
+	}
+	r := slog.NewRecord(time.Now(), level, "panic recovered", pc)
+	r.AddAttrs(slog.String(PanicKey, panicValueString(v)))
+	if len(pcs) > 0 {
+		r.AddAttrs(slog.String(StackKey, formatStack(pcs)))
+	}
+	logger.Handler().Handle(ctx, r)
+}
+
+// panicStack はパニックを起こした関数から下のスタックを返します。
+// logPanic と回復した関数、runtime のパニック処理のフレームを取り除きます。
+func panicStack() []uintptr {
+	var buf [maxStackDepth + 16]uintptr
+	// runtime.Callers, panicStack, logPanic, RecoverAndLog を飛ばす
+	pcs := buf[:runtime.Callers(4, buf[:])]
+	for len(pcs) > 1 {
+		fn := runtime.FuncForPC(pcs[0] - 1)
+		if fn == nil || !strings.HasPrefix(fn.Name(), "runtime.") {
+			break
+		}
+		pcs = pcs[1:]
+	}
+	return slices.Clone(pcs[:min(len(pcs), maxStackDepth)])
+}
+
+// panicValueString はパニックの値を文字列にします
+func panicValueString(v any) string {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,88 @@
+package loggo
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// panicking は nil のマップへの書き込みでランタイムのパニックを起こします
+func panicking() {
+	var m map[string]int
+	m["key"] = 1
+}
+
+// TestRecoverAndLog はパニックの値とパニックが発生した箇所からのスタックトレースが記録されることをテストします
+func TestRecoverAndLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{AddSource: true}))
+
+	func() {
+		defer RecoverAndLog(logger)
+		panicking()
+	}()
+
+	out := buf.String()
+	if !strings.Contains(out, `[ERROR] msg="panic recovered" source="recover_test.go:`) {
+		t.Errorf("expected error record with panic location, got %q", out)
+	}
+	if !strings.Contains(out, `panic="assignment to entry in nil map"`) {
+		t.Errorf("expected panic value, got %q", out)
+	}
+	if !strings.Contains(out, ` stack="github.com/f0reth/golog.panicking\n\t`) {
+		t.Errorf("expected stack to start at the panicking function, got %q", out)
+	}
+}
+
+// TestRecoverAndLogWith はレベルの指定と再パニックをテストします
+func TestRecoverAndLogWith(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, nil))
+	errBoom := errors.New("boom")
+
+	var repanicked any
+	func() {
+		defer func() { repanicked = recover() }()
+		defer RecoverAndLogWith(logger, RecoverOptions{Level: slog.LevelError + 4, Repanic: true})
+		panic(errBoom)
+	}()
+
+	if repanicked != errBoom {
+		t.Errorf("expected the same value to be re-panicked, got %v", repanicked)
+	}
+	if !strings.Contains(buf.String(), `[ERROR+4] msg="panic recovered" panic="boom"`) {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+// TestRecoverAndLogNoPanic はパニックが発生していない場合に何も記録しないことをテストします
+func TestRecoverAndLogNoPanic(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, nil))
+	func() {
+		defer RecoverAndLog(logger)
+	}()
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %q", buf.String())
+	}
+}
+
+// TestRecoverAndLogFingerprint は StackTraceLevel でスタックトレースを重複して付加しないことをテストします
+func TestRecoverAndLogFingerprint(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{StackTraceLevel: slog.LevelError, Fingerprint: true}))
+	func() {
+		defer RecoverAndLog(logger)
+		panicking()
+	}()
+
+	out := buf.String()
+	if n := strings.Count(out, StackKey+"="); n != 1 {
+		t.Errorf("expected 1 stack attr, got %d: %q", n, out)
+	}
+	if !fingerprintPattern.MatchString(out) {
+		t.Errorf("expected fingerprint, got %q", out)
+	}
+}
@@ -0,0 +1,66 @@
+package loggo
+
+import (
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// Renderer は slog.Handler を介さずに、Handler と同じ形式でレコードを整形します。
+// ログを解析して表示し直すツールや、保存したレコードを再生するツールで、golog の出力形式を再利用するために使います。
+//
+//	r := golog.NewRenderer(&golog.Options{UseColors: true})
+//	line := r.WriteRecord(nil, time.Now(), slog.LevelInfo, "started", slog.Int("port", 8080))
+//	os.Stdout.Write(line)
+//
+// 整形に関するオプション（Format、TimeFormat、UseColors、ReplaceAttr など）は Handler と同じように使用されます。
+// レベルによる絞り込み、出力先と書き込み方式、BeforeHandle や OnRecord などのフック、Banner、
+// コンテキストやスタックトレースから属性を追加するオプションは使用されません。
+// 複数のゴルーチンから使用できます。
+type Renderer struct {
+	h *Handler
+}
+
+// NewRenderer は opts の形式で整形する Renderer を作成します。opts が nil の場合はデフォルトの設定を使用します。
+func NewRenderer(opts *Options) *Renderer {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	// 出力先を使わないため、書き込み用のゴルーチンやロックを作成しない
+	o.WriteMode = WriteModeBatched
+	o.NoLock = true
+	o.Banner = nil
+	o.Routes = nil
+	return &Renderer{h: NewHandler(io.Discard, &o)}
+}
+
+// WriteRecord はレコードを1行に整形して buf に追加し、拡張したスライスを返します。
+// 行は改行（Options.LineEnding）で終わります。t がゼロ値の場合も時刻を出力するため、
+// 時刻を出力しない場合は ReplaceAttr で削除します。
+func (r *Renderer) WriteRecord(buf []byte, t time.Time, level slog.Level, msg string, attrs ...slog.Attr) []byte {
+	rec := slog.NewRecord(t, level, msg, 0)
+	rec.AddAttrs(attrs...)
+	return r.AppendRecord(buf, rec)
+}
+
+// AppendRecord は slog.Record を1行に整形して buf に追加し、拡張したスライスを返します。
+// rec.PC が 0 でない場合、AddSource が有効であればソースの位置も出力します。
+func (r *Renderer) AppendRecord(buf []byte, rec slog.Record) []byte {
+	b := buffer.New()
+	defer b.Free()
+	r.h.render(b, rec)
+	return append(buf, *b...)
+}
+
+// WithAttrs は attrs を常に出力する Renderer を返します（slog.Handler.WithAttrs と同じ）
+func (r *Renderer) WithAttrs(attrs ...slog.Attr) *Renderer {
+	return &Renderer{h: r.h.WithAttrs(attrs).(*Handler)}
+}
+
+// WithGroup は以降の属性を name のグループに入れる Renderer を返します（slog.Handler.WithGroup と同じ）
+func (r *Renderer) WithGroup(name string) *Renderer {
+	return &Renderer{h: r.h.WithGroup(name).(*Handler)}
+}
@@ -0,0 +1,43 @@
+package loggo
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// TestRenderer は Renderer が Handler と同じ行を出力することをテストします
+func TestRenderer(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC)
+	for _, opts := range []*Options{nil, {Format: FormatJSON}, {UseColors: true, SortAttrs: true, LineEnding: "\r\n"}} {
+		var want bytes.Buffer
+		h := NewHandler(&want, opts).WithAttrs([]slog.Attr{slog.String("svc", "api")}).WithGroup("http")
+		rec := slog.NewRecord(now, slog.LevelWarn, "slow", 0)
+		rec.AddAttrs(slog.Int("status", 503), slog.String("path", "/a b"))
+		if err := h.Handle(context.Background(), rec); err != nil {
+			t.Fatal(err)
+		}
+
+		r := NewRenderer(opts).WithAttrs(slog.String("svc", "api")).WithGroup("http")
+		got := r.WriteRecord([]byte("prefix:"), now, slog.LevelWarn, "slow", slog.Int("status", 503), slog.String("path", "/a b"))
+		if string(got) != "prefix:"+want.String() {
+			t.Errorf("opts %+v:\n got %q\nwant %q", opts, got, "prefix:"+want.String())
+		}
+	}
+}
+
+// TestRendererIgnoresLevel は Renderer がレベルで絞り込まず、DebugOnly の出力は Level に従うことをテストします
+func TestRendererIgnoresLevel(t *testing.T) {
+	r := NewRenderer(&Options{Level: slog.LevelError, ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		return a
+	}})
+	got := string(r.WriteRecord(nil, time.Time{}, slog.LevelDebug, "x", DebugOnly(slog.Int("n", 1))))
+	if want := "[DEBUG] msg=\"x\"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
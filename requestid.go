@@ -0,0 +1,57 @@
+package loggo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDKey はリクエスト ID を出力する属性のキー
+const RequestIDKey = "request_id"
+
+// RequestIDHeader は RequestIDMiddleware がリクエスト ID を読み書きする HTTP ヘッダー
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength は受け付けるリクエスト ID の最大の長さ。これを超えるヘッダーの値は無視します。
+const maxRequestIDLength = 128
+
+// requestIDKey はリクエスト ID を保持するコンテキストのキー
+type requestIDKey struct{}
+
+// NewRequestID はランダムな 128 ビットのリクエスト ID を 32 文字の16進数で返します
+func NewRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// ContextWithRequestID はリクエスト ID を保持するコンテキストを返します。
+// このコンテキストを渡したログ呼び出し（InfoContext など）のレコードには、
+// Handler が request_id 属性を自動で付加します。
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext は ContextWithRequestID で設定されたリクエスト ID を返します
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// RequestIDMiddleware はリクエストの X-Request-ID ヘッダーの値、または無い場合は NewRequestID で
+// 生成した ID をリクエストのコンテキストに設定し、レスポンスの X-Request-ID ヘッダーにも設定します。
+// gRPC ではインターセプターで metadata から読み取った値を ContextWithRequestID に渡してください。
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(ContextWithRequestID(r.Context(), id)))
+	})
+}
@@ -0,0 +1,61 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestNewRequestID はリクエスト ID が一意な 32 文字の16進数であることをテストします
+func TestNewRequestID(t *testing.T) {
+	a, b := NewRequestID(), NewRequestID()
+	if len(a) != 32 || strings.Trim(a, "0123456789abcdef") != "" {
+		t.Errorf("unexpected request ID %q", a)
+	}
+	if a == b {
+		t.Error("request IDs should be unique")
+	}
+}
+
+// TestRequestIDAttr はコンテキストのリクエスト ID が自動で出力されることをテストします
+func TestRequestIDAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, nil))
+
+	ctx := ContextWithRequestID(t.Context(), "req-1")
+	logger.InfoContext(ctx, "handled", "status", 200)
+	logger.Info("background")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if !strings.HasSuffix(lines[0], `msg="handled" status=200 request_id="req-1"`) {
+		t.Errorf("expected request_id attribute, got %s", lines[0])
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Errorf("records without a request ID should not have the attribute: %s", lines[1])
+	}
+}
+
+// TestRequestIDMiddleware はヘッダーの ID を引き継ぎ、無い場合は生成することをテストします
+func TestRequestIDMiddleware(t *testing.T) {
+	var got string
+	h := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "upstream-id")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got != "upstream-id" || rec.Header().Get(RequestIDHeader) != "upstream-id" {
+		t.Errorf("expected upstream ID to be propagated, got %q (response %q)", got, rec.Header().Get(RequestIDHeader))
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(got) != 32 || rec.Header().Get(RequestIDHeader) != got {
+		t.Errorf("expected a generated ID, got %q (response %q)", got, rec.Header().Get(RequestIDHeader))
+	}
+}
@@ -0,0 +1,116 @@
+package loggo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// Route は Options.Routes の出力先の1つ。ハンドラーの出力先 w と同じ形式で、独自の最小レベルのレコードを書き込みます。
+type Route struct {
+	// Writer は出力先
+	Writer io.Writer
+	// Level はこの出力先の最小レベル。nil の場合は w と同じレベル（WithMinLevel や TemporaryLevel を含む）です。
+	Level slog.Leveler
+}
+
+// route は Route の出力先と、その出力先用の output
+type route struct {
+	level slog.Leveler
+	w     io.Writer // 配送の状態の確認に使う
+	out   output
+}
+
+// newRoutes は routes のそれぞれに、w と同じ書き込み方式の output を作成します
+func newRoutes(routes []Route, mode WriteMode, opts outputOptions) []route {
+	if len(routes) == 0 {
+		return nil
+	}
+	rs := make([]route, len(routes))
+	for i, r := range routes {
+		rs[i] = route{level: r.Level, w: r.Writer, out: newOutput(newTerminal(r.Writer, opts.noLock), mode, opts)}
+	}
+	return rs
+}
+
+// enabledLevel は w と Routes の最小レベルのうち最も低いレベルを返します
+func (h *Handler) enabledLevel(ctx context.Context) slog.Level {
+	level := h.effectiveLevel(ctx)
+	for _, r := range h.routes {
+		if r.level != nil {
+			level = min(level, r.level.Level())
+		}
+	}
+	return level
+}
+
+// writeRoutes は r を、level が最小レベル以上の出力先（w と Routes）へ書き込みます。
+// filter が false の場合（バナーなど）はすべての出力先へ書き込みます。
+// DebugOnly の属性は出力先ごとの最小レベルで判断するため、整形は最大2通り行います。
+// 書き込んだ出力先が無い場合は written に false を返します。
+func (h *Handler) writeRoutes(ctx context.Context, buf *buffer.Buffer, r slog.Record, filter bool) (n int, written bool, err error) {
+	var other *buffer.Buffer // h.verbose と異なる設定で整形した行
+	defer func() {
+		if other != nil {
+			other.Free()
+		}
+	}()
+	line := func(threshold slog.Level) []byte {
+		if verbose := threshold <= slog.LevelDebug; verbose != h.verbose {
+			if other == nil {
+				other = buffer.New()
+				clone := *h
+				clone.verbose = verbose
+				clone.render(other, r)
+			}
+			return *other
+		}
+		if buf.Len() == 0 {
+			h.render(buf, r)
+		}
+		return *buf
+	}
+
+	primary := h.effectiveLevel(ctx)
+	var errs []error
+	if !filter || r.Level >= primary {
+		p := line(primary)
+		n, written = len(p), true
+		errs = append(errs, h.out.write(p))
+	}
+	for _, rt := range h.routes {
+		threshold := primary
+		if rt.level != nil {
+			threshold = rt.level.Level()
+		}
+		if !filter || r.Level >= threshold {
+			p := line(threshold)
+			if !written {
+				n, written = len(p), true
+			}
+			errs = append(errs, rt.out.write(p))
+		}
+	}
+	return n, written, errors.Join(errs...)
+}
+
+// flushRoutes は Routes の未書き込みのレコードを書き出します
+func (h *Handler) flushRoutes() error {
+	var errs []error
+	for _, r := range h.routes {
+		errs = append(errs, r.out.flush())
+	}
+	return errors.Join(errs...)
+}
+
+// closeRoutes は Routes のバックグラウンドの書き込み処理を停止します
+func (h *Handler) closeRoutes() error {
+	var errs []error
+	for _, r := range h.routes {
+		errs = append(errs, r.out.close())
+	}
+	return errors.Join(errs...)
+}
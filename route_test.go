@@ -0,0 +1,141 @@
+package loggo
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRoutes は出力先ごとの最小レベルでレコードを振り分けることをテストします
+func TestRoutes(t *testing.T) {
+	for _, mode := range []WriteMode{WriteModeBatched, WriteModeAsync, WriteModeSerial} {
+		t.Run(mode.String(), func(t *testing.T) {
+			var console, file, audit bytes.Buffer
+			h := NewHandler(&console, &Options{
+				Level:     slog.LevelWarn,
+				WriteMode: mode,
+				Routes: []Route{
+					{Writer: &file, Level: slog.LevelDebug},
+					{Writer: &audit},
+				},
+			})
+			logger := slog.New(h).With("svc", "api")
+			if !logger.Enabled(context.Background(), slog.LevelDebug) {
+				t.Error("Debug should be enabled by the file route")
+			}
+			logger.Debug("debug")
+			logger.Warn("warn")
+			logger.InfoContext(WithMinLevel(context.Background(), slog.LevelInfo), "info")
+			if err := h.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			check := func(name string, buf *bytes.Buffer, want ...string) {
+				t.Helper()
+				lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+				if len(lines) != len(want) {
+					t.Fatalf("%s: got %q, want %q", name, lines, want)
+				}
+				for i, w := range want {
+					if !strings.Contains(lines[i], `msg="`+w+`" svc="api"`) {
+						t.Errorf("%s line %d: got %q, want %q", name, i, lines[i], w)
+					}
+				}
+			}
+			check("console", &console, "warn", "info")
+			check("file", &file, "debug", "warn", "info")
+			check("audit", &audit, "warn", "info")
+		})
+	}
+}
+
+// TestRoutesFormatOnce はレコードを1回だけ整形し、すべての出力先へ同じ行を書き込むことをテストします
+func TestRoutesFormatOnce(t *testing.T) {
+	var a, b bytes.Buffer
+	calls := 0
+	h := NewHandler(&a, &Options{
+		Routes: []Route{{Writer: &b}},
+		ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			if attr.Key == "n" {
+				calls++
+			}
+			return attr
+		},
+	})
+	slog.New(h).Info("m", "n", 1)
+	if calls != 1 {
+		t.Errorf("ReplaceAttr called %d times, want 1", calls)
+	}
+	if a.String() == "" || a.String() != b.String() {
+		t.Errorf("outputs differ:\n%q\n%q", a.String(), b.String())
+	}
+}
+
+// TestRoutesWriteError は出力先の書き込みエラーが他の出力先に影響しないことをテストします
+func TestRoutesWriteError(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(errorWriter{}, &Options{Routes: []Route{{Writer: &buf}}})
+	err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "m", 0))
+	if err == nil || !strings.Contains(err.Error(), "write failed") {
+		t.Errorf("err = %v, want write failed", err)
+	}
+	if !strings.Contains(buf.String(), `msg="m"`) {
+		t.Errorf("route output = %q", buf.String())
+	}
+}
+
+// TestRoutesDebugOnly は DebugOnly の属性を出力先ごとの最小レベルで判断することをテストします
+func TestRoutesDebugOnly(t *testing.T) {
+	var console, file bytes.Buffer
+	h := NewHandler(&console, &Options{
+		Level:  slog.LevelInfo,
+		Routes: []Route{{Writer: &file, Level: slog.LevelDebug}},
+	})
+	slog.New(h).Info("done", "status", 200, DebugOnly(slog.String("query", "q=1")))
+
+	if strings.Contains(console.String(), "query") {
+		t.Errorf("DebugOnly attrs should not be written to the Info output: %q", console.String())
+	}
+	if !strings.Contains(file.String(), `status=200 query="q=1"`) {
+		t.Errorf("DebugOnly attrs should be written to the Debug route: %q", file.String())
+	}
+}
+
+// flippingLeveler は最初の呼び出しで first を、以降は rest を返す slog.Leveler です
+type flippingLeveler struct {
+	first, rest slog.Level
+	calls       int
+}
+
+func (l *flippingLeveler) Level() slog.Level {
+	l.calls++
+	if l.calls == 1 {
+		return l.first
+	}
+	return l.rest
+}
+
+// TestRoutesHooksSkipped は書き込んだ出力先が無いレコードでフックを呼び出さないことをテストします
+func TestRoutesHooksSkipped(t *testing.T) {
+	var console, file bytes.Buffer
+	hooks := 0
+	h := NewHandler(&console, &Options{
+		Level: slog.LevelError,
+		// 判定の後に最小レベルが上がり、どの出力先にも書き込まれない
+		Routes:     []Route{{Writer: &file, Level: &flippingLeveler{first: slog.LevelDebug, rest: slog.LevelError}}},
+		AfterWrite: func(context.Context, slog.Record, int, error) { hooks++ },
+		OnRecord:   []RecordCallback{{Level: slog.LevelDebug, Func: func(context.Context, slog.Record) { hooks++ }}},
+	})
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "m", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if console.Len() != 0 || file.Len() != 0 {
+		t.Fatalf("nothing should be written: %q %q", console.String(), file.String())
+	}
+	if hooks != 0 {
+		t.Errorf("hooks called %d times, want 0", hooks)
+	}
+}
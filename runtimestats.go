@@ -0,0 +1,28 @@
+package loggo
+
+import (
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// RuntimeStats はランタイムの統計情報を "runtime" グループの属性として返します。
+// heap_inuse（使用中のヒープのバイト数）、goroutines（ゴルーチン数）、
+// gc_pause（直前の GC の停止時間）を含みます。
+// runtime.ReadMemStats を呼び出すため短時間 stop-the-world が発生します。
+//
+//	logger.Warn("memory pressure", golog.RuntimeStats())
+func RuntimeStats() slog.Attr {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	var pause time.Duration
+	if m.NumGC > 0 {
+		pause = time.Duration(m.PauseNs[(m.NumGC+255)%256])
+	}
+	return slog.Group("runtime",
+		slog.Uint64("heap_inuse", m.HeapInuse),
+		slog.Int("goroutines", runtime.NumGoroutine()),
+		slog.Duration("gc_pause", pause),
+	)
+}
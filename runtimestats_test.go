@@ -0,0 +1,50 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestRuntimeStats はランタイムの統計情報がグループとして出力されることをテストします
+func TestRuntimeStats(t *testing.T) {
+	runtime.GC()
+	attr := RuntimeStats()
+	if attr.Key != "runtime" || attr.Value.Kind() != slog.KindGroup {
+		t.Fatalf("expected runtime group, got %v", attr)
+	}
+	values := map[string]slog.Value{}
+	for _, a := range attr.Value.Group() {
+		values[a.Key] = a.Value
+	}
+	if values["heap_inuse"].Uint64() == 0 {
+		t.Error("heap_inuse should be positive")
+	}
+	if values["goroutines"].Int64() < 1 {
+		t.Error("goroutines should be at least 1")
+	}
+	if values["gc_pause"].Kind() != slog.KindDuration {
+		t.Errorf("gc_pause should be a duration, got %v", values["gc_pause"].Kind())
+	}
+}
+
+// TestRuntimeStatsLevel は RuntimeStatsLevel 以上のレコードにのみ統計情報が付加されることをテストします
+func TestRuntimeStatsLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{RuntimeStatsLevel: slog.LevelWarn}))
+
+	logger.Info("normal")
+	logger.Warn("pressure", "k", "v")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if strings.Contains(lines[0], "runtime.") {
+		t.Errorf("INFO record should not have runtime stats: %s", lines[0])
+	}
+	for _, key := range []string{`k="v" runtime.heap_inuse=`, " runtime.goroutines=", " runtime.gc_pause="} {
+		if !strings.Contains(lines[1], key) {
+			t.Errorf("WARN record should contain %q: %s", key, lines[1])
+		}
+	}
+}
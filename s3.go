@@ -0,0 +1,281 @@
+package loggo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultS3KeyTemplate は S3UploaderOptions.KeyTemplate のデフォルト値
+	defaultS3KeyTemplate = "{host}/{yyyy}/{mm}/{dd}/{name}"
+	// defaultS3Timeout は S3UploaderOptions.Client が nil の場合のアップロードのタイムアウト
+	defaultS3Timeout = 5 * time.Minute
+	// s3TimeFormat は SigV4 の x-amz-date の書式
+	s3TimeFormat = "20060102T150405Z"
+)
+
+// S3UploaderOptions は S3Uploader の設定
+type S3UploaderOptions struct {
+	// Bucket はアップロード先のバケット
+	Bucket string
+	// Region はバケットのリージョン。空の場合は "us-east-1" です。
+	Region string
+	// Endpoint は S3 互換ストレージの URL（"http://minio:9000" など）。
+	// 空の場合は "https://s3.<Region>.amazonaws.com" です。
+	Endpoint string
+	// PathStyle はバケットをホスト名ではなくパスに含めます（"<Endpoint>/<Bucket>/<key>"）。
+	// Endpoint を指定した場合は常にパス形式です。
+	PathStyle bool
+
+	// AccessKeyID, SecretAccessKey, SessionToken は署名に使う認証情報。
+	// AccessKeyID が空の場合は環境変数 AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN の値です。
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// KeyTemplate はオブジェクトのキーのテンプレート。空の場合は "{host}/{yyyy}/{mm}/{dd}/{name}" です。
+	// {host} はホスト名、{name} はファイル名、{yyyy} {mm} {dd} {hh} はファイルの最終更新時刻（UTC）に置き換えます。
+	// 日付で区切ったプレフィックスは、ライフサイクルルールで古いログを移動・削除するのに適しています。
+	KeyTemplate string
+	// StorageClass は x-amz-storage-class ヘッダーの値（"STANDARD_IA" など）。空の場合は指定しません。
+	StorageClass string
+	// DeleteAfterUpload はアップロードに成功したローカルのファイルを削除します
+	DeleteAfterUpload bool
+	// Client はアップロードに使うクライアント。nil の場合はタイムアウトが 5 分のクライアントです。
+	Client *http.Client
+}
+
+// S3Uploader はファイルを S3 または S3 互換のオブジェクトストレージへアップロードします。
+// FileWriterOptions.OnArchive に Upload を指定すると、ローテーションして圧縮したファイルを保管できます。
+// 署名（SigV4）は標準ライブラリだけで行うため、AWS SDK に依存しません。
+//
+//	uploader, err := golog.NewS3Uploader(&golog.S3UploaderOptions{Bucket: "app-logs", Region: "ap-northeast-1"})
+//	...
+//	fw, err := golog.OpenFile("/var/log/app.log", &golog.FileWriterOptions{
+//	    MaxSize:    100 << 20,
+//	    Compressor: golog.GzipCompressor,
+//	    OnArchive:  uploader.Upload,
+//	})
+type S3Uploader struct {
+	opts     S3UploaderOptions
+	endpoint *url.URL
+	hostname string
+	client   *http.Client
+	now      func() time.Time // テストで置き換える
+}
+
+// NewS3Uploader は opts の設定で S3Uploader を作成します。Bucket が空の場合や Endpoint が不正な URL の場合はエラーを返します。
+func NewS3Uploader(opts *S3UploaderOptions) (*S3Uploader, error) {
+	u := &S3Uploader{now: time.Now}
+	if opts != nil {
+		u.opts = *opts
+	}
+	if u.opts.Bucket == "" {
+		return nil, fmt.Errorf("golog: S3 bucket is required")
+	}
+	if u.opts.Region == "" {
+		u.opts.Region = "us-east-1"
+	}
+	if u.opts.KeyTemplate == "" {
+		u.opts.KeyTemplate = defaultS3KeyTemplate
+	}
+	if u.opts.AccessKeyID == "" {
+		u.opts.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		u.opts.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		u.opts.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	endpoint := u.opts.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + u.opts.Region + ".amazonaws.com"
+	} else {
+		u.opts.PathStyle = true
+	}
+	var err error
+	if u.endpoint, err = url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("golog: invalid S3 endpoint: %w", err)
+	}
+	if u.endpoint.Scheme == "" || u.endpoint.Host == "" {
+		return nil, fmt.Errorf("golog: invalid S3 endpoint %q", endpoint)
+	}
+	u.client = u.opts.Client
+	if u.client == nil {
+		u.client = &http.Client{Timeout: defaultS3Timeout}
+	}
+	u.hostname, _ = os.Hostname()
+	return u, nil
+}
+
+// Key は path のファイルをアップロードする際のオブジェクトのキーを返します
+func (u *S3Uploader) Key(path string, modTime time.Time) string {
+	t := modTime.UTC()
+	return strings.NewReplacer(
+		"{host}", u.hostname,
+		"{name}", filepath.Base(path),
+		"{yyyy}", t.Format("2006"),
+		"{mm}", t.Format("01"),
+		"{dd}", t.Format("02"),
+		"{hh}", t.Format("15"),
+	).Replace(u.opts.KeyTemplate)
+}
+
+// Upload は path のファイルをアップロードします。DeleteAfterUpload の場合は成功した後にファイルを削除します。
+func (u *S3Uploader) Upload(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	// 署名にはボディのハッシュが必要なため、一度読み取ってから先頭に戻す
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	payloadHash := hex.EncodeToString(h.Sum(nil))
+
+	key := u.Key(path, fi.ModTime())
+	req, err := http.NewRequest(http.MethodPut, u.objectURL(key), io.NopCloser(f))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = fi.Size()
+	req.Header.Set("Content-Type", s3ContentType(path))
+	if u.opts.StorageClass != "" {
+		req.Header.Set("X-Amz-Storage-Class", u.opts.StorageClass)
+	}
+	u.sign(req, payloadHash)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("golog: upload %s: %w", key, err)
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("golog: upload %s: %s: %s", key, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if u.opts.DeleteAfterUpload {
+		f.Close()
+		return os.Remove(path)
+	}
+	return nil
+}
+
+// objectURL はキーのオブジェクトの URL を返します
+func (u *S3Uploader) objectURL(key string) string {
+	e := *u.endpoint
+	if u.opts.PathStyle {
+		e.Path = strings.TrimSuffix(e.Path, "/") + "/" + u.opts.Bucket + "/" + key
+	} else {
+		e.Host = u.opts.Bucket + "." + e.Host
+		e.Path = "/" + key
+	}
+	e.RawPath = s3EscapePath(e.Path)
+	return e.String()
+}
+
+// sign はリクエストに AWS Signature Version 4 の署名を付けます。
+// host と x-amz-* のヘッダーを署名の対象にします。
+func (u *S3Uploader) sign(req *http.Request, payloadHash string) {
+	now := u.now().UTC()
+	amzDate := now.Format(s3TimeFormat)
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if u.opts.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", u.opts.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		if name := strings.ToLower(name); strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + u.opts.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	signature := hex.EncodeToString(hmacSHA256(s3SigningKey(u.opts.SecretAccessKey, date, u.opts.Region, "s3"), stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+u.opts.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// s3SigningKey は SigV4 の署名鍵を導出します
+func s3SigningKey(secret, date, region, service string) []byte {
+	k := hmacSHA256([]byte("AWS4"+secret), date)
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	return hmacSHA256(k, "aws4_request")
+}
+
+// hmacSHA256 は key による data の HMAC-SHA256 を返します
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// s3EscapePath は SigV4 の規則（RFC 3986 の非予約文字以外をエンコードし、"/" は残す）でパスをエスケープします
+func s3EscapePath(p string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if isLetter(c) || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&15])
+		}
+	}
+	return b.String()
+}
+
+// s3ContentType はファイルの拡張子から Content-Type を決めます
+func s3ContentType(path string) string {
+	switch filepath.Ext(path) {
+	case ".gz":
+		return "application/gzip"
+	case ".zst":
+		return "application/zstd"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
@@ -0,0 +1,139 @@
+package loggo
+
+import (
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// s3Request はテスト用のサーバーが受け取ったリクエスト
+type s3Request struct {
+	method, path string
+	header       http.Header
+	body         string
+}
+
+// newS3Server は受け取ったリクエストを記録して status で応答するサーバーを開始します
+func newS3Server(t *testing.T, status int) (*httptest.Server, func() []s3Request) {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		reqs []s3Request
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		reqs = append(reqs, s3Request{r.Method, r.URL.EscapedPath(), r.Header.Clone(), string(b)})
+		mu.Unlock()
+		rw.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []s3Request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]s3Request(nil), reqs...)
+	}
+}
+
+// TestS3Upload はテンプレートから作ったキーへ署名付きの PUT でアップロードすることをテストします
+func TestS3Upload(t *testing.T) {
+	srv, requests := newS3Server(t, http.StatusOK)
+	u, err := NewS3Uploader(&S3UploaderOptions{
+		Bucket:            "logs",
+		Region:            "ap-northeast-1",
+		Endpoint:          srv.URL,
+		AccessKeyID:       "AKID",
+		SecretAccessKey:   "secret",
+		SessionToken:      "token",
+		KeyTemplate:       "app/{yyyy}/{mm}/{dd}/{hh}/{name}",
+		StorageClass:      "STANDARD_IA",
+		DeleteAfterUpload: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.now = func() time.Time { return time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC) }
+
+	path := filepath.Join(t.TempDir(), "app-2024-03-05T08-59-59.000.log.gz")
+	if err := os.WriteFile(path, []byte("archived"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 3, 5, 8, 59, 59, 0, time.UTC)
+	os.Chtimes(path, mtime, mtime)
+
+	if err := u.Upload(path); err != nil {
+		t.Fatal(err)
+	}
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("requests = %d, want 1", len(reqs))
+	}
+	r := reqs[0]
+	if r.method != http.MethodPut || r.path != "/logs/app/2024/03/05/08/app-2024-03-05T08-59-59.000.log.gz" || r.body != "archived" {
+		t.Errorf("unexpected request: %s %s %q", r.method, r.path, r.body)
+	}
+	if r.header.Get("Content-Type") != "application/gzip" || r.header.Get("X-Amz-Storage-Class") != "STANDARD_IA" ||
+		r.header.Get("X-Amz-Security-Token") != "token" || r.header.Get("X-Amz-Date") != "20240305T090000Z" {
+		t.Errorf("unexpected headers: %v", r.header)
+	}
+	auth := r.header.Get("Authorization")
+	wantPrefix := "AWS4-HMAC-SHA256 Credential=AKID/20240305/ap-northeast-1/s3/aws4_request, " +
+		"SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token;x-amz-storage-class, Signature="
+	if !strings.HasPrefix(auth, wantPrefix) || len(auth) != len(wantPrefix)+64 {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("file should be deleted after upload")
+	}
+}
+
+// TestS3UploadError はエラーのレスポンスでファイルを残してエラーを返すことをテストします
+func TestS3UploadError(t *testing.T) {
+	srv, _ := newS3Server(t, http.StatusForbidden)
+	u, err := NewS3Uploader(&S3UploaderOptions{Bucket: "logs", Endpoint: srv.URL, DeleteAfterUpload: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("x"), 0o644)
+	if err := u.Upload(path); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Upload = %v, want 403 error", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error("file should be kept after a failed upload")
+	}
+}
+
+// TestS3SigningKey は AWS のドキュメントの例と同じ署名鍵を導出することをテストします
+func TestS3SigningKey(t *testing.T) {
+	key := s3SigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("signing key = %s, want %s", got, want)
+	}
+}
+
+// TestS3ObjectURL は仮想ホスト形式とパス形式の URL、キーのエスケープをテストします
+func TestS3ObjectURL(t *testing.T) {
+	u, err := NewS3Uploader(&S3UploaderOptions{Bucket: "logs", Region: "eu-west-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.objectURL("a b/c+d.log"), "https://logs.s3.eu-west-1.amazonaws.com/a%20b/c%2Bd.log"; got != want {
+		t.Errorf("objectURL = %q, want %q", got, want)
+	}
+	u.opts.PathStyle = true
+	if got, want := u.objectURL("x.log"), "https://s3.eu-west-1.amazonaws.com/logs/x.log"; got != want {
+		t.Errorf("objectURL = %q, want %q", got, want)
+	}
+	if _, err := NewS3Uploader(&S3UploaderOptions{}); err == nil {
+		t.Error("expected error without bucket")
+	}
+}
@@ -0,0 +1,254 @@
+package loggo
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// countersPerLevel はレベルごとのカウンターの数。
+// メッセージのハッシュでカウンターを選ぶため、異なるメッセージが同じカウンターを共有することがあります。
+const countersPerLevel = 1024
+
+// SamplingOptions はサンプリングの設定
+type SamplingOptions struct {
+	// Tick はカウンターをリセットする間隔。0 の場合は1秒
+	Tick time.Duration
+	// First は Tick ごとに、同じレベルとメッセージのレコードを無条件に出力する件数
+	First int
+	// Thereafter は First 件を超えたレコードのうち、何件ごとに1件を出力するか。0 の場合は以降をすべて破棄します
+	Thereafter int
+}
+
+// Sampler は同じレベルとメッセージのレコードを Tick ごとに最初の First 件まで出力し、
+// 以降は Thereafter 件ごとに1件だけ出力するハンドラー（zap のサンプリングと同じ方式）
+type Sampler struct {
+	next  slog.Handler
+	state *samplerState
+}
+
+// samplerState は WithAttrs や WithGroup で作られたハンドラー間で共有される状態
+type samplerState struct {
+	tick       time.Duration
+	first      uint64
+	thereafter uint64
+	counters   [4][countersPerLevel]samplingCounter
+	sampled    atomic.Uint64
+	dropped    atomic.Uint64
+}
+
+// samplingCounter は Tick ごとにリセットされるカウンター
+type samplingCounter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+// NewSampler は next にレコードをサンプリングして渡すハンドラーを作成します
+func NewSampler(next slog.Handler, opts SamplingOptions) *Sampler {
+	tick := opts.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	return &Sampler{
+		next: next,
+		state: &samplerState{
+			tick:       tick,
+			first:      uint64(max(opts.First, 0)),
+			thereafter: uint64(max(opts.Thereafter, 0)),
+		},
+	}
+}
+
+// Enabled は次のハンドラーの判定を返します
+func (s *Sampler) Enabled(ctx context.Context, level slog.Level) bool {
+	return s.next.Enabled(ctx, level)
+}
+
+// Handle はサンプリングの対象となったレコードのみを次のハンドラーに渡します
+func (s *Sampler) Handle(ctx context.Context, r slog.Record) error {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	st := s.state
+	c := &st.counters[levelIndex(r.Level)][fnv32a(r.Message)%countersPerLevel]
+	n := c.incCheckReset(t, st.tick)
+	if n > st.first && (st.thereafter == 0 || (n-st.first)%st.thereafter != 0) {
+		st.dropped.Add(1)
+		return nil
+	}
+	st.sampled.Add(1)
+	return s.next.Handle(ctx, r)
+}
+
+// WithAttrs はカウンターを共有したまま、属性を追加した次のハンドラーを持つハンドラーを返します
+func (s *Sampler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Sampler{next: s.next.WithAttrs(attrs), state: s.state}
+}
+
+// WithGroup はカウンターを共有したまま、グループを追加した次のハンドラーを持つハンドラーを返します
+func (s *Sampler) WithGroup(name string) slog.Handler {
+	return &Sampler{next: s.next.WithGroup(name), state: s.state}
+}
+
+// Sampled はこれまでに次のハンドラーへ渡したレコードの数を返します
+func (s *Sampler) Sampled() uint64 {
+	return s.state.sampled.Load()
+}
+
+// Dropped はこれまでに破棄したレコードの数を返します
+func (s *Sampler) Dropped() uint64 {
+	return s.state.dropped.Load()
+}
+
+// incCheckReset はカウンターを増やして値を返します。Tick が経過していた場合は 1 にリセットします。
+func (c *samplingCounter) incCheckReset(t time.Time, tick time.Duration) uint64 {
+	now := t.UnixNano()
+	resetAt := c.resetAt.Load()
+	if resetAt > now {
+		return c.count.Add(1)
+	}
+
+	c.count.Store(1)
+	if !c.resetAt.CompareAndSwap(resetAt, now+int64(tick)) {
+		// 他のゴルーチンが先にリセットした
+		return c.count.Add(1)
+	}
+	return 1
+}
+
+// levelIndex は標準のレベルごとにカウンターの組を選びます
+func levelIndex(level slog.Level) int {
+	switch {
+	case level < slog.LevelInfo:
+		return 0
+	case level < slog.LevelWarn:
+		return 1
+	case level < slog.LevelError:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// fnv32a はアロケーションなしでメッセージのハッシュ値を計算します
+func fnv32a(s string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= prime32
+	}
+	return h
+}
+
+// AdaptiveSamplingOptions は AdaptiveSampler の設定
+type AdaptiveSamplingOptions struct {
+	// Budget は1秒あたりに出力するレコード数の目安
+	Budget int
+	// Window は流量を計測して間引きの割合を見直す間隔。0 の場合は1秒
+	Window time.Duration
+	// Preserve 以上のレベルのレコードは間引かずに常に出力します。nil の場合は slog.LevelError
+	Preserve slog.Leveler
+}
+
+// AdaptiveSampler は流量を監視し、Budget を超えた場合に間引きの割合を自動的に強めるハンドラー。
+// 障害時などにログが急増してもディスクを保護し、流量が落ち着くと間引きを緩めます。
+type AdaptiveSampler struct {
+	next  slog.Handler
+	state *adaptiveState
+}
+
+// adaptiveState は WithAttrs や WithGroup で作られたハンドラー間で共有される状態
+type adaptiveState struct {
+	budget   float64
+	window   time.Duration
+	preserve slog.Leveler
+
+	windowStart atomic.Int64  // 計測中の区間の開始時刻（UnixNano）
+	observed    atomic.Uint64 // 計測中の区間に到着したレコード数
+	rate        atomic.Uint64 // N 件に1件を出力する
+	dropped     atomic.Uint64
+}
+
+// NewAdaptiveSampler は next にレコードを流量に応じて間引いて渡すハンドラーを作成します
+func NewAdaptiveSampler(next slog.Handler, opts AdaptiveSamplingOptions) *AdaptiveSampler {
+	window := opts.Window
+	if window <= 0 {
+		window = time.Second
+	}
+	preserve := opts.Preserve
+	if preserve == nil {
+		preserve = slog.LevelError
+	}
+	st := &adaptiveState{
+		budget:   float64(max(opts.Budget, 1)),
+		window:   window,
+		preserve: preserve,
+	}
+	st.rate.Store(1)
+	return &AdaptiveSampler{next: next, state: st}
+}
+
+// Enabled は次のハンドラーの判定を返します
+func (s *AdaptiveSampler) Enabled(ctx context.Context, level slog.Level) bool {
+	return s.next.Enabled(ctx, level)
+}
+
+// Handle は現在の割合で間引いたレコードを次のハンドラーに渡します
+func (s *AdaptiveSampler) Handle(ctx context.Context, r slog.Record) error {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	st := s.state
+	st.adjust(t.UnixNano())
+	seq := st.observed.Add(1)
+
+	if r.Level < st.preserve.Level() && seq%st.rate.Load() != 0 {
+		st.dropped.Add(1)
+		return nil
+	}
+	return s.next.Handle(ctx, r)
+}
+
+// adjust は区間が終わっていれば、その区間の流量から次の区間の割合を決めます
+func (st *adaptiveState) adjust(now int64) {
+	start := st.windowStart.Load()
+	if start == 0 {
+		st.windowStart.CompareAndSwap(0, now)
+		return
+	}
+	elapsed := now - start
+	if elapsed < int64(st.window) || !st.windowStart.CompareAndSwap(start, now) {
+		return
+	}
+	perSecond := float64(st.observed.Swap(0)) / time.Duration(elapsed).Seconds()
+	rate := uint64(math.Ceil(perSecond / st.budget))
+	st.rate.Store(max(rate, 1))
+}
+
+// WithAttrs は状態を共有したまま、属性を追加した次のハンドラーを持つハンドラーを返します
+func (s *AdaptiveSampler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &AdaptiveSampler{next: s.next.WithAttrs(attrs), state: s.state}
+}
+
+// WithGroup は状態を共有したまま、グループを追加した次のハンドラーを持つハンドラーを返します
+func (s *AdaptiveSampler) WithGroup(name string) slog.Handler {
+	return &AdaptiveSampler{next: s.next.WithGroup(name), state: s.state}
+}
+
+// Rate は現在の割合（N 件に1件を出力）を返します。1 の場合は間引いていません。
+func (s *AdaptiveSampler) Rate() uint64 {
+	return s.state.rate.Load()
+}
+
+// Dropped はこれまでに破棄したレコードの数を返します
+func (s *AdaptiveSampler) Dropped() uint64 {
+	return s.state.dropped.Load()
+}
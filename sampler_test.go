@@ -0,0 +1,101 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSampler は最初の First 件の後に Thereafter 件ごとに出力されることをテストします
+func TestSampler(t *testing.T) {
+	var buf bytes.Buffer
+	sampler := NewSampler(NewHandler(&buf, nil), SamplingOptions{Tick: time.Hour, First: 3, Thereafter: 5})
+	logger := slog.New(sampler)
+
+	for i := range 20 {
+		logger.Info("repeated", "i", i)
+	}
+	logger.Info("other")
+	logger.Warn("repeated")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	var got []string
+	for _, line := range lines {
+		if strings.Contains(line, `msg="repeated" i=`) {
+			got = append(got, line[strings.Index(line, "i="):])
+		}
+	}
+	want := []string{"i=0", "i=1", "i=2", "i=7", "i=12", "i=17"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected sampled records: %v", got)
+	}
+	if !strings.Contains(buf.String(), `msg="other"`) || !strings.Contains(buf.String(), `[ WARN] msg="repeated"`) {
+		t.Errorf("different messages and levels should be counted separately, got: %s", buf.String())
+	}
+	if sampler.Sampled() != 8 || sampler.Dropped() != 14 {
+		t.Errorf("unexpected counters: sampled=%d dropped=%d", sampler.Sampled(), sampler.Dropped())
+	}
+}
+
+// TestSamplerReset は Tick が経過するとカウンターがリセットされることをテストします
+func TestSamplerReset(t *testing.T) {
+	var buf bytes.Buffer
+	sampler := NewSampler(NewHandler(&buf, nil), SamplingOptions{Tick: time.Minute, First: 1})
+	h := sampler.WithAttrs([]slog.Attr{slog.String("shared", "state")})
+
+	start := time.Now()
+	for _, offset := range []time.Duration{0, time.Second, 2 * time.Minute, 2*time.Minute + time.Second} {
+		r := slog.NewRecord(start.Add(offset), slog.LevelInfo, "tick", 0)
+		if err := h.Handle(t.Context(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Errorf("expected 2 records (one per tick), got %d: %s", n, buf.String())
+	}
+	if sampler.Dropped() != 2 {
+		t.Errorf("handlers created by WithAttrs should share counters, dropped=%d", sampler.Dropped())
+	}
+}
+
+// TestAdaptiveSampler は流量に応じて間引きの割合が変わることをテストします
+func TestAdaptiveSampler(t *testing.T) {
+	var buf bytes.Buffer
+	sampler := NewAdaptiveSampler(NewHandler(&buf, nil), AdaptiveSamplingOptions{Budget: 10, Window: time.Second})
+	h := sampler.WithGroup("g")
+
+	start := time.Now()
+	emit := func(offset time.Duration, level slog.Level, count int) int {
+		before := strings.Count(buf.String(), "\n")
+		for i := range count {
+			at := start.Add(offset + time.Duration(i)*time.Millisecond)
+			if err := h.Handle(t.Context(), slog.NewRecord(at, level, "storm", 0)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return strings.Count(buf.String(), "\n") - before
+	}
+
+	if n := emit(0, slog.LevelInfo, 100); n != 100 {
+		t.Errorf("first window should not be sampled, got %d", n)
+	}
+	if n := emit(time.Second, slog.LevelInfo, 100); n != 10 {
+		t.Errorf("expected 1 in 10 after exceeding budget, got %d (rate %d)", n, sampler.Rate())
+	}
+	if n := emit(time.Second+500*time.Millisecond, slog.LevelError, 5); n != 5 {
+		t.Errorf("records at Preserve level should not be sampled, got %d", n)
+	}
+	// 直前の区間は 105 件/秒のため、11 件に1件となりこの 3 件はすべて破棄される
+	if n := emit(2*time.Second, slog.LevelInfo, 3); n != 0 {
+		t.Errorf("expected all records to be dropped, got %d (rate %d)", n, sampler.Rate())
+	}
+	if n := emit(3*time.Second, slog.LevelInfo, 10); n != 10 || sampler.Rate() != 1 {
+		t.Errorf("sampling should relax when volume drops, got %d records (rate %d)", n, sampler.Rate())
+	}
+	if sampler.Dropped() != 93 {
+		t.Errorf("expected 93 dropped records, got %d", sampler.Dropped())
+	}
+}
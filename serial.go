@@ -0,0 +1,244 @@
+package loggo
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// serialBufPool は serialOutput のキューに追加するレコードのバッファのプール
+var serialBufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// serialItem は serialOutput のキューの要素。p が nil の場合は制御の要求です。
+type serialItem struct {
+	p    *[]byte
+	w    io.Writer  // 制御の要求で nil でない場合は出力先を置き換える
+	done chan error // 制御の要求の完了を通知する
+}
+
+// serialOutput はレコードを容量のあるチャネルに送り、1つのゴルーチンがすべての書き込みを行う output。
+//
+// Handle の経路には共有のミューテックスが無く、書き込み側のゴルーチンはチャネルに溜まった
+// レコードを1回の Write にまとめます。チャネルが満杯の場合、Handle は空きができるまで待機します。
+// 出力先とエラーは書き込み側のゴルーチンだけが扱い、flush と setWriter もチャネルを通して要求します。
+type serialOutput struct {
+	ch        chan serialItem
+	stop      chan struct{}
+	done      chan struct{}
+	closed    atomic.Bool
+	writers   atomic.Int32 // closed を確認してからチャネルに追加するまでの write の数
+	abandoned atomic.Bool  // shutdown の期限を過ぎた場合に true。残りのレコードを書き出さない
+
+	// 以下のフィールドは書き込み側のゴルーチンのみが扱う（done のクローズ後は shutdown が読み取る）
+	w     io.Writer
+	err   error
+	batch []byte
+}
+
+func newSerialOutput(w io.Writer, queueSize int) *serialOutput {
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	o := &serialOutput{
+		ch:   make(chan serialItem, queueSize),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+		w:    w,
+	}
+	go o.loop()
+	registerDrainer(o)
+	return o
+}
+
+func (o *serialOutput) write(p []byte) error {
+	// shutdown は closed を設定した後、writers が 0 になるまでチャネルのレコードを取り出す
+	o.writers.Add(1)
+	defer o.writers.Add(-1)
+	if o.closed.Load() {
+		return ErrClosed
+	}
+	bp := serialBufPool.Get().(*[]byte)
+	*bp = append((*bp)[:0], p...)
+	select {
+	case o.ch <- serialItem{p: bp}:
+		return nil
+	case <-o.done:
+		serialBufPool.Put(bp)
+		return ErrClosed
+	}
+}
+
+// loop はチャネルのレコードを書き出し、制御の要求を処理します
+func (o *serialOutput) loop() {
+	defer close(o.done)
+	for !o.abandoned.Load() {
+		select {
+		case item := <-o.ch:
+			o.handle(item)
+		case <-o.stop:
+			// 停止の前にチャネルに追加されたレコードを書き出す
+			for !o.abandoned.Load() {
+				select {
+				case item := <-o.ch:
+					o.handle(item)
+				default:
+					return
+				}
+			}
+			return
+		}
+	}
+}
+
+// handle は item を処理します。レコードの場合は、続けてチャネルに溜まっているレコードをまとめて書き出します。
+func (o *serialOutput) handle(item serialItem) {
+	for item.p != nil {
+		o.batch = append(o.batch, *item.p...)
+		releaseSerialBuf(item.p)
+		if len(o.batch) >= maxRetainedBatchSize {
+			item = serialItem{}
+			break
+		}
+		select {
+		case item = <-o.ch:
+			continue
+		default:
+			item = serialItem{}
+		}
+	}
+	if len(o.batch) > 0 {
+		if _, err := o.w.Write(o.batch); err != nil {
+			o.err = err
+		}
+		if cap(o.batch) > maxRetainedBatchSize {
+			o.batch = nil
+		} else {
+			o.batch = o.batch[:0]
+		}
+	}
+
+	if item.done != nil {
+		o.control(item)
+	}
+}
+
+// control は flush または setWriter の要求を処理し、前回の flush 以降のエラーを返します
+func (o *serialOutput) control(item serialItem) {
+	if item.w != nil {
+		o.w = item.w
+		item.done <- nil
+		return
+	}
+	item.done <- o.err
+	o.err = nil
+}
+
+// request は制御の要求をチャネルに送り、書き込み側のゴルーチンが処理するまで待機します。
+// ゴルーチンが停止していて処理されなかった場合は false を返します。
+func (o *serialOutput) request(item serialItem) (bool, error) {
+	item.done = make(chan error, 1)
+	select {
+	case o.ch <- item:
+	case <-o.done:
+		return false, nil
+	}
+	select {
+	case err := <-item.done:
+		return true, err
+	case <-o.done:
+		// 停止時に処理された可能性がある
+		select {
+		case err := <-item.done:
+			return true, err
+		default:
+			return false, nil
+		}
+	}
+}
+
+// flush はそれまでにチャネルに追加されたレコードが書き出されるまで待機し、
+// 前回の flush 以降に発生した書き込みエラーを返します
+func (o *serialOutput) flush() error {
+	_, err := o.request(serialItem{})
+	return err
+}
+
+// setWriter はそれまでにチャネルに追加されたレコードを現在の出力先へ書き出してから、出力先を置き換えます
+func (o *serialOutput) setWriter(w io.Writer) {
+	if ok, _ := o.request(serialItem{w: w}); !ok {
+		<-o.done
+		o.w = w
+	}
+}
+
+func (o *serialOutput) close() error {
+	_, err := o.shutdown(context.Background())
+	return err
+}
+
+// shutdown は新しいレコードの受け付けを停止し、ctx の期限までチャネルのレコードを書き出します。
+// 期限を過ぎた場合は書き出し中のバッチの完了を待たず、チャネルに残っていたレコードを破棄してその数を返します。
+func (o *serialOutput) shutdown(ctx context.Context) (int, error) {
+	if o.closed.Swap(true) {
+		return 0, nil
+	}
+	close(o.stop)
+	unregisterDrainer(o)
+
+	select {
+	case <-o.done:
+		// 書き込み側のゴルーチンが停止した後にチャネルへ追加されたレコードを書き出す
+		o.drainRemaining(func(p []byte) { o.batch = append(o.batch, p...) })
+		if len(o.batch) > 0 {
+			if _, err := o.w.Write(o.batch); err != nil {
+				o.err = err
+			}
+			o.batch = nil
+		}
+		err := o.err
+		o.err = nil
+		return 0, err
+	case <-ctx.Done():
+	}
+
+	o.abandoned.Store(true)
+	undelivered := 0
+	o.drainRemaining(func([]byte) { undelivered++ })
+	return undelivered, nil
+}
+
+// drainRemaining は closed の設定後に、チャネルに残っているレコードを fn に渡して取り出します。
+// 書き込み中の write が無くなり、チャネルが空になるまで繰り返すため、write が nil を返したレコードは必ず取り出されます。
+// 制御の要求には ErrClosed を返します。
+func (o *serialOutput) drainRemaining(fn func(p []byte)) {
+	for {
+		select {
+		case item := <-o.ch:
+			if item.p != nil {
+				fn(*item.p)
+				releaseSerialBuf(item.p)
+			} else {
+				item.done <- ErrClosed
+			}
+		default:
+			if o.writers.Load() == 0 && len(o.ch) == 0 {
+				return
+			}
+			runtime.Gosched()
+		}
+	}
+}
+
+// releaseSerialBuf は大きすぎないバッファをプールに戻します
+func releaseSerialBuf(bp *[]byte) {
+	if cap(*bp) <= maxRetainedBatchSize {
+		serialBufPool.Put(bp)
+	}
+}
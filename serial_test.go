@@ -0,0 +1,168 @@
+package loggo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSerialOrder は単一ゴルーチンからの書き込み順序が保持され、Flush で書き出されることをテストします
+func TestSerialOrder(t *testing.T) {
+	var out countingWriter
+	o := newSerialOutput(&out, 0)
+	defer o.close()
+
+	for _, s := range []string{"a\n", "b\n", "c\n"} {
+		if err := o.write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := o.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "a\nb\nc\n" {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+// TestSerialBatching は書き込み中にチャネルに溜まったレコードが1回の Write にまとめられることをテストします
+func TestSerialBatching(t *testing.T) {
+	out := newGateWriter()
+	h := NewHandler(out, &Options{WriteMode: WriteModeSerial})
+	logger := slog.New(h)
+
+	logger.Info("record", "i", 0)
+	<-out.started
+	for i := 1; i <= 5; i++ {
+		logger.Info("record", "i", i)
+	}
+	close(out.release)
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := recordIndexes(out.String()); got != "0,1,2,3,4,5" {
+		t.Errorf("expected all records in order, got %s", got)
+	}
+	if out.Writes() != 2 {
+		t.Errorf("expected queued records to be coalesced into one write, got %d writes", out.Writes())
+	}
+}
+
+// TestSerialConcurrent は並行して記録したレコードが失われず、行が混ざらないことをテストします
+func TestSerialConcurrent(t *testing.T) {
+	var out countingWriter
+	h := NewHandler(&out, &Options{WriteMode: WriteModeSerial, QueueSize: 4})
+	logger := slog.New(h)
+
+	const goroutines = 8
+	const iterations = 100
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Go(func() {
+			for i := range iterations {
+				logger.Info("concurrent", "goroutine", g, "iteration", i)
+			}
+		})
+	}
+	wg.Wait()
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != goroutines*iterations {
+		t.Fatalf("expected %d lines, got %d", goroutines*iterations, len(lines))
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, `msg="concurrent" goroutine=`) {
+			t.Fatalf("unexpected line: %q", line)
+		}
+	}
+}
+
+// TestSerialError は書き込みエラーが Flush から返されることをテストします
+func TestSerialError(t *testing.T) {
+	h := NewHandler(errorWriter{}, &Options{WriteMode: WriteModeSerial})
+	defer h.Close()
+	slog.New(h).Info("test")
+	if err := h.Flush(); err == nil {
+		t.Error("expected write error to be returned")
+	}
+	if err := h.Flush(); err != nil {
+		t.Errorf("expected error to be reported once, got %v", err)
+	}
+}
+
+// TestSerialClosed はクローズ後の書き込みがエラーになることをテストします
+func TestSerialClosed(t *testing.T) {
+	h := NewHandler(&countingWriter{}, &Options{WriteMode: WriteModeSerial})
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Handle(t.Context(), slog.NewRecord(time.Now(), slog.LevelInfo, "late", 0)); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if err := h.Flush(); err != nil {
+		t.Errorf("expected Flush after Close to succeed, got %v", err)
+	}
+}
+
+// TestSerialShutdownDeadline は期限までに書き出せなかったレコード数が返されることをテストします
+func TestSerialShutdownDeadline(t *testing.T) {
+	out := newGateWriter()
+	defer close(out.release)
+	logger := slog.New(NewHandler(out, &Options{WriteMode: WriteModeSerial}))
+
+	logger.Info("record", "i", 0)
+	<-out.started
+	logger.Info("record", "i", 1)
+	logger.Info("record", "i", 2)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	undelivered, err := Shutdown(ctx)
+	if undelivered != 2 {
+		t.Errorf("expected 2 undelivered records, got %d", undelivered)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+// TestSerialCloseRace はクローズと並行した書き込みが成功を返した場合、そのレコードが失われないことをテストします
+func TestSerialCloseRace(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	for range 50 {
+		var out countingWriter
+		o := newSerialOutput(&out, 4)
+
+		const goroutines = 8
+		var accepted sync.WaitGroup
+		var mu sync.Mutex
+		written := 0
+		for range goroutines {
+			accepted.Go(func() {
+				for o.write([]byte("x\n")) == nil {
+					mu.Lock()
+					written++
+					mu.Unlock()
+				}
+			})
+		}
+		time.Sleep(time.Millisecond)
+		if err := o.close(); err != nil {
+			t.Fatal(err)
+		}
+		accepted.Wait()
+
+		if got := strings.Count(out.String(), "\n"); got != written {
+			t.Fatalf("write returned nil for %d records, but %d were written", written, got)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package loggo
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// SeverityThreshold は SeverityMap の1つの段階。Level 以上のレベルを Severity に対応させます。
+type SeverityThreshold[T any] struct {
+	Level    slog.Level
+	Severity T
+}
+
+// SeverityMap はレベルを syslog や Cloud Logging などの外部の形式の重要度へ変換する表。
+// レベルは、そのレベル以下で最も高い段階の重要度になります。どの段階よりも低いレベルは最も低い段階の重要度です。
+// With でカスタムレベルの重要度を個別に指定できます。SeverityMap は変更できないため、複数のゴルーチンから使用できます。
+//
+//	const LevelNotice = slog.LevelInfo + 2
+//	severities := golog.SyslogSeverities.With(LevelNotice, 5)
+//	handler := golog.NewHandler(conn, &golog.Options{
+//	    Syslog: &golog.SyslogOptions{Severities: severities},
+//	})
+type SeverityMap[T any] struct {
+	thresholds []SeverityThreshold[T] // レベルの降順
+	overrides  map[slog.Level]T
+}
+
+// NewSeverityMap は段階から SeverityMap を作成します。段階の順序は問いません。段階が無い場合はゼロ値に変換します。
+func NewSeverityMap[T any](thresholds ...SeverityThreshold[T]) *SeverityMap[T] {
+	ts := slices.Clone(thresholds)
+	slices.SortStableFunc(ts, func(a, b SeverityThreshold[T]) int {
+		return int(b.Level) - int(a.Level)
+	})
+	return &SeverityMap[T]{thresholds: ts}
+}
+
+// With は level の重要度を severity に固定した SeverityMap を返します。m は変更しません。
+func (m *SeverityMap[T]) With(level slog.Level, severity T) *SeverityMap[T] {
+	m2 := &SeverityMap[T]{thresholds: m.thresholds, overrides: make(map[slog.Level]T, len(m.overrides)+1)}
+	maps.Copy(m2.overrides, m.overrides)
+	m2.overrides[level] = severity
+	return m2
+}
+
+// Lookup はレベルの重要度を返します
+func (m *SeverityMap[T]) Lookup(level slog.Level) T {
+	if s, ok := m.overrides[level]; ok {
+		return s
+	}
+	for _, t := range m.thresholds {
+		if level >= t.Level {
+			return t.Severity
+		}
+	}
+	if len(m.thresholds) == 0 {
+		var zero T
+		return zero
+	}
+	return m.thresholds[len(m.thresholds)-1].Severity
+}
+
+var (
+	// SyslogSeverities は syslog（RFC 3164/5424）の重要度への変換表。
+	// ERROR より 4 以上高いレベル（FATAL など）は crit (2)、ERROR は err (3)、WARN は warning (4)、
+	// INFO は info (6)、DEBUG 以下は debug (7) です。
+	SyslogSeverities = NewSeverityMap(
+		SeverityThreshold[int]{slog.LevelError + 4, 2},
+		SeverityThreshold[int]{slog.LevelError, 3},
+		SeverityThreshold[int]{slog.LevelWarn, 4},
+		SeverityThreshold[int]{slog.LevelInfo, 6},
+		SeverityThreshold[int]{slog.LevelDebug, 7},
+	)
+
+	// GELFSeverities は GELF の level への変換表。GELF は syslog と同じ値を使います。
+	GELFSeverities = SyslogSeverities
+
+	// GCPSeverities は Google Cloud Logging の LogSeverity への変換表。
+	// ERROR より 4 以上高いレベルは CRITICAL です。
+	GCPSeverities = NewSeverityMap(
+		SeverityThreshold[string]{slog.LevelError + 4, "CRITICAL"},
+		SeverityThreshold[string]{slog.LevelError, "ERROR"},
+		SeverityThreshold[string]{slog.LevelWarn, "WARNING"},
+		SeverityThreshold[string]{slog.LevelInfo, "INFO"},
+		SeverityThreshold[string]{slog.LevelDebug, "DEBUG"},
+	)
+
+	// OTLPSeverities は OpenTelemetry のログの SeverityNumber への変換表。
+	// DEBUG より低いレベルは TRACE (1)、DEBUG は 5、INFO は 9、WARN は 13、ERROR は 17、
+	// ERROR より 4 以上高いレベルは FATAL (21) です。
+	OTLPSeverities = NewSeverityMap(
+		SeverityThreshold[int]{slog.LevelError + 4, 21},
+		SeverityThreshold[int]{slog.LevelError, 17},
+		SeverityThreshold[int]{slog.LevelWarn, 13},
+		SeverityThreshold[int]{slog.LevelInfo, 9},
+		SeverityThreshold[int]{slog.LevelDebug, 5},
+		SeverityThreshold[int]{slog.LevelDebug - 4, 1},
+	)
+
+	// CEFSeverities は CEF の Severity（0〜10）への変換表
+	CEFSeverities = NewSeverityMap(
+		SeverityThreshold[int]{slog.LevelError + 4, 10},
+		SeverityThreshold[int]{slog.LevelError, 8},
+		SeverityThreshold[int]{slog.LevelWarn, 6},
+		SeverityThreshold[int]{slog.LevelInfo, 3},
+		SeverityThreshold[int]{slog.LevelDebug, 1},
+	)
+)
@@ -0,0 +1,73 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestSeverityMap は段階による変換と、With による個別の指定をテストします
+func TestSeverityMap(t *testing.T) {
+	const levelNotice = slog.LevelInfo + 2
+	m := GCPSeverities.With(levelNotice, "NOTICE")
+	tests := []struct {
+		level slog.Level
+		want  string
+	}{
+		{slog.LevelDebug - 8, "DEBUG"}, // 最も低い段階より低いレベル
+		{slog.LevelDebug, "DEBUG"},
+		{slog.LevelInfo, "INFO"},
+		{levelNotice, "NOTICE"},
+		{slog.LevelInfo + 3, "INFO"},
+		{slog.LevelWarn, "WARNING"},
+		{slog.LevelError, "ERROR"},
+		{slog.LevelError + 4, "CRITICAL"},
+	}
+	for _, tt := range tests {
+		if got := m.Lookup(tt.level); got != tt.want {
+			t.Errorf("Lookup(%v) = %q, want %q", tt.level, got, tt.want)
+		}
+	}
+	if got := GCPSeverities.Lookup(levelNotice); got != "INFO" {
+		t.Errorf("With should not modify the original map, got %q", got)
+	}
+	if got := NewSeverityMap[int]().Lookup(slog.LevelInfo); got != 0 {
+		t.Errorf("empty map = %d, want 0", got)
+	}
+}
+
+// TestOTLPSeverities はレベルから OpenTelemetry の SeverityNumber への変換をテストします
+func TestOTLPSeverities(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  int
+	}{
+		{slog.LevelDebug - 4, 1},
+		{slog.LevelDebug, 5},
+		{slog.LevelInfo, 9},
+		{slog.LevelWarn, 13},
+		{slog.LevelError, 17},
+		{slog.LevelError + 4, 21},
+	}
+	for _, tt := range tests {
+		if got := OTLPSeverities.Lookup(tt.level); got != tt.want {
+			t.Errorf("OTLPSeverities.Lookup(%v) = %d, want %d", tt.level, got, tt.want)
+		}
+	}
+}
+
+// TestSyslogSeverityOverride は SyslogOptions.Severities で指定した重要度を PRI に使うことをテストします
+func TestSyslogSeverityOverride(t *testing.T) {
+	const levelNotice = slog.LevelInfo + 2
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{
+		Syslog: &SyslogOptions{Severities: SyslogSeverities.With(levelNotice, 5)},
+	}))
+	logger.Log(t.Context(), levelNotice, "config reloaded")
+
+	// user (1) * 8 + notice (5) = 13
+	if !strings.HasPrefix(buf.String(), "<13>") {
+		t.Errorf("unexpected PRI: %q", buf.String())
+	}
+}
@@ -0,0 +1,260 @@
+package loggo
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// maxShardSize はシャードに蓄積できる最大バイト数。
+// 超えた場合は書き込んだゴルーチン自身がシャードを書き出します。
+const maxShardSize = 1 << 20 // 1MB
+
+// shard は1つのシャードのバッファ
+type shard struct {
+	mu   sync.Mutex
+	buf  []byte
+	recs []shardRecord // buf 内の各レコードの通し番号と終端
+	_    [16]byte      // 隣接するシャードとのフォールスシェアリングを避ける
+}
+
+// shardRecord はシャードに追加したレコードの通し番号と、buf 内の終端の位置
+type shardRecord struct {
+	seq uint64
+	end int
+}
+
+// shardSpan は書き出し時に通し番号順へ並べ替えるレコードの範囲
+type shardSpan struct {
+	seq        uint64
+	shard      int
+	start, end int
+}
+
+// shardedOutput は書き込みを複数のシャードに分散し、専用のゴルーチンが
+// まとめて出力先へ書き出す output。
+//
+// Handle はシャードへの追記のみを行うためミューテックスの競合がほとんど発生しません。
+// 各レコードにはシャードのロック中に通し番号を付け、書き出す際にすべてのシャードを
+// 通し番号順に並べ替えるため、1つのゴルーチンが書き込んだレコードは書き込んだ順に出力されます。
+type shardedOutput struct {
+	w      io.Writer
+	wmu    sync.Mutex // w への書き込みと、書き出し用のバッファを保護
+	shards []shard
+	seq    atomic.Uint64
+
+	// 書き出し用のバッファ（wmu で保護）
+	bufs  [][]byte        // シャードから取り出したバッファ
+	recs  [][]shardRecord // シャードから取り出したレコードの位置
+	spans []shardSpan
+	batch []byte
+
+	notify chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+
+	errMu  sync.Mutex
+	err    error
+	closed atomic.Bool
+}
+
+func newShardedOutput(w io.Writer) *shardedOutput {
+	n := runtime.GOMAXPROCS(0)
+	o := &shardedOutput{
+		w:      w,
+		shards: make([]shard, n),
+		bufs:   make([][]byte, n),
+		recs:   make([][]shardRecord, n),
+		notify: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go o.loop()
+	registerDrainer(o)
+	return o
+}
+
+func (o *shardedOutput) write(p []byte) error {
+	if o.closed.Load() {
+		return ErrClosed
+	}
+
+	// 空いているシャードを探し、すべて使用中の場合は最初の候補で待機する
+	n := len(o.shards)
+	start := int(o.seq.Load() % uint64(n))
+	s := &o.shards[start]
+	locked := false
+	for i := range n {
+		c := &o.shards[(start+i)%n]
+		if c.mu.TryLock() {
+			s, locked = c, true
+			break
+		}
+	}
+	if !locked {
+		s.mu.Lock()
+	}
+	// shutdown はすべてのシャードをロックして最後の書き出しを行うため、ロック中に確認すれば
+	// 最後の書き出しより後にレコードを追加することはない
+	if o.closed.Load() {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+
+	// 通し番号はロック中に採番するため、シャード内のレコードは常に通し番号順に並ぶ
+	s.buf = append(s.buf, p...)
+	s.recs = append(s.recs, shardRecord{seq: o.seq.Add(1), end: len(s.buf)})
+	full := len(s.buf) >= maxShardSize
+	s.mu.Unlock()
+
+	if full {
+		return o.drainAll()
+	}
+
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// loop は通知を受けるたびにすべてのシャードを書き出します
+func (o *shardedOutput) loop() {
+	defer close(o.done)
+	for {
+		select {
+		case <-o.notify:
+			o.drainAll()
+		case <-o.stop:
+			o.drainAll()
+			return
+		}
+	}
+}
+
+// drainAll はすべてのシャードのレコードを通し番号順に出力先へ書き出します
+func (o *shardedOutput) drainAll() error {
+	o.wmu.Lock()
+	defer o.wmu.Unlock()
+	return o.drainLocked()
+}
+
+// drainLocked は wmu を保持した状態ですべてのシャードのレコードを書き出します。
+//
+// すべてのシャードを同時にロックしてから取り出すため、取り出したレコードの中に
+// あるゴルーチンのレコードがあれば、そのゴルーチンがそれ以前に書き込んだレコードも必ず含まれます。
+func (o *shardedOutput) drainLocked() error {
+	for i := range o.shards {
+		o.shards[i].mu.Lock()
+	}
+	total := 0
+	for i := range o.shards {
+		s := &o.shards[i]
+		o.bufs[i], s.buf = s.buf, o.bufs[i][:0]
+		o.recs[i], s.recs = s.recs, o.recs[i][:0]
+		total += len(o.recs[i])
+	}
+	for i := range o.shards {
+		o.shards[i].mu.Unlock()
+	}
+	if total == 0 {
+		return nil
+	}
+
+	spans := o.spans[:0]
+	for i, recs := range o.recs {
+		start := 0
+		for _, r := range recs {
+			spans = append(spans, shardSpan{seq: r.seq, shard: i, start: start, end: r.end})
+			start = r.end
+		}
+	}
+	slices.SortFunc(spans, func(a, b shardSpan) int { return cmp.Compare(a.seq, b.seq) })
+	batch := o.batch[:0]
+	for _, sp := range spans {
+		batch = append(batch, o.bufs[sp.shard][sp.start:sp.end]...)
+	}
+
+	_, err := o.w.Write(batch)
+
+	o.spans = spans[:0]
+	o.batch = retainBatch(batch)
+	for i := range o.bufs {
+		o.bufs[i] = retainBatch(o.bufs[i])
+	}
+
+	if err != nil {
+		o.errMu.Lock()
+		o.err = err
+		o.errMu.Unlock()
+	}
+	return err
+}
+
+// retainBatch は再利用する容量のバッファを空にして返し、大きすぎるバッファは破棄します
+func retainBatch(b []byte) []byte {
+	if cap(b) > maxRetainedBatchSize {
+		return nil
+	}
+	return b[:0]
+}
+
+// flush はすべてのシャードを書き出し、前回の flush 以降に発生した書き込みエラーを返します
+func (o *shardedOutput) flush() error {
+	o.drainAll()
+
+	o.errMu.Lock()
+	err := o.err
+	o.err = nil
+	o.errMu.Unlock()
+	return err
+}
+
+// setWriter はすべてのシャードを現在の出力先へ書き出してから、出力先を置き換えます
+func (o *shardedOutput) setWriter(w io.Writer) {
+	o.wmu.Lock()
+	defer o.wmu.Unlock()
+	o.drainLocked()
+	o.w = w
+}
+
+func (o *shardedOutput) close() error {
+	_, err := o.shutdown(context.Background())
+	return err
+}
+
+// shutdown は新しいレコードの受け付けを停止し、ctx の期限まですべてのシャードを書き出します。
+// 期限を過ぎた場合はシャードに残っていたレコードを破棄し、その数を返します。
+func (o *shardedOutput) shutdown(ctx context.Context) (int, error) {
+	if o.closed.Swap(true) {
+		return 0, nil
+	}
+	close(o.stop)
+	unregisterDrainer(o)
+
+	select {
+	case <-o.done:
+		return 0, o.flush()
+	case <-ctx.Done():
+	}
+
+	// 書き出し中のバッチの完了は待たず、シャードに残っているレコードを破棄する
+	undelivered := 0
+	for i := range o.shards {
+		s := &o.shards[i]
+		s.mu.Lock()
+		undelivered += len(s.recs)
+		s.buf = s.buf[:0]
+		s.recs = s.recs[:0]
+		s.mu.Unlock()
+	}
+	o.errMu.Lock()
+	err := o.err
+	o.err = nil
+	o.errMu.Unlock()
+	return undelivered, err
+}
@@ -0,0 +1,180 @@
+package loggo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestShardedOutput はシャードモードで全レコードが欠落なく書き出されることをテストします
+func TestShardedOutput(t *testing.T) {
+	var out countingWriter
+	h := NewHandler(&out, &Options{WriteMode: WriteModeSharded})
+	logger := slog.New(h)
+
+	const goroutines = 20
+	const iterations = 50
+
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for i := range iterations {
+				logger.Info("sharded", "goroutine", id, "iteration", i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != goroutines*iterations {
+		t.Fatalf("expected %d lines, got %d", goroutines*iterations, len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "[") || !strings.Contains(line, `msg="sharded"`) {
+			t.Fatalf("line was corrupted: %q", line)
+		}
+	}
+}
+
+// TestShardedOutputFlush は Flush でシャードの内容が書き出されることをテストします
+func TestShardedOutputFlush(t *testing.T) {
+	var out countingWriter
+	h := NewHandler(&out, &Options{WriteMode: WriteModeSharded})
+	defer h.Close()
+
+	logger := slog.New(h).With("shared", "output")
+	logger.Info("first")
+	logger.Info("second")
+
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.Contains(out.String(), `msg="first"`) || !strings.Contains(out.String(), `msg="second"`) {
+		t.Errorf("expected both records after flush, got: %q", out.String())
+	}
+}
+
+// TestShardedOutputClosed はクローズ後の書き込みがエラーになることをテストします
+func TestShardedOutputClosed(t *testing.T) {
+	h := NewHandler(&bytes.Buffer{}, &Options{WriteMode: WriteModeSharded})
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("second Close should be a no-op, got %v", err)
+	}
+
+	if err := h.Handle(t.Context(), slog.Record{Message: "late"}); err != ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+// BenchmarkHandleConcurrentSharded はシャードモードでの並行ログ出力のベンチマークです
+func BenchmarkHandleConcurrentSharded(b *testing.B) {
+	h := NewHandler(discardWriter{}, &Options{
+		Level:     slog.LevelInfo,
+		WriteMode: WriteModeSharded,
+	})
+	defer h.Close()
+
+	logger := slog.New(h)
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			logger.Info("benchmark test", "iteration", i, "data", "some data")
+			i++
+		}
+	})
+}
+
+// TestShardedOutputOrder は1つのゴルーチンのレコードがシャードをまたいでも書き込んだ順に出力されることをテストします
+func TestShardedOutputOrder(t *testing.T) {
+	// シャードが複数になるようにする
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	var out countingWriter
+	h := NewHandler(&out, &Options{WriteMode: WriteModeSharded})
+	logger := slog.New(h)
+
+	const goroutines = 8
+	const iterations = 500
+
+	// 他のゴルーチンがシャードを使用中にして、書き込み先のシャードを切り替えさせる
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Go(func() {
+			for i := range iterations {
+				logger.Info("ordered", "goroutine", g, "iteration", i)
+			}
+		})
+	}
+	wg.Wait()
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	next := make(map[string]int)
+	for line := range strings.Lines(out.String()) {
+		_, rest, _ := strings.Cut(line, "goroutine=")
+		id, iter, _ := strings.Cut(strings.TrimSpace(rest), " iteration=")
+		var i int
+		if _, err := fmt.Sscan(iter, &i); err != nil {
+			t.Fatalf("unexpected line: %q", line)
+		}
+		if i != next[id] {
+			t.Fatalf("goroutine %s: expected iteration %d, got %d", id, next[id], i)
+		}
+		next[id]++
+	}
+	if len(next) != goroutines {
+		t.Fatalf("expected %d goroutines, got %d", goroutines, len(next))
+	}
+}
+
+// TestShardedShutdownRace は shutdown と並行した書き込みが成功を返した場合、そのレコードが失われないことをテストします
+func TestShardedShutdownRace(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	for range 50 {
+		var out countingWriter
+		o := newShardedOutput(&out)
+
+		const goroutines = 8
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		written := 0
+		for range goroutines {
+			wg.Go(func() {
+				for o.write([]byte("x\n")) == nil {
+					mu.Lock()
+					written++
+					mu.Unlock()
+				}
+			})
+		}
+		time.Sleep(time.Millisecond)
+		if _, err := o.shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		wg.Wait()
+
+		if got := strings.Count(out.String(), "\n"); got != written {
+			t.Fatalf("write returned nil for %d records, but %d were written", written, got)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package loggo
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// drainer はバックグラウンドで書き出しを行い、終了時に残りのレコードを書き出す必要がある出力先
+type drainer interface {
+	// shutdown は新しいレコードの受け付けを停止し、ctx の期限まで残りのレコードを書き出します。
+	// 期限までに書き出せなかったレコード数と、書き出し中に発生したエラーを返します。
+	shutdown(ctx context.Context) (undelivered int, err error)
+}
+
+// drainers は Shutdown の対象となる出力先。Close または Shutdown で取り除かれます。
+// 出力先は書き出し用のゴルーチンからも参照されているため、弱い参照にしてもガベージコレクションの対象にはなりません。
+// 使い終わった出力先は Close で取り除き、ゴルーチンを停止する必要があります。
+var drainers struct {
+	mu sync.Mutex
+	m  map[drainer]struct{}
+}
+
+// registerDrainer は d を Shutdown の対象に追加します
+func registerDrainer(d drainer) {
+	drainers.mu.Lock()
+	defer drainers.mu.Unlock()
+	if drainers.m == nil {
+		drainers.m = make(map[drainer]struct{})
+	}
+	drainers.m[d] = struct{}{}
+}
+
+// unregisterDrainer は d を Shutdown の対象から取り除きます
+func unregisterDrainer(d drainer) {
+	drainers.mu.Lock()
+	defer drainers.mu.Unlock()
+	delete(drainers.m, d)
+}
+
+// Shutdown はバックグラウンドで書き出しを行うすべての出力先（WriteModeSharded, WriteModeAsync, WriteModeSerial, WebhookWriter）で
+// 新しいレコードの受け付けを停止し、ctx の期限まで残りのレコードを並行して書き出します。
+// 期限までに書き出せずに破棄したレコード数を返します。期限を過ぎた場合は ctx.Err() を、
+// 書き出し中にエラーが発生した場合はそのエラーを合わせて返します。
+// シグナルを受け取った後のプロセスの終了処理で呼び出します。出力先の io.Writer はクローズしません。
+//
+// 登録した出力先は Close または Shutdown を呼び出すまで保持され、書き出し用のゴルーチンも動き続けます。
+// リクエストごとなど、プロセスより短い期間だけ使うハンドラーやライターは、使い終わったら必ず Close を呼び出してください。
+func Shutdown(ctx context.Context) (undelivered int, err error) {
+	drainers.mu.Lock()
+	targets := make([]drainer, 0, len(drainers.m))
+	for d := range drainers.m {
+		targets = append(targets, d)
+	}
+	drainers.m = nil
+	drainers.mu.Unlock()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for _, d := range targets {
+		wg.Go(func() {
+			n, derr := d.shutdown(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			undelivered += n
+			if derr != nil {
+				errs = append(errs, derr)
+			}
+		})
+	}
+	wg.Wait()
+
+	if undelivered > 0 {
+		errs = append(errs, ctx.Err())
+	}
+	return undelivered, errors.Join(errs...)
+}
@@ -0,0 +1,63 @@
+package loggo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestShutdown はバックグラウンドで書き出す出力先のレコードがすべて書き出されることをテストします
+func TestShutdown(t *testing.T) {
+	var asyncOut, shardedOut countingWriter
+	asyncHandler := NewHandler(&asyncOut, &Options{WriteMode: WriteModeAsync})
+	shardedHandler := NewHandler(&shardedOut, &Options{WriteMode: WriteModeSharded})
+
+	for i := range 10 {
+		slog.New(asyncHandler).Info("async", "i", i)
+		slog.New(shardedHandler).Info("sharded", "i", i)
+	}
+
+	undelivered, err := Shutdown(t.Context())
+	if undelivered != 0 || err != nil {
+		t.Fatalf("expected all records to be delivered, got undelivered=%d err=%v", undelivered, err)
+	}
+	if n := strings.Count(asyncOut.String(), "\n"); n != 10 {
+		t.Errorf("expected 10 async records, got %d", n)
+	}
+	if n := strings.Count(shardedOut.String(), "\n"); n != 10 {
+		t.Errorf("expected 10 sharded records, got %d", n)
+	}
+	for _, h := range []*Handler{asyncHandler, shardedHandler} {
+		if err := h.Handle(t.Context(), slog.NewRecord(time.Now(), slog.LevelInfo, "late", 0)); !errors.Is(err, ErrClosed) {
+			t.Errorf("expected ErrClosed after Shutdown, got %v", err)
+		}
+		if err := h.Close(); err != nil {
+			t.Errorf("Close after Shutdown should be a no-op, got %v", err)
+		}
+	}
+}
+
+// TestShutdownDeadline は期限までに書き出せなかったレコード数が返されることをテストします
+func TestShutdownDeadline(t *testing.T) {
+	out := newGateWriter()
+	defer close(out.release)
+	logger := slog.New(NewHandler(out, &Options{WriteMode: WriteModeAsync}))
+
+	logger.Info("record", "i", 0)
+	<-out.started
+	logger.Info("record", "i", 1)
+	logger.Info("record", "i", 2)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	undelivered, err := Shutdown(ctx)
+	if undelivered != 2 {
+		t.Errorf("expected 2 undelivered records, got %d", undelivered)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+}
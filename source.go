@@ -0,0 +1,74 @@
+package loggo
+
+import (
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SourceFormat は AddSource で出力するソースコードの場所の形式
+type SourceFormat int
+
+const (
+	// SourceFormatBase はファイル名と行番号を "file.go:42" の形式で出力します（デフォルト）
+	SourceFormatBase SourceFormat = iota
+	// SourceFormatFileURL は絶対パスを "file:///abs/path/file.go:42" の形式で出力します。
+	// 多くの端末でクリックするとファイルを開けます。
+	SourceFormatFileURL
+	// SourceFormatVSCode は絶対パスを "vscode://file/abs/path/file.go:42" の形式で出力します。
+	// クリックすると VS Code で呼び出し元の行を開けます。
+	SourceFormatVSCode
+)
+
+// sourceFormatNames は SourceFormat のテキスト表現
+var sourceFormatNames = []string{
+	SourceFormatBase:    "base",
+	SourceFormatFileURL: "file",
+	SourceFormatVSCode:  "vscode",
+}
+
+// String はソースコードの場所の形式の名前を返します
+func (s SourceFormat) String() string {
+	if s >= 0 && int(s) < len(sourceFormatNames) {
+		return sourceFormatNames[s]
+	}
+	return "SourceFormat(" + strconv.Itoa(int(s)) + ")"
+}
+
+// MarshalText はソースコードの場所の形式の名前を返します
+func (s SourceFormat) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText は "base", "file", "vscode" を解析します
+func (s *SourceFormat) UnmarshalText(text []byte) error {
+	i, err := parseEnumName("source format", sourceFormatNames, string(text))
+	if err != nil {
+		return err
+	}
+	*s = SourceFormat(i)
+	return nil
+}
+
+// file は行番号の前に出力するファイルの部分を返します
+func (s SourceFormat) file(path string) string {
+	switch s {
+	case SourceFormatFileURL:
+		return "file://" + sourceURLPath(path)
+	case SourceFormatVSCode:
+		return "vscode://file" + sourceURLPath(path)
+	default:
+		return filepath.Base(path)
+	}
+}
+
+// sourceURLPath はファイルのパスを URL のパスに変換します。
+// Windows のドライブ文字で始まるパスは "/C:/..." の形にします。
+func sourceURLPath(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Path: path}).EscapedPath()
+}
@@ -0,0 +1,72 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"testing"
+)
+
+// TestSourceFormat はソースコードの場所が指定した形式で出力されることをテストします
+func TestSourceFormat(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	path := sourceURLPath(file)
+
+	tests := []struct {
+		format SourceFormat
+		want   string
+	}{
+		{SourceFormatBase, `source="` + filepath.Base(file) + `:\d+"`},
+		{SourceFormatFileURL, `source="file://` + regexp.QuoteMeta(path) + `:\d+"`},
+		{SourceFormatVSCode, `source="vscode://file` + regexp.QuoteMeta(path) + `:\d+"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf, &Options{AddSource: true, SourceFormat: tt.format})).Info("test")
+			if !regexp.MustCompile(tt.want).MatchString(buf.String()) {
+				t.Errorf("expected %s, got %q", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+// TestSourceURLPath はパスが URL のパスに変換されることをテストします
+func TestSourceURLPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/home/user/app/main.go", "/home/user/app/main.go"},
+		{"/home/user/my app/main.go", "/home/user/my%20app/main.go"},
+		{"C:/Users/app/main.go", "/C:/Users/app/main.go"},
+	}
+	for _, tt := range tests {
+		if got := sourceURLPath(tt.path); got != tt.want {
+			t.Errorf("sourceURLPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestSourceFormatText は SourceFormat とテキストの相互変換をテストします
+func TestSourceFormatText(t *testing.T) {
+	for _, s := range []SourceFormat{SourceFormatBase, SourceFormatFileURL, SourceFormatVSCode} {
+		text, err := s.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got SourceFormat
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+		if got != s {
+			t.Errorf("round trip of %v returned %v", s, got)
+		}
+	}
+	var s SourceFormat
+	if err := s.UnmarshalText([]byte("emacs")); err == nil {
+		t.Error("expected error for unknown source format")
+	}
+}
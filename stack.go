@@ -0,0 +1,104 @@
+package loggo
+
+import (
+	"encoding/hex"
+	"hash/fnv"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// StackTraceLevel と Fingerprint で使用する属性のキー
+const (
+	// StackKey はスタックトレースの属性のキー
+	StackKey = "stack"
+	// FingerprintKey はエラーのフィンガープリントの属性のキー
+	FingerprintKey = "fingerprint"
+)
+
+const (
+	// maxStackDepth はスタックトレースに含めるフレームの最大数
+	maxStackDepth = 32
+	// fingerprintFrames はフィンガープリントの計算に使う上位のフレームの数
+	fingerprintFrames = 5
+)
+
+// addStack はレコードを記録した箇所からのスタックトレースを StackKey の属性として付加します。
+// fingerprint が有効な場合は FingerprintKey の属性も付加します。
+func (h *Handler) addStack(r slog.Record) slog.Record {
+	pcs := callerStack(r.PC)
+	if len(pcs) == 0 {
+		return r
+	}
+	hasStack := false
+	r.Attrs(func(a slog.Attr) bool {
+		hasStack = a.Key == StackKey
+		return !hasStack
+	})
+	r = r.Clone()
+	// RecoverAndLog などで既にスタックトレースを持つレコードには付加しない
+	if !hasStack {
+		r.AddAttrs(slog.String(StackKey, formatStack(pcs)))
+	}
+	if h.fingerprint {
+		r.AddAttrs(slog.String(FingerprintKey, fingerprint(r.Message, pcs)))
+	}
+	return r
+}
+
+// callerStack は現在のゴルーチンのスタックのうち、pc の位置から下の部分を返します。
+// slog の内部やハンドラーのフレームを含めないために、レコードの PC を起点にします。
+// pc がスタックに見つからない場合（別のゴルーチンで処理された場合など）は pc だけを返します。
+func callerStack(pc uintptr) []uintptr {
+	if pc == 0 {
+		return nil
+	}
+	var buf [maxStackDepth + 32]uintptr
+	pcs := buf[:runtime.Callers(2, buf[:])]
+	i := slices.Index(pcs, pc)
+	if i < 0 {
+		return []uintptr{pc}
+	}
+	pcs = pcs[i:]
+	return slices.Clone(pcs[:min(len(pcs), maxStackDepth)])
+}
+
+// formatStack は runtime/debug.Stack と同じく、フレームごとに関数名と "\tfile:line" の2行を出力します
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(f.Function)
+		b.WriteString("\n\t")
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(f.Line))
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// fingerprint はメッセージと上位のフレームの関数名から、エラーをまとめるためのハッシュを計算します。
+// ファイルのパスや行番号を含めないため、ホストやビルドの違い、無関係な行の追加では変わりません。
+func fingerprint(msg string, pcs []uintptr) string {
+	h := fnv.New64a()
+	h.Write([]byte(msg))
+	frames := runtime.CallersFrames(pcs)
+	for range fingerprintFrames {
+		f, more := frames.Next()
+		h.Write([]byte{0})
+		h.Write([]byte(f.Function))
+		if !more {
+			break
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
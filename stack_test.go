@@ -0,0 +1,68 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestStackTrace は StackTraceLevel 以上のレコードに記録した箇所からのスタックトレースが付加されることをテストします
+func TestStackTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{StackTraceLevel: slog.LevelError}))
+
+	logger.Warn("warn")
+	if strings.Contains(buf.String(), StackKey+"=") {
+		t.Errorf("unexpected stack below StackTraceLevel: %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.Error("failed")
+	out := buf.String()
+	if !strings.Contains(out, ` stack="github.com/f0reth/golog.TestStackTrace\n\t`) {
+		t.Errorf("expected stack to start at the caller, got %q", out)
+	}
+	if strings.Contains(out, "log/slog.") {
+		t.Errorf("stack should not contain slog frames: %q", out)
+	}
+	if strings.Contains(out, FingerprintKey+"=") {
+		t.Errorf("unexpected fingerprint without Fingerprint option: %q", out)
+	}
+}
+
+// logFailure は同じ関数から記録したレコードのフィンガープリントを比較するためのヘルパーです
+func logFailure(logger *slog.Logger, msg string) {
+	logger.Error(msg, "host", "a")
+}
+
+var fingerprintPattern = regexp.MustCompile(`fingerprint="([0-9a-f]{16})"`)
+
+// TestFingerprint はフィンガープリントがメッセージと関数が同じ場合に一致し、行番号に依存しないことをテストします
+func TestFingerprint(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{StackTraceLevel: slog.LevelError, Fingerprint: true}))
+
+	logFailure(logger, "connection refused")
+	logFailure(logger, "connection refused")
+	logFailure(logger, "disk full")
+	logger.Error("connection refused")
+
+	var got []string
+	for _, m := range fingerprintPattern.FindAllStringSubmatch(buf.String(), -1) {
+		got = append(got, m[1])
+	}
+	if len(got) != 4 {
+		t.Fatalf("expected 4 fingerprints, got %d:\n%s", len(got), buf.String())
+	}
+	if got[0] != got[1] {
+		t.Errorf("expected identical errors from different lines to share a fingerprint, got %s and %s", got[0], got[1])
+	}
+	if got[0] == got[2] {
+		t.Error("expected different messages to have different fingerprints")
+	}
+	if got[0] == got[3] {
+		t.Error("expected different call stacks to have different fingerprints")
+	}
+}
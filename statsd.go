@@ -0,0 +1,230 @@
+package loggo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultStatsDPrefix は StatsDOptions.Prefix のデフォルト値
+	defaultStatsDPrefix = "golog"
+	// defaultStatsDFlushInterval は StatsDOptions.FlushInterval のデフォルト値
+	defaultStatsDFlushInterval = time.Second
+	// statsDMaxPacketSize は1つのデータグラムの最大サイズ。経路の MTU で分割されない大きさに抑える。
+	statsDMaxPacketSize = 1432
+)
+
+// StatsDOptions は StatsD のオプション
+type StatsDOptions struct {
+	// Prefix はメトリクス名の接頭辞。空の場合は "golog" です。
+	Prefix string
+	// MetricKey はメトリクス名として使う属性のキー。空でない場合、レコードにこのキーの属性があれば
+	// レベルごとの件数に加えて、属性の値をメトリクス名とするカウンターも増やします。
+	MetricKey string
+	// Tags はレベルをメトリクス名に含めず、DogStatsD 形式のタグ（"|#level:error"）として送ります。
+	// Datadog Agent や Telegraf などタグに対応したサーバーで指定します。
+	Tags bool
+	// FlushInterval は集計したカウンターを送信する間隔。0 の場合は 1 秒です。
+	FlushInterval time.Duration
+	// OnError は送信のエラーを受け取ります
+	OnError func(err error)
+}
+
+// StatsD は処理したレコードの件数を StatsD のカウンターとして送信します。
+// Prometheus を使っていない環境で、レベルごとのエラーの件数などをログと同じ経路でメトリクスにできます。
+// カウンターはメモリ上で集計し、FlushInterval ごとに UDP でまとめて送信するため、レコードごとの送信は発生しません。
+//
+// カウンターの名前は "<Prefix>.records.<level>" です。MetricKey を指定した場合、その属性を持つレコードでは
+// "<Prefix>.<属性の値>.<level>" も増やします。Tags を指定した場合、レベルは名前ではなくタグになります。
+//
+//	stats, err := golog.DialStatsD("127.0.0.1:8125", &golog.StatsDOptions{Prefix: "api", MetricKey: "metric"})
+//	...
+//	defer stats.Close()
+//	handler := golog.NewHandler(os.Stdout, &golog.Options{
+//	    OnRecord: []golog.RecordCallback{stats.Callback(slog.LevelDebug)},
+//	})
+//	logger.Error("payment failed", "metric", "payment_failed") // api.records.error と api.payment_failed.error を増やす
+type StatsD struct {
+	conn      net.Conn
+	prefix    string
+	metricKey string
+	tags      bool
+	onError   func(err error)
+
+	mu     sync.Mutex
+	counts map[string]int64 // counterName ごとの件数
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// DialStatsD は addr の StatsD サーバーへ送信する StatsD を作成します。
+// UDP のため、サーバーが起動していなくてもエラーにはなりません。
+func DialStatsD(addr string, opts *StatsDOptions) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &StatsD{
+		conn:   conn,
+		prefix: defaultStatsDPrefix,
+		counts: make(map[string]int64),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	interval := defaultStatsDFlushInterval
+	if opts != nil {
+		if opts.Prefix != "" {
+			s.prefix = sanitizeMetricName(opts.Prefix)
+		}
+		s.metricKey = opts.MetricKey
+		s.tags = opts.Tags
+		if opts.FlushInterval > 0 {
+			interval = opts.FlushInterval
+		}
+		s.onError = opts.OnError
+	}
+	go s.loop(interval)
+	return s, nil
+}
+
+// Callback は level 以上のレコードを数える Options.OnRecord 用のコールバックを返します
+func (s *StatsD) Callback(level slog.Leveler) RecordCallback {
+	return RecordCallback{Level: level, Func: s.Count}
+}
+
+// Count はレコードのレベルと、MetricKey の属性の値ごとのカウンターを増やします。
+// MetricKey の属性はレコードに直接追加されたものだけを参照し、Logger.With の属性は参照しません。
+func (s *StatsD) Count(_ context.Context, r slog.Record) {
+	level := sanitizeMetricName(strings.ToLower(LevelName(r.Level)))
+	var metric string
+	if s.metricKey != "" {
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == s.metricKey {
+				metric = sanitizeMetricName(a.Value.Resolve().String())
+				return false
+			}
+			return true
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[s.counterName("records", level)]++
+	if metric != "" {
+		s.counts[s.counterName(metric, level)]++
+	}
+}
+
+// counterName は counts のキーを返します。Tags の場合はメトリクス名とレベルを "\x00" で区切ります。
+func (s *StatsD) counterName(name, level string) string {
+	if s.tags {
+		return s.prefix + "." + name + "\x00" + level
+	}
+	return s.prefix + "." + name + "." + level
+}
+
+// loop は interval ごとにカウンターを送信します
+func (s *StatsD) loop(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+		case <-s.stop:
+			s.Flush()
+			return
+		}
+	}
+}
+
+// Flush は集計したカウンターを送信してリセットします
+func (s *StatsD) Flush() error {
+	s.mu.Lock()
+	counts := s.counts
+	if len(counts) > 0 {
+		s.counts = make(map[string]int64, len(counts))
+	}
+	s.mu.Unlock()
+	if len(counts) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var firstErr error
+	send := func(packet []byte) {
+		if _, err := s.conn.Write(packet); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	var packet []byte
+	for _, name := range names {
+		var line []byte
+		if metric, level, ok := strings.Cut(name, "\x00"); ok {
+			line = append(line, metric...)
+			line = append(line, ':')
+			line = strconv.AppendInt(line, counts[name], 10)
+			line = append(line, "|c|#level:"...)
+			line = append(line, level...)
+		} else {
+			line = append(line, name...)
+			line = append(line, ':')
+			line = strconv.AppendInt(line, counts[name], 10)
+			line = append(line, "|c"...)
+		}
+		if len(packet) > 0 && len(packet)+1+len(line) > statsDMaxPacketSize {
+			send(packet)
+			packet = packet[:0]
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	send(packet)
+	if firstErr != nil {
+		err := fmt.Errorf("golog: send StatsD metrics: %w", firstErr)
+		if s.onError != nil {
+			s.onError(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Close は集計中のカウンターを送信して接続を閉じます
+func (s *StatsD) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.stop)
+		<-s.done
+		err = s.conn.Close()
+	})
+	return err
+}
+
+// sanitizeMetricName は StatsD のメトリクス名に使えない文字を "_" に置き換えます
+func sanitizeMetricName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !isLetter(c) && (c < '0' || c > '9') && c != '_' && c != '-' && c != '.' {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,90 @@
+package loggo
+
+import (
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readStatsD は pc で受信したデータグラムの行を並べ替えて返します
+func readStatsD(t *testing.T, pc net.PacketConn) []string {
+	t.Helper()
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 2048)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(buf[:n]), "\n")
+	slices.Sort(lines)
+	return lines
+}
+
+// TestStatsD はレベルと MetricKey の属性ごとに件数を集計して送信することをテストします
+func TestStatsD(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	stats, err := DialStatsD(pc.LocalAddr().String(), &StatsDOptions{
+		Prefix:        "api",
+		MetricKey:     "metric",
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stats.Close()
+
+	logger := slog.New(NewHandler(discardWriter{}, &Options{
+		Level:    slog.LevelDebug,
+		OnRecord: []RecordCallback{stats.Callback(slog.LevelInfo)},
+	}))
+	logger.Debug("not counted")
+	logger.Info("started")
+	logger.Error("payment failed", "metric", "payment failed")
+	logger.Error("payment failed", "metric", "payment failed")
+	if err := stats.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"api.payment_failed.error:2|c",
+		"api.records.error:2|c",
+		"api.records.info:1|c",
+	}
+	if got := readStatsD(t, pc); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// TestStatsDTags は DogStatsD 形式のタグでレベルを送り、Close で残りを送信することをテストします
+func TestStatsDTags(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	stats, err := DialStatsD(pc.LocalAddr().String(), &StatsDOptions{Tags: true, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(NewHandler(discardWriter{}, &Options{
+		OnRecord: []RecordCallback{stats.Callback(nil)},
+	}))
+	logger.Warn("slow")
+	if err := stats.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"golog.records:1|c|#level:warn"}
+	if got := readStatsD(t, pc); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
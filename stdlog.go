@@ -0,0 +1,92 @@
+package loggo
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// RedirectStdLog は handler を slog のデフォルトのハンドラーに設定し、log パッケージの出力も handler に送ります。
+// log.Printf などのメッセージが "[ERROR] ..." や "warn: ..." のようにレベルの名前で始まる場合は、
+// そのレベルで記録してメッセージから取り除きます。それ以外は INFO で記録します。
+// 時刻は handler が付けるため、log パッケージのフラグは 0 に設定されます。
+// 戻り値の関数を呼び出すと、slog のデフォルトのロガーと log パッケージの設定を元に戻します。
+//
+//	restore := golog.RedirectStdLog(handler)
+//	defer restore()
+//	log.Printf("[WARN] cache miss ratio %.2f", ratio) // WARN で記録される
+func RedirectStdLog(handler slog.Handler) (restore func()) {
+	prevDefault := slog.Default()
+	prevOutput := log.Writer()
+	prevFlags := log.Flags()
+
+	capturePC := prevFlags&(log.Lshortfile|log.Llongfile) != 0
+	slog.SetDefault(slog.New(handler))
+	// slog.SetDefault が設定する出力はレベルを固定するため置き換える
+	log.SetOutput(&stdLogWriter{handler: handler, capturePC: capturePC})
+	log.SetFlags(0)
+
+	return func() {
+		slog.SetDefault(prevDefault)
+		log.SetOutput(prevOutput)
+		log.SetFlags(prevFlags)
+	}
+}
+
+// stdLogWriter は log パッケージの出力を1行ずつレコードとして handler に送る io.Writer
+type stdLogWriter struct {
+	handler   slog.Handler
+	capturePC bool
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	level, msg := splitStdLogLevel(strings.TrimSuffix(string(p), "\n"))
+	ctx := context.Background()
+	if !w.handler.Enabled(ctx, level) {
+		return len(p), nil
+	}
+	var pc uintptr
+	if w.capturePC {
+		// runtime.Callers, Write, log.(*Logger).output, log.Printf を飛ばす
+		var pcs [1]uintptr
+		runtime.Callers(4, pcs[:])
+		pc = pcs[0]
+	}
+	r := slog.NewRecord(time.Now(), level, msg, pc)
+	return len(p), w.handler.Handle(ctx, r)
+}
+
+// splitStdLogLevel は "[LEVEL] msg" または "level: msg" の形式のメッセージからレベルを取り出します。
+// レベルの名前は ParseLevel で解析し、数値は受け付けません。一致しない場合は INFO とメッセージ全体を返します。
+func splitStdLogLevel(s string) (slog.Level, string) {
+	var name, rest string
+	if after, ok := strings.CutPrefix(s, "["); ok {
+		name, rest, ok = strings.Cut(after, "]")
+		if !ok {
+			return slog.LevelInfo, s
+		}
+	} else {
+		var ok bool
+		name, rest, ok = strings.Cut(s, ":")
+		if !ok {
+			return slog.LevelInfo, s
+		}
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || !isLetter(name[0]) || strings.ContainsAny(name, " \t") {
+		return slog.LevelInfo, s
+	}
+	level, err := ParseLevel(name)
+	if err != nil {
+		return slog.LevelInfo, s
+	}
+	return level, strings.TrimLeft(rest, " \t")
+}
+
+// isLetter は c が ASCII の英字かどうかを判定します
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
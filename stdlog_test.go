@@ -0,0 +1,86 @@
+package loggo
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestRedirectStdLog は log パッケージと slog のデフォルトのロガーの出力が handler に送られることをテストします
+func TestRedirectStdLog(t *testing.T) {
+	var buf bytes.Buffer
+	restore := RedirectStdLog(NewHandler(&buf, &Options{Level: slog.LevelDebug}))
+	t.Cleanup(restore)
+
+	log.Printf("[WARN] cache miss ratio %.2f", 0.5)
+	log.Println("error: connection refused")
+	log.Print("42: not a level")
+	log.Print("plain message")
+	slog.Debug("from slog", "k", 1)
+
+	want := []string{
+		`[ WARN] msg="cache miss ratio 0.50"`,
+		`[ERROR] msg="connection refused"`,
+		`[ INFO] msg="42: not a level"`,
+		`[ INFO] msg="plain message"`,
+		`[DEBUG] msg="from slog" k=1`,
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %d:\n%s", len(want), len(lines), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, want[i]) {
+			t.Errorf("line %d: want suffix %q, got %q", i, want[i], line)
+		}
+	}
+}
+
+// TestRedirectStdLogRestore は戻り値の関数で設定が元に戻ることをテストします
+func TestRedirectStdLogRestore(t *testing.T) {
+	prevDefault := slog.Default()
+	prevFlags := log.Flags()
+
+	var buf bytes.Buffer
+	restore := RedirectStdLog(NewHandler(&buf, nil))
+	if log.Flags() != 0 {
+		t.Errorf("expected flags to be cleared, got %d", log.Flags())
+	}
+	restore()
+
+	if slog.Default() != prevDefault {
+		t.Error("expected default logger to be restored")
+	}
+	if log.Flags() != prevFlags {
+		t.Errorf("expected flags %d, got %d", prevFlags, log.Flags())
+	}
+	if _, ok := log.Writer().(*stdLogWriter); ok {
+		t.Error("expected log output to be restored")
+	}
+}
+
+// TestSplitStdLogLevel はメッセージの先頭のレベルの解析をテストします
+func TestSplitStdLogLevel(t *testing.T) {
+	tests := []struct {
+		in    string
+		level slog.Level
+		msg   string
+	}{
+		{"[ERROR] failed", slog.LevelError, "failed"},
+		{"[debug]no space", slog.LevelDebug, "no space"},
+		{"Warning: low disk", slog.LevelWarn, "low disk"},
+		{"info:", slog.LevelInfo, ""},
+		{"[unclosed", slog.LevelInfo, "[unclosed"},
+		{"[-4] numeric", slog.LevelInfo, "[-4] numeric"},
+		{"request failed: timeout", slog.LevelInfo, "request failed: timeout"},
+		{"url: http://x", slog.LevelInfo, "url: http://x"},
+	}
+	for _, tt := range tests {
+		level, msg := splitStdLogLevel(tt.in)
+		if level != tt.level || msg != tt.msg {
+			t.Errorf("splitStdLogLevel(%q) = %v, %q; want %v, %q", tt.in, level, msg, tt.level, tt.msg)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package loggo
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// Facility は syslog のファシリティ
+type Facility int
+
+// RFC 3164 で定義されたファシリティ
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+	FacilityLocal0 Facility = iota + 4
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// rfc3164TimeFormat は RFC 3164 の TIMESTAMP の書式。日が1桁の場合は空白で埋めます。
+const rfc3164TimeFormat = "Jan _2 15:04:05"
+
+// SyslogOptions は RFC 3164（BSD syslog）形式のヘッダーの設定
+type SyslogOptions struct {
+	// Facility はファシリティ。ゼロ値の FacilityKern はカーネル以外が使わないため、FacilityUser として扱います。
+	Facility Facility
+	// Hostname は HOSTNAME フィールド。空の場合は os.Hostname の値です。
+	Hostname string
+	// Tag は TAG フィールド（プログラム名）。空の場合は実行ファイルの名前です。
+	Tag string
+	// Severities はレベルから PRI の重要度への変換表。nil の場合は SyslogSeverities です。
+	Severities *SeverityMap[int]
+}
+
+// syslogHeader は RFC 3164 のヘッダーの書き込みに必要な値。時刻以外は NewHandler で計算します。
+type syslogHeader struct {
+	facility   Facility
+	severities *SeverityMap[int]
+	suffix     string // " HOSTNAME TAG[PID]: "
+}
+
+// newSyslogHeader は opts からヘッダーの値を計算します
+func newSyslogHeader(opts SyslogOptions) *syslogHeader {
+	facility := opts.Facility
+	if facility == FacilityKern {
+		facility = FacilityUser
+	}
+	hostname := opts.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+		if hostname == "" {
+			hostname = "localhost"
+		}
+	}
+	tag := opts.Tag
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	severities := opts.Severities
+	if severities == nil {
+		severities = SyslogSeverities
+	}
+	return &syslogHeader{
+		facility:   facility,
+		severities: severities,
+		suffix:     " " + hostname + " " + tag + "[" + strconv.Itoa(os.Getpid()) + "]: ",
+	}
+}
+
+// append は "<PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: " を書き込みます。
+// RFC 3164 に従い、時刻はタイムゾーンを含まないローカル時刻です。
+func (s *syslogHeader) append(buf *buffer.Buffer, r slog.Record) {
+	buf.WriteByte('<')
+	*buf = strconv.AppendInt(*buf, int64(s.facility)*8+int64(s.severities.Lookup(r.Level)), 10)
+	buf.WriteByte('>')
+	*buf = r.Time.Local().AppendFormat(*buf, rfc3164TimeFormat)
+	buf.WriteString(s.suffix)
+}
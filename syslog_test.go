@@ -0,0 +1,75 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSyslogHeader は RFC 3164 形式のヘッダーを各行の先頭に付けることをテストします
+func TestSyslogHeader(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(&buf, &Options{
+		Syslog: &SyslogOptions{Facility: FacilityLocal0, Hostname: "web1", Tag: "api"},
+	})
+	ts := time.Date(2024, time.March, 5, 9, 4, 7, 0, time.Local)
+	r := slog.NewRecord(ts, slog.LevelWarn, "disk almost full", 0)
+	r.AddAttrs(slog.Int("pct", 93))
+	if err := h.Handle(t.Context(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	// local0 (16) * 8 + warning (4) = 132
+	want := "<132>Mar  5 09:04:07 web1 api[" + strconv.Itoa(os.Getpid()) + "]: [ WARN] msg=\"disk almost full\" pct=93\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
+
+// TestSyslogHeaderDefaults はファシリティ、ホスト名、タグのデフォルト値と JSON 形式との組み合わせをテストします
+func TestSyslogHeaderDefaults(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, &Options{Format: FormatJSON, Syslog: &SyslogOptions{}}))
+	logger.Error("boom")
+
+	line := buf.String()
+	// user (1) * 8 + err (3) = 11
+	if !strings.HasPrefix(line, "<11>") {
+		t.Errorf("unexpected PRI: %q", line)
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	if !strings.Contains(line, " "+hostname+" ") {
+		t.Errorf("hostname %q not found: %q", hostname, line)
+	}
+	if _, body, ok := strings.Cut(line, "]: "); !ok || !strings.HasPrefix(body, `{"time":`) {
+		t.Errorf("unexpected body: %q", line)
+	}
+}
+
+// TestSyslogSeverities はレベルから syslog の重要度への変換をテストします
+func TestSyslogSeverities(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  int
+	}{
+		{slog.LevelDebug - 4, 7},
+		{slog.LevelDebug, 7},
+		{slog.LevelInfo, 6},
+		{slog.LevelInfo + 2, 6},
+		{slog.LevelWarn, 4},
+		{slog.LevelError, 3},
+		{slog.LevelError + 4, 2},
+	}
+	for _, tt := range tests {
+		if got := SyslogSeverities.Lookup(tt.level); got != tt.want {
+			t.Errorf("SyslogSeverities.Lookup(%v) = %d, want %d", tt.level, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package loggo
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// levelOverride は TemporaryLevel で一時的に変更したレベルを保持します。Handler のクローンで共有する。
+type levelOverride struct {
+	active atomic.Bool
+	level  atomic.Int64 // active の場合に有効な、一時的なレベルのうち最も低いもの
+
+	mu     sync.Mutex
+	nextID uint64
+	levels map[uint64]slog.Level
+}
+
+// get は一時的なレベルを返します。一時的なレベルが無い場合は false を返します。
+func (o *levelOverride) get() (slog.Level, bool) {
+	if !o.active.Load() {
+		return 0, false
+	}
+	return slog.Level(o.level.Load()), true
+}
+
+// add は一時的なレベルを追加し、remove に渡す ID を返します
+func (o *levelOverride) add(level slog.Level) uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.levels == nil {
+		o.levels = make(map[uint64]slog.Level)
+	}
+	o.nextID++
+	o.levels[o.nextID] = level
+	o.updateLocked()
+	return o.nextID
+}
+
+// remove は add で追加した一時的なレベルを取り除きます
+func (o *levelOverride) remove(id uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.levels, id)
+	o.updateLocked()
+}
+
+// updateLocked は有効なレベルを計算し直します。mu を保持して呼び出します。
+func (o *levelOverride) updateLocked() {
+	if len(o.levels) == 0 {
+		o.active.Store(false)
+		return
+	}
+	first := true
+	var level slog.Level
+	for _, l := range o.levels {
+		if first || l < level {
+			level, first = l, false
+		}
+	}
+	o.level.Store(int64(level))
+	o.active.Store(true)
+}
+
+// TemporaryLevel は logger のハンドラーの最小レベルを一時的に level まで下げ、元に戻す関数を返します。
+// level が Options.Level より詳細でない場合は何も変わりません。
+// d が 0 より大きい場合は d が経過した時点でも自動的に元に戻します。戻す関数は何度呼び出しても構いません。
+//
+//	restore := golog.TemporaryLevel(logger, slog.LevelDebug, 5*time.Minute)
+//	defer restore()
+//
+// 変更は logger と同じ Handler から With や WithGroup で作成したすべてのロガーに適用されます。
+// 複数の一時的なレベルが重なる場合は最も詳細なレベルが有効になります。
+// WithMinLevel で設定したコンテキストのレベルはこの変更より優先されます。
+// logger のハンドラーが Handler でない場合は何もしません。
+func TemporaryLevel(logger *slog.Logger, level slog.Level, d time.Duration) func() {
+	h, ok := logger.Handler().(*Handler)
+	if !ok {
+		return func() {}
+	}
+	id := h.override.add(level)
+	var once sync.Once
+	remove := func() {
+		once.Do(func() { h.override.remove(id) })
+	}
+	if d <= 0 {
+		return remove
+	}
+	timer := time.AfterFunc(d, remove)
+	return func() {
+		timer.Stop()
+		remove()
+	}
+}
+
+// WithTemporaryLevel は logger のハンドラーの最小レベルを level まで下げて fn を呼び出し、fn が戻った後に元に戻します。
+// fn がパニックした場合も元に戻します。
+//
+//	golog.WithTemporaryLevel(logger, slog.LevelDebug, func() {
+//	    migrate(ctx) // この間だけ Debug のログを出力する
+//	})
+func WithTemporaryLevel(logger *slog.Logger, level slog.Level, fn func()) {
+	defer TemporaryLevel(logger, level, 0)()
+	fn()
+}
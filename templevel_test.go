@@ -0,0 +1,73 @@
+package loggo
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWithTemporaryLevel は fn の間だけレベルが下がり、With で作成したロガーにも適用されることをテストします
+func TestWithTemporaryLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, nil))
+	child := logger.With("component", "db")
+
+	logger.Debug("before")
+	WithTemporaryLevel(logger, slog.LevelDebug, func() {
+		logger.Debug("inside")
+		child.Debug("child inside", DebugOnly(slog.Int("n", 1)))
+		// コンテキストのレベルが優先される
+		logger.DebugContext(WithMinLevel(context.Background(), slog.LevelInfo), "context")
+	})
+	logger.Debug("after")
+
+	got := buf.String()
+	for _, unwanted := range []string{"before", "after", "context"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("unexpected %q in %s", unwanted, got)
+		}
+	}
+	if !strings.Contains(got, `msg="inside"`) || !strings.Contains(got, `msg="child inside" component="db" n=1`) {
+		t.Errorf("missing records: %s", got)
+	}
+}
+
+// TestTemporaryLevel は重なった一時的なレベルの扱いと、期限による自動的な復元をテストします
+func TestTemporaryLevel(t *testing.T) {
+	h := NewHandler(&bytes.Buffer{}, nil)
+	logger := slog.New(h)
+	ctx := context.Background()
+
+	restoreWarn := TemporaryLevel(logger, slog.LevelWarn, 0)
+	if !h.Enabled(ctx, slog.LevelInfo) {
+		t.Error("a less verbose temporary level should not raise the handler level")
+	}
+	restoreDebug := TemporaryLevel(logger, slog.LevelDebug, 0)
+	restoreWarn()
+	if !h.Enabled(ctx, slog.LevelDebug) {
+		t.Error("debug should be enabled")
+	}
+	restoreDebug()
+	restoreDebug()
+	if h.Enabled(ctx, slog.LevelDebug) {
+		t.Error("debug should be disabled after restore")
+	}
+
+	TemporaryLevel(logger, slog.LevelDebug, 10*time.Millisecond)
+	if !h.Enabled(ctx, slog.LevelDebug) {
+		t.Error("debug should be enabled until the deadline")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for h.Enabled(ctx, slog.LevelDebug) {
+		if time.Now().After(deadline) {
+			t.Fatal("temporary level was not restored after the duration")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Handler 以外のハンドラーでは何もしない
+	TemporaryLevel(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)), slog.LevelDebug, 0)()
+}
@@ -0,0 +1,133 @@
+package loggo
+
+import (
+	"io"
+	"sync"
+)
+
+// clearLine は行頭に戻り、カーソルから行末までを消去するエスケープシーケンス
+const clearLine = "\r\033[K"
+
+// terminal は Handler とそのクローンで共有される、出力先への書き込みを排他制御する io.Writer。
+// output はこれを出力先として書き込み、進捗の行（status）が表示されている場合は
+// 一旦消してからログの行を書き込み、その後に進捗の行を表示し直します。
+type terminal struct {
+	mu     sync.Mutex // 以下のフィールドと w への書き込みを保護。noLock の場合は使用しない
+	noLock bool
+	w      io.Writer
+	tty    bool   // w が端末の場合に true
+	status []byte // 表示中の進捗の行。空の場合は表示していない
+	buf    []byte
+}
+
+func newTerminal(w io.Writer, noLock bool) *terminal {
+	return &terminal{w: w, tty: isTerminal(w), noLock: noLock}
+}
+
+// lock は mu を取得します。noLock の場合は何もしません。
+func (t *terminal) lock() {
+	if !t.noLock {
+		t.mu.Lock()
+	}
+}
+
+// unlock は mu を解放します。noLock の場合は何もしません。
+func (t *terminal) unlock() {
+	if !t.noLock {
+		t.mu.Unlock()
+	}
+}
+
+// Write は p を出力先へ書き込みます。進捗の行が表示されている場合は、その下に p が残るように書き込みます。
+func (t *terminal) Write(p []byte) (int, error) {
+	t.lock()
+	defer t.unlock()
+	if len(t.status) == 0 {
+		return t.w.Write(p)
+	}
+	buf := append(t.buf[:0], clearLine...)
+	buf = append(buf, p...)
+	buf = append(buf, t.status...)
+	_, err := t.w.Write(buf)
+	if cap(buf) <= maxRetainedBatchSize {
+		t.buf = buf[:0]
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writer は現在の出力先を返します
+func (t *terminal) writer() io.Writer {
+	t.lock()
+	defer t.unlock()
+	return t.w
+}
+
+// setWriter は出力先を w に置き換えます。進捗の行は元の出力先から消去します。
+func (t *terminal) setWriter(w io.Writer) {
+	t.lock()
+	defer t.unlock()
+	if len(t.status) > 0 {
+		io.WriteString(t.w, clearLine)
+		t.status = t.status[:0]
+	}
+	t.w = w
+	t.tty = isTerminal(w)
+}
+
+// isTTY は出力先が端末かどうかを返します
+func (t *terminal) isTTY() bool {
+	t.lock()
+	defer t.unlock()
+	return t.tty
+}
+
+// setStatus は進捗の行を line に置き換えて表示します。出力先が端末でない場合は何もしません。
+func (t *terminal) setStatus(line []byte) {
+	t.lock()
+	defer t.unlock()
+	if !t.tty {
+		return
+	}
+	t.status = append(t.status[:0], line...)
+	buf := append(append(t.buf[:0], clearLine...), line...)
+	t.w.Write(buf)
+	t.buf = buf[:0]
+}
+
+// clearStatus は表示中の進捗の行を消去します
+func (t *terminal) clearStatus() {
+	t.lock()
+	defer t.unlock()
+	if len(t.status) == 0 {
+		return
+	}
+	io.WriteString(t.w, clearLine)
+	t.status = t.status[:0]
+}
+
+// WithOutputLock は出力先への書き込みを排他的に確保した状態で fn を呼び出します。
+// それまでに記録されたレコードは fn の前に書き出され、fn の実行中に記録されたレコードは fn の後に書き出されるため、
+// プロンプトや表を出力先 w に直接書き込んでもログの行と混ざりません。表示中の進捗の行は fn の間だけ消去されます。
+// クローンを含むすべてのハンドラーが出力先を共有しているため、どのハンドラーから呼び出しても同じです。
+//
+// fn の実行中に同じハンドラーでログを記録すると、WriteModeBatched ではデッドロックします。
+//
+//	handler.WithOutputLock(func(w io.Writer) {
+//		fmt.Fprint(w, "Continue? [y/N] ")
+//		answer, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+//	})
+func (h *Handler) WithOutputLock(fn func(w io.Writer)) {
+	h.drainOutput()
+
+	t := h.term
+	t.lock()
+	defer t.unlock()
+	if len(t.status) > 0 {
+		io.WriteString(t.w, clearLine)
+		defer t.w.Write(t.status)
+	}
+	fn(t.w)
+}
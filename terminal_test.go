@@ -0,0 +1,68 @@
+package loggo
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestWithOutputLock はロック中の直接の書き込みとログの行が混ざらないことをテストします
+func TestWithOutputLock(t *testing.T) {
+	for _, mode := range []WriteMode{WriteModeBatched, WriteModeSharded, WriteModeAsync, WriteModeSerial} {
+		t.Run(mode.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			h := NewHandler(&buf, &Options{WriteMode: mode})
+			logger := slog.New(h)
+			logger.Info("before")
+
+			var wg sync.WaitGroup
+			for range 4 {
+				wg.Go(func() {
+					for range 50 {
+						logger.Info("concurrent")
+					}
+				})
+			}
+			h.WithOutputLock(func(w io.Writer) {
+				io.WriteString(w, "+-----+\n")
+				io.WriteString(w, "| tbl |\n")
+				io.WriteString(w, "+-----+\n")
+			})
+			wg.Wait()
+			h.Close()
+
+			out := buf.String()
+			if !strings.Contains(out, "+-----+\n| tbl |\n+-----+\n") {
+				t.Errorf("direct output was interleaved: %q", out)
+			}
+			if strings.Index(out, `msg="before"`) > strings.Index(out, "+-----+") {
+				t.Error("records logged before WithOutputLock should be written first")
+			}
+			if n := strings.Count(out, `msg="concurrent"`); n != 200 {
+				t.Errorf("expected 200 concurrent records, got %d", n)
+			}
+		})
+	}
+}
+
+// TestWithOutputLockProgress はロック中は進捗の行が消去され、後で表示し直されることをテストします
+func TestWithOutputLockProgress(t *testing.T) {
+	orig := isTerminal
+	t.Cleanup(func() { isTerminal = orig })
+	isTerminal = func(io.Writer) bool { return true }
+
+	var buf bytes.Buffer
+	h := NewHandler(&buf, nil)
+	h.Progress("sync", 0).Add(3)
+	buf.Reset()
+
+	h.WithOutputLock(func(w io.Writer) {
+		io.WriteString(w, "prompt> ")
+	})
+	if want := clearLine + "prompt> " + "sync 3"; buf.String() != want {
+		t.Errorf("unexpected output: got %q, want %q", buf.String(), want)
+	}
+}
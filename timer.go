@@ -0,0 +1,67 @@
+package loggo
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Timer と AutoElapsed で使用する属性のキー
+const (
+	// StartKey は処理の開始時刻の属性のキー
+	StartKey = "start"
+	// ElapsedKey は経過時間の属性のキー
+	ElapsedKey = "elapsed"
+)
+
+// Since は start からの経過時間を ElapsedKey の属性として返します
+//
+//	start := time.Now()
+//	defer func() { logger.Info("query done", golog.Since(start)) }()
+func Since(start time.Time) slog.Attr {
+	return slog.Duration(ElapsedKey, time.Since(start))
+}
+
+// Timer は呼び出した時点から計測を始め、呼び出すたびにその時点までの経過時間を
+// ElapsedKey の属性として返す関数を返します
+//
+//	elapsed := golog.Timer()
+//	doWork()
+//	logger.Info("work done", elapsed())
+func Timer() func() slog.Attr {
+	start := time.Now()
+	return func() slog.Attr {
+		return Since(start)
+	}
+}
+
+// StartTime は t を StartKey の属性として返します。
+// Options.AutoElapsed が有効なハンドラーでは、この属性を持つレコードに経過時間が付加されます。
+//
+//	logger := logger.With(golog.StartTime(time.Now()))
+//	logger.Info("step 1 done") // elapsed が付加される
+func StartTime(t time.Time) slog.Attr {
+	return slog.Time(StartKey, t)
+}
+
+// addElapsed はレコード、または With で追加された StartKey の時刻からレコードの時刻までの
+// 経過時間を ElapsedKey の属性として付加します。レコードの属性を優先します。
+func (h *Handler) addElapsed(r slog.Record) slog.Record {
+	start := h.elapsedStart
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == StartKey && a.Value.Kind() == slog.KindTime {
+			start = a.Value.Time()
+			return false
+		}
+		return true
+	})
+	if start.IsZero() {
+		return r
+	}
+	end := r.Time
+	if end.IsZero() {
+		end = time.Now()
+	}
+	r = r.Clone()
+	r.AddAttrs(slog.Duration(ElapsedKey, end.Sub(start)))
+	return r
+}
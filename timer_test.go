@@ -0,0 +1,57 @@
+package loggo
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTimer は Timer と Since が経過時間の属性を返すことをテストします
+func TestTimer(t *testing.T) {
+	elapsed := Timer()
+	time.Sleep(2 * time.Millisecond)
+	a := elapsed()
+	if a.Key != ElapsedKey || a.Value.Kind() != slog.KindDuration || a.Value.Duration() < 2*time.Millisecond {
+		t.Errorf("unexpected attr %v", a)
+	}
+	if later := elapsed(); later.Value.Duration() < a.Value.Duration() {
+		t.Errorf("elapsed should not decrease: %v < %v", later.Value, a.Value)
+	}
+
+	if a := Since(time.Now().Add(-time.Second)); a.Value.Duration() < time.Second {
+		t.Errorf("unexpected Since attr %v", a)
+	}
+}
+
+// TestAutoElapsed は開始時刻の属性を持つレコードに経過時間が付加されることをテストします
+func TestAutoElapsed(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(&buf, &Options{AutoElapsed: true})
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	r := slog.NewRecord(start.Add(1500*time.Millisecond), slog.LevelInfo, "from record", 0)
+	r.AddAttrs(StartTime(start))
+	h.Handle(ctx, r)
+
+	// With で追加した開始時刻は、以降のすべてのレコードに使われる
+	h.WithAttrs([]slog.Attr{StartTime(start)}).Handle(ctx, slog.NewRecord(start.Add(time.Second), slog.LevelInfo, "from With", 0))
+
+	// 開始時刻が無いレコードには付加しない
+	h.Handle(ctx, slog.NewRecord(start, slog.LevelInfo, "none", 0))
+
+	// AutoElapsed が無効な場合は付加しない
+	NewHandler(&buf, nil).Handle(ctx, r)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	wants := []string{"elapsed=1500000000", "elapsed=1000000000", "", ""}
+	for i, want := range wants {
+		has := strings.Contains(lines[i], "elapsed=")
+		if want == "" && has || want != "" && !strings.HasSuffix(lines[i], want) {
+			t.Errorf("line %d: want %q, got %q", i, want, lines[i])
+		}
+	}
+}
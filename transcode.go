@@ -0,0 +1,65 @@
+package loggo
+
+import (
+	"io"
+	"sync"
+	"unicode/utf8"
+)
+
+// Encoder は UTF-8 のバイト列を別の文字コードに変換するエンコーダー。
+// golang.org/x/text/encoding の *encoding.Encoder（japanese.ShiftJIS.NewEncoder() など）をそのまま指定できます。
+// このパッケージは標準ライブラリ以外に依存しないため、変換表は呼び出し側で用意します。
+type Encoder interface {
+	Bytes(b []byte) ([]byte, error)
+}
+
+// TranscodingWriter は UTF-8 の出力を Encoder で別の文字コード（Shift_JIS/CP932 など）に変換して書き込むライター。
+// UTF-8 を表示できない Windows のコンソール（cmd.exe）などで日本語のログを読むために使います。
+// 変換できない文字は '?' に置き換えます。
+type TranscodingWriter struct {
+	mu  sync.Mutex // enc は並行して使えないため、変換と書き込みを保護
+	w   io.Writer
+	enc Encoder
+	buf []byte
+}
+
+// NewTranscodingWriter は enc で変換して w へ書き込む TranscodingWriter を作成します
+//
+//	w := golog.NewTranscodingWriter(os.Stdout, japanese.ShiftJIS.NewEncoder())
+//	handler := golog.NewHandler(w, nil)
+func NewTranscodingWriter(w io.Writer, enc Encoder) *TranscodingWriter {
+	return &TranscodingWriter{w: w, enc: enc}
+}
+
+// Write は p を変換して書き込みます。成功した場合は len(p) を返します。
+func (t *TranscodingWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out, err := t.enc.Bytes(p)
+	if err != nil {
+		out = t.encodeRunes(p)
+	}
+	if _, err := t.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// encodeRunes は p を1文字ずつ変換し、変換できない文字を '?' に置き換えます
+func (t *TranscodingWriter) encodeRunes(p []byte) []byte {
+	buf := t.buf[:0]
+	for len(p) > 0 {
+		_, n := utf8.DecodeRune(p)
+		if b, err := t.enc.Bytes(p[:n]); err == nil {
+			buf = append(buf, b...)
+		} else {
+			buf = append(buf, '?')
+		}
+		p = p[n:]
+	}
+	if cap(buf) <= maxRetainedBatchSize {
+		t.buf = buf
+	}
+	return buf
+}
@@ -0,0 +1,52 @@
+package loggo
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// latin1Encoder は U+00FF までの文字を ISO-8859-1 に変換するテスト用の Encoder です
+type latin1Encoder struct{}
+
+func (latin1Encoder) Bytes(b []byte) ([]byte, error) {
+	out := make([]byte, 0, len(b))
+	for _, r := range string(b) {
+		if r > 0xff || r == utf8.RuneError {
+			return nil, errors.New("unsupported character")
+		}
+		out = append(out, byte(r))
+	}
+	return out, nil
+}
+
+// TestTranscodingWriter は出力が変換され、変換できない文字が '?' に置き換えられることをテストします
+func TestTranscodingWriter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(NewTranscodingWriter(&buf, latin1Encoder{}), nil))
+
+	logger.Info("café")
+	logger.Info("café 日本")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if !strings.HasSuffix(lines[0], "msg=\"caf\xe9\"") {
+		t.Errorf("expected Latin-1 output, got %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "msg=\"caf\xe9 ??\"") {
+		t.Errorf("expected unsupported characters to be replaced, got %q", lines[1])
+	}
+}
+
+// TestTranscodingWriterError は出力先のエラーが返されることをテストします
+func TestTranscodingWriterError(t *testing.T) {
+	w := NewTranscodingWriter(errorWriter{}, latin1Encoder{})
+	if n, err := w.Write([]byte("test")); err == nil || n != 0 {
+		t.Errorf("expected write error, got n=%d err=%v", n, err)
+	}
+}
@@ -0,0 +1,100 @@
+package loggo
+
+import (
+	"log/slog"
+	"maps"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// KeyTransforms は属性のキーごとに値を変換する関数の登録先。
+// ReplaceAttr を実装しているため、Options.ReplaceAttrs に指定するとすべての呼び出し箇所の属性に
+// 出力の前に一様に適用されます。キーはグループ名を "." で連結したパス（"user.email"）、
+// またはグループを問わない属性のキー（"email"）で登録します。パスの登録を優先します。
+//
+//	transforms := golog.NewKeyTransforms().
+//	    Register("email", golog.MaskEmailDomain).
+//	    Register("duration_ms", golog.RoundFloat(1))
+//	handler := golog.NewHandler(os.Stdout, &golog.Options{
+//	    ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{transforms.ReplaceAttr},
+//	})
+type KeyTransforms struct {
+	mu sync.Mutex
+	// fns は ReplaceAttr でロックせずに参照できるよう、登録のたびに作り直すマップ
+	fns atomic.Pointer[map[string]func(slog.Value) slog.Value]
+}
+
+// NewKeyTransforms は空の KeyTransforms を作成します
+func NewKeyTransforms() *KeyTransforms {
+	return &KeyTransforms{}
+}
+
+// Register は key の属性の値を fn で変換するよう登録し、t を返します。同じキーの登録は置き換えます。
+// ハンドラーで使用し始めた後に登録することもできます。
+func (t *KeyTransforms) Register(key string, fn func(slog.Value) slog.Value) *KeyTransforms {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fns := make(map[string]func(slog.Value) slog.Value)
+	if old := t.fns.Load(); old != nil {
+		maps.Copy(fns, *old)
+	}
+	fns[key] = fn
+	t.fns.Store(&fns)
+	return t
+}
+
+// ReplaceAttr は登録された関数で属性の値を変換します
+func (t *KeyTransforms) ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	fns := t.fns.Load()
+	if fns == nil {
+		return a
+	}
+	if len(groups) > 0 {
+		if fn, ok := (*fns)[strings.Join(groups, ".")+"."+a.Key]; ok {
+			a.Value = fn(a.Value.Resolve())
+			return a
+		}
+	}
+	if fn, ok := (*fns)[a.Key]; ok {
+		a.Value = fn(a.Value.Resolve())
+	}
+	return a
+}
+
+// MaskEmailDomain はメールアドレスのドメインを "***" に置き換えます（"alice@example.com" は "alice@***"）。
+// "@" を含まない文字列や文字列以外の値は変更しません。
+func MaskEmailDomain(v slog.Value) slog.Value {
+	if v.Kind() != slog.KindString {
+		return v
+	}
+	s := v.String()
+	i := strings.LastIndexByte(s, '@')
+	if i < 0 {
+		return v
+	}
+	return slog.StringValue(s[:i+1] + "***")
+}
+
+// RoundFloat は浮動小数点数を小数点以下 places 桁に丸める関数を返します。整数や時間は変更しません。
+func RoundFloat(places int) func(slog.Value) slog.Value {
+	scale := math.Pow10(places)
+	return func(v slog.Value) slog.Value {
+		if v.Kind() != slog.KindFloat64 {
+			return v
+		}
+		return slog.Float64Value(math.Round(v.Float64()*scale) / scale)
+	}
+}
+
+// RoundDuration は時間を m の倍数に丸める関数を返します（time.Duration.Round と同じ）
+func RoundDuration(m time.Duration) func(slog.Value) slog.Value {
+	return func(v slog.Value) slog.Value {
+		if v.Kind() != slog.KindDuration {
+			return v
+		}
+		return slog.DurationValue(v.Duration().Round(m))
+	}
+}
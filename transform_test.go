@@ -0,0 +1,47 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestKeyTransforms はキーとグループのパスで登録した関数が、すべての呼び出し箇所の属性に適用されることをテストします
+func TestKeyTransforms(t *testing.T) {
+	var buf bytes.Buffer
+	transforms := NewKeyTransforms().
+		Register("email", MaskEmailDomain).
+		Register("duration_ms", RoundFloat(1)).
+		Register("billing.email", func(slog.Value) slog.Value { return slog.StringValue("[hidden]") })
+	logger := slog.New(NewHandler(&buf, &Options{
+		ReplaceAttrs: []func([]string, slog.Attr) slog.Attr{transforms.ReplaceAttr},
+	}))
+
+	logger.With("email", "alice@example.com").Info("signup", "duration_ms", 12.345)
+	logger.WithGroup("billing").Info("invoice", "email", "bob@example.com")
+	logger.Info("contact", slog.Group("user", "email", "carol@example.com"))
+
+	out := buf.String()
+	for _, want := range []string{
+		`email="alice@***"`, `duration_ms=12.3`, `billing.email="[hidden]"`, `user.email="carol@***"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%s not found in\n%s", want, out)
+		}
+	}
+}
+
+// TestBuiltinTransforms は組み込みの変換関数が対象外の値を変更しないことをテストします
+func TestBuiltinTransforms(t *testing.T) {
+	if got := MaskEmailDomain(slog.StringValue("no-at-sign")).String(); got != "no-at-sign" {
+		t.Errorf("MaskEmailDomain = %q", got)
+	}
+	if got := RoundFloat(2)(slog.IntValue(3)).Int64(); got != 3 {
+		t.Errorf("RoundFloat changed an int: %d", got)
+	}
+	if got := RoundDuration(time.Millisecond)(slog.DurationValue(1234567 * time.Nanosecond)).Duration(); got != time.Millisecond {
+		t.Errorf("RoundDuration = %v", got)
+	}
+}
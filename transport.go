@@ -0,0 +1,222 @@
+package loggo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// defaultMaxBodyBytes は TransportOptions.MaxBodyBytes のデフォルト値
+const defaultMaxBodyBytes = 1024
+
+// redactedValue は伏せたヘッダーの値の代わりに出力する文字列
+const redactedValue = "[REDACTED]"
+
+// defaultRedactHeaders は TransportOptions.RedactHeaders が nil の場合に値を伏せるヘッダー
+var defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// TransportOptions は NewTransport の設定
+type TransportOptions struct {
+	// Level は成功したリクエストを記録するレベル。nil の場合は slog.LevelInfo です。
+	// ステータスコードが 500 以上のレスポンスは WARN、通信のエラーは ERROR で記録します。
+	Level slog.Leveler
+	// LogHeaders はリクエストとレスポンスのヘッダーを req_headers, resp_headers グループとして記録します
+	LogHeaders bool
+	// RedactHeaders は値を "[REDACTED]" に置き換えるヘッダー。大文字と小文字は区別しません。
+	// nil の場合は Authorization, Proxy-Authorization, Cookie, Set-Cookie です。
+	RedactHeaders []string
+	// LogBody はリクエストとレスポンスのボディの先頭 MaxBodyBytes バイトを req_body, resp_body として記録します。
+	// レスポンスのボディの先頭を読み取るまで RoundTrip が戻らないため、ストリーミングのレスポンスには使用しないでください。
+	LogBody bool
+	// MaxBodyBytes は記録するボディの最大のバイト数。0 の場合は 1024 です。
+	MaxBodyBytes int
+	// RedactBody は記録する前にボディを加工する関数。トークンやパスワードを伏せるために使います。
+	// 実際に送受信するボディには影響しません。
+	RedactBody func(body []byte) []byte
+}
+
+// retryKey はリトライの回数を保持するコンテキストのキー
+type retryKey struct{}
+
+// ContextWithRetry はリトライの回数（最初の試行は 0）を保持するコンテキストを返します。
+// リトライを行う呼び出し側がリクエストのコンテキストに設定すると、NewTransport はその回数を retry 属性として記録します。
+func ContextWithRetry(ctx context.Context, retry int) context.Context {
+	return context.WithValue(ctx, retryKey{}, retry)
+}
+
+// RetryFromContext は ContextWithRetry で設定されたリトライの回数を返します
+func RetryFromContext(ctx context.Context) (int, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	retry, ok := ctx.Value(retryKey{}).(int)
+	return retry, ok
+}
+
+// transport はリクエストとレスポンスを記録する http.RoundTripper
+type transport struct {
+	next     http.RoundTripper
+	logger   *slog.Logger
+	level    slog.Leveler
+	opts     TransportOptions
+	redacted map[string]bool // 正規化したヘッダー名
+}
+
+// NewTransport は next で送信したリクエストのメソッド、URL、ステータスコード、所要時間を記録する
+// http.RoundTripper を返します。next が nil の場合は http.DefaultTransport を使用します。
+//
+// 記録はリクエストのコンテキストで行うため、ContextWithRequestID で設定したリクエスト ID は
+// request_id 属性として付加されます。また、リクエストに X-Request-ID ヘッダーが無い場合は、
+// コンテキストのリクエスト ID をヘッダーに設定して送信先に伝えます。
+//
+//	client := &http.Client{Transport: golog.NewTransport(nil, logger, nil)}
+func NewTransport(next http.RoundTripper, logger *slog.Logger, opts *TransportOptions) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &transport{next: next, logger: logger, level: slog.LevelInfo}
+	if opts != nil {
+		t.opts = *opts
+		if opts.Level != nil {
+			t.level = opts.Level
+		}
+	}
+	if t.opts.MaxBodyBytes <= 0 {
+		t.opts.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	headers := t.opts.RedactHeaders
+	if headers == nil {
+		headers = defaultRedactHeaders
+	}
+	t.redacted = make(map[string]bool, len(headers))
+	for _, h := range headers {
+		t.redacted[http.CanonicalHeaderKey(h)] = true
+	}
+	return t
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if id, ok := RequestIDFromContext(ctx); ok && req.Header.Get(RequestIDHeader) == "" {
+		// RoundTripper はリクエストを変更してはならないため複製する
+		req = req.Clone(ctx)
+		req.Header.Set(RequestIDHeader, id)
+	}
+	// 記録しないレベルの場合もエラーやステータスによっては記録するため、最も高いレベルで判定する
+	if !t.logger.Enabled(ctx, max(t.level.Level(), slog.LevelError)) {
+		return t.next.RoundTrip(req)
+	}
+
+	attrs := make([]slog.Attr, 0, 10)
+	attrs = append(attrs,
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.Redacted()),
+	)
+	if retry, ok := RetryFromContext(ctx); ok {
+		attrs = append(attrs, slog.Int("retry", retry))
+	}
+	if t.opts.LogHeaders {
+		attrs = append(attrs, t.headerAttr("req_headers", req.Header))
+	}
+	if t.opts.LogBody {
+		var body []byte
+		req, body = t.peekRequestBody(req)
+		if body != nil {
+			attrs = append(attrs, t.bodyAttr("req_body", body))
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	attrs = append(attrs, slog.Duration(ElapsedKey, time.Since(start)))
+
+	level := t.level.Level()
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.String("error", err.Error()))
+	} else {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+		if resp.StatusCode >= http.StatusInternalServerError {
+			level = max(level, slog.LevelWarn)
+		}
+		if t.opts.LogHeaders {
+			attrs = append(attrs, t.headerAttr("resp_headers", resp.Header))
+		}
+		if t.opts.LogBody && resp.Body != nil {
+			var body []byte
+			body, resp.Body = t.peekBody(resp.Body)
+			attrs = append(attrs, t.bodyAttr("resp_body", body))
+		}
+	}
+	t.logger.LogAttrs(ctx, level, "http request", attrs...)
+	return resp, err
+}
+
+// headerAttr はヘッダーを、RedactHeaders の値を伏せたグループの属性にします
+func (t *transport) headerAttr(key string, header http.Header) slog.Attr {
+	attrs := make([]slog.Attr, 0, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if t.redacted[http.CanonicalHeaderKey(name)] {
+			value = redactedValue
+		}
+		attrs = append(attrs, slog.String(name, value))
+	}
+	return slog.Attr{Key: key, Value: slog.GroupValue(attrs...)}
+}
+
+// bodyAttr はボディを RedactBody で加工した文字列の属性にします
+func (t *transport) bodyAttr(key string, body []byte) slog.Attr {
+	if t.opts.RedactBody != nil {
+		body = t.opts.RedactBody(body)
+	}
+	return slog.String(key, string(body))
+}
+
+// peekRequestBody はリクエストのボディの先頭を読み取ります。
+// GetBody がある場合はその複製から読み取り、無い場合はボディを読み直せるように置き換えた複製のリクエストを返します。
+func (t *transport) peekRequestBody(req *http.Request) (*http.Request, []byte) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			peeked, _ := t.peekBody(body)
+			body.Close()
+			return req, peeked
+		}
+	}
+	req = req.Clone(req.Context())
+	var peeked []byte
+	peeked, req.Body = t.peekBody(req.Body)
+	return req, peeked
+}
+
+// peekBody は body の先頭 MaxBodyBytes バイトを読み取り、読み取った部分を含めて元と同じ内容を返す body を返します。
+// 切り詰めた場合は UTF-8 の文字の途中で切らず、末尾に "..." を付けます。
+func (t *transport) peekBody(body io.ReadCloser) ([]byte, io.ReadCloser) {
+	buf := make([]byte, t.opts.MaxBodyBytes+1)
+	n, _ := io.ReadFull(body, buf)
+	buf = buf[:n]
+	rest := readCloser{io.MultiReader(bytes.NewReader(buf), body), body}
+
+	if n <= t.opts.MaxBodyBytes {
+		return buf, rest
+	}
+	keep := t.opts.MaxBodyBytes
+	for keep > 0 && !utf8.RuneStart(buf[keep]) {
+		keep--
+	}
+	return append(buf[:keep:keep], messageEllipsis...), rest
+}
+
+// readCloser は Read と Close を別々の値で実装する io.ReadCloser
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
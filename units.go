@@ -0,0 +1,119 @@
+package loggo
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// unitKind は単位付きの値の単位
+type unitKind uint8
+
+const (
+	unitBytes unitKind = iota
+	unitMillis
+	unitPercent
+)
+
+// unitValue は単位付きの値。テキスト形式では単位を付けた読みやすい形で、JSON 形式では数値のまま出力します。
+type unitValue struct {
+	kind unitKind
+	n    int64   // unitBytes のバイト数、unitMillis の time.Duration
+	f    float64 // unitPercent の値
+}
+
+// Bytes はバイト数の属性を返します。テキスト形式では "1.5MiB" のように2進接頭辞で、JSON 形式ではバイト数の整数で出力します。
+//
+//	logger.Info("uploaded", golog.Bytes("size", 1572864)) // size=1.5MiB / {"size":1572864}
+func Bytes(key string, n int64) slog.Attr {
+	return slog.Any(key, unitValue{kind: unitBytes, n: n})
+}
+
+// Millis は時間の属性を返します。テキスト形式では "12.5ms" や "1.2s" のように、JSON 形式ではミリ秒の数値で出力します。
+//
+//	logger.Info("query done", golog.Millis("latency_ms", elapsed)) // latency_ms=12.5ms / {"latency_ms":12.5}
+func Millis(key string, d time.Duration) slog.Attr {
+	return slog.Any(key, unitValue{kind: unitMillis, n: int64(d)})
+}
+
+// Percent は百分率の属性を返します。f は 42.5 のように百分率の値で指定します。
+// テキスト形式では "42.5%" のように、JSON 形式では数値で出力します。
+//
+//	logger.Info("host stats", golog.Percent("cpu", 42.5)) // cpu=42.5% / {"cpu":42.5}
+func Percent(key string, f float64) slog.Attr {
+	return slog.Any(key, unitValue{kind: unitPercent, f: f})
+}
+
+// String は単位を付けた読みやすい表現を返します
+func (u unitValue) String() string {
+	return string(u.appendHuman(nil))
+}
+
+// MarshalJSON は単位を付けない数値を返します。このパッケージ以外のハンドラーで JSON に出力する場合に使われます。
+func (u unitValue) MarshalJSON() ([]byte, error) {
+	if u.kind == unitBytes {
+		return strconv.AppendInt(nil, u.n, 10), nil
+	}
+	return strconv.AppendFloat(nil, u.float(), 'f', -1, 64), nil
+}
+
+// float は unitMillis のミリ秒と unitPercent の値を返します
+func (u unitValue) float() float64 {
+	if u.kind == unitMillis {
+		return float64(u.n) / float64(time.Millisecond)
+	}
+	return u.f
+}
+
+// appendHuman は単位を付けた読みやすい表現を書き込みます
+func (u unitValue) appendHuman(b []byte) []byte {
+	switch u.kind {
+	case unitBytes:
+		return appendHumanBytes(b, u.n)
+	case unitMillis:
+		return append(b, time.Duration(u.n).String()...)
+	default:
+		b = strconv.AppendFloat(b, u.f, 'f', -1, 64)
+		return append(b, '%')
+	}
+}
+
+// appendHumanBytes はバイト数を2進接頭辞（KiB, MiB, ...）で小数点以下1桁まで書き込みます
+func appendHumanBytes(b []byte, n int64) []byte {
+	const units = "KMGTPE"
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	if abs < 1024 {
+		b = strconv.AppendInt(b, n, 10)
+		return append(b, 'B')
+	}
+	f := float64(n)
+	i := -1
+	for (f >= 1024 || f <= -1024) && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	s := strconv.AppendFloat(nil, f, 'f', 1, 64)
+	if len(s) > 2 && s[len(s)-2] == '.' && s[len(s)-1] == '0' {
+		s = s[:len(s)-2]
+	}
+	b = append(b, s...)
+	return append(b, units[i], 'i', 'B')
+}
+
+// appendUnit は単位付きの値を、JSON 形式では数値で、テキスト形式では単位を付けて書き込みます
+func (f *valueFormatter) appendUnit(buf *buffer.Buffer, u unitValue) {
+	if !f.JSON {
+		*buf = u.appendHuman(*buf)
+		return
+	}
+	if u.kind == unitBytes {
+		f.appendInt(buf, u.n)
+		return
+	}
+	f.appendFloat(buf, u.float(), 64)
+}
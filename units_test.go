@@ -0,0 +1,63 @@
+package loggo
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestUnitAttrs は単位付きの属性をテキスト形式では読みやすく、JSON 形式では数値で出力することをテストします
+func TestUnitAttrs(t *testing.T) {
+	attrs := []any{
+		Bytes("size", 1572864),
+		Bytes("small", 512),
+		Millis("latency", 12500*time.Microsecond),
+		Percent("cpu", 42.5),
+	}
+
+	var text bytes.Buffer
+	slog.New(NewHandler(&text, nil)).Info("stats", attrs...)
+	if !strings.HasSuffix(text.String(), ` size=1.5MiB small=512B latency=12.5ms cpu=42.5%`+"\n") {
+		t.Errorf("text: %s", text.String())
+	}
+
+	var js bytes.Buffer
+	slog.New(NewHandler(&js, &Options{Format: FormatJSON})).Info("stats", attrs...)
+	if !strings.Contains(js.String(), `"size":1572864,"small":512,"latency":12.5,"cpu":42.5}`) {
+		t.Errorf("json: %s", js.String())
+	}
+
+	// 標準の JSONHandler でも数値で出力される
+	var std bytes.Buffer
+	slog.New(slog.NewJSONHandler(&std, nil)).Info("stats", attrs...)
+	var m map[string]any
+	if err := json.Unmarshal(std.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["size"] != 1572864.0 || m["latency"] != 12.5 {
+		t.Errorf("std json: %s", std.String())
+	}
+}
+
+// TestHumanBytes はバイト数の2進接頭辞による表現をテストします
+func TestHumanBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1KiB"},
+		{1536, "1.5KiB"},
+		{5 << 30, "5GiB"},
+		{-2048, "-2KiB"},
+	}
+	for _, tt := range tests {
+		if got := string(appendHumanBytes(nil, tt.n)); got != tt.want {
+			t.Errorf("appendHumanBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package loggo
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// defaultW3CFields は W3COptions.Fields のデフォルト値
+var defaultW3CFields = []string{
+	"date", "time", "c-ip", "cs-method", "cs-uri-stem", "cs-uri-query",
+	"sc-status", "sc-bytes", "time-taken", "cs(User-Agent)", "cs(Referer)",
+}
+
+// w3cAttrKeys は W3C のフィールドと、AccessLogMiddleware が記録する属性のキーの対応
+var w3cAttrKeys = map[string]string{
+	"c-ip":           "remote_addr",
+	"cs-method":      "method",
+	"cs-uri-stem":    "path",
+	"cs-uri-query":   "query",
+	"cs-version":     "proto",
+	"cs-host":        "host",
+	"sc-status":      "status",
+	"sc-bytes":       "bytes",
+	"time-taken":     ElapsedKey,
+	"cs(User-Agent)": "user_agent",
+	"cs(Referer)":    "referer",
+}
+
+// W3COptions は W3CHandler のオプション
+type W3COptions struct {
+	// Fields は出力するフィールド。nil の場合は
+	// date time c-ip cs-method cs-uri-stem cs-uri-query sc-status sc-bytes time-taken cs(User-Agent) cs(Referer) です。
+	// AccessLogMiddleware の属性に対応しないフィールドは、同じキーの属性（"x-" で始まる場合は "x-" を除いたキー）の値です。
+	Fields []string
+	// Software は #Software ディレクティブの値。空の場合は "golog" です。
+	Software string
+	// Level は出力する最小のレベル。nil の場合は slog.LevelInfo です。
+	Level slog.Leveler
+}
+
+// W3CHandler は AccessLogMiddleware のレコードを W3C 拡張ログファイル形式で出力するハンドラー。
+// IIS 形式のログを前提とする古いアクセスログの解析ツールに取り込むために使います。
+//
+// フィールドの値は空白で区切り、値の中の空白は "+" に、空の値は "-" に置き換えます。時刻は UTC です。
+// 出力先が SetHeader を実装している場合（FileWriter など）は、新しいファイルごとに最初のレコードの前に
+// #Fields などのヘッダーを書き込むよう設定します。それ以外の出力先には、最初のレコードの前に一度だけヘッダーを書き込みます。
+//
+//	fw, _ := golog.OpenFile("access.log", &golog.FileWriterOptions{MaxSize: 100 << 20})
+//	access := slog.New(golog.NewW3CHandler(fw, nil))
+//	handler := golog.AccessLogMiddleware(access, nil)(mux)
+type W3CHandler struct {
+	w        io.Writer
+	mu       *sync.Mutex // WithAttrs や WithGroup で作られたハンドラー間で共有し、書き込みを直列化する
+	header   *bool       // 出力先が SetHeader を実装しない場合に、ヘッダーを書き込んだか
+	fields   []string
+	software string
+	level    slog.Leveler
+	goas     []groupOrAttrs
+}
+
+// NewW3CHandler は w に W3C 拡張ログファイル形式の行を書き込むハンドラーを作成します
+func NewW3CHandler(w io.Writer, opts *W3COptions) *W3CHandler {
+	h := &W3CHandler{
+		w:        w,
+		mu:       new(sync.Mutex),
+		header:   new(bool),
+		fields:   defaultW3CFields,
+		software: "golog",
+		level:    slog.LevelInfo,
+	}
+	if opts != nil {
+		if opts.Fields != nil {
+			h.fields = slices.Clone(opts.Fields)
+		}
+		if opts.Software != "" {
+			h.software = opts.Software
+		}
+		if opts.Level != nil {
+			h.level = opts.Level
+		}
+	}
+	if hw, ok := w.(interface{ SetHeader(func() []byte) error }); ok {
+		if err := hw.SetHeader(h.Header); err == nil {
+			*h.header = true
+		}
+	}
+	return h
+}
+
+// Header は #Software, #Version, #Date, #Fields のディレクティブを返します
+func (h *W3CHandler) Header() []byte {
+	var b []byte
+	b = append(b, "#Software: "...)
+	b = append(b, h.software...)
+	b = append(b, "\n#Version: 1.0\n#Date: "...)
+	b = time.Now().UTC().AppendFormat(b, time.DateTime)
+	b = append(b, "\n#Fields: "...)
+	b = append(b, strings.Join(h.fields, " ")...)
+	return append(b, '\n')
+}
+
+// Enabled はレベルが Level 以上かを返します
+func (h *W3CHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle はレコードを1行として書き込みます
+func (h *W3CHandler) Handle(ctx context.Context, r slog.Record) error {
+	values := make(map[string]slog.Value, r.NumAttrs()+4)
+	add := func(key string, v slog.Value) {
+		values[key] = v
+	}
+	prefix := ""
+	for _, goa := range h.goas {
+		if goa.group != "" {
+			prefix += goa.group + "."
+			continue
+		}
+		for _, a := range goa.attrs {
+			flattenAttr(prefix, a, add)
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		flattenAttr(prefix, a, add)
+		return true
+	})
+	if _, ok := values[RequestIDKey]; !ok {
+		if id, ok := RequestIDFromContext(ctx); ok {
+			values[RequestIDKey] = slog.StringValue(id)
+		}
+	}
+
+	buf := buffer.New()
+	defer buf.Free()
+	t := r.Time.UTC()
+	for i, field := range h.fields {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		switch field {
+		case "date":
+			*buf = t.AppendFormat(*buf, time.DateOnly)
+			continue
+		case "time":
+			*buf = t.AppendFormat(*buf, time.TimeOnly)
+			continue
+		}
+		key, ok := w3cAttrKeys[field]
+		if !ok {
+			key = field
+			if _, found := values[key]; !found {
+				key = strings.TrimPrefix(field, "x-")
+			}
+		}
+		v, ok := values[key]
+		if !ok {
+			buf.WriteByte('-')
+			continue
+		}
+		switch field {
+		case "c-ip":
+			s := v.String()
+			if host, _, err := net.SplitHostPort(s); err == nil {
+				s = host
+			}
+			appendW3CValue(buf, s)
+		case "time-taken":
+			// W3C 拡張ログ形式では秒数
+			if v.Kind() == slog.KindDuration {
+				*buf = strconv.AppendFloat(*buf, v.Duration().Seconds(), 'f', 3, 64)
+			} else {
+				appendW3CValue(buf, v.String())
+			}
+		default:
+			appendW3CValue(buf, v.String())
+		}
+	}
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !*h.header {
+		*h.header = true
+		if _, err := h.w.Write(h.Header()); err != nil {
+			return err
+		}
+	}
+	_, err := h.w.Write(*buf)
+	return err
+}
+
+// WithAttrs は属性を追加したハンドラーを返します
+func (h *W3CHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(slices.Clip(h.goas), groupOrAttrs{attrs: slices.Clone(attrs)})
+	return &h2
+}
+
+// WithGroup はグループを追加したハンドラーを返します
+func (h *W3CHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(slices.Clip(h.goas), groupOrAttrs{group: name})
+	return &h2
+}
+
+// appendW3CValue は値の空白と制御文字を "+" に置き換えて書き込みます。空の値は "-" とします。
+func appendW3CValue(buf *buffer.Buffer, s string) {
+	if s == "" {
+		buf.WriteByte('-')
+		return
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c == 0x7f {
+			buf.WriteByte('+')
+		} else {
+			buf.WriteByte(c)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestW3CHandler はヘッダーを最初のレコードの前に一度だけ書き込み、フィールドを W3C の規則で出力することをテストします
+func TestW3CHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewW3CHandler(&buf, &W3COptions{
+		Fields: []string{"date", "time", "c-ip", "cs-method", "cs-uri-stem", "cs-uri-query", "sc-status", "time-taken", "cs(User-Agent)", "x-request_id"},
+	}))
+	ctx := ContextWithRequestID(t.Context(), "req-1")
+	ts := time.Date(2024, time.March, 5, 18, 4, 7, 0, time.FixedZone("JST", 9*60*60))
+	r := slog.NewRecord(ts, slog.LevelInfo, "http access", 0)
+	r.AddAttrs(
+		slog.String("method", "GET"),
+		slog.String("path", "/users"),
+		slog.String("remote_addr", "192.0.2.1:54321"),
+		slog.String("user_agent", "Mozilla/5.0 (X11)"),
+		slog.Int("status", 200),
+		slog.Duration(ElapsedKey, 1234*time.Millisecond),
+	)
+	logger.Handler().Handle(ctx, r)
+	logger.Handler().Handle(ctx, r)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 6 || lines[0] != "#Software: golog" || lines[1] != "#Version: 1.0" || !strings.HasPrefix(lines[2], "#Date: ") {
+		t.Fatalf("unexpected header:\n%s", buf.String())
+	}
+	if want := "#Fields: date time c-ip cs-method cs-uri-stem cs-uri-query sc-status time-taken cs(User-Agent) x-request_id"; lines[3] != want {
+		t.Errorf("got  %q\nwant %q", lines[3], want)
+	}
+	want := "2024-03-05 09:04:07 192.0.2.1 GET /users - 200 1.234 Mozilla/5.0+(X11) req-1"
+	if lines[4] != want || lines[5] != want {
+		t.Errorf("got  %q\nwant %q", lines[4], want)
+	}
+}
+
+// TestW3CHandlerFileRotation は FileWriter のローテーションの後の新しいファイルにもヘッダーを書き込むことをテストします
+func TestW3CHandlerFileRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	fw, err := OpenFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Close()
+	logger := slog.New(NewW3CHandler(fw, nil))
+	logger.Info("http access", "method", "GET", "path", "/")
+	if err := fw.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("http access", "method", "GET", "path", "/health")
+
+	files, _ := filepath.Glob(filepath.Join(dir, "access*.log"))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %v", files)
+	}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(b), "#Fields: "); n != 1 {
+			t.Errorf("%s has %d #Fields directives:\n%s", file, n, b)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package loggo
+
+import (
+	"log/slog"
+	"runtime"
+	"time"
+
+	"github.com/f0reth/golog/internal/buffer"
+)
+
+// WarmupOptions は Handler.Warmup のオプション
+type WarmupOptions struct {
+	// Buffers はバッファのプールに事前に用意するバッファの数。0 の場合は GOMAXPROCS です。
+	Buffers int
+	// Attrs は実際に記録するものと同じキーと型の属性。値のエンコード手順のコンパイルと
+	// キーのフォーマットの結果がキャッシュされます。構造体の値ではゼロ値を渡せば十分です。
+	Attrs []slog.Attr
+}
+
+// Warmup はバッファのプール、型ごとのエンコード手順、キーのキャッシュを事前に用意します。
+// 起動直後の最初のリクエストでアロケーションやリフレクションによる遅延が発生するのを避けるため、
+// レイテンシが重要なサービスでは、トラフィックを受け付ける前に一度呼び出します。
+//
+//	handler.Warmup(&golog.WarmupOptions{
+//	    Attrs: []slog.Attr{slog.Any("order", Order{}), slog.String("request id", "")},
+//	})
+//
+// opts.Attrs を含むレコードを実際にフォーマットしますが、出力先には書き込みません。
+// sync.Pool の性質上、使われなかったバッファはガベージコレクションで解放されることがあります。
+func (h *Handler) Warmup(opts *WarmupOptions) {
+	n := runtime.GOMAXPROCS(0)
+	var attrs []slog.Attr
+	if opts != nil {
+		if opts.Buffers > 0 {
+			n = opts.Buffers
+		}
+		attrs = opts.Attrs
+	}
+
+	// 用意したバッファをフォーマットで取り出さないよう、先にフォーマットする
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "golog: warmup", 0)
+	r.AddAttrs(attrs...)
+	buf := buffer.New()
+	h.format(buf, r)
+	buf.Free()
+
+	buffer.Preallocate(n)
+}
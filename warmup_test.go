@@ -0,0 +1,39 @@
+package loggo
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+// warmupOrder は Warmup でエンコード手順をコンパイルする型
+type warmupOrder struct {
+	ID    int      `json:"id"`
+	Items []string `json:"items"`
+}
+
+// TestWarmup は Warmup がエンコード手順とキーをキャッシュし、何も書き込まないことをテストします
+func TestWarmup(t *testing.T) {
+	for _, format := range []Format{FormatText, FormatJSON} {
+		t.Run(format.String(), func(t *testing.T) {
+			var w countingWriter
+			h := NewHandler(&w, &Options{Format: format})
+			encoderCache.Delete(reflect.TypeFor[warmupOrder]())
+
+			h.Warmup(&WarmupOptions{
+				Buffers: 4,
+				Attrs:   []slog.Attr{slog.Any("order", warmupOrder{}), slog.String("request id", "")},
+			})
+			if _, ok := encoderCache.Load(reflect.TypeFor[warmupOrder]()); !ok {
+				t.Error("encoder for warmupOrder should be cached")
+			}
+			if h.keys.n.Load() == 0 {
+				t.Error("quoted key should be cached")
+			}
+			if w.writes != 0 {
+				t.Errorf("Warmup should not write, got %d writes", w.writes)
+			}
+		})
+	}
+	NewHandler(discardWriter{}, nil).Warmup(nil)
+}
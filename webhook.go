@@ -0,0 +1,348 @@
+package loggo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultWebhookFlushInterval は WebhookWriterOptions.FlushInterval のデフォルト値
+	defaultWebhookFlushInterval = time.Second
+	// defaultWebhookMaxBatchBytes は WebhookWriterOptions.MaxBatchBytes のデフォルト値
+	defaultWebhookMaxBatchBytes = 1 << 20
+	// defaultWebhookMaxRetries は WebhookWriterOptions.MaxRetries のデフォルト値
+	defaultWebhookMaxRetries = 3
+	// defaultWebhookRetryBackoff は WebhookWriterOptions.RetryBackoff のデフォルト値
+	defaultWebhookRetryBackoff = 500 * time.Millisecond
+	// defaultWebhookMaxBufferBytes は WebhookWriterOptions.MaxBufferBytes のデフォルト値
+	defaultWebhookMaxBufferBytes = 8 << 20
+	// defaultWebhookTimeout は WebhookWriterOptions.Client が nil の場合の送信のタイムアウト
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+// WebhookWriterOptions は WebhookWriter のオプション
+type WebhookWriterOptions struct {
+	// JSONArray はバッチを NDJSON ではなく JSON の配列として送信します。
+	// Format: FormatJSON と組み合わせて、配列のみを受け付ける送信先に使います。
+	JSONArray bool
+	// Header はリクエストに付けるヘッダー。認証には "Authorization" などを指定します。
+	Header http.Header
+	// Gzip はリクエストのボディを gzip で圧縮します
+	Gzip bool
+	// Client は送信に使うクライアント。nil の場合はタイムアウトが 10 秒のクライアントです。
+	// 応答しない送信先で送信や Close が止まらないよう、独自のクライアントにもタイムアウトを設定してください。
+	Client *http.Client
+	// FlushInterval はバッファした行を送信する間隔。0 の場合は 1 秒です。
+	FlushInterval time.Duration
+	// MaxBatchBytes はバッファした行がこのバイト数を超えた時点で、間隔を待たずに送信します。0 の場合は 1MB です。
+	MaxBatchBytes int
+	// MaxBufferBytes は送信待ちとしてバッファする最大のバイト数。送信先が遅い場合や応答しない場合に、
+	// 超えた行は破棄して数え、次の送信時に OnError に通知します。0 の場合は 8MB です。
+	MaxBufferBytes int
+	// MaxRetries は通信のエラー、429、5xx のレスポンスで再送する最大の回数。0 の場合は 3 回、負の場合は再送しません。
+	MaxRetries int
+	// RetryBackoff は最初の再送までの待機時間。再送ごとに2倍になります。0 の場合は 500 ミリ秒です。
+	RetryBackoff time.Duration
+	// OnError は再送しても送信できずにバッチを破棄した場合と、MaxBufferBytes を超えて行を破棄した場合のエラーを受け取ります
+	OnError func(err error)
+}
+
+// WebhookWriter は行をバッファし、一定の間隔で HTTP の POST でまとめて送信するライター。
+// 1行を1レコードとして NDJSON（または JSON の配列）のボディを作るため、多くの SaaS のログの受信口に直接送れます。
+//
+// Write はバッファに追加するだけで送信を待ちません。送信はバックグラウンドのゴルーチンが行い、
+// Shutdown の対象になります。使い終わったら Close を呼び出してください（呼び出すまでゴルーチンと共に保持されます）。
+type WebhookWriter struct {
+	url           string
+	header        http.Header
+	client        *http.Client
+	jsonArray     bool
+	gzip          bool
+	maxBatchBytes int
+	maxBuffer     int
+	maxRetries    int
+	retryBackoff  time.Duration
+	onError       func(err error)
+
+	mu      sync.Mutex
+	pending []byte // 送信待ちの行（各行は改行で終わる）
+	lines   int
+	dropped int // MaxBufferBytes を超えたため破棄した行の数
+	closed  bool
+	lastErr error // 直前の送信のエラー
+
+	sendMu sync.Mutex // 送信を直列化して行の順序を保つ
+	kick   chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewWebhookWriter は url へ行を送信するライターを作成します
+//
+//	w := golog.NewWebhookWriter("https://logs.example.com/ingest", &golog.WebhookWriterOptions{
+//	    Header: http.Header{"Authorization": {"Bearer " + token}},
+//	    Gzip:   true,
+//	})
+//	defer w.Close()
+//	handler := golog.NewHandler(w, &golog.Options{Format: golog.FormatJSON})
+func NewWebhookWriter(url string, opts *WebhookWriterOptions) *WebhookWriter {
+	w := &WebhookWriter{
+		url:           url,
+		client:        &http.Client{Timeout: defaultWebhookTimeout},
+		maxBatchBytes: defaultWebhookMaxBatchBytes,
+		maxBuffer:     defaultWebhookMaxBufferBytes,
+		maxRetries:    defaultWebhookMaxRetries,
+		retryBackoff:  defaultWebhookRetryBackoff,
+		kick:          make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	interval := defaultWebhookFlushInterval
+	if opts != nil {
+		w.header = opts.Header.Clone()
+		w.jsonArray = opts.JSONArray
+		w.gzip = opts.Gzip
+		if opts.Client != nil {
+			w.client = opts.Client
+		}
+		if opts.FlushInterval > 0 {
+			interval = opts.FlushInterval
+		}
+		if opts.MaxBatchBytes > 0 {
+			w.maxBatchBytes = opts.MaxBatchBytes
+		}
+		if opts.MaxBufferBytes > 0 {
+			w.maxBuffer = opts.MaxBufferBytes
+		}
+		if opts.MaxRetries != 0 {
+			w.maxRetries = max(opts.MaxRetries, 0)
+		}
+		if opts.RetryBackoff > 0 {
+			w.retryBackoff = opts.RetryBackoff
+		}
+		w.onError = opts.OnError
+	}
+	go w.loop(interval)
+	registerDrainer(w)
+	return w
+}
+
+// Write は p の行をバッファに追加します。送信はバックグラウンドで行われます。
+// バッファが MaxBufferBytes を超える行は破棄して数えます。
+func (w *WebhookWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	for line := range bytes.Lines(p) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		size := len(line)
+		if line[len(line)-1] != '\n' {
+			size++
+		}
+		if len(w.pending)+size > w.maxBuffer {
+			w.dropped++
+			continue
+		}
+		w.pending = append(w.pending, line...)
+		if line[len(line)-1] != '\n' {
+			w.pending = append(w.pending, '\n')
+		}
+		w.lines++
+	}
+	if len(w.pending) >= w.maxBatchBytes {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// loop は interval ごと、またはバッファが MaxBatchBytes を超えた時点でバッファした行を送信します
+func (w *WebhookWriter) loop(interval time.Duration) {
+	defer close(w.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.kick:
+		case <-w.stop:
+			return
+		}
+		w.Flush(context.Background())
+	}
+}
+
+// Flush はバッファした行をすぐに送信し、完了を待ちます。
+// 再送しても送信できなかった場合はそのバッチを破棄してエラーを返します。
+func (w *WebhookWriter) Flush(ctx context.Context) error {
+	_, err := w.flush(ctx)
+	return err
+}
+
+// flush はバッファした行を送信し、送信できなかった行数と、バッファが満杯のため破棄した行数の合計を返します
+func (w *WebhookWriter) flush(ctx context.Context) (int, error) {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+
+	w.mu.Lock()
+	batch, lines, dropped := w.pending, w.lines, w.dropped
+	w.pending, w.lines, w.dropped = nil, 0, 0
+	w.mu.Unlock()
+
+	var errs []error
+	if dropped > 0 {
+		errs = append(errs, w.reportError(fmt.Errorf("golog: webhook buffer is full, dropped %d records", dropped)))
+	}
+	if lines > 0 {
+		err := w.send(ctx, batch)
+		w.mu.Lock()
+		w.lastErr = err
+		w.mu.Unlock()
+		if err != nil {
+			dropped += lines
+			errs = append(errs, w.reportError(fmt.Errorf("golog: webhook dropped %d records: %w", lines, err)))
+		}
+	}
+	return dropped, errors.Join(errs...)
+}
+
+// reportError は err を OnError に渡して返します
+func (w *WebhookWriter) reportError(err error) error {
+	if w.onError != nil {
+		w.onError(err)
+	}
+	return err
+}
+
+// send はバッチを POST し、失敗した場合は RetryBackoff の間隔を2倍にしながら再送します
+func (w *WebhookWriter) send(ctx context.Context, batch []byte) error {
+	body, contentType := batch, "application/x-ndjson"
+	if w.jsonArray {
+		body, contentType = ndjsonToArray(batch), "application/json"
+	}
+	if w.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(body)
+		zw.Close()
+		body = buf.Bytes()
+	}
+
+	backoff := w.retryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, body, contentType)
+		if err == nil || !retry || attempt >= w.maxRetries {
+			return err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// post はボディを1回送信します。再送すれば成功する可能性がある場合は retry に true を返します。
+func (w *WebhookWriter) post(ctx context.Context, body []byte, contentType string) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for name, values := range w.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", contentType)
+	if w.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096)) // 接続を再利用するために読み捨てる
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("golog: webhook returned %s", resp.Status)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// ndjsonToArray は改行で区切られた JSON の値を JSON の配列にします
+func ndjsonToArray(batch []byte) []byte {
+	out := make([]byte, 0, len(batch)+2)
+	out = append(out, '[')
+	for line := range bytes.Lines(batch) {
+		if len(out) > 1 {
+			out = append(out, ',')
+		}
+		out = append(out, bytes.TrimRight(line, "\r\n")...)
+	}
+	return append(out, ']')
+}
+
+// Healthy は直前の送信が成功したかを返します
+func (w *WebhookWriter) Healthy() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.closed && w.lastErr == nil
+}
+
+// Ping はクローズ済みの場合や直前の送信が失敗した場合にエラーを返します
+func (w *WebhookWriter) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	return w.lastErr
+}
+
+// Close はバッファした行を送信してバックグラウンドのゴルーチンを停止します
+func (w *WebhookWriter) Close() error {
+	_, err := w.shutdown(context.Background())
+	return err
+}
+
+// shutdown は新しい行の受け付けを停止し、ctx の期限までにバッファした行を送信します
+func (w *WebhookWriter) shutdown(ctx context.Context) (int, error) {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.stop)
+	unregisterDrainer(w)
+	// 送信中のバッチの完了を待つ
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		// 期限切れのエラーは Shutdown が追加する
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.lines + w.dropped, w.lastErr
+	}
+	return w.flush(ctx)
+}
@@ -0,0 +1,240 @@
+package loggo
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// webhookRequest はテスト用のサーバーが受け取ったリクエスト
+type webhookRequest struct {
+	header http.Header
+	body   string
+}
+
+// newWebhookServer は受け取ったリクエストを記録し、status が返すステータスコードで応答するサーバーを開始します
+func newWebhookServer(t *testing.T, status func(n int) int) (*httptest.Server, func() []webhookRequest) {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		reqs []webhookRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body = zr
+		}
+		b, _ := io.ReadAll(body)
+		mu.Lock()
+		reqs = append(reqs, webhookRequest{header: r.Header.Clone(), body: string(b)})
+		n := len(reqs)
+		mu.Unlock()
+		rw.WriteHeader(status(n))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []webhookRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]webhookRequest(nil), reqs...)
+	}
+}
+
+// TestWebhookWriter は行を NDJSON のバッチとしてヘッダーと gzip を伴って送信することをテストします
+func TestWebhookWriter(t *testing.T) {
+	srv, requests := newWebhookServer(t, func(int) int { return http.StatusAccepted })
+	w := NewWebhookWriter(srv.URL, &WebhookWriterOptions{
+		Header:        http.Header{"Authorization": {"Bearer secret"}},
+		Gzip:          true,
+		FlushInterval: time.Hour,
+	})
+	defer w.Close()
+
+	logger := slog.New(NewHandler(w, &Options{Format: FormatJSON}))
+	logger.Info("first", "n", 1)
+	logger.Info("second", "n", 2)
+	if len(requests()) != 0 {
+		t.Fatal("records should be buffered until flush")
+	}
+	if err := w.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("requests = %d, want 1", len(reqs))
+	}
+	req := reqs[0]
+	if req.header.Get("Authorization") != "Bearer secret" || req.header.Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("unexpected headers: %v", req.header)
+	}
+	lines := strings.Split(strings.TrimSuffix(req.body, "\n"), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"msg":"first"`) || !strings.Contains(lines[1], `"msg":"second"`) {
+		t.Errorf("unexpected body: %q", req.body)
+	}
+	if !w.Healthy() {
+		t.Error("writer should be healthy")
+	}
+}
+
+// TestWebhookWriterJSONArray は JSONArray でバッチを JSON の配列として送信することをテストします
+func TestWebhookWriterJSONArray(t *testing.T) {
+	srv, requests := newWebhookServer(t, func(int) int { return http.StatusOK })
+	w := NewWebhookWriter(srv.URL, &WebhookWriterOptions{JSONArray: true, FlushInterval: time.Hour})
+	w.Write([]byte("{\"a\":1}\n{\"b\":2}\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("requests = %d, want 1", len(reqs))
+	}
+	if reqs[0].body != `[{"a":1},{"b":2}]` || reqs[0].header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected request: %q %v", reqs[0].body, reqs[0].header)
+	}
+	if _, err := w.Write([]byte("late\n")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write after Close = %v, want ErrClosed", err)
+	}
+}
+
+// TestWebhookWriterRetry は 5xx のレスポンスで再送し、4xx では再送しないことをテストします
+func TestWebhookWriterRetry(t *testing.T) {
+	srv, requests := newWebhookServer(t, func(n int) int {
+		if n < 3 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+	w := NewWebhookWriter(srv.URL, &WebhookWriterOptions{FlushInterval: time.Hour, RetryBackoff: time.Millisecond})
+	defer w.Close()
+	w.Write([]byte("retried\n"))
+	if err := w.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(requests()); n != 3 {
+		t.Errorf("requests = %d, want 3", n)
+	}
+
+	srv2, requests2 := newWebhookServer(t, func(int) int { return http.StatusBadRequest })
+	var reported atomic.Value
+	w2 := NewWebhookWriter(srv2.URL, &WebhookWriterOptions{
+		FlushInterval: time.Hour,
+		RetryBackoff:  time.Millisecond,
+		OnError:       func(err error) { reported.Store(err) },
+	})
+	defer w2.Close()
+	w2.Write([]byte("rejected\n"))
+	if err := w2.Flush(t.Context()); err == nil {
+		t.Fatal("expected error for 400")
+	}
+	if n := len(requests2()); n != 1 {
+		t.Errorf("requests = %d, want 1 (no retry for 4xx)", n)
+	}
+	if reported.Load() == nil {
+		t.Error("OnError was not called")
+	}
+	if w2.Healthy() || w2.Ping(t.Context()) == nil {
+		t.Error("writer should be unhealthy after a dropped batch")
+	}
+}
+
+// TestWebhookWriterMaxBatchBytes はバッファが MaxBatchBytes を超えた時点で間隔を待たずに送信することをテストします
+func TestWebhookWriterMaxBatchBytes(t *testing.T) {
+	srv, requests := newWebhookServer(t, func(int) int { return http.StatusOK })
+	w := NewWebhookWriter(srv.URL, &WebhookWriterOptions{FlushInterval: time.Hour, MaxBatchBytes: 10})
+	defer w.Close()
+	w.Write([]byte("0123456789abcdef\n"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(requests()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("batch was not sent")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestWebhookWriterMaxBufferBytes はバッファが満杯の場合に行を破棄して通知することをテストします
+func TestWebhookWriterMaxBufferBytes(t *testing.T) {
+	srv, requests := newWebhookServer(t, func(int) int { return http.StatusOK })
+	var errs []error
+	w := NewWebhookWriter(srv.URL, &WebhookWriterOptions{
+		FlushInterval:  time.Hour,
+		MaxBatchBytes:  1 << 20,
+		MaxBufferBytes: 10,
+		OnError:        func(err error) { errs = append(errs, err) },
+	})
+	defer w.Close()
+
+	w.Write([]byte("first\nsecond\nthird\n"))
+	err := w.Flush(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "dropped 2 records") {
+		t.Errorf("expected dropped records to be reported, got %v", err)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected OnError to be called once, got %v", errs)
+	}
+	if reqs := requests(); len(reqs) != 1 || reqs[0].body != "first\n" {
+		t.Errorf("unexpected requests: %v", reqs)
+	}
+}
+
+// TestWebhookWriterShutdown は Shutdown でバッファした行を送信することをテストします
+func TestWebhookWriterShutdown(t *testing.T) {
+	srv, requests := newWebhookServer(t, func(int) int { return http.StatusOK })
+	w := NewWebhookWriter(srv.URL, &WebhookWriterOptions{FlushInterval: time.Hour})
+	w.Write([]byte("pending\n"))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if n, err := Shutdown(ctx); n != 0 || err != nil {
+		t.Fatalf("Shutdown() = %d, %v", n, err)
+	}
+	if reqs := requests(); len(reqs) != 1 || reqs[0].body != "pending\n" {
+		t.Errorf("unexpected requests: %v", reqs)
+	}
+}
+
+// TestWebhookWriterShutdownDeadline は期限までに送信できなかった行数を返し、期限切れのエラーを1つだけ返すことをテストします
+func TestWebhookWriterShutdownDeadline(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	w := NewWebhookWriter(srv.URL, &WebhookWriterOptions{FlushInterval: time.Millisecond})
+	w.Write([]byte("sending\n"))
+	<-started
+	w.Write([]byte("pending\n"))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	n, err := Shutdown(ctx)
+	if n != 1 {
+		t.Errorf("expected 1 undelivered line, got %d", n)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || strings.Count(err.Error(), context.DeadlineExceeded.Error()) != 1 {
+		t.Errorf("expected a single DeadlineExceeded, got %v", err)
+	}
+}
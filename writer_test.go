@@ -0,0 +1,135 @@
+package loggo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingWriter は書き込み回数を記録するスレッドセーフな io.Writer です
+type countingWriter struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writes++
+	return c.buf.Write(p)
+}
+
+func (c *countingWriter) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
+
+func (c *countingWriter) Writes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writes
+}
+
+// TestBufferedWriterSizeFlush はサイズ閾値によるフラッシュをテストします
+func TestBufferedWriterSizeFlush(t *testing.T) {
+	var out countingWriter
+	w := NewBufferedWriter(&out, &BufferedWriterOptions{Size: 16, FlushInterval: -1})
+	defer w.Close()
+
+	w.Write([]byte("0123456789\n"))
+	if out.Writes() != 0 {
+		t.Fatalf("expected no writes before threshold, got %d", out.Writes())
+	}
+
+	// 閾値を超えるので、追加前に既存のデータがフラッシュされる
+	w.Write([]byte("abcdefghij\n"))
+	if out.Writes() != 1 {
+		t.Fatalf("expected 1 write after threshold, got %d", out.Writes())
+	}
+	if out.String() != "0123456789\n" {
+		t.Errorf("expected only first line to be flushed, got %q", out.String())
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "0123456789\nabcdefghij\n" {
+		t.Errorf("unexpected output after flush: %q", out.String())
+	}
+}
+
+// TestBufferedWriterLargeWrite は閾値以上の書き込みが直接書き出されることをテストします
+func TestBufferedWriterLargeWrite(t *testing.T) {
+	var out countingWriter
+	w := NewBufferedWriter(&out, &BufferedWriterOptions{Size: 8, FlushInterval: -1})
+	defer w.Close()
+
+	w.Write([]byte("abc"))
+	w.Write([]byte("0123456789"))
+
+	if out.String() != "abc0123456789" {
+		t.Errorf("expected buffered data followed by large write, got %q", out.String())
+	}
+}
+
+// TestBufferedWriterIntervalFlush は定期フラッシュをテストします
+func TestBufferedWriterIntervalFlush(t *testing.T) {
+	var out countingWriter
+	w := NewBufferedWriter(&out, &BufferedWriterOptions{FlushInterval: 10 * time.Millisecond})
+	defer w.Close()
+
+	w.Write([]byte("tick\n"))
+
+	deadline := time.Now().Add(time.Second)
+	for out.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if out.String() != "tick\n" {
+		t.Errorf("expected periodic flush, got %q", out.String())
+	}
+}
+
+// TestBufferedWriterClose は Close でデータが書き出され、以降の書き込みが失敗することをテストします
+func TestBufferedWriterClose(t *testing.T) {
+	var out countingWriter
+	w := NewBufferedWriter(&out, nil)
+
+	w.Write([]byte("last\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "last\n" {
+		t.Errorf("expected data to be flushed on close, got %q", out.String())
+	}
+
+	if _, err := w.Write([]byte("after\n")); err != ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close should be a no-op, got %v", err)
+	}
+}
+
+// TestBufferedWriterWithHandler はハンドラーと組み合わせた場合の書き込み回数をテストします
+func TestBufferedWriterWithHandler(t *testing.T) {
+	var out countingWriter
+	w := NewBufferedWriter(&out, &BufferedWriterOptions{FlushInterval: -1})
+
+	logger := slog.New(NewHandler(w, nil))
+	for i := range 100 {
+		logger.Info("buffered", "i", i)
+	}
+	w.Close()
+
+	if out.Writes() != 1 {
+		t.Errorf("expected records to be coalesced into 1 write, got %d", out.Writes())
+	}
+	if got := strings.Count(out.String(), "\n"); got != 100 {
+		t.Errorf("expected 100 lines, got %d", got)
+	}
+}
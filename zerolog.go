@@ -0,0 +1,116 @@
+package loggo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/f0reth/golog/internal/jsonattr"
+)
+
+// zerologLevels は zerolog のレベル名と slog のレベルの対応
+var zerologLevels = map[string]slog.Level{
+	"trace": slog.LevelDebug - 4,
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+	"fatal": slog.LevelError + 4,
+	"panic": slog.LevelError + 8,
+}
+
+// ZerologWriter は zerolog が出力する JSON のイベントを解析し、slog.Handler で出力し直す io.Writer。
+// zerolog を使う依存ライブラリの出力を zerolog.New(w) で受け取り、golog のコンソール形式に揃えるために使います。
+//
+// level, time, message 以外のフィールドは元の順序のまま属性になり、オブジェクトはグループになります。
+// JSON として解析できない行は INFO レベルのメッセージとして出力します。
+type ZerologWriter struct {
+	handler slog.Handler
+}
+
+// NewZerologWriter は handler で出力し直す ZerologWriter を作成します
+func NewZerologWriter(handler slog.Handler) *ZerologWriter {
+	return &ZerologWriter{handler: handler}
+}
+
+// Write は p に含まれる改行区切りのイベントを出力します
+func (w *ZerologWriter) Write(p []byte) (int, error) {
+	ctx := context.Background()
+	var errs []error
+	for line := range bytes.Lines(p) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		r, ok := parseZerologEvent(line)
+		if !ok {
+			r = slog.NewRecord(time.Now(), slog.LevelInfo, string(line), 0)
+		}
+		if !w.handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := w.handler.Handle(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(p), errors.Join(errs...)
+}
+
+// parseZerologEvent は1つの JSON のイベントをレコードに変換します
+func parseZerologEvent(line []byte) (slog.Record, bool) {
+	attrs, err := jsonattr.Decode(line)
+	if err != nil {
+		return slog.Record{}, false
+	}
+
+	level := slog.LevelInfo
+	t := time.Now()
+	var msg string
+	fields := attrs[:0]
+	for _, a := range attrs {
+		switch a.Key {
+		case "level":
+			if l, ok := zerologLevels[a.Value.String()]; ok {
+				level = l
+			}
+		case "time":
+			if parsed, ok := parseZerologTime(a.Value); ok {
+				t = parsed
+			}
+		case "message":
+			msg = a.Value.String()
+		default:
+			fields = append(fields, a)
+		}
+	}
+
+	r := slog.NewRecord(t, level, msg, 0)
+	r.AddAttrs(fields...)
+	return r, true
+}
+
+// parseZerologTime は RFC3339 の文字列、または Unix 時刻の数値を解析します。
+// 数値は桁数から秒、ミリ秒、マイクロ秒のいずれかと判断します。
+func parseZerologTime(v slog.Value) (time.Time, bool) {
+	switch v.Kind() {
+	case slog.KindString:
+		t, err := time.Parse(time.RFC3339Nano, v.String())
+		return t, err == nil
+	case slog.KindInt64:
+		n := v.Int64()
+		switch {
+		case n > 1e15:
+			return time.UnixMicro(n), true
+		case n > 1e12:
+			return time.UnixMilli(n), true
+		default:
+			return time.Unix(n, 0), true
+		}
+	case slog.KindFloat64:
+		f := v.Float64()
+		return time.Unix(0, int64(f*float64(time.Second))), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,40 @@
+package loggo
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestZerologWriter は zerolog のイベントが golog の形式で出力し直されることをテストします
+func TestZerologWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewZerologWriter(NewHandler(&buf, &Options{Level: slog.LevelDebug}))
+
+	events := []string{
+		`{"level":"warn","user":"alice","attempt":2,"ratio":0.5,"time":"2024-01-15T10:30:45.123Z","message":"login failed"}`,
+		`{"level":"error","error":"timeout","req":{"method":"GET","path":"/"},"tags":["a","b"],"time":1705314645,"message":"request failed"}`,
+		`{"level":"trace","message":"filtered by handler level"}`,
+		`not json`,
+	}
+	for _, e := range events {
+		fmt.Fprintln(w, e)
+	}
+
+	want := []string{
+		`[2024-01-15 10:30:45.123] [ WARN] msg="login failed" user="alice" attempt=2 ratio=0.5`,
+		`[ERROR] msg="request failed" error="timeout" req.method="GET" req.path="/" tags=["a","b"]`,
+		`[ INFO] msg="not json"`,
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %d:\n%s", len(want), len(lines), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, want[i]) {
+			t.Errorf("line %d: expected %q in %q", i, want[i], line)
+		}
+	}
+}